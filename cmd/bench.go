@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/bufpool"
+	"golamv2/pkg/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// AssumedFetchLatency is the response time of a typical remote page, used to turn the
+// measured processing rates into a worker count
+const AssumedFetchLatency = 500 * time.Millisecond
+
+// Bounds of the recommended worker count
+const (
+	MinBenchWorkers = 10
+	MaxBenchWorkers = 500
+)
+
+var (
+	benchRequests int
+	benchWrites   int
+)
+
+// benchCmd measures what this machine can sustain
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure fetch, parse and storage throughput on this machine",
+	Long: `Run short micro-benchmarks: fetching from a local test server at several
+concurrency levels, extracting emails and links from a test page, counting the
+allocations per page at 50 and 100 workers, and writing URLs and results to a
+scratch Badger database. Nothing is crawled.
+
+Prints a recommended --workers and --memory for this machine.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runBench(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().IntVar(&benchRequests, "requests", 2000, "Requests per fetch concurrency level")
+	benchCmd.Flags().IntVar(&benchWrites, "writes", 20000, "URLs and results written to the scratch database")
+	benchCmd.Flags().IntVar(&maxMemoryMB, "memory", 500, "Memory of the scratch database in MB")
+}
+
+func runBench() error {
+	page := benchPage()
+
+	fmt.Println("Fetch (local test server)")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, page)
+	}))
+	defer server.Close()
+
+	fetchRate := 0.0
+	for _, workers := range []int{10, 25, 50, 100, 200} {
+		rate := benchFetch(server.URL, workers, benchRequests)
+		fmt.Printf("  %3d workers: %8.0f pages/s\n", workers, rate)
+		fetchRate = math.Max(fetchRate, rate)
+	}
+
+	fmt.Println("Parse and extract")
+	parseRate := benchParse(page)
+	fmt.Printf("  %.0f pages/s on %d CPU(s) (%dKB page, emails and links)\n", parseRate, runtime.NumCPU(), len(page)/1024)
+
+	fmt.Println("Allocations per page (read, parse, extract)")
+	for _, workers := range []int{50, 100} {
+		before := benchAllocations(page, workers, false)
+		after := benchAllocations(page, workers, true)
+		fmt.Printf("  %3d workers: %6.0f allocs %6.0fKB parsing every step, %6.0f allocs %6.0fKB pooled and parsed once\n",
+			workers, before.allocs, before.kb, after.allocs, after.kb)
+	}
+
+	fmt.Println("Badger writes")
+	urlRate, resultRate, err := benchStorage(benchWrites)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  %.0f URLs/s, %.0f results/s\n", urlRate, resultRate)
+
+	// Pages per second the machine can process, the slowest stage sets the pace.
+	// Remote fetches mostly wait, enough workers keep that pace despite the latency
+	capacity := math.Min(fetchRate, math.Min(parseRate, resultRate))
+	workers := int(capacity * AssumedFetchLatency.Seconds())
+	workers = max(MinBenchWorkers, min(workers, MaxBenchWorkers))
+
+	bloomMB, queueMB, responsesMB := crawlStateMB(workers)
+	memory := int(math.Ceil((bloomMB+queueMB+responsesMB)/CrawlStateShare/50)) * 50
+
+	fmt.Println()
+	fmt.Printf("Recommended: --workers %d --memory %d\n", workers, memory)
+	fmt.Printf("(about %.0f pages/s at %s per remote fetch, real sites are usually slower)\n",
+		math.Min(capacity, float64(workers)/AssumedFetchLatency.Seconds()), AssumedFetchLatency)
+	return nil
+}
+
+// benchPage builds a page the size of a typical article with emails and links to find
+func benchPage() string {
+	var b strings.Builder
+	b.WriteString("<html><head><title>Benchmark page</title></head><body>")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, `<p>Paragraph %d with some text to scan, write to contact%d@example.com. `, i, i)
+		fmt.Fprintf(&b, `<a href="/article/%d?ref=bench">Article %d</a> and <a href="https://other%d.example.org/">another site</a></p>`, i, i, i%20)
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+// benchFetch returns the pages per second workers fetch from url
+func benchFetch(url string, workers, requests int) float64 {
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: workers, MaxConnsPerHost: workers},
+	}
+
+	jobs := make(chan struct{}, requests)
+	for i := 0; i < requests; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				resp, err := client.Get(url)
+				if err != nil {
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return float64(requests) / time.Since(start).Seconds()
+}
+
+// benchParse returns the pages per second the extractor gets emails and links from, on all CPUs
+func benchParse(page string) float64 {
+	extractor := infrastructure.NewContentExtractor(infrastructure.DefaultDeadLinkCheckerConfig)
+	defer extractor.Close()
+
+	var pages int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Since(start) < 2*time.Second {
+				extractor.ExtractEmails(page)
+				extractor.ExtractLinks(page, "https://example.com/")
+				atomic.AddInt64(&pages, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return float64(pages) / time.Since(start).Seconds()
+}
+
+// pageAllocations is what processing one page allocates
+type pageAllocations struct {
+	allocs float64
+	kb     float64
+}
+
+// benchAllocations returns what workers allocate per page reading it and running the
+// extraction steps of a crawl on it. Without reuse the body is read with io.ReadAll and
+// every step parses the page, as before pooled buffers and shared documents
+func benchAllocations(page string, workers int, reuse bool) pageAllocations {
+	extractor := infrastructure.NewContentExtractor(infrastructure.DefaultDeadLinkCheckerConfig)
+	defer extractor.Close()
+
+	const pagesPerWorker = 20
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			// Workers crawl different pages, they would share parsed documents otherwise
+			body := fmt.Sprintf("%s<!-- %d -->", page, worker)
+			for j := 0; j < pagesPerWorker; j++ {
+				var content string
+				if reuse {
+					content, _ = bufpool.ReadString(strings.NewReader(body), int64(len(body)))
+				} else {
+					raw, _ := io.ReadAll(strings.NewReader(body))
+					content = string(raw)
+				}
+
+				steps := []func(){
+					func() { extractor.ExtractTitle(content) },
+					func() { extractor.ExtractMetaDescription(content) },
+					func() { extractor.ExtractMetaRobots(content) },
+					func() { extractor.ExtractHiddenLinks(content, "https://example.com/") },
+					func() { extractor.ExtractEmails(content) },
+					func() { extractor.ExtractLinks(content, "https://example.com/") },
+					func() { extractor.ExtractAnchors(content, "https://example.com/") },
+				}
+				for _, step := range steps {
+					step()
+					if !reuse {
+						extractor.ReleaseDocument(content)
+					}
+				}
+				extractor.ReleaseDocument(content)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	runtime.ReadMemStats(&after)
+	pages := float64(workers * pagesPerWorker)
+	return pageAllocations{
+		allocs: float64(after.Mallocs-before.Mallocs) / pages,
+		kb:     float64(after.TotalAlloc-before.TotalAlloc) / 1024 / pages,
+	}
+}
+
+// benchStorage returns the URL and result writes per second of a scratch database
+func benchStorage(writes int) (urlRate, resultRate float64, err error) {
+	dir, err := os.MkdirTemp("", "golamv2-bench-")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create scratch directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := storage.NewBadgerStorage(dir, domain.ModeAll, maxMemoryMB)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer store.Close()
+
+	start := time.Now()
+	for i := 0; i < writes; i++ {
+		task := domain.URLTask{URL: fmt.Sprintf("https://example.com/page/%d", i), Timestamp: time.Now()}
+		if err := store.StoreURL(task); err != nil {
+			return 0, 0, fmt.Errorf("failed to store URL: %v", err)
+		}
+	}
+	urlRate = float64(writes) / time.Since(start).Seconds()
+
+	start = time.Now()
+	for i := 0; i < writes; i++ {
+		result := domain.CrawlResult{
+			URL:         fmt.Sprintf("https://example.com/page/%d", i),
+			StatusCode:  http.StatusOK,
+			Emails:      []string{"contact@example.com"},
+			ProcessedAt: time.Now(),
+		}
+		if err := store.StoreResult(result); err != nil {
+			return 0, 0, fmt.Errorf("failed to store result: %v", err)
+		}
+	}
+	resultRate = float64(writes) / time.Since(start).Seconds()
+
+	return urlRate, resultRate, nil
+}
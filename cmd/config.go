@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"golamv2/internal/domain"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variable of every flag, e.g. GOLAMV2_WORKERS for --workers
+const EnvPrefix = "GOLAMV2_"
+
+// applyConfigFile loads a YAML config whose keys are flag names, e.g.
+//
+//	url: https://example.com
+//	email: true
+//	keywords: [pricing, contact]
+//	workers: 20
+//
+// Flags given on the command line always win over the file, the file wins over the environment
+func applyConfigFile(flags *pflag.FlagSet, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	values, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range values {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("unknown setting %q in config file %s", name, path)
+		}
+		if flag.Changed {
+			continue
+		}
+
+		if err := setFlagValue(flag, configValueString(value)); err != nil {
+			return fmt.Errorf("invalid value for %q in config file: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// readConfigFile parses a YAML config into its flag name keyed values
+func readConfigFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return values, nil
+}
+
+// configValueString converts a YAML value into the string form pflag parses
+func configValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, "\n") // Items of repeatable flags may hold commas
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// envName is the environment variable of a flag, --exclude-domains is GOLAMV2_EXCLUDE_DOMAINS
+func envName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnvironment sets the flags missing from the command line from their GOLAMV2_* variable,
+// empty variables are ignored. The flags are not marked as changed so a config file still
+// overrides them
+func applyEnvironment(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		value := os.Getenv(envName(flag.Name))
+		if err != nil || value == "" || flag.Changed {
+			return
+		}
+		if setErr := setFlagValue(flag, value); setErr != nil {
+			err = fmt.Errorf("invalid value for %s: %v", envName(flag.Name), setErr)
+		}
+	})
+	return err
+}
+
+// setFlagValue sets a flag from the environment or a config file, lists are replaced rather
+// than appended to as repeated command line flags would be
+func setFlagValue(flag *pflag.Flag, value string) error {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		if flag.Value.Type() == "stringArray" {
+			return slice.Replace(splitConfigList(value, "\n"))
+		}
+		return slice.Replace(splitConfigList(value, ",\n"))
+	}
+	return flag.Value.Set(value)
+}
+
+// splitConfigList splits a config list on any of the separators, dropping empty entries
+func splitConfigList(value, separators string) []string {
+	items := []string{}
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(separators, r) }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// keywordFileRules are the rules read from --keywords-file, their keywords survive config
+// reloads that replace --keywords
+var keywordFileRules []domain.KeywordRule
+
+// withFileKeywords adds the keywords of --keywords-file that keywords lacks
+func withFileKeywords(keywords []string) []string {
+	for _, rule := range keywordFileRules {
+		if !slices.ContainsFunc(keywords, func(keyword string) bool { return strings.EqualFold(keyword, rule.Keyword) }) {
+			keywords = append(keywords, rule.Keyword)
+		}
+	}
+	return keywords
+}
+
+// readKeywordsFile parses a --keywords-file, a YAML list whose entries are a keyword or
+// phrase, or a rule like {keyword: order number, regex: 'order #?\d{6}'}
+func readKeywordsFile(path string) ([]domain.KeywordRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keywords file: %v", err)
+	}
+
+	var entries []yaml.Node
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse keywords file %s: expected a list of keywords: %v", path, err)
+	}
+
+	rules := make([]domain.KeywordRule, 0, len(entries))
+	for _, entry := range entries {
+		var rule domain.KeywordRule
+		if entry.Kind == yaml.ScalarNode {
+			rule.Keyword = entry.Value
+		} else if err := entry.Decode(&rule); err != nil {
+			return nil, fmt.Errorf("invalid keyword rule in %s line %d: %v", path, entry.Line, err)
+		}
+		rule.Keyword = strings.TrimSpace(rule.Keyword)
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, entry.Line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
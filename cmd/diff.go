@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/export"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffBefore     string
+	diffAfter      string
+	diffData       string
+	diffSession    string
+	diffBeforeFrom string
+	diffBeforeTo   string
+	diffAfterFrom  string
+	diffAfterTo    string
+	diffFormat     string
+	diffOutput     string
+	diffNamespace  string
+)
+
+// diffCmd compares the results of two crawls
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare two crawl snapshots",
+	Long: `Report what changed between two crawls of a site: pages added and removed,
+status code changes, new and resolved dead links, new and disappeared emails and
+keyword count changes. Only the newest result of every page is compared.
+
+Compare two data directories with --before and --after, or two time ranges of
+the results of one data directory with --before-from/--before-to and
+--after-from/--after-to. Times are dates like 2024-05-01, local times like
+2024-05-01T14:30 or RFC 3339, a date as the end includes that whole day.
+Both can be combined, a side without a directory reads --data and --session.
+
+Examples:
+  golamv2 diff --before golamv2_data/acme-20240501-030000 --after golamv2_data/acme-20240502-030000
+  golamv2 diff --session acme --before-to 2024-05-01 --after-from 2024-05-02 --format html -o changes.html`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runDiff(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVar(&diffBefore, "before", "", "Data directory of the earlier crawl")
+	diffCmd.Flags().StringVar(&diffAfter, "after", "", "Data directory of the later crawl")
+	diffCmd.Flags().StringVarP(&diffData, "data", "d", infrastructure.DefaultDataDir, "Path to GolamV2 data directory, for a side without --before or --after")
+	diffCmd.Flags().StringVarP(&diffSession, "session", "s", "", "Named crawl session inside the data directory")
+	diffCmd.Flags().StringVar(&diffNamespace, "namespace", "", "Compare the crawls kept under this --namespace")
+	diffCmd.Flags().StringVar(&diffBeforeFrom, "before-from", "", "Compare the results processed from this time on")
+	diffCmd.Flags().StringVar(&diffBeforeTo, "before-to", "", "Compare the results processed before this time")
+	diffCmd.Flags().StringVar(&diffAfterFrom, "after-from", "", "Compare with the results processed from this time on")
+	diffCmd.Flags().StringVar(&diffAfterTo, "after-to", "", "Compare with the results processed before this time")
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "json", "Report format (json|csv|html)")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", "Output file (default: stdout)")
+}
+
+func runDiff() error {
+	if !slices.Contains(export.ChangeReportFormats, diffFormat) {
+		return fmt.Errorf("unknown report format %q (available: %s)", diffFormat, strings.Join(export.ChangeReportFormats, ", "))
+	}
+
+	beforeRange, err := domain.ParseTimeRange(diffBeforeFrom, diffBeforeTo)
+	if err != nil {
+		return err
+	}
+	afterRange, err := domain.ParseTimeRange(diffAfterFrom, diffAfterTo)
+	if err != nil {
+		return err
+	}
+
+	beforeDir, afterDir := diffBefore, diffAfter
+	if beforeDir == "" || afterDir == "" {
+		dataDir, err := sessionDataDir(diffData, diffSession)
+		if err != nil {
+			return err
+		}
+		if beforeDir == "" {
+			beforeDir = dataDir
+		}
+		if afterDir == "" {
+			afterDir = dataDir
+		}
+	}
+	if beforeDir == afterDir && beforeRange == afterRange {
+		return fmt.Errorf("nothing to compare, give two data directories with --before and --after or two time ranges")
+	}
+
+	before, err := readResultsInRange(beforeDir, beforeRange)
+	if err != nil {
+		return err
+	}
+	after, err := readResultsInRange(afterDir, afterRange)
+	if err != nil {
+		return err
+	}
+
+	report := domain.CompareResults(before, after)
+	report.Before, report.After = describeSnapshot(beforeDir, beforeRange), describeSnapshot(afterDir, afterRange)
+
+	var w io.Writer = os.Stdout
+	if diffOutput != "" {
+		file, err := os.Create(diffOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := export.WriteChangeReportAs(w, diffFormat, report); err != nil {
+		return err
+	}
+
+	if diffOutput != "" {
+		summary := report.Summary()
+		fmt.Printf("%d pages added, %d removed, %d status changes, %d new dead links, %d resolved, %d new emails, %d disappeared, %d keyword counts changed\n",
+			summary.AddedURLs, summary.RemovedURLs, summary.StatusChanges, summary.NewDeadLinks, summary.ResolvedDeadLinks,
+			summary.NewEmails, summary.DisappearedEmails, summary.KeywordChanges)
+		fmt.Printf("Report written to %s\n", diffOutput)
+	}
+	return nil
+}
+
+// readResultsInRange reads the results of a data directory processed within r
+func readResultsInRange(dataDir string, r domain.TimeRange) ([]domain.CrawlResult, error) {
+	results, err := readResults(dataDir, diffNamespace)
+	if err != nil || r.IsZero() {
+		return results, err
+	}
+
+	within := results[:0]
+	for _, result := range results {
+		if r.Contains(result.ProcessedAt) {
+			within = append(within, result)
+		}
+	}
+	return within, nil
+}
+
+// describeSnapshot names one side of the comparison in the report
+func describeSnapshot(dataDir string, r domain.TimeRange) string {
+	const layout = "2006-01-02 15:04"
+	switch {
+	case r.IsZero():
+		return dataDir
+	case r.From.IsZero():
+		return fmt.Sprintf("%s before %s", dataDir, r.To.Format(layout))
+	case r.To.IsZero():
+		return fmt.Sprintf("%s from %s", dataDir, r.From.Format(layout))
+	default:
+		return fmt.Sprintf("%s from %s to %s", dataDir, r.From.Format(layout), r.To.Format(layout))
+	}
+}
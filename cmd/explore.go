@@ -7,25 +7,33 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"golamv2/internal/domain"
+	"golamv2/pkg/export"
+	"golamv2/pkg/storage"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/spf13/cobra"
 )
 
 const (
-	URLPrefix    = "url:"
-	ResultPrefix = "result:"
-	MetricsKey   = "metrics"
+	URLPrefix        = "url:"
+	ResultPrefix     = "result:"
+	DeadLetterPrefix = "deadletter:"
+	DNSPrefix        = "dns:"
+	MetricsKey       = "metrics"
 )
 
 var (
-	dataPath   string
-	outputFile string
+	dataPath         string
+	outputFile       string
+	exploreSession   string
+	exploreNamespace string
 )
 
 // exploreCmd - the explore command
@@ -48,6 +56,8 @@ func init() {
 	rootCmd.AddCommand(exploreCmd)
 	exploreCmd.Flags().StringVarP(&dataPath, "data", "d", "golamv2_data", "Path to GolamV2 data directory")
 	exploreCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file for exports (optional)")
+	exploreCmd.Flags().StringVarP(&exploreSession, "session", "s", "", "Explore a named crawl session inside the data directory")
+	exploreCmd.Flags().StringVar(&exploreNamespace, "namespace", "", "Explore the crawl kept under this --namespace")
 }
 
 type Explorer struct {
@@ -55,10 +65,17 @@ type Explorer struct {
 	resultsDB *badger.DB
 	dataPath  string
 	scanner   *bufio.Scanner
+	namespace string // Empty for the default namespace
+	keyPrefix string // What the keys of the namespace start with
 }
 
 func runExplore() error {
-	explorer, err := NewExplorer(dataPath)
+	path, err := sessionDataDir(dataPath, exploreSession)
+	if err != nil {
+		return err
+	}
+
+	explorer, err := NewExplorer(path, exploreNamespace)
 	if err != nil {
 		return fmt.Errorf("failed to initialize explorer: %v", err)
 	}
@@ -69,7 +86,15 @@ func runExplore() error {
 	return nil
 }
 
-func NewExplorer(dbPath string) (*Explorer, error) {
+// NewExplorer opens the databases of a data directory, namespace picks the crawl of a
+// --namespace instead of the default one
+func NewExplorer(dbPath, namespace string) (*Explorer, error) {
+	if namespace != "" {
+		if err := storage.ValidateNamespace(namespace); err != nil {
+			return nil, err
+		}
+	}
+
 	// Check if data directory exists
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("data directory not found: %s", dbPath)
@@ -97,9 +122,16 @@ func NewExplorer(dbPath string) (*Explorer, error) {
 		resultsDB: resultsDB,
 		dataPath:  dbPath,
 		scanner:   bufio.NewScanner(os.Stdin),
+		namespace: namespace,
+		keyPrefix: storage.NamespaceKeyPrefix(namespace),
 	}, nil
 }
 
+// key puts a key into the namespace explored
+func (e *Explorer) key(key string) []byte {
+	return []byte(e.keyPrefix + key)
+}
+
 func (e *Explorer) Close() {
 	if e.urlDB != nil {
 		e.urlDB.Close()
@@ -114,20 +146,34 @@ func (e *Explorer) printBanner() {
 	fmt.Println("========================")
 	fmt.Println("Interactive tool to explore crawl data")
 	fmt.Printf("Data path: %s\n", e.dataPath)
+	if e.namespace != "" {
+		fmt.Printf("Namespace: %s\n", e.namespace)
+	}
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  help          - Show this help")
 	fmt.Println("  stats         - Show database statistics")
 	fmt.Println("  urls [limit]  - List URLs (default: 10)")
 	fmt.Println("  results [limit] - List results (default: 10)")
-	fmt.Println("  search <term> - Search in results")
+	fmt.Println("  label <name> [limit] - List results tagged with a label")
+	fmt.Println("  search <term> [domain:<host>] [status:<code|4xx>] [type:<email|keyword|dead_link|dead_domain|error>] [label:<name>] - Search in results")
+	fmt.Println("  lookup <email|keyword|domain> <value> [limit] - Pages with an email, a keyword or a host, read from the indexes")
 	fmt.Println("  emails [limit] - Show found emails")
 	fmt.Println("  keywords [limit] - Show found keywords")
 	fmt.Println("  deadlinks [limit] - Show dead links")
-	fmt.Println("  export <type> - Export data (urls|results|emails|keywords)")
+	fmt.Println("  report [limit] - Emails grouped by mail domain")
+	fmt.Println("  report deadlinks [limit|json|csv|html] - Dead links grouped by source page, printed or written to a file")
+	fmt.Println("  a11y [limit]  - Accessibility issues by domain (--a11y)")
+	fmt.Println("  deadletter list [limit] - URLs that failed all their retries")
+	fmt.Println("  deadletter requeue <url|all> - Queue dead letters for the next crawl")
+	fmt.Println("  slow [limit]  - Slowest fetches, recorded with --slow-threshold")
+	fmt.Println("  dns list [limit] - DNS records of crawled hosts (--dns-records)")
+	fmt.Println("  dns shared    - Addresses, mail hosts and name servers shared by several hosts")
+	fmt.Println("  duplicates [title|description|content] [limit] - Titles or descriptions shared by several pages of a domain, or pages with the same content")
+	fmt.Println("  export <type> [format] - Export data (urls|results|emails|keywords), results also as csv, ndjson, parquet, sqlite, xlsx, email-report, deadlink-report, a11y-report, graphml or dot")
 	fmt.Println("  raw <key>     - Show raw data for specific key")
 	fmt.Println("  analyze       - Detailed analysis of crawl data")
-	fmt.Println("  timeline      - Show crawling timeline")
+	fmt.Println("  timeline [from] [to] - Show crawling timeline, of the results processed from a date or time like 2024-05-01 or 2024-05-01T14:30 up to another")
 	fmt.Println("  domains       - Show domain statistics")
 	fmt.Println("  clear         - Clear screen")
 	fmt.Println("  quit/exit     - Exit explorer")
@@ -169,14 +215,38 @@ func (e *Explorer) runInteractiveShell() {
 					limit = l
 				}
 			}
-			e.listResults(limit)
+			e.listResults(limit, "")
+		case "label":
+			if len(parts) < 2 {
+				fmt.Println("Usage: label <name> [limit]")
+				continue
+			}
+			limit := 10
+			if len(parts) > 2 {
+				if l, err := strconv.Atoi(parts[2]); err == nil {
+					limit = l
+				}
+			}
+			e.listResults(limit, parts[1])
 		case "search":
 			if len(parts) < 2 {
-				fmt.Println("Usage: search <term>")
+				fmt.Println("Usage: search <term> [domain:<host>] [status:<code|4xx>] [type:<type>] [label:<name>]")
 				continue
 			}
 			term := strings.Join(parts[1:], " ")
 			e.searchResults(term)
+		case "lookup":
+			if len(parts) < 3 {
+				fmt.Println("Usage: lookup <email|keyword|domain> <value> [limit]")
+				continue
+			}
+			limit := 10
+			if len(parts) > 3 {
+				if l, err := strconv.Atoi(parts[3]); err == nil {
+					limit = l
+				}
+			}
+			e.lookupResults(parts[1], parts[2], limit)
 		case "emails":
 			limit := 10
 			if len(parts) > 1 {
@@ -201,12 +271,119 @@ func (e *Explorer) runInteractiveShell() {
 				}
 			}
 			e.showDeadLinks(limit)
+		case "report":
+			// report [limit] is the email report, like report emails [limit]
+			kind, args := "emails", parts[1:]
+			if len(args) > 0 {
+				if _, err := strconv.Atoi(args[0]); err != nil {
+					kind, args = strings.ToLower(args[0]), args[1:]
+				}
+			}
+			limit := 10
+			format := ""
+			if len(args) > 0 {
+				if l, err := strconv.Atoi(args[0]); err == nil {
+					limit = l
+				} else {
+					format = strings.ToLower(args[0])
+				}
+			}
+			switch {
+			case kind == "emails" && format == "":
+				e.showEmailReport(limit)
+			case kind == "deadlinks" && format == "":
+				e.showDeadLinkReport(limit)
+			case kind == "deadlinks":
+				e.writeDeadLinkReport(format)
+			default:
+				fmt.Println("Usage: report [emails] [limit] | report deadlinks [limit|json|csv|html]")
+			}
+		case "a11y":
+			limit := 10
+			if len(parts) > 1 {
+				if l, err := strconv.Atoi(parts[1]); err == nil {
+					limit = l
+				}
+			}
+			e.showAccessibilityReport(limit)
+		case "deadletter":
+			if len(parts) < 2 {
+				fmt.Println("Usage: deadletter list [limit] | deadletter requeue <url|all>")
+				continue
+			}
+			switch strings.ToLower(parts[1]) {
+			case "list":
+				limit := 10
+				if len(parts) > 2 {
+					if l, err := strconv.Atoi(parts[2]); err == nil {
+						limit = l
+					}
+				}
+				e.listDeadLetters(limit)
+			case "requeue":
+				if len(parts) < 3 {
+					fmt.Println("Usage: deadletter requeue <url|all>")
+					continue
+				}
+				e.requeueDeadLetters(parts[2])
+			default:
+				fmt.Println("Usage: deadletter list [limit] | deadletter requeue <url|all>")
+			}
+		case "slow":
+			limit := 10
+			if len(parts) > 1 {
+				if l, err := strconv.Atoi(parts[1]); err == nil {
+					limit = l
+				}
+			}
+			e.listSlowRequests(limit)
+		case "dns":
+			if len(parts) < 2 {
+				fmt.Println("Usage: dns list [limit] | dns shared")
+				continue
+			}
+			switch strings.ToLower(parts[1]) {
+			case "list":
+				limit := 10
+				if len(parts) > 2 {
+					if l, err := strconv.Atoi(parts[2]); err == nil {
+						limit = l
+					}
+				}
+				e.listDNSRecords(limit)
+			case "shared":
+				e.showSharedInfrastructure()
+			default:
+				fmt.Println("Usage: dns list [limit] | dns shared")
+			}
+		case "duplicates":
+			kind, limit := domain.MetaTitle, 10
+			for _, arg := range parts[1:] {
+				if l, err := strconv.Atoi(arg); err == nil {
+					limit = l
+				} else if arg == domain.MetaTitle || arg == domain.MetaDescription || arg == "content" {
+					kind = arg
+				} else {
+					fmt.Println("Usage: duplicates [title|description|content] [limit]")
+					kind = ""
+					break
+				}
+			}
+			if kind == "content" {
+				e.showDuplicatePages(limit)
+			} else if kind != "" {
+				e.showDuplicateMeta(kind, limit)
+			}
 		case "export":
 			if len(parts) < 2 {
-				fmt.Println("Usage: export <type> (urls|results|emails|keywords)")
+				fmt.Println("Usage: export <type> [format] (urls|results|emails|keywords) [json|csv|ndjson|parquet|sqlite|xlsx|email-report|deadlink-report|a11y-report|graphml|dot]")
 				continue
 			}
-			e.exportData(parts[1])
+			format := "json"
+			if len(parts) > 2 {
+				format = parts[2]
+			}
+			e.exportData(parts[1], format)
 		case "raw":
 			if len(parts) < 2 {
 				fmt.Println("Usage: raw <key>")
@@ -217,7 +394,19 @@ func (e *Explorer) runInteractiveShell() {
 		case "analyze":
 			e.analyzeData()
 		case "timeline":
-			e.showTimeline()
+			from, to := "", ""
+			if len(parts) > 1 {
+				from = parts[1]
+			}
+			if len(parts) > 2 {
+				to = parts[2]
+			}
+			timeRange, err := domain.ParseTimeRange(from, to)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			e.showTimeline(timeRange)
 		case "domains":
 			e.showDomainStats()
 		case "clear":
@@ -243,7 +432,7 @@ func (e *Explorer) showStats() {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(URLPrefix)
+		prefix := e.key(URLPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			urlCount++
 		}
@@ -262,7 +451,7 @@ func (e *Explorer) showStats() {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 			err := item.Value(func(val []byte) error {
@@ -308,11 +497,11 @@ func (e *Explorer) listURLs(limit int) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(URLPrefix)
+		prefix := e.key(URLPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix) && count < limit; it.Next() {
 			item := it.Item()
 			key := item.Key()
-			url := string(key[len(URLPrefix):])
+			url := string(key[len(e.key(URLPrefix)):])
 
 			err := item.Value(func(val []byte) error {
 				var task domain.URLTask
@@ -338,8 +527,331 @@ func (e *Explorer) listURLs(limit int) {
 	fmt.Println()
 }
 
-func (e *Explorer) listResults(limit int) {
-	fmt.Printf("\nResults (showing %d):\n", limit)
+func (e *Explorer) listDeadLetters(limit int) {
+	fmt.Printf("\n Dead Letters (showing %d):\n", limit)
+	fmt.Println("============================")
+
+	count := 0
+	e.urlDB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := e.key(DeadLetterPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix) && count < limit; it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var letter domain.DeadLetter
+				if err := json.Unmarshal(val, &letter); err == nil {
+					fmt.Printf("%d. %s\n", count+1, letter.Task.URL)
+					fmt.Printf("   Error: %s\n", letter.Error)
+					fmt.Printf("   Depth: %d, Retries: %d\n", letter.Task.Depth, letter.Task.Retries)
+					fmt.Printf("   Failed: %s\n", letter.FailedAt.Format("2006-01-02 15:04:05"))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			count++
+			fmt.Println()
+		}
+		return nil
+	})
+
+	if count == 0 {
+		fmt.Println("No dead letters found.")
+	}
+	fmt.Println()
+}
+
+// requeueDeadLetters moves dead letters back into the URL queue with fresh retries,
+// target is a URL or "all". The next crawl on this data picks them up
+func (e *Explorer) requeueDeadLetters(target string) {
+	requeued := 0
+	err := e.urlDB.Update(func(txn *badger.Txn) error {
+		var letters []domain.DeadLetter
+
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		prefix := e.key(DeadLetterPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if target != "all" && string(it.Item().Key()[len(e.key(DeadLetterPrefix)):]) != target {
+				continue
+			}
+			err := it.Item().Value(func(val []byte) error {
+				var letter domain.DeadLetter
+				if err := json.Unmarshal(val, &letter); err != nil {
+					return err
+				}
+				letters = append(letters, letter)
+				return nil
+			})
+			if err != nil {
+				it.Close()
+				return err
+			}
+		}
+		it.Close()
+
+		for _, letter := range letters {
+			task := letter.Task
+			task.Retries, task.RobotsWaits, task.ChallengeWaits = 0, 0, 0
+			task.Timestamp = time.Now()
+
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			if err := txn.Set(e.key(URLPrefix+task.URL), data); err != nil {
+				return err
+			}
+			if err := txn.Delete(e.key(DeadLetterPrefix + task.URL)); err != nil {
+				return err
+			}
+			requeued++
+		}
+		return nil
+	})
+
+	if err != nil {
+		fmt.Printf("Error requeueing dead letters: %v\n", err)
+		return
+	}
+	if requeued == 0 {
+		fmt.Println("No matching dead letters found.")
+		return
+	}
+	fmt.Printf("Requeued %d URLs, the next crawl on this data will fetch them\n", requeued)
+}
+
+// listSlowRequests lists the fetches slower than --slow-threshold, slowest first
+func (e *Explorer) listSlowRequests(limit int) {
+	var requests []domain.SlowRequest
+	err := e.urlDB.View(func(txn *badger.Txn) error {
+		var err error
+		requests, err = storage.ReadSlowRequests(txn, e.keyPrefix, limit)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Error reading slow requests: %v\n", err)
+		return
+	}
+	if len(requests) == 0 {
+		fmt.Println("No slow requests found, crawl with --slow-threshold to record them.")
+		return
+	}
+
+	fmt.Printf("\n Slowest Fetches (showing %d):\n", len(requests))
+	fmt.Println("============================")
+	for i, request := range requests {
+		fmt.Printf("%d. %s\n", i+1, request.URL)
+		status := "no answer"
+		if request.StatusCode != 0 {
+			status = strconv.Itoa(request.StatusCode)
+		}
+		fmt.Printf("   Took: %s, Status: %s, Bytes: %d, Worker: %d\n", request.Duration.Round(time.Millisecond), status, request.Bytes, request.Worker)
+		if request.Error != "" {
+			fmt.Printf("   Error: %s\n", request.Error)
+		}
+		fmt.Printf("   Fetched: %s\n", request.FetchedAt.Format("2006-01-02 15:04:05"))
+		fmt.Println()
+	}
+}
+
+// showDuplicateMeta lists the titles or descriptions shared by several pages of a domain, largest groups first
+func (e *Explorer) showDuplicateMeta(kind string, limit int) {
+	var groups []domain.DuplicateMeta
+	err := e.urlDB.View(func(txn *badger.Txn) error {
+		var err error
+		groups, err = storage.ScanDuplicateMeta(txn, e.keyPrefix, kind, "")
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Error reading page %ss: %v\n", kind, err)
+		return
+	}
+	if len(groups) == 0 {
+		fmt.Printf("No duplicate %ss found.\n", kind)
+		return
+	}
+
+	fmt.Printf("\n Duplicate %ss (%d groups, showing %d):\n", kind, len(groups), min(limit, len(groups)))
+	fmt.Println("==========================================")
+	for i, group := range groups {
+		if i >= limit {
+			break
+		}
+		fmt.Printf("%d. %s - %d pages\n   %q\n", i+1, group.Domain, len(group.URLs), truncateString(group.Value, 100))
+		for _, url := range group.URLs {
+			fmt.Printf("   %s\n", url)
+		}
+		fmt.Println()
+	}
+}
+
+// showDuplicatePages lists the pages flagged as duplicates while crawling under the page they
+// duplicate, largest groups first
+func (e *Explorer) showDuplicatePages(limit int) {
+	results, err := e.exportResults()
+	if err != nil {
+		fmt.Printf("Error reading results: %v\n", err)
+		return
+	}
+
+	// Recrawled pages have several results, each page is listed once
+	groups := make(map[string][]domain.CrawlResult)
+	listed := make(map[string]bool)
+	for _, result := range results {
+		if result.DuplicateOf == "" || listed[result.URL] {
+			continue
+		}
+		listed[result.URL] = true
+		groups[result.DuplicateOf] = append(groups[result.DuplicateOf], result)
+	}
+	if len(groups) == 0 {
+		fmt.Println("No duplicate pages found.")
+		return
+	}
+
+	originals := make([]string, 0, len(groups))
+	for original := range groups {
+		originals = append(originals, original)
+	}
+	sort.Slice(originals, func(i, j int) bool {
+		if len(groups[originals[i]]) != len(groups[originals[j]]) {
+			return len(groups[originals[i]]) > len(groups[originals[j]])
+		}
+		return originals[i] < originals[j]
+	})
+
+	fmt.Printf("\n Duplicate pages (%d groups, showing %d):\n", len(originals), min(limit, len(originals)))
+	fmt.Println("==========================================")
+	for i, original := range originals {
+		if i >= limit {
+			break
+		}
+		fmt.Printf("%d. %s - %d duplicate(s)\n", i+1, original, len(groups[original]))
+		for _, result := range groups[original] {
+			if result.NearDuplicate {
+				fmt.Printf("   %s (near, simhash %s)\n", result.URL, result.SimHash)
+			} else {
+				fmt.Printf("   %s\n", result.URL)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// forEachDNSRecords calls fn with the DNS records of every host, until it returns false
+func (e *Explorer) forEachDNSRecords(fn func(records domain.DNSRecords) bool) error {
+	return e.urlDB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := e.key(DNSPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var records domain.DNSRecords
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &records)
+			})
+			if err != nil {
+				continue
+			}
+			if !fn(records) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func (e *Explorer) listDNSRecords(limit int) {
+	fmt.Printf("\n DNS Records (showing %d):\n", limit)
+	fmt.Println("===========================")
+
+	count := 0
+	e.forEachDNSRecords(func(records domain.DNSRecords) bool {
+		count++
+		fmt.Printf("%d. %s (%s)\n", count, records.Host, records.Domain)
+		for _, kind := range []struct {
+			name   string
+			values []string
+		}{
+			{"A", records.A}, {"AAAA", records.AAAA}, {"MX", records.MX}, {"NS", records.NS}, {"TXT", records.TXT},
+		} {
+			if len(kind.values) > 0 {
+				fmt.Printf("   %-5s %s\n", kind.name+":", strings.Join(kind.values, ", "))
+			}
+		}
+		for _, failed := range records.Errors {
+			fmt.Printf("   Error: %s\n", failed)
+		}
+		fmt.Println()
+		return count < limit
+	})
+
+	if count == 0 {
+		fmt.Println("No DNS records found, crawl with --dns-records to collect them.")
+	}
+	fmt.Println()
+}
+
+// showSharedInfrastructure groups hosts by the addresses, mail hosts and name servers they
+// share, revealing shared hosting and common mail or DNS providers
+func (e *Explorer) showSharedInfrastructure() {
+	shared := map[string]map[string][]string{"Addresses": {}, "Mail hosts": {}, "Name servers": {}}
+	hosts := 0
+	e.forEachDNSRecords(func(records domain.DNSRecords) bool {
+		hosts++
+		for _, addr := range append(records.A, records.AAAA...) {
+			shared["Addresses"][addr] = append(shared["Addresses"][addr], records.Host)
+		}
+		for _, mx := range records.MX {
+			shared["Mail hosts"][mx] = append(shared["Mail hosts"][mx], records.Host)
+		}
+		for _, ns := range records.NS {
+			shared["Name servers"][ns] = append(shared["Name servers"][ns], records.Host)
+		}
+		return true
+	})
+
+	if hosts == 0 {
+		fmt.Println("No DNS records found, crawl with --dns-records to collect them.")
+		return
+	}
+
+	fmt.Printf("\n Shared Infrastructure (%d hosts):\n", hosts)
+	fmt.Println("==================================")
+	for _, kind := range []string{"Addresses", "Mail hosts", "Name servers"} {
+		var targets []string
+		for target, users := range shared[kind] {
+			if len(users) > 1 {
+				targets = append(targets, target)
+			}
+		}
+		// Most shared first
+		sort.Slice(targets, func(i, j int) bool {
+			a, b := len(shared[kind][targets[i]]), len(shared[kind][targets[j]])
+			if a != b {
+				return a > b
+			}
+			return targets[i] < targets[j]
+		})
+
+		fmt.Printf("\n%s shared by several hosts: %d\n", kind, len(targets))
+		for _, target := range targets {
+			users := shared[kind][target]
+			fmt.Printf("  %s (%d): %s\n", target, len(users), strings.Join(users, ", "))
+		}
+	}
+	fmt.Println()
+}
+
+// listResults prints stored results, only those tagged with label unless it is empty
+func (e *Explorer) listResults(limit int, label string) {
+	if label != "" {
+		fmt.Printf("\nResults labelled %s (showing %d):\n", label, limit)
+	} else {
+		fmt.Printf("\nResults (showing %d):\n", limit)
+	}
 	fmt.Println("========================")
 
 	count := 0
@@ -348,13 +860,15 @@ func (e *Explorer) listResults(limit int) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix) && count < limit; it.Next() {
 			item := it.Item()
 
+			matched := false
 			err := item.Value(func(val []byte) error {
 				var result domain.CrawlResult
-				if err := json.Unmarshal(val, &result); err == nil {
+				if err := json.Unmarshal(val, &result); err == nil && (label == "" || result.HasLabel(label)) {
+					matched = true
 					fmt.Printf("%d. %s\n", count+1, result.URL)
 					fmt.Printf("   Status: %d, Title: %s\n", result.StatusCode, truncateString(result.Title, 50))
 					fmt.Printf("   Processed: %s\n", result.ProcessedAt.Format("2006-01-02 15:04:05"))
@@ -369,6 +883,9 @@ func (e *Explorer) listResults(limit int) {
 					if len(result.DeadLinks) > 0 {
 						fmt.Printf("   Dead Links: %d found\n", len(result.DeadLinks))
 					}
+					if len(result.Labels) > 0 {
+						fmt.Printf("   Labels: %s\n", strings.Join(result.Labels, ", "))
+					}
 					if result.Error != "" {
 						fmt.Printf("   Error: %s\n", truncateString(result.Error, 100))
 					}
@@ -378,6 +895,9 @@ func (e *Explorer) listResults(limit int) {
 			if err != nil {
 				return err
 			}
+			if !matched {
+				continue
+			}
 			count++
 			fmt.Println()
 		}
@@ -390,8 +910,14 @@ func (e *Explorer) listResults(limit int) {
 	fmt.Println()
 }
 
-func (e *Explorer) searchResults(term string) {
-	fmt.Printf("\n Search results for '%s':\n", term)
+func (e *Explorer) searchResults(search string) {
+	term, filters, err := domain.ParseResultQuery(search)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\n Search results for '%s':\n", search)
 	fmt.Println("============================")
 
 	lowerTerm := strings.ToLower(term)
@@ -402,58 +928,46 @@ func (e *Explorer) searchResults(term string) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
 			err := item.Value(func(val []byte) error {
 				var result domain.CrawlResult
-				if err := json.Unmarshal(val, &result); err == nil {
-					// Search in URL, title, emails, and keywords
-					found := false
-					if strings.Contains(strings.ToLower(result.URL), lowerTerm) ||
-						strings.Contains(strings.ToLower(result.Title), lowerTerm) {
-						found = true
-					}
+				if err := json.Unmarshal(val, &result); err != nil || !domain.MatchesResult(result, term, filters) {
+					return nil
+				}
 
-					// Search in emails
-					for _, email := range result.Emails {
-						if strings.Contains(strings.ToLower(email), lowerTerm) {
-							found = true
-							break
-						}
+				count++
+				fmt.Printf("%d. %s\n", count, result.URL)
+				fmt.Printf("   Status: %d, Title: %s\n", result.StatusCode, truncateString(result.Title, 60))
+				fmt.Printf("   Processed: %s\n", result.ProcessedAt.Format("2006-01-02 15:04:05"))
+				if term == "" {
+					fmt.Println()
+					return nil
+				}
+
+				// Show matching emails
+				for _, email := range result.Emails {
+					if strings.Contains(strings.ToLower(email), lowerTerm) {
+						fmt.Printf("    Email: %s\n", email)
 					}
+				}
 
-					// Search in keywords
-					for keyword := range result.Keywords {
-						if strings.Contains(strings.ToLower(keyword), lowerTerm) {
-							found = true
-							break
-						}
+				// Show matching keywords
+				for keyword, freq := range result.Keywords {
+					if strings.Contains(strings.ToLower(keyword), lowerTerm) {
+						fmt.Printf("    Keyword: %s (%d times)\n", keyword, freq)
 					}
+				}
 
-					if found {
-						count++
-						fmt.Printf("%d. %s\n", count, result.URL)
-						fmt.Printf("   Title: %s\n", truncateString(result.Title, 60))
-						fmt.Printf("   Processed: %s\n", result.ProcessedAt.Format("2006-01-02 15:04:05"))
-
-						// Show matching emails
-						for _, email := range result.Emails {
-							if strings.Contains(strings.ToLower(email), lowerTerm) {
-								fmt.Printf("    Email: %s\n", email)
-							}
-						}
-
-						// Show matching keywords
-						for keyword, freq := range result.Keywords {
-							if strings.Contains(strings.ToLower(keyword), lowerTerm) {
-								fmt.Printf("    Keyword: %s (%d times)\n", keyword, freq)
-							}
-						}
-						fmt.Println()
+				// Show matching dead links
+				for _, link := range result.DeadLinks {
+					if strings.Contains(strings.ToLower(link), lowerTerm) {
+						fmt.Printf("    Dead link: %s\n", link)
 					}
 				}
+				fmt.Println()
 				return nil
 			})
 			if err != nil {
@@ -464,13 +978,55 @@ func (e *Explorer) searchResults(term string) {
 	})
 
 	if count == 0 {
-		fmt.Printf("No results found for '%s'.\n", term)
+		fmt.Printf("No results found for '%s'.\n", search)
 	} else {
 		fmt.Printf("Found %d matching results.\n", count)
 	}
 	fmt.Println()
 }
 
+// lookupIndexes maps the lookup command's index names to their key prefixes
+var lookupIndexes = map[string]string{
+	"email":   storage.EmailIndexPrefix,
+	"keyword": storage.KeywordIndexPrefix,
+	"domain":  storage.DomainIndexPrefix,
+}
+
+func (e *Explorer) lookupResults(index, value string, limit int) {
+	prefix, ok := lookupIndexes[index]
+	if !ok {
+		fmt.Println("Usage: lookup <email|keyword|domain> <value> [limit]")
+		return
+	}
+
+	var results []domain.CrawlResult
+	var next string
+	err := e.resultsDB.View(func(txn *badger.Txn) error {
+		var err error
+		results, next, err = storage.LookupIndex(txn, e.keyPrefix, prefix, value, "", limit)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\nPages with %s %s:\n", index, value)
+	fmt.Println("========================")
+	for i, result := range results {
+		fmt.Printf("%d. %s\n", i+1, result.URL)
+		fmt.Printf("   Status: %d, Title: %s\n", result.StatusCode, truncateString(result.Title, 50))
+		fmt.Printf("   Processed: %s\n", result.ProcessedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No pages found. Databases written before the indexes are indexed when a crawl opens them.")
+	} else if next != "" {
+		fmt.Printf("Showing the first %d pages.\n", len(results))
+	}
+	fmt.Println()
+}
+
 func (e *Explorer) showEmails(limit int) {
 	fmt.Printf("\n Found Emails (showing %d):\n", limit)
 	fmt.Println("=============================")
@@ -483,7 +1039,7 @@ func (e *Explorer) showEmails(limit int) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -539,7 +1095,7 @@ func (e *Explorer) showKeywords(limit int) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -598,7 +1154,7 @@ func (e *Explorer) showDeadLinks(limit int) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -645,16 +1201,166 @@ func (e *Explorer) showDeadLinks(limit int) {
 	fmt.Println()
 }
 
-func (e *Explorer) exportData(dataType string) {
-	filename := fmt.Sprintf("golamv2_%s_export_%s.json", dataType, time.Now().Format("20060102_150405"))
+// showEmailReport prints found emails grouped by mail domain
+func (e *Explorer) showEmailReport(limit int) {
+	fmt.Printf("\n Emails by Domain (showing %d):\n", limit)
+	fmt.Println("=================================")
+
+	results, err := e.exportResults()
+	if err != nil {
+		fmt.Printf("Error reading results: %v\n", err)
+		return
+	}
+
+	reports := export.BuildEmailReport(results)
+	for i, report := range reports {
+		if i >= limit {
+			fmt.Printf("... and %d more domains\n", len(reports)-limit)
+			break
+		}
+
+		fmt.Printf("%d. %s\n", i+1, report.Domain)
+		fmt.Printf("   %d email(s), found %d time(s) on %d page(s)\n", len(report.Emails), report.Occurrences, report.SourcePages)
+		fmt.Printf("   First seen: %s, last seen: %s\n", report.FirstSeen.Format("2006-01-02 15:04:05"), report.LastSeen.Format("2006-01-02 15:04:05"))
+		for j, email := range report.Emails {
+			if j == 3 {
+				fmt.Printf("   - ... and %d more\n", len(report.Emails)-3)
+				break
+			}
+			fmt.Printf("   - %s\n", email)
+		}
+		fmt.Println()
+	}
+
+	if len(reports) == 0 {
+		fmt.Println("No emails found in database.")
+	}
+	fmt.Println()
+}
+
+// showDeadLinkReport prints dead links grouped by the page linking to them
+func (e *Explorer) showDeadLinkReport(limit int) {
+	fmt.Printf("\n Dead Links by Source Page (showing %d):\n", limit)
+	fmt.Println("===========================================")
+
+	results, err := e.exportResults()
+	if err != nil {
+		fmt.Printf("Error reading results: %v\n", err)
+		return
+	}
+
+	pages := export.BuildDeadLinkReport(results)
+	for i, page := range pages {
+		if i >= limit {
+			fmt.Printf("... and %d more pages\n", len(pages)-limit)
+			break
+		}
+
+		fmt.Printf("%d. %s (%d dead)\n", i+1, page.SourceURL, len(page.Links))
+		for _, link := range page.Links {
+			status := "no answer"
+			if link.StatusCode != 0 {
+				status = fmt.Sprint(link.StatusCode)
+			}
+			fmt.Printf("   - %s [%s] first seen %s\n", link.URL, status, link.FirstSeen.Format("2006-01-02 15:04:05"))
+		}
+		fmt.Println()
+	}
+
+	if len(pages) == 0 {
+		fmt.Println("No dead links found in database.")
+	}
+	fmt.Println()
+}
+
+// writeDeadLinkReport writes the dead link report to a file as json, csv or html
+func (e *Explorer) writeDeadLinkReport(format string) {
+	if !slices.Contains(export.DeadLinkReportFormats, format) {
+		fmt.Printf("Unknown report format %q, expected %s\n", format, strings.Join(export.DeadLinkReportFormats, ", "))
+		return
+	}
+
+	filename := fmt.Sprintf("golamv2_deadlinks_report_%s.%s", time.Now().Format("20060102_150405"), format)
+	if outputFile != "" {
+		filename = outputFile
+	}
+
+	results, err := e.exportResults()
+	if err != nil {
+		fmt.Printf("Error reading results: %v\n", err)
+		return
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	if err := export.WriteDeadLinkReportAs(file, format, results); err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Dead link report written to %s\n", filename)
+}
+
+func (e *Explorer) showAccessibilityReport(limit int) {
+	fmt.Printf("\n Accessibility by Domain (showing %d):\n", limit)
+	fmt.Println("========================================")
+
+	results, err := e.exportResults()
+	if err != nil {
+		fmt.Printf("Error reading results: %v\n", err)
+		return
+	}
+
+	reports := export.BuildAccessibilityReport(results)
+	for i, report := range reports {
+		if i >= limit {
+			fmt.Printf("... and %d more domains\n", len(reports)-limit)
+			break
+		}
+
+		fmt.Printf("%d. %s\n", i+1, report.Domain)
+		fmt.Printf("   %d issue(s) on %d of %d page(s)\n", report.Issues(), report.PagesWithIssues, report.PagesAudited)
+		fmt.Printf("   Missing alt: %d, missing labels: %d, heading order: %d, pages without lang: %d\n",
+			report.MissingAlt, report.MissingLabels, report.HeadingOrder, report.MissingLang)
+		fmt.Println()
+	}
+
+	if len(reports) == 0 {
+		fmt.Println("No accessibility audits found, crawl with --a11y to run them.")
+	}
+	fmt.Println()
+}
+
+func (e *Explorer) exportData(dataType, formatName string) {
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	filename := fmt.Sprintf("golamv2_%s_export_%s%s", dataType, time.Now().Format("20060102_150405"), format.Extension())
 	if outputFile != "" {
 		filename = outputFile
 	}
 
+	// Columnar formats only make sense for results
+	if format != export.FormatJSON {
+		if strings.ToLower(dataType) != "results" {
+			fmt.Printf("The %s format is only available for results\n", format)
+			return
+		}
+		e.exportResultsAs(format, filename)
+		return
+	}
+
 	fmt.Printf("Exporting %s data to %s...\n", dataType, filename)
 
 	var data interface{}
-	var err error
 
 	switch strings.ToLower(dataType) {
 	case "urls":
@@ -692,6 +1398,24 @@ func (e *Explorer) exportData(dataType string) {
 	fmt.Printf("Successfully exported to %s\n", filename)
 }
 
+// exportResultsAs writes all results in one of the export package formats
+func (e *Explorer) exportResultsAs(format export.Format, filename string) {
+	fmt.Printf("Exporting results as %s to %s...\n", format, filename)
+
+	results, err := e.exportResults()
+	if err != nil {
+		fmt.Printf("Error exporting data: %v\n", err)
+		return
+	}
+
+	if err := export.WriteFile(format, filename, results, export.Options{}); err != nil {
+		fmt.Printf("Error writing data: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Successfully exported %d results to %s\n", len(results), filename)
+}
+
 func (e *Explorer) exportURLs() ([]domain.URLTask, error) {
 	var urls []domain.URLTask
 
@@ -700,7 +1424,7 @@ func (e *Explorer) exportURLs() ([]domain.URLTask, error) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(URLPrefix)
+		prefix := e.key(URLPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -729,7 +1453,7 @@ func (e *Explorer) exportResults() ([]domain.CrawlResult, error) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -758,7 +1482,7 @@ func (e *Explorer) exportEmails() (map[string][]string, error) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -791,7 +1515,7 @@ func (e *Explorer) exportKeywords() (map[string]interface{}, error) {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -912,7 +1636,7 @@ func (e *Explorer) analyzeData() {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(URLPrefix)
+		prefix := e.key(URLPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -937,7 +1661,7 @@ func (e *Explorer) analyzeData() {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
@@ -1038,7 +1762,7 @@ func (e *Explorer) analyzeData() {
 	fmt.Println()
 }
 
-func (e *Explorer) showTimeline() {
+func (e *Explorer) showTimeline(timeRange domain.TimeRange) {
 	fmt.Println("\n Crawling Timeline")
 	fmt.Println("===================")
 
@@ -1046,44 +1770,75 @@ func (e *Explorer) showTimeline() {
 	var firstTime, lastTime time.Time
 	resultCount := 0
 
-	e.resultsDB.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		it := txn.NewIterator(opts)
-		defer it.Close()
+	addResult := func(result domain.CrawlResult) {
+		resultCount++
 
-		prefix := []byte(ResultPrefix)
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			item := it.Item()
-
-			err := item.Value(func(val []byte) error {
-				var result domain.CrawlResult
-				if err := json.Unmarshal(val, &result); err == nil {
-					resultCount++
+		if resultCount == 1 {
+			firstTime = result.ProcessedAt
+			lastTime = result.ProcessedAt
+		} else {
+			if result.ProcessedAt.Before(firstTime) {
+				firstTime = result.ProcessedAt
+			}
+			if result.ProcessedAt.After(lastTime) {
+				lastTime = result.ProcessedAt
+			}
+		}
 
-					if resultCount == 1 {
-						firstTime = result.ProcessedAt
-						lastTime = result.ProcessedAt
-					} else {
-						if result.ProcessedAt.Before(firstTime) {
-							firstTime = result.ProcessedAt
-						}
-						if result.ProcessedAt.After(lastTime) {
-							lastTime = result.ProcessedAt
-						}
-					}
+		hourKey := result.ProcessedAt.Format("2006-01-02 15:00")
+		timeMap[hourKey]++
+	}
 
-					hourKey := result.ProcessedAt.Format("2006-01-02 15:00")
-					timeMap[hourKey]++
-				}
-				return nil
+	if !timeRange.IsZero() {
+		// A window is read from the time index, page by page
+		cursor := ""
+		for {
+			var results []domain.CrawlResult
+			err := e.resultsDB.View(func(txn *badger.Txn) error {
+				var err error
+				results, cursor, err = storage.ScanTimeRange(txn, e.keyPrefix, timeRange, cursor, storage.BatchSize)
+				return err
 			})
 			if err != nil {
-				return err
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			for _, result := range results {
+				addResult(result)
+			}
+			if cursor == "" {
+				break
 			}
 		}
-		return nil
-	})
+	} else {
+		e.resultsDB.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			prefix := e.key(ResultPrefix)
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				item := it.Item()
+
+				err := item.Value(func(val []byte) error {
+					var result domain.CrawlResult
+					if err := json.Unmarshal(val, &result); err == nil {
+						addResult(result)
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
 
+	if resultCount == 0 && !timeRange.IsZero() {
+		fmt.Println("No results processed in that window. Databases written before the time index are indexed when a crawl opens them.")
+		return
+	}
 	if resultCount == 0 {
 		fmt.Println("No results found for timeline analysis.")
 		return
@@ -1096,13 +1851,18 @@ func (e *Explorer) showTimeline() {
 	if len(timeMap) > 0 {
 		fmt.Println("\nActivity by Hour:")
 		// Sort and display timeline (simplified)
+		hours := make([]string, 0, len(timeMap))
+		for timeKey := range timeMap {
+			hours = append(hours, timeKey)
+		}
+		sort.Strings(hours)
 		count := 0
-		for timeKey, pages := range timeMap {
+		for _, timeKey := range hours {
 			if count >= 20 { // Show first 20 entries
 				fmt.Printf("... and %d more time periods\n", len(timeMap)-20)
 				break
 			}
-			fmt.Printf("%s: %d pages processed\n", timeKey, pages)
+			fmt.Printf("%s: %d pages processed\n", timeKey, timeMap[timeKey])
 			count++
 		}
 	}
@@ -1127,7 +1887,7 @@ func (e *Explorer) showDomainStats() {
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		prefix := []byte(ResultPrefix)
+		prefix := e.key(ResultPrefix)
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
 
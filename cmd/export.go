@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/export"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat    string
+	exportOutput    string
+	exportData      string
+	exportSession   string
+	exportGraph     string
+	exportNamespace string
+)
+
+// exportCmd writes the crawl results of a data directory to a file
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export crawl results and reports to files",
+	Long: `Export every crawl result of a data directory or session in one go.
+
+Formats:
+  json             Indented JSON array of results
+  parquet          Columnar file for DuckDB, Spark or Athena
+  sqlite           Normalized tables (results, emails, keywords, dead_links, dead_domains,
+                   headers) with indexes. Needs a binary built with CGO_ENABLED=1
+  xlsx             Spreadsheet with Emails, Keywords, Dead Links and Domains sheets
+  email-report     CSV of found emails grouped by mail domain with counts and first/last seen
+  a11y-report      CSV of accessibility issues per domain (after a --a11y crawl)
+  deadlink-report  Styled HTML broken link report grouped by source page
+  graphml, dot     Link graph for Gephi, Graphviz or graph databases, see --graph-level
+
+Example:
+  golamv2 export --format sqlite -o crawl.db`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runExport(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "json", "Export format (json|csv|ndjson|parquet|sqlite|xlsx|email-report|deadlink-report|a11y-report|graphml|dot)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file (default: golamv2_export_<time>.<ext>)")
+	exportCmd.Flags().StringVarP(&exportData, "data", "d", infrastructure.DefaultDataDir, "Path to GolamV2 data directory")
+	exportCmd.Flags().StringVarP(&exportSession, "session", "s", "", "Export a named crawl session inside the data directory")
+	exportCmd.Flags().StringVar(&exportNamespace, "namespace", "", "Export the crawl kept under this --namespace")
+	exportCmd.Flags().StringVar(&exportGraph, "graph-level", "page", "Nodes of graph exports: page or domain")
+}
+
+func runExport() error {
+	format, err := export.ParseFormat(exportFormat)
+	if err != nil {
+		return err
+	}
+
+	graphLevel, err := export.ParseGraphLevel(exportGraph)
+	if err != nil {
+		return err
+	}
+
+	path, err := sessionDataDir(exportData, exportSession)
+	if err != nil {
+		return err
+	}
+
+	filename := exportOutput
+	if filename == "" {
+		filename = fmt.Sprintf("golamv2_export_%s%s", time.Now().Format("20060102_150405"), format.Extension())
+	}
+
+	explorer, err := NewExplorer(path, exportNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to open crawl data: %v", err)
+	}
+	defer explorer.Close()
+
+	results, err := explorer.exportResults()
+	if err != nil {
+		return fmt.Errorf("failed to read results: %v", err)
+	}
+
+	if err := export.WriteFile(format, filename, results, export.Options{GraphLevel: graphLevel}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d results to %s\n", len(results), filename)
+	return nil
+}
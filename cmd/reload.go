@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golamv2/internal/application"
+	"golamv2/pkg/logging"
+
+	"github.com/spf13/pflag"
+)
+
+// reloadableSettings are the config file keys applied to a running crawl on reload,
+// everything else needs a restart
+var reloadableSettings = []string{"rate", "exclude-domains", "keywords", "log-level", "quiet", "verbose"}
+
+// configReloader re-reads the config file on SIGHUP or /api/reload and applies the
+// reloadable settings to the running crawl
+type configReloader struct {
+	mu      sync.Mutex
+	path    string
+	cmdLine map[string]bool // Flags given on the command line, they keep winning over the file
+	app     *application.CrawlerService
+}
+
+// newConfigReloader must be created before the config file is applied, to tell the flags
+// given on the command line from the ones set by the file
+func newConfigReloader(flags *pflag.FlagSet, path string) *configReloader {
+	r := &configReloader{path: path, cmdLine: make(map[string]bool)}
+	flags.Visit(func(flag *pflag.Flag) {
+		r.cmdLine[flag.Name] = true
+	})
+	return r
+}
+
+// Attach points the reloader at the crawl currently running
+func (r *configReloader) Attach(app *application.CrawlerService) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.app = app
+}
+
+// Reload applies the reloadable settings of the config file, keys missing from the file
+// keep their current value
+func (r *configReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.path == "" {
+		return fmt.Errorf("no config file to reload, start the crawl with --config")
+	}
+
+	values, err := readConfigFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	// The file is parsed into copies of the settings first, so a bad value leaves the
+	// running crawl untouched instead of half reloaded
+	rate, excluded, words := requestRate, excludeDomains, keywords
+	level, errorsOnly, debug := logLevel, quiet, verbose
+	parsed := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	parsed.Float64Var(&rate, "rate", rate, "")
+	parsed.StringSliceVar(&excluded, "exclude-domains", excluded, "")
+	parsed.StringSliceVar(&words, "keywords", words, "")
+	parsed.StringVar(&level, "log-level", level, "")
+	parsed.BoolVar(&errorsOnly, "quiet", errorsOnly, "")
+	parsed.BoolVar(&debug, "verbose", debug, "")
+
+	for _, name := range reloadableSettings {
+		value, ok := values[name]
+		if !ok || r.cmdLine[name] {
+			continue
+		}
+
+		flag := parsed.Lookup(name)
+		if flag == nil {
+			continue
+		}
+		if err := setFlagValue(flag, configValueString(value)); err != nil {
+			return fmt.Errorf("invalid value for %q in config file: %v", name, err)
+		}
+	}
+
+	logs, err := loggingLevel(level, errorsOnly, debug)
+	if err != nil {
+		return fmt.Errorf("invalid logging settings in config file: %v", err)
+	}
+
+	requestRate, excludeDomains, keywords = rate, excluded, withFileKeywords(words)
+	logLevel, quiet, verbose = level, errorsOnly, debug
+	logging.SetLevel(logs)
+
+	if r.app != nil {
+		r.app.ApplySettings(application.LiveSettings{
+			Rate:           requestRate,
+			ExcludeDomains: excludeDomains,
+			Keywords:       keywords,
+		})
+	}
+
+	logging.Infof("Config reloaded from %s: rate %.0f/s, %d excluded domains, %d keywords, log level %s",
+		r.path, requestRate, len(excludeDomains), len(keywords), logLevel)
+	return nil
+}
+
+// Watch reloads on every SIGHUP until ctx is done
+func (r *configReloader) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := r.Reload(); err != nil {
+				logging.Errorf("Config reload failed: %v", err)
+			}
+		}
+	}
+}
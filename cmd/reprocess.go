@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"golamv2/internal/application"
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/logging"
+	"golamv2/pkg/warc"
+
+	"github.com/spf13/cobra"
+)
+
+// reprocessWARCDir reads the pages of WARC files from anywhere instead of the session's archive
+var reprocessWARCDir string
+
+// reprocessCmd runs the extractors again over archived pages
+var reprocessCmd = &cobra.Command{
+	Use:   "reprocess",
+	Short: "Re-extract archived pages with the current hunting modes, offline",
+	Long: `Run the extractors of the given hunting modes over the pages archived by a
+crawl with --archive and store a fresh result for each, without a single request.
+Use it to hunt for keywords added after the crawl, or to re-run extraction after
+an upgrade.
+
+Pages are read from the WARC files of the data directory when there are any and
+from the Badger archive otherwise, --archive picks one. --warc-dir reads the WARC
+files of any other tool. Only the newest copy of a page archived several times is
+reprocessed. Dead links are not checked again and no links are followed.
+
+Example:
+  golamv2 reprocess --session acme --keywords pricing,careers`,
+	Args: cobra.NoArgs,
+	Run:  runReprocess,
+}
+
+func init() {
+	rootCmd.AddCommand(reprocessCmd)
+	addCrawlFlags(reprocessCmd.Flags())
+	reprocessCmd.Flags().StringVar(&reprocessWARCDir, "warc-dir", "", "Reprocess the .warc.gz and .warc files of this directory, from any tool")
+}
+
+func runReprocess(cmd *cobra.Command, args []string) {
+	if err := applyConfigFile(cmd.Flags(), configFile); err != nil {
+		log.Fatal(err)
+	}
+	validateCrawlFlags()
+	mode := determineCrawlMode()
+	if dryRun {
+		log.Fatal("reprocess makes no requests, there is nothing to dry run")
+	}
+
+	dataDir, err := sessionDataDir(dataRoot, sessionName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := reprocessArchive(ctx, dataDir, mode); err != nil {
+		log.Fatalf("Reprocessing failed: %v", err)
+	}
+}
+
+// reprocessArchive stores fresh results of the archived pages of dataDir in its storage
+func reprocessArchive(ctx context.Context, dataDir, mode string) error {
+	sinks, err := openResultSinks(dataDir)
+	if err != nil {
+		return err
+	}
+
+	// Pages are read from the archive, not archived again, and nothing is fetched
+	options := infrastructureOptions(sinks)
+	options.Archive = ""
+	options.RequestLog = ""
+	infra, err := infrastructure.NewInfrastructure(dataDir, options)
+	if err != nil {
+		closeResultSinks(sinks)
+		return fmt.Errorf("failed to initialize infrastructure: %v", err)
+	}
+	defer func() {
+		if err := infra.Close(); err != nil {
+			logging.Errorf("%v", err)
+		}
+	}()
+
+	archive, err := reprocessSource(dataDir, infra.Storage)
+	if err != nil {
+		return err
+	}
+	if files, ok := archive.(*warc.Archive); ok {
+		defer files.Close()
+	}
+
+	crawl := crawlOptions(false)
+	crawl.Offline = true
+	app := application.NewCrawlerService(infra, domain.CrawlMode(mode), keywords, domainMode, crawl)
+	logging.Infof("Mode: %s", mode)
+	logging.Debugf("Extractors: %s", strings.Join(app.Extractors(), ", "))
+	if domainMode {
+		logging.Warnf("Dead links are not checked again, reprocess makes no requests")
+	}
+
+	report, err := app.Reprocess(ctx, archive)
+	if ctx.Err() != nil {
+		logging.Warnf("Stopped after %d pages", report.Pages)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logging.Infof("Reprocessed %d pages in %s", report.Pages, report.Duration.Round(time.Millisecond))
+	if report.Older > 0 {
+		logging.Infof("Older copies of pages skipped: %d", report.Older)
+	}
+	if report.Skipped > 0 {
+		logging.Infof("Redirects and non-HTML pages skipped: %d", report.Skipped)
+	}
+	return nil
+}
+
+// reprocessSource picks the archive to read: --warc-dir, the one --archive names, or the WARC
+// files of the data directory when there are any and the Badger archive otherwise
+func reprocessSource(dataDir string, store domain.Storage) (domain.PageArchive, error) {
+	dir := reprocessWARCDir
+	source := domain.ArchiveWARC
+	if dir == "" {
+		dir = infrastructure.WARCDir(dataDir, namespace)
+		source = domain.ArchiveMode(archiveMode)
+		if source == "" {
+			source = domain.ArchiveBadger
+			if _, err := os.Stat(dir); err == nil {
+				source = domain.ArchiveWARC
+			}
+		}
+	}
+
+	if source == domain.ArchiveWARC {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("no WARC files in %s", dir)
+		}
+		return warc.NewArchive(dir)
+	}
+
+	archive, ok := store.(domain.PageArchive)
+	if !ok {
+		return nil, fmt.Errorf("--storage %s keeps no archive, crawl with --archive warc or use --warc-dir", storageBackend)
+	}
+	return archive, nil
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golamv2/internal/application"
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/internal/interfaces"
+	"golamv2/pkg/logging"
+	"golamv2/pkg/metrics"
+
+	"github.com/spf13/cobra"
+)
+
+// cronSpec is the schedule, the argument of the command can give it too
+var cronSpec string
+
+// scheduleCmd runs recurring crawls from a resident process
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule [cron-spec]",
+	Short: "Run crawls on a recurring schedule",
+	Long: `Keep GolamV2 running and start a crawl at every time matching the cron spec.
+
+The spec, given as argument or with --cron, uses the standard 5 fields (minute
+hour day-of-month month day-of-week) or descriptors like @daily and @every 6h.
+Each run is stored in its own timestamped session and the run history is served
+at /api/runs.
+
+Every run is compared with the previous completed one: new and resolved dead
+links, new and disappeared emails and keyword count changes are logged and
+written to changes.json in the run's session. Comparing needs --storage badger.
+
+Example:
+  golamv2 schedule --cron "0 3 * * *" --config site.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runSchedule,
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	addCrawlFlags(scheduleCmd.Flags())
+	scheduleCmd.Flags().StringVar(&cronSpec, "cron", "", "Cron spec of the runs, like \"0 3 * * *\" or @daily")
+}
+
+func runSchedule(cmd *cobra.Command, args []string) {
+	reloader := newConfigReloader(cmd.Flags(), configFile)
+	if err := applyConfigFile(cmd.Flags(), configFile); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(args) == 1 {
+		if cronSpec != "" && cronSpec != args[0] {
+			log.Fatal("give the schedule either as argument or with --cron")
+		}
+		cronSpec = args[0]
+	}
+	if cronSpec == "" {
+		log.Fatal("a schedule is required, like --cron \"0 3 * * *\"")
+	}
+
+	requireStartURL()
+	validateCrawlFlags()
+	mode := determineCrawlMode()
+	compare := storageBackend == string(domain.StorageBadger) && !dryRun
+	if !compare && !dryRun {
+		logging.Warnf("Runs are not compared with --storage %s, only badger results can be read back", storageBackend)
+	}
+
+	// Runs are stored as <session>-<timestamp> next to each other
+	baseName := sessionName
+	if baseName == "" {
+		baseName = "scheduled"
+	}
+	baseDir, err := sessionDataDir(dataRoot, baseName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(dataRoot, 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	// golamv2 status --session <base> finds the scheduler there
+	defer announceInstance(baseDir, dashboardPort)()
+
+	// Up between the runs too, the crawl data comes and goes with them
+	dashboard := interfaces.NewDashboard(nil, nil, nil, dashboardPort)
+	dashboard.SetReloader(reloader.Reload)
+	historyPath := filepath.Join(dataRoot, baseName+"_runs.json")
+
+	var scheduler *application.Scheduler
+	scheduler, err = application.NewScheduler(cronSpec, historyPath, func(ctx context.Context, run *domain.CrawlRun) error {
+		run.Session = fmt.Sprintf("%s-%s", baseName, run.ID)
+		run.DataDir = filepath.Join(dataRoot, run.Session)
+
+		logging.Infof("Starting scheduled crawl %s of %s (mode: %s)", run.ID, startURL, mode)
+
+		var collector *metrics.MetricsCollector
+		err := executeCrawl(ctx, run.DataDir, mode, true, func(infra *infrastructure.Infrastructure, app *application.CrawlerService) {
+			collector = infra.GetMetrics()
+			reloader.Attach(app)
+			dashboard.Attach(infra.GetMetrics(), infra.Storage, infra.URLQueue)
+			dashboard.SetEvents(app.Events())
+			dashboard.SetCollapseRules(infra.URLCollapser.Rules)
+			dashboard.SetQuarantinedHosts(infra.Challenges.QuarantinedHosts)
+			dashboard.SetScreenshotDir(filepath.Join(run.DataDir, infrastructure.ScreenshotsDirName))
+		}, dashboard.Detach)
+
+		if collector != nil {
+			run.URLsProcessed = collector.GetMetrics().URLsProcessed
+			run.Findings = collector.GetTotalFinds()
+		}
+		// An interrupted run is partial, comparing it would report pages as removed
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || !compare {
+			return err
+		}
+
+		if previous := scheduler.LastCompleted(); previous != nil {
+			if err := reportChanges(previous, run); err != nil {
+				logging.Warnf("Failed to compare with run %s: %v", previous.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	dashboard.SetRunHistory(scheduler.History)
+	go dashboard.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		logging.Infof("\nStopping scheduler...")
+		cancel()
+	}()
+	go reloader.Watch(ctx)
+
+	logging.Infof("GolamV2 scheduler started")
+	logging.Infof("Schedule: %s", scheduler.Spec())
+	logging.Infof("Start URL: %s", startURL)
+	logging.Infof("Mode: %s", mode)
+	logging.Infof("Dashboard: http://localhost:%d", dashboardPort)
+
+	scheduler.Start(ctx)
+}
+
+// reportChanges compares the results of a run with those of the previous one, logs what
+// changed and writes the report to the run's directory
+func reportChanges(previous, run *domain.CrawlRun) error {
+	before, err := readResults(previous.DataDir, namespace)
+	if err != nil {
+		return err
+	}
+	after, err := readResults(run.DataDir, namespace)
+	if err != nil {
+		return err
+	}
+
+	report := domain.CompareResults(before, after)
+	report.Before, report.After = previous.ID, run.ID
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(run.DataDir, domain.ChangesFileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write change report: %v", err)
+	}
+
+	summary := report.Summary()
+	run.ComparedTo, run.Changes = previous.ID, &summary
+	if summary.Empty() {
+		logging.Infof("No changes since run %s", previous.ID)
+		return nil
+	}
+	logging.Infof("Changes since run %s: %d pages added, %d removed, %d status changes, %d new dead links, %d resolved, %d new emails, %d disappeared, %d keyword counts changed (%s)",
+		previous.ID, summary.AddedURLs, summary.RemovedURLs, summary.StatusChanges, summary.NewDeadLinks,
+		summary.ResolvedDeadLinks, summary.NewEmails, summary.DisappearedEmails, summary.KeywordChanges, path)
+	for _, change := range report.NewDeadLinks {
+		logging.Infof("  New dead link %s on %s", change.Link, change.Page)
+	}
+	for _, change := range report.NewEmails {
+		logging.Infof("  New email %s on %s", change.Email, strings.Join(change.Pages, ", "))
+	}
+	for _, change := range report.DisappearedEmails {
+		logging.Infof("  Email %s disappeared from %s", change.Email, strings.Join(change.Pages, ", "))
+	}
+	for _, change := range report.KeywordChanges {
+		logging.Infof("  Keyword %q: %d -> %d", change.Keyword, change.Before, change.After)
+	}
+	return nil
+}
+
+// readResults reads every result stored in a data directory, under a namespace unless empty
+func readResults(dataDir, namespace string) ([]domain.CrawlResult, error) {
+	if !isSessionDir(dataDir) {
+		return nil, fmt.Errorf("no crawl data in %s", dataDir)
+	}
+	explorer, err := NewExplorer(dataDir, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer explorer.Close()
+	return explorer.exportResults()
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golamv2/internal/infrastructure"
+
+	"github.com/spf13/cobra"
+)
+
+var sessionsRoot string
+
+// sessionsCmd lists the named crawl sessions
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List named crawl sessions",
+	Long: `List the crawl sessions created with --session.
+
+Each session lives in its own directory under the data root with separate
+databases and metrics, so investigations never share a store.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listSessions(sessionsRoot); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+	sessionsCmd.Flags().StringVarP(&sessionsRoot, "data", "d", infrastructure.DefaultDataDir, "Path to GolamV2 data root")
+}
+
+// SessionInfo describes a session directory on disk
+type SessionInfo struct {
+	Name      string
+	Path      string
+	SizeBytes int64
+	Modified  time.Time
+}
+
+// sessionDataDir resolves the data directory for a session, an empty name means the default store
+func sessionDataDir(root, name string) (string, error) {
+	if name == "" {
+		return root, nil
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid session name %q", name)
+	}
+	return filepath.Join(root, name), nil
+}
+
+// isSessionDir reports whether a directory holds GolamV2 databases
+func isSessionDir(path string) bool {
+	info, err := os.Stat(filepath.Join(path, "urls"))
+	return err == nil && info.IsDir()
+}
+
+// findSessions returns every session found under root
+func findSessions(root string) ([]SessionInfo, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(root, entry.Name())
+		if !isSessionDir(path) {
+			continue
+		}
+
+		session := SessionInfo{Name: entry.Name(), Path: path}
+		filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			session.SizeBytes += info.Size()
+			if info.ModTime().After(session.Modified) {
+				session.Modified = info.ModTime()
+			}
+			return nil
+		})
+		sessions = append(sessions, session)
+	}
+
+	// Most recently used first
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Modified.After(sessions[j].Modified)
+	})
+
+	return sessions, nil
+}
+
+func listSessions(root string) error {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return fmt.Errorf("data directory not found: %s", root)
+	}
+
+	sessions, err := findSessions(root)
+	if err != nil {
+		return fmt.Errorf("failed to read sessions: %v", err)
+	}
+
+	fmt.Println("\n Crawl Sessions")
+	fmt.Println("================")
+
+	if isSessionDir(root) {
+		fmt.Printf("(default session stored directly in %s)\n\n", root)
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No named sessions found. Start one with --session <name>.")
+		fmt.Println()
+		return nil
+	}
+
+	for i, session := range sessions {
+		fmt.Printf("%d. %s\n", i+1, session.Name)
+		fmt.Printf("   Path: %s\n", session.Path)
+		fmt.Printf("   Size: %.1f MB, Last Active: %s\n", float64(session.SizeBytes)/1024/1024, session.Modified.Format("2006-01-02 15:04:05"))
+		fmt.Println()
+	}
+
+	return nil
+}
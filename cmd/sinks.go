@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/sink"
+)
+
+// openResultSinks connects the result sinks enabled by the flags, keyed by name. Sinks keep
+// their spools in dataDir
+func openResultSinks(dataDir string) (map[string]domain.ResultSink, error) {
+	sinks := make(map[string]domain.ResultSink)
+
+	if mongoSink.URI != "" {
+		mongo, err := sink.NewMongoSink(mongoSink)
+		if err != nil {
+			closeResultSinks(sinks)
+			return nil, err
+		}
+		sinks["mongodb"] = mongo
+	}
+
+	if clickHouseSink.URL != "" {
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			closeResultSinks(sinks)
+			return nil, fmt.Errorf("failed to create data directory: %v", err)
+		}
+		clickHouse, err := sink.NewClickHouseSink(clickHouseSink, filepath.Join(dataDir, sink.ClickHouseSpoolFile))
+		if err != nil {
+			closeResultSinks(sinks)
+			return nil, err
+		}
+		sinks["clickhouse"] = clickHouse
+	}
+
+	for _, target := range publishTargets {
+		parsed, err := sink.ParsePublishTarget(target)
+		if err == nil {
+			sinks[parsed.Name()], err = sink.NewPublisher(parsed)
+		}
+		if err != nil {
+			closeResultSinks(sinks)
+			return nil, err
+		}
+	}
+
+	if s3Sink.Bucket != "" {
+		config := s3Sink
+		config.RotateSize = int64(s3RotateMB) << 20
+		if config.AccessKey == "" {
+			config.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if config.SecretKey == "" {
+			config.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+
+		s3, err := sink.NewS3Sink(config, filepath.Join(dataDir, sink.S3OutboxDir))
+		if err != nil {
+			closeResultSinks(sinks)
+			return nil, err
+		}
+		sinks["s3"] = s3
+	}
+
+	return sinks, nil
+}
+
+// closeResultSinks closes sinks the infrastructure never took over
+func closeResultSinks(sinks map[string]domain.ResultSink) {
+	for _, s := range sinks {
+		s.Close()
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// InstanceFileName is the discovery file a running crawler leaves in its data directory
+const InstanceFileName = "instance.json"
+
+var (
+	statusPort    int
+	statusSession string
+	statusData    string
+	statusWatch   time.Duration
+)
+
+// statusCmd prints a summary of a running crawler from its API
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the progress of a running crawler",
+	Long: `Print a short summary of a running crawler: rate, queue, findings and errors.
+
+The crawler is found through the instance file it keeps in its data directory,
+use --session for named sessions or --port to ask a port directly.
+
+Example:
+  golamv2 status --session shop --watch 5s`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runStatus(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().IntVar(&statusPort, "port", 0, "Dashboard port of the crawler (default: read from the data directory)")
+	statusCmd.Flags().StringVar(&statusSession, "session", "", "Named crawl session to look for")
+	statusCmd.Flags().StringVarP(&statusData, "data", "d", infrastructure.DefaultDataDir, "Path to GolamV2 data root")
+	statusCmd.Flags().DurationVar(&statusWatch, "watch", 0, "Refresh the summary at this interval until interrupted")
+}
+
+// instanceInfo is what the discovery file holds
+type instanceInfo struct {
+	PID       int       `json:"pid"`
+	Port      int       `json:"port"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// writeInstanceFile announces the dashboard port of this process in dir, the returned
+// function removes the file again
+func writeInstanceFile(dir string, port int) (func(), error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	data, err := json.Marshal(instanceInfo{PID: os.Getpid(), Port: port, StartedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, InstanceFileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write instance file: %v", err)
+	}
+	return func() { os.Remove(path) }, nil
+}
+
+// announceInstance writes the instance file, a failure only costs status its discovery
+func announceInstance(dir string, port int) func() {
+	remove, err := writeInstanceFile(dir, port)
+	if err != nil {
+		logging.Warnf("%v", err)
+		return func() {}
+	}
+	return remove
+}
+
+func runStatus() error {
+	port := statusPort
+	if port == 0 {
+		dataDir, err := sessionDataDir(statusData, statusSession)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(filepath.Join(dataDir, InstanceFileName))
+		if err != nil {
+			return fmt.Errorf("no running crawler found in %s, pass --port or --session", dataDir)
+		}
+		var info instanceInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return fmt.Errorf("failed to read instance file: %v", err)
+		}
+		port = info.Port
+	}
+
+	if err := printStatus(port); err != nil || statusWatch <= 0 {
+		return err
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(statusWatch)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sigChan:
+			return nil
+		case <-ticker.C:
+			fmt.Println()
+			if err := printStatus(port); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// printStatus fetches the metrics of the crawler on port and prints the summary
+func printStatus(port int) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/api/metrics", port))
+	if err != nil {
+		return fmt.Errorf("no crawler answering on port %d: %v", port, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("crawler on port %d answered %s", port, resp.Status)
+	}
+
+	var m domain.CrawlMetrics
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return fmt.Errorf("failed to decode metrics: %v", err)
+	}
+
+	fmt.Printf("GolamV2 on port %d, up %s\n", port, time.Since(m.StartTime).Round(time.Second))
+	fmt.Printf("  Rate:     %.1f URLs/s, %d processed\n", m.URLsPerSecond, m.URLsProcessed)
+	fmt.Printf("  Queue:    %d in memory, %d in database, %d workers\n", m.URLsInQueue, m.URLsInDB, m.ActiveWorkers)
+	fmt.Printf("  Findings: %d emails, %d keywords, %d dead links, %d dead domains\n",
+		m.EmailsFound, m.KeywordsFound, m.DeadLinksFound, m.DeadDomainsFound)
+	fmt.Printf("  Errors:   %d (%d retried, %d dead letters)\n", m.Errors, m.URLsRetried, m.URLsDeadLettered)
+	if t := m.Transport; t.Fetches > 0 {
+		fmt.Printf("  Network:  dns %.1fms, connect %.1fms, tls %.1fms, ttfb %.1fms, download %.1fms, %.0f%% reused\n",
+			t.AvgDNSMs, t.AvgConnectMs, t.AvgTLSMs, t.AvgTTFBMs, t.AvgDownloadMs, t.ConnReuseRate*100)
+		fmt.Printf("  Extract:  %.1fms per page\n", t.AvgExtractMs)
+	}
+	for _, level := range m.RateLimits {
+		if level.Limit > 0 {
+			fmt.Printf("  Limit:    %s %.0f/s, %.0f%% of requests waited %.1fs in total\n",
+				level.Level, level.Limit, level.Saturation*100, level.WaitSeconds)
+		}
+	}
+	fmt.Printf("  Memory:   %.1f MB\n", m.MemoryUsageMB)
+	return nil
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/bloom"
+	"golamv2/pkg/queue"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd checks the crawl settings without crawling
+var validateCmd = &cobra.Command{
+	Use:   "validate [seed-url...]",
+	Short: "Check crawl settings and seeds before crawling",
+	Long: `Check the flags or config file of a crawl without starting it: flag values,
+seed URL syntax, DNS and robots.txt of the seeds, write access to the data
+directory and the memory settings. Seeds are --url and any arguments.
+
+Exits with status 1 when a problem would stop or cripple the crawl.
+
+Example:
+  golamv2 validate --config site.yaml`,
+	Run: runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	addCrawlFlags(validateCmd.Flags())
+}
+
+// preflight collects the results of the validate checks
+type preflight struct {
+	failures int
+	warnings int
+}
+
+func (p *preflight) ok(format string, args ...interface{}) {
+	fmt.Printf("  ok    %s\n", fmt.Sprintf(format, args...))
+}
+
+func (p *preflight) warn(format string, args ...interface{}) {
+	p.warnings++
+	fmt.Printf("  warn  %s\n", fmt.Sprintf(format, args...))
+}
+
+func (p *preflight) fail(format string, args ...interface{}) {
+	p.failures++
+	fmt.Printf("  FAIL  %s\n", fmt.Sprintf(format, args...))
+}
+
+func runValidate(cmd *cobra.Command, args []string) {
+	if err := applyConfigFile(cmd.Flags(), configFile); err != nil {
+		log.Fatal(err)
+	}
+
+	p := &preflight{}
+
+	fmt.Println("Settings")
+	if err := checkCrawlFlags(); err != nil {
+		p.fail("%v", err)
+	} else {
+		p.ok("mode %s, scope %s, robots %s, dedup %s", determineCrawlMode(), scope, robotsMode, dedupMode)
+	}
+	if maxWorkers < 1 {
+		p.fail("--workers must be at least 1, got %d", maxWorkers)
+	}
+	if maxDepth < 0 {
+		p.fail("--depth can not be negative, got %d", maxDepth)
+	}
+
+	fmt.Println("Seeds")
+	seeds := args
+	if startURL != "" {
+		seeds = append([]string{startURL}, seeds...)
+	}
+	if len(seeds) == 0 {
+		p.warn("no seed URL, only golamv2 serve runs without --url")
+	}
+	for _, seed := range seeds {
+		checkSeed(p, seed)
+	}
+
+	fmt.Println("Data directory")
+	checkDataDir(p)
+
+	fmt.Println("Memory")
+	checkMemory(p)
+
+	fmt.Printf("\n%d problem(s), %d warning(s)\n", p.failures, p.warnings)
+	if p.failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkSeed checks the syntax, DNS and robots.txt of a seed URL
+func checkSeed(p *preflight, seed string) {
+	u, err := url.Parse(seed)
+	if err != nil || !domain.IsValidURL(seed) {
+		p.fail("%s: not a valid http(s) URL", seed)
+		return
+	}
+
+	scopeCheck := domain.NewScope(domain.ScopePolicy(scope), seed)
+	scopeCheck.Exclude(excludeDomains...)
+	if scopeCheck.Excludes(seed) {
+		p.fail("%s: on a domain listed in --exclude-domains", seed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		p.fail("%s: DNS lookup of %s failed: %v", seed, u.Hostname(), err)
+		return
+	}
+	p.ok("%s resolves to %s", u.Hostname(), addrs[0])
+
+	if robotsMode == string(domain.RobotsOff) {
+		p.ok("%s: robots.txt ignored (--robots off)", seed)
+		return
+	}
+
+	robots := infrastructure.NewRobotsChecker(identity)
+	robots.SetPolicy(robotsPolicy)
+	status := robots.Status(u.Host)
+	switch robots.Check(identity.Agent(), seed) {
+	case domain.RobotsAllowed:
+		p.ok("%s: allowed by robots.txt (%s)", seed, status)
+	case domain.RobotsRetryLater:
+		p.warn("%s: robots.txt is %s, the URL waits for it to come back (--robots-unreachable)", seed, status)
+	default:
+		p.fail("%s: blocked by robots.txt (%s), nothing would be crawled", seed, status)
+	}
+}
+
+// checkDataDir makes sure the crawl can write its databases and is not locked by a running crawl
+func checkDataDir(p *preflight) {
+	dataDir, err := sessionDataDir(dataRoot, sessionName)
+	if err != nil {
+		p.fail("%v", err)
+		return
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		p.fail("%s can not be created: %v", dataDir, err)
+		return
+	}
+	file, err := os.CreateTemp(dataDir, ".validate-*")
+	if err != nil {
+		p.fail("%s is not writable: %v", dataDir, err)
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+	p.ok("%s is writable", dataDir)
+
+	if _, err := os.Stat(filepath.Join(dataDir, InstanceFileName)); err == nil {
+		p.warn("%s looks in use by a running crawler (golamv2 status), its databases would be locked", dataDir)
+	}
+}
+
+// checkMemory compares --memory with what the crawl needs besides the database caches
+func checkMemory(p *preflight) {
+	if maxMemoryMB <= 0 {
+		p.fail("--memory must be positive, got %d", maxMemoryMB)
+		return
+	}
+
+	bloomMB, queueMB, responsesMB := crawlStateMB(maxWorkers)
+	needed := bloomMB + queueMB + responsesMB
+	reserve := float64(maxMemoryMB) * CrawlStateShare
+
+	if needed > reserve {
+		p.warn("--memory %dMB leaves %.0fMB next to the databases, the bloom filter (%.0fMB), a full queue (%.0fMB) and %d workers (up to %.0fMB) may need %.0fMB",
+			maxMemoryMB, reserve, bloomMB, queueMB, maxWorkers, responsesMB, needed)
+		return
+	}
+	p.ok("--memory %dMB, %.0fMB of it left next to the databases for about %.0fMB of crawl state", maxMemoryMB, reserve, needed)
+}
+
+// CrawlStateShare is the part of --memory left once the databases took theirs
+const CrawlStateShare = 0.3
+
+// crawlStateMB estimates the memory held outside the databases: the bloom filter,
+// a full queue (about 200 bytes a URL) and the response bodies in flight (2MB each)
+func crawlStateMB(workers int) (bloomMB, queueMB, responsesMB float64) {
+	var filter bloom.Filter = bloom.NewURLBloomFilter()
+	if bloomFilter == "counting" {
+		filter = bloom.NewCountingURLBloomFilter()
+	}
+	return filter.GetMemoryUsageMB(), float64(queue.MaxQueueSize) * 200 / 1024 / 1024, float64(workers) * 2
+}
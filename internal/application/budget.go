@@ -0,0 +1,55 @@
+package application
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+// startBudgets stops the crawl once MaxPages URLs were taken or MaxDuration passed, like
+// Ctrl-C: pages in flight drain and the rest of the queue is saved for --resume
+func (c *CrawlerService) startBudgets(ctx context.Context, cancel context.CancelFunc) {
+	c.stopBudget = cancel
+	if c.options.MaxDuration <= 0 {
+		return
+	}
+
+	go func() {
+		timer := time.NewTimer(c.options.MaxDuration)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			logging.Infof("Time budget of %s used up, stopping the crawl", c.options.MaxDuration)
+			cancel()
+		}
+	}()
+}
+
+// takePage counts a URL against MaxPages, false once the budget is used up. Taking the last
+// page stops the crawl, the workers that popped a URL meanwhile put it back with returnTask
+func (c *CrawlerService) takePage() bool {
+	if c.options.MaxPages <= 0 {
+		return true
+	}
+
+	taken := atomic.AddInt64(&c.pagesTaken, 1)
+	if taken == int64(c.options.MaxPages) {
+		logging.Infof("Page budget of %d used up, stopping the crawl", c.options.MaxPages)
+		c.stopBudget()
+	}
+	return taken <= int64(c.options.MaxPages)
+}
+
+// returnTask puts a popped URL back untouched, the shutdown saves it with the rest of the queue
+func (c *CrawlerService) returnTask(task domain.URLTask) {
+	if limiter, ok := c.infra.URLQueue.(domain.HostLimiter); ok {
+		limiter.Done(task)
+	}
+	if err := c.infra.URLQueue.PushOrSpill(task); err != nil {
+		logging.Debugf("Failed to put %s back: %v", task.URL, err)
+	}
+}
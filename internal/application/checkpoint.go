@@ -0,0 +1,79 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+// DefaultCheckpointInterval is how often the frontier is saved when CrawlOptions.CheckpointInterval is not set
+const DefaultCheckpointInterval = time.Minute
+
+// startWork and finishWork track the task a worker is processing, so checkpoints include it
+func (c *CrawlerService) startWork(workerID int, task domain.URLTask) {
+	c.workingMu.Lock()
+	defer c.workingMu.Unlock()
+	c.working[workerID] = task
+}
+
+func (c *CrawlerService) finishWork(workerID int) {
+	c.workingMu.Lock()
+	defer c.workingMu.Unlock()
+	delete(c.working, workerID)
+}
+
+// checkpoint saves the frontier and the seen URLs for --resume. Tasks waiting for a retry
+// are only included while crawling, at shutdown the whole frontier goes to the storage instead
+func (c *CrawlerService) checkpoint(withRetries bool) {
+	store, ok := c.infra.Storage.(domain.CheckpointStore)
+	if !ok || c.options.DryRun {
+		return
+	}
+	snapshot, ok := c.infra.URLQueue.(domain.QueueSnapshot)
+	if !ok {
+		return
+	}
+
+	checkpoint := domain.Checkpoint{Tasks: snapshot.Snapshot(), SavedAt: time.Now()}
+
+	c.workingMu.Lock()
+	for _, task := range c.working {
+		checkpoint.Tasks = append(checkpoint.Tasks, task)
+	}
+	c.workingMu.Unlock()
+
+	if withRetries {
+		checkpoint.Tasks = append(checkpoint.Tasks, c.retries.Snapshot()...)
+	}
+
+	if filter, ok := c.infra.BloomFilter.(domain.PersistentBloomFilter); ok {
+		data, err := filter.MarshalBinary()
+		if err != nil {
+			logging.Warnf("Checkpoint saved without the seen URLs: %v", err)
+		}
+		checkpoint.Bloom = data
+	}
+
+	if err := store.SaveCheckpoint(checkpoint); err != nil {
+		logging.Warnf("Saving the checkpoint failed: %v", err)
+		return
+	}
+	logging.Debugf("Checkpoint saved: %d tasks, %d bytes of seen URLs", len(checkpoint.Tasks), len(checkpoint.Bloom))
+}
+
+// saveCheckpoints saves a checkpoint every interval until the crawl ends
+func (c *CrawlerService) saveCheckpoints(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkpoint(true)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+// Controller pauses, reconfigures and stops a running crawl, it backs the dashboard's
+// /api/control endpoints
+type Controller struct {
+	app      *CrawlerService
+	stop     context.CancelFunc
+	stopping atomic.Bool
+}
+
+// NewController controls app, stop cancels the context the crawl was started with
+func NewController(app *CrawlerService, stop context.CancelFunc) *Controller {
+	return &Controller{app: app, stop: stop}
+}
+
+// Pause holds the workers before their next URL, the pages being fetched are finished
+func (c *Controller) Pause() {
+	c.app.pool.pause()
+	logging.Infof("Crawl paused")
+}
+
+// Resume lets paused workers take URLs again
+func (c *Controller) Resume() {
+	c.app.pool.resume()
+	logging.Infof("Crawl resumed")
+}
+
+// SetWorkers changes the number of workers, retired ones finish the page they are on
+func (c *Controller) SetWorkers(workers int) error {
+	if err := c.app.pool.resize(workers); err != nil {
+		return err
+	}
+	logging.Infof("Workers set to %d", workers)
+	return nil
+}
+
+// SetRate changes the requests per second of all workers together, 0 means DefaultRate
+func (c *Controller) SetRate(requestsPerSecond float64) error {
+	if requestsPerSecond < 0 {
+		return fmt.Errorf("the rate can not be negative")
+	}
+	c.app.rateLimiter.SetGlobal(globalRate(requestsPerSecond))
+	logging.Infof("Rate set to %.0f requests per second", globalRate(requestsPerSecond))
+	return nil
+}
+
+// Stop stops the crawl cleanly, like Ctrl-C: pages in flight get DrainTimeout to finish
+// and the frontier is saved
+func (c *Controller) Stop() {
+	if c.stopping.CompareAndSwap(false, true) {
+		logging.Infof("Stopping the crawl...")
+		c.stop()
+	}
+}
+
+// Status returns the current state of the crawl
+func (c *Controller) Status() domain.ControlStatus {
+	return domain.ControlStatus{
+		Paused:   c.app.pool.paused() != nil,
+		Workers:  c.app.pool.workers(),
+		Rate:     c.app.rateLimiter.Global(),
+		Stopping: c.stopping.Load(),
+	}
+}
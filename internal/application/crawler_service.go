@@ -2,10 +2,13 @@ package application
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,23 +16,159 @@ import (
 
 	"golamv2/internal/domain"
 	"golamv2/internal/infrastructure"
-
-	"golang.org/x/time/rate"
+	"golamv2/pkg/bufpool"
+	"golamv2/pkg/logging"
+	"golamv2/pkg/metrics"
+	"golamv2/pkg/queue"
+	"golamv2/pkg/ratelimit"
 )
 
 // CrawlerService implements the main crawler application logic
 type CrawlerService struct {
 	infra            *infrastructure.Infrastructure
 	mode             domain.CrawlMode
+	settingsMu       sync.RWMutex // Guards keywords, see ApplySettings
 	keywords         []string
 	activeWorkers    int64
+	inFlight         int64 // URLs currently being processed
 	httpClient       *http.Client
-	rateLimiter      *rate.Limiter
+	rateLimiter      *ratelimit.Limiter
 	checkDeadDomains bool // Track if --domains flag was explicitly passed
+	options          CrawlOptions
+	scope            *domain.Scope
+	seedHost         string
+	sitemaps         *infrastructure.SitemapFetcher // Only set with UseSitemaps
+	retries          *queue.RetryQueue              // Transiently failed URLs waiting for their backoff
+	discovery        *discoveryRecorder             // Only set with DryRun
+	// Tasks the workers are processing by worker, for checkpoints
+	workingMu sync.Mutex
+	working   map[int]domain.URLTask
+	// Paused and resized by a Controller while crawling
+	pool workerPool
+	// Live results, dead links and errors, see Events
+	events *EventBus
+	// Run on every page, the built-in ones of the mode first, see extractors.go
+	extractors []domain.Extractor
+	// URLs taken against MaxPages, and what stops the crawl once a budget is used up
+	pagesTaken int64
+	stopBudget context.CancelFunc
+}
+
+// CrawlOptions holds optional crawler behaviour
+type CrawlOptions struct {
+	// Focused scores discovered links against the keywords and crawls the relevant ones first
+	Focused bool
+	// StopWhenIdle ends the crawl once the queue and the URL database stay empty
+	StopWhenIdle bool
+	// Incremental skips pages that have not changed since the previous crawl
+	Incremental bool
+	// Scope limits which discovered links are followed, relative to the start URL
+	Scope domain.ScopePolicy
+	// ExcludeDomains are never requested, neither crawled nor checked for dead links
+	ExcludeDomains []string
+	// URLLimits drops pathological URLs before they reach the queue
+	URLLimits domain.URLLimits
+	// URLFilter keeps discovered URLs to the include patterns and drops the exclude ones
+	URLFilter domain.URLFilter
+	// SkipHiddenLinks neither crawls nor checks links users can not see, they are likely
+	// honeypots that get crawlers blocked
+	SkipHiddenLinks bool
+	// QueryRules strips tracking parameters from discovered URLs before they are deduplicated
+	QueryRules domain.QueryRules
+	// Labels tag the start URL, every page found from it and their results
+	Labels []string
+	// UseSitemaps queues the URLs of the sitemaps listed in every robots.txt fetched,
+	// and of /sitemap.xml for the start host when its robots.txt lists none
+	UseSitemaps bool
+	// Robots is the robots compliance mode, every mode but off spaces out fetches by Crawl-delay
+	// and respects noindex
+	Robots domain.RobotsMode
+	// RobotsPolicy handles hosts whose robots.txt is forbidden or unreachable
+	RobotsPolicy domain.RobotsPolicy
+	// Rate caps the requests per second of all workers together, 0 means DefaultRate
+	Rate float64
+	// Accessibility audits every page for missing alt text, form labels and lang attributes,
+	// and headings skipping levels
+	Accessibility bool
+	// KeywordMatching adds stemming and synonyms to keyword matching
+	KeywordMatching domain.KeywordMatching
+	// DryRun tallies the discovered URLs per host for DiscoveryReport, the infrastructure
+	// is expected to keep its storage in memory
+	DryRun bool
+	// CheckpointInterval is how often the queue and seen URLs are saved for --resume,
+	// 0 only saves them when the crawl stops
+	CheckpointInterval time.Duration
+	// Resume continues from a restored checkpoint, the start URL only sets the scope then
+	// and is not crawled again
+	Resume bool
+	// MaxRetries is how often a URL failing with a network error, 5xx, 429 or a bot challenge
+	// is retried before it goes to the dead letters, challenges are counted apart. 0 gives up on
+	// the first failure
+	MaxRetries int
+	// DrainTimeout is how long pages in flight may take to finish once the crawl is stopped,
+	// 0 cancels them right away. The next run fetches cancelled pages again
+	DrainTimeout time.Duration
+	// MaxPages stops the crawl once this many URLs were taken by the workers, retries
+	// included, 0 means no limit. The rest of the queue is saved for --resume
+	MaxPages int
+	// MaxDuration stops the crawl once it ran this long, 0 means no limit
+	MaxDuration time.Duration
+	// Offline leaves out the extractors that make requests, for Reprocess
+	Offline bool
+	// RespectNoFollow follows no link of pages with a nofollow robots directive, and no link
+	// only found with rel="nofollow"
+	RespectNoFollow bool
+	// RespectNoIndex keeps no findings and no archived copy of pages with a noindex robots
+	// directive, strict robots mode does too
+	RespectNoIndex bool
+	// SlowThreshold records the fetches taking at least this long for the explorer's slow
+	// command, 0 records none
+	SlowThreshold time.Duration
+}
+
+// fetchResponse is what fetchURL hands back to processURL
+type fetchResponse struct {
+	content      string
+	statusCode   int
+	etag         string
+	lastModified string
+	contentType  string
+	robotsTag    string            // X-Robots-Tag header
+	headers      map[string]string // Headers listed in domain.RecordedHeaders
+	notModified  bool              // Server answered 304 to our conditional request
+	challenge    string            // Vendor of the bot challenge served instead of the page
+	finalURL     string            // Where redirects ended, empty when there were none
+	cached       bool              // Served by the response cache, nothing was requested
+	rendered     string            // DOM after scripts ran, rendering mode only
+	screenshot   string            // Screenshot file name, rendering mode only
 }
 
+// MaxCrawlDelay caps robots.txt crawl delays so one host cannot stall its URLs forever
+const MaxCrawlDelay = time.Minute
+
+// MaxRobotsRetries is how often a URL waits for its host's robots.txt before giving up
+const MaxRobotsRetries = 3
+
+const (
+	// MaxFetchRetries is the default of CrawlOptions.MaxRetries
+	MaxFetchRetries = 3
+
+	// Backoff before the first retry, doubled for every further one
+	RetryBaseDelay = 30 * time.Second
+	MaxRetryDelay  = 10 * time.Minute
+)
+
+// DefaultRate is the request rate of all workers together when CrawlOptions.Rate is not set
+const DefaultRate = 200
+
+// SitemapDepth is the depth sitemap URLs are queued at, as if linked from the start page
+const SitemapDepth = 1
+
+// IdleTimeout is how long the crawler must sit idle before StopWhenIdle ends the crawl
+const IdleTimeout = 10 * time.Second
+
 // NewCrawlerService creates a new crawler service
-func NewCrawlerService(infra *infrastructure.Infrastructure, mode domain.CrawlMode, keywords []string, checkDeadDomains bool) *CrawlerService {
+func NewCrawlerService(infra *infrastructure.Infrastructure, mode domain.CrawlMode, keywords []string, checkDeadDomains bool, options CrawlOptions) *CrawlerService {
 	transport := &http.Transport{
 		// Connection limits - CRITICAL FIX for aggressive domains
 		MaxIdleConnsPerHost: 25,  // Allow 25 idle connections per host (default: 2)
@@ -48,63 +187,240 @@ func NewCrawlerService(infra *infrastructure.Infrastructure, mode domain.CrawlMo
 		DisableCompression: false, // Keep compression for bandwidth efficiency^
 	}
 
-	return &CrawlerService{
+	// Seeds are added by StartCrawling and Submit
+	scope := domain.NewScope(options.Scope)
+	scope.Exclude(options.ExcludeDomains...)
+
+	// The per-host and per-address levels come with the infrastructure
+	infra.RateLimiter.SetGlobal(globalRate(options.Rate))
+
+	var discovery *discoveryRecorder
+	if options.DryRun {
+		discovery = newDiscoveryRecorder()
+	}
+
+	c := &CrawlerService{
 		infra:            infra,
 		mode:             mode,
 		keywords:         keywords,
 		checkDeadDomains: checkDeadDomains,
+		options:          options,
 		httpClient: &http.Client{
-			Timeout:   5 * time.Second, // 5 second timeout
-			Transport: transport,
+			Timeout:       5 * time.Second, // 5 second timeout
+			Transport:     transport,
+			CheckRedirect: infra.HTTPSUpgrades.CheckRedirect, // Learns http->https upgrades
 		},
-		rateLimiter: rate.NewLimiter(rate.Limit(200), 200),
+		rateLimiter: infra.RateLimiter,
+		scope:       scope,
+		retries:     queue.NewRetryQueue(),
+		discovery:   discovery,
+		working:     make(map[int]domain.URLTask),
+		events:      NewEventBus(),
+	}
+	infra.SetEvents(c.events)
+	c.extractors = append(c.builtinExtractors(), registeredExtractors()...)
+
+	return c
+}
+
+// globalRate is the request rate of all workers together, DefaultRate unless set
+func globalRate(requestsPerSecond float64) float64 {
+	if requestsPerSecond <= 0 {
+		return DefaultRate
+	}
+	return requestsPerSecond
+}
+
+// LiveSettings are the crawl settings that can change while crawling, see ApplySettings
+type LiveSettings struct {
+	Rate           float64 // 0 means DefaultRate
+	ExcludeDomains []string
+	Keywords       []string
+}
+
+// ApplySettings changes the settings of a running crawl, the frontier is kept
+func (c *CrawlerService) ApplySettings(settings LiveSettings) {
+	c.rateLimiter.SetGlobal(globalRate(settings.Rate))
+
+	c.scope.SetExcluded(settings.ExcludeDomains...)
+
+	c.settingsMu.Lock()
+	c.keywords = settings.Keywords
+	c.settingsMu.Unlock()
+}
+
+// currentKeywords returns the keywords hunted for, they can be reloaded during the crawl
+func (c *CrawlerService) currentKeywords() []string {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+	return c.keywords
+}
+
+// Events returns the bus the live events of the crawl are published on
+func (c *CrawlerService) Events() *EventBus {
+	return c.events
+}
+
+// DiscoveryReport returns what a dry run found so far, empty unless DryRun is set
+func (c *CrawlerService) DiscoveryReport() DiscoveryReport {
+	if c.discovery == nil {
+		return DiscoveryReport{}
+	}
+	return c.discovery.report()
+}
+
+// Submit queues the seed URLs of a new crawl job while the crawler runs, the scope grows to
+// include their hosts and the job's labels tag everything found from them. It returns the
+// URLs queued, invalid, excluded and already seen ones are skipped unless the job recrawls.
+// Jobs for another namespace than the crawl's are refused
+func (c *CrawlerService) Submit(job domain.CrawlJob) ([]string, error) {
+	if job.Namespace != "" && job.Namespace != c.infra.Namespace {
+		return nil, fmt.Errorf("this crawl stores into namespace %q, not %q", c.infra.Namespace, job.Namespace)
+	}
+
+	var queued []string
+	for _, link := range job.URLs {
+		link = c.normalizeURL(link)
+		if !domain.IsValidURL(link) || c.scope.Excludes(link) {
+			continue
+		}
+		c.scope.AddSeeds(link)
+
+		if !c.infra.Dedup.MarkSeen(domain.URLKey(link)) && !job.Recrawl {
+			continue
+		}
+
+		task := domain.URLTask{
+			URL:       link,
+			Depth:     0,
+			Timestamp: time.Now(),
+			Retries:   0,
+			Labels:    job.Labels,
+		}
+		if err := c.infra.URLQueue.PushOrSpill(task); err != nil {
+			continue
+		}
+		if c.discovery != nil {
+			c.discovery.recordQueued(link)
+		}
+		queued = append(queued, link)
 	}
+
+	return queued, nil
 }
 
-// StartCrawling starts the crawling process
+// StartCrawling starts the crawling process, without a start URL the workers wait for Submit
 func (c *CrawlerService) StartCrawling(ctx context.Context, startURL string, maxWorkers, maxDepth int) error {
-	startTask := domain.URLTask{
-		URL:       startURL,
-		Depth:     0,
-		Timestamp: time.Now(),
-		Retries:   0,
+	if startURL != "" {
+		startURL = c.normalizeURL(startURL)
+		startTask := domain.URLTask{
+			URL:       startURL,
+			Depth:     0,
+			Timestamp: time.Now(),
+			Retries:   0,
+			Labels:    c.options.Labels,
+		}
+
+		// Mark as seen
+		if c.infra.Dedup.MarkSeen(domain.URLKey(startURL)) || !c.options.Resume {
+			if err := c.infra.URLQueue.Push(startTask); err != nil {
+				return fmt.Errorf("failed to add start URL to queue: %v", err)
+			}
+		}
+		c.scope.AddSeeds(startURL)
+		if c.discovery != nil {
+			c.discovery.recordQueued(startURL)
+		}
+	}
+
+	if extractor, ok := c.infra.ContentExtractor.(*infrastructure.ContentExtractor); ok {
+		extractor.SetLinkFilter(c.allowsLinkCheck)
+		extractor.SetKeywordMatching(c.options.KeywordMatching)
+	}
+	c.infra.Metrics.SetRobotsMode(c.options.Robots)
+	if robots, ok := c.infra.RobotsChecker.(*infrastructure.RobotsChecker); ok {
+		robots.SetPolicy(c.options.RobotsPolicy)
 	}
 
-	if err := c.infra.URLQueue.Push(startTask); err != nil {
-		return fmt.Errorf("failed to add start URL to queue: %v", err)
+	if c.options.UseSitemaps {
+		if u, err := url.Parse(startURL); err == nil {
+			c.seedHost = u.Host
+		}
+		c.sitemaps = infrastructure.NewSitemapFetcher(c.infra.Identity)
+		if robots, ok := c.infra.RobotsChecker.(*infrastructure.RobotsChecker); ok {
+			robots.SetSitemapHandler(c.enqueueSitemaps)
+		}
 	}
 
-	// Add to Bloom filter
-	c.infra.BloomFilter.Add(startURL)
+	if c.options.StopWhenIdle {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go c.watchIdle(ctx, cancel)
+	}
 
-	// Start worker pool
-	var wg sync.WaitGroup
-	for i := 0; i < maxWorkers; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			c.worker(ctx, workerID, maxDepth)
-		}(i)
+	if c.options.MaxPages > 0 || c.options.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		c.startBudgets(ctx, cancel)
 	}
 
+	// Stopping the crawl stops the workers taking new URLs, the pages they are fetching
+	// get DrainTimeout to finish
+	work, stopWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopWork()
+	go c.drainAfter(ctx, work, stopWork)
+
+	// Start worker pool
+	c.pool.start(ctx, maxWorkers, func(quit <-chan struct{}, workerID int) {
+		c.worker(ctx, work, quit, workerID, maxDepth)
+	})
+
 	// Start metrics updater
 	go c.updateMetrics(ctx)
 
+	// Feed retries back once their backoff passed
+	go c.pumpRetries(ctx)
+
+	// Save the frontier now and then, a killed crawl resumes from the last checkpoint
+	if c.options.CheckpointInterval > 0 {
+		go c.saveCheckpoints(ctx, c.options.CheckpointInterval)
+	}
+
 	// Wait for all workers to finish
-	wg.Wait()
+	c.pool.wait()
+	stopWork()
+
+	// The queue is lost when the infrastructure closes
+	c.shutdown()
 
 	return nil
 }
 
-// worker implements the main crawler worker logic
-func (c *CrawlerService) worker(ctx context.Context, workerID, maxDepth int) {
+// worker implements the main crawler worker logic, it takes URLs until ctx is done or quit
+// is closed and processes them with work
+func (c *CrawlerService) worker(ctx, work context.Context, quit <-chan struct{}, workerID, maxDepth int) {
 	defer atomic.AddInt64(&c.activeWorkers, -1)
 	atomic.AddInt64(&c.activeWorkers, 1)
 
 	for {
+		// Paused by a Controller
+		if resumed := c.pool.paused(); resumed != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-quit:
+				return
+			case <-resumed:
+			}
+		}
+
 		select {
 		case <-ctx.Done():
 			return
+		case <-quit:
+			return
 		default:
 			// Try to get a URL from the queue
 			task, err := c.infra.URLQueue.Pop()
@@ -113,166 +429,528 @@ func (c *CrawlerService) worker(ctx context.Context, workerID, maxDepth int) {
 				time.Sleep(10 * time.Millisecond)
 				continue
 			}
+			if !c.takePage() {
+				c.returnTask(task)
+				continue
+			}
 
 			// Process the URL
-			c.processURL(ctx, task, maxDepth)
+			atomic.AddInt64(&c.inFlight, 1)
+			c.startWork(workerID, task)
+			c.processURL(work, task, maxDepth, workerID)
+			if limiter, ok := c.infra.URLQueue.(domain.HostLimiter); ok {
+				limiter.Done(task)
+			}
+			// Tasks cut short by the shutdown go back to the storage with the queue
+			if work.Err() == nil {
+				c.finishWork(workerID)
+			}
+			atomic.AddInt64(&c.inFlight, -1)
 		}
 	}
 }
 
 // processes a single URL
-func (c *CrawlerService) processURL(ctx context.Context, task domain.URLTask, maxDepth int) {
+func (c *CrawlerService) processURL(ctx context.Context, task domain.URLTask, maxDepth, workerID int) {
 	startTime := time.Now()
 
+	// Tasks queued before their host redirected to https, retried ones and the ones added
+	// from the dashboard are fetched over https too
+	task.URL = c.normalizeURL(task.URL)
+
 	result := domain.CrawlResult{
 		URL:         task.URL,
 		ProcessedAt: startTime,
+		Labels:      task.Labels,
 	}
 
+	requeued := false
 	defer func() {
+		if requeued {
+			c.pageStored(result.URL, false)
+			return
+		}
 		result.ProcessTime = time.Since(startTime)
-		c.infra.Storage.StoreResult(result)
+		err := c.infra.Storage.StoreResult(result)
+		c.pageStored(result.URL, err == nil)
 		c.infra.Metrics.UpdateURLsProcessed(1)
 	}()
 
-	// Check robots.txt compliance incase we got ourselves explicitly blocked or rather forbidden
-	if !c.infra.RobotsChecker.CanFetch("GolamV2-Crawler/1.0", task.URL) {
-		result.Error = "blocked by robots.txt"
+	// Hosts that kept serving bot challenges are not requested again
+	if c.infra.Challenges.Quarantined(domain.GetDomain(task.URL)) {
+		result.Error = "domain quarantined after repeated bot challenges"
 		return
 	}
 
-	// Respect crawl delay - DISABLED FOR PERFORMANCE
-	// domain := domain.GetDomain(task.URL)
-	// crawlDelay := c.infra.RobotsChecker.GetCrawlDelay("GolamV2-Crawler/1.0", domain)
-	// if crawlDelay > 0 {
-	//     time.Sleep(crawlDelay)
-	// }
+	// Check robots.txt compliance incase we got ourselves explicitly blocked or rather forbidden
+	if c.options.Robots != domain.RobotsOff {
+		switch c.infra.RobotsChecker.Check(c.infra.Identity.Agent(), task.URL) {
+		case domain.RobotsBlocked:
+			result.Error = "blocked by robots.txt"
+			return
+		case domain.RobotsRetryLater:
+			if requeued = c.retryAfterRobots(task); !requeued {
+				result.Error = "robots.txt unreachable"
+			}
+			return
+		}
+	}
+
+	// Respect crawl delay, the queue holds the host's next tasks back instead of this worker sleeping.
+	// The limiter keeps the host's requests to the delay too, dead link checks included
+	if c.options.Robots != domain.RobotsOff {
+		host := domain.GetDomain(task.URL)
+		if crawlDelay := c.infra.RobotsChecker.GetCrawlDelay(c.infra.Identity.Agent(), host); crawlDelay > 0 {
+			crawlDelay = min(crawlDelay, MaxCrawlDelay)
+			c.rateLimiter.SetHostDelay(host, crawlDelay)
+			if scheduler, ok := c.infra.URLQueue.(domain.HostScheduler); ok {
+				scheduler.SetHostDelay(host, crawlDelay)
+			}
+		}
+	}
 
 	// Rate limiting
-	if err := c.rateLimiter.Wait(ctx); err != nil {
-		result.Error = "rate limit context cancelled"
+	// Pages cut short by the shutdown are not stored, the next run fetches them again
+	if err := c.rateLimiter.Wait(ctx, task.URL); err != nil {
+		requeued = true
 		return
 	}
 
+	// Previous state of the page, only looked up for incremental recrawls
+	var previous *domain.PageState
+	pageStates, incremental := c.infra.Storage.(domain.PageStateStore)
+	incremental = incremental && c.options.Incremental
+	if incremental {
+		previous, _ = pageStates.GetPageState(task.URL)
+	}
+
 	// Fetch the URL
-	content, statusCode, err := c.fetchURL(task.URL)
-	result.StatusCode = statusCode
+	fetchStart := time.Now()
+	resp, err := c.fetchURL(ctx, task.URL, previous)
+	if ctx.Err() != nil {
+		requeued = true
+		return
+	}
+	c.recordFetch(workerID, task, resp, err, time.Since(fetchStart))
+	result.StatusCode = resp.statusCode
+	result.Headers = resp.headers
+	result.FinalURL = resp.finalURL
+
+	// Hosts that answered are mapped once, whatever they answered
+	if c.infra.DNS != nil && resp.statusCode != 0 {
+		c.infra.DNS.Collect(task.URL)
+	}
+
+	// A WAF or CAPTCHA answered, the page was not crawled
+	if resp.challenge != "" {
+		requeued = c.challenged(task, &result, resp.challenge)
+		return
+	}
+	if err == nil && resp.statusCode < 400 {
+		c.infra.Challenges.Clear(domain.GetDomain(task.URL))
+	}
+
+	// Network errors and server trouble are usually temporary, retry with backoff
+	if (err != nil && resp.statusCode == 0) || resp.statusCode >= 500 || resp.statusCode == http.StatusTooManyRequests {
+		if err == nil {
+			err = fmt.Errorf("server answered %d", resp.statusCode)
+		}
+		if requeued = c.retryLater(task); requeued {
+			return
+		}
+		c.deadLetter(task, resp.statusCode, err)
+	}
 
 	if err != nil {
+		logging.Debugf("Fetching %s failed: %v", task.URL, err)
+		c.events.Publish(domain.CrawlEvent{Type: domain.EventError, URL: task.URL, Message: err.Error()})
 		result.Error = err.Error()
 		c.infra.Metrics.UpdateErrors(1)
 		return
 	}
+	logging.Debugf("Fetched %s (%d) in %s", task.URL, resp.statusCode, time.Since(startTime).Round(time.Millisecond))
+
+	content := resp.content
+	contentHash := ""
+	if !resp.notModified {
+		contentHash = hashContent(content)
+		c.infra.URLCollapser.Observe(task.URL, contentHash)
+		result.ContentHash = contentHash
+		result.ContentLength = int64(len(content))
+	} else {
+		// A 304 has no body, the page is still what we hashed last time
+		result.ContentHash = previous.ContentHash
+		result.ContentLength = previous.ContentLength
+	}
+
+	if incremental {
+		// Nothing changed, skip extraction but keep walking the links we saw last time
+		if resp.notModified || (previous != nil && previous.ContentHash == contentHash) {
+			result.Unchanged = true
+			c.infra.Metrics.UpdatePagesUnchanged(1)
+			if task.Depth < maxDepth {
+				result.NewURLs = c.addNewURLs(previous.Links, task.Depth+1, nil, task.Labels)
+			}
+			result.Links = previous.Links
+			return
+		}
+	}
+
+	body := content // As fetched, for the archive
+	// Rendering mode swaps the raw body for the DOM after scripts ran
+	if resp.rendered != "" {
+		content = resp.rendered
+	}
+	result.Screenshot = resp.screenshot
+
+	extractStart := time.Now()
 
-	// Extract title
-	result.Title = c.infra.ContentExtractor.ExtractTitle(content)
+	// The extraction steps below share one parsed page, freed once the page is done
+	if documents, ok := c.infra.ContentExtractor.(domain.DocumentCache); ok {
+		defer documents.ReleaseDocument(content)
+	}
 
-	// Extract data based on mode
-	switch c.mode {
-	case "email":
-		result.Emails = c.infra.ContentExtractor.ExtractEmails(content)
-		c.infra.Metrics.UpdateEmailsFound(int64(len(result.Emails)))
+	c.analyzePage(&result, content, resp.robotsTag)
 
-	case "keywords":
-		result.Keywords = c.infra.ContentExtractor.ExtractKeywords(content, c.keywords)
-		keywordCount := int64(0)
-		for _, count := range result.Keywords {
-			keywordCount += int64(count)
+	// Pages asking not to be indexed are not kept when that is respected
+	if c.infra.Archive != nil && !result.NoIndex {
+		err := c.infra.Archive.ArchivePage(domain.ArchivedPage{
+			URL:         task.URL,
+			StatusCode:  resp.statusCode,
+			ContentType: resp.contentType,
+			Body:        body,
+			FetchedAt:   startTime,
+		})
+		if err != nil {
+			logging.Warnf("Failed to archive %s: %v", task.URL, err)
 		}
-		c.infra.Metrics.UpdateKeywordsFound(keywordCount)
+	}
 
-	case "domains":
-		links := c.infra.ContentExtractor.ExtractLinks(content, task.URL)
-		result.DeadLinks, result.DeadDomains = c.infra.ContentExtractor.CheckDeadLinks(links, task.URL)
-		c.infra.Metrics.UpdateLinksChecked(int64(len(links)))
-		c.infra.Metrics.UpdateDeadLinksFound(int64(len(result.DeadLinks)))
-		c.infra.Metrics.UpdateDeadDomainsFound(int64(len(result.DeadDomains)))
+	// Every link is kept for the link graph, new ones are crawled if not at max depth
+	pageLinks := c.followedLinks(content, &result)
+	result.Links = pageLinks
+	if task.Depth < maxDepth {
+		scores := c.scoreLinks(content, task.URL, result.Keywords)
+		result.NewURLs = c.addNewURLs(pageLinks, task.Depth+1, scores, task.Labels)
+	}
+	c.infra.Metrics.RecordExtract(time.Since(extractStart))
 
-	case "all":
-		// Extract everything - enable dead link checking if domains mode was requested
-		result.Emails = c.infra.ContentExtractor.ExtractEmails(content)
-		result.Keywords = c.infra.ContentExtractor.ExtractKeywords(content, c.keywords)
+	if incremental {
+		// Remember every link on the page, a later run may reach it at a shallower depth
+		pageStates.StorePageState(domain.PageState{
+			URL:           task.URL,
+			ETag:          resp.etag,
+			LastModified:  resp.lastModified,
+			ContentHash:   contentHash,
+			ContentLength: int64(len(content)),
+			Links:         pageLinks,
+			FetchedAt:     time.Now(),
+		})
+	}
+}
+
+// analyzePage runs the extraction steps on the body of a page whose result holds its URL
+// and status already, for crawls and Reprocess alike
+func (c *CrawlerService) analyzePage(result *domain.CrawlResult, content, robotsTag string) {
+	// Links users can not see are recorded, and skipped with SkipHiddenLinks
+	result.HiddenLinks = c.infra.ContentExtractor.ExtractHiddenLinks(content, result.URL)
+	if hidden := int64(len(result.HiddenLinks)); c.options.SkipHiddenLinks {
+		c.infra.Metrics.UpdateHiddenLinks(hidden, hidden)
+	} else {
+		c.infra.Metrics.UpdateHiddenLinks(hidden, 0)
+	}
 
-		// Check if domains mode was explicitly requested
-		if c.shouldCheckDeadLinks() {
-			links := c.infra.ContentExtractor.ExtractLinks(content, task.URL)
-			result.DeadLinks, result.DeadDomains = c.infra.ContentExtractor.CheckDeadLinks(links, task.URL)
-			c.infra.Metrics.UpdateLinksChecked(int64(len(links)))
-			c.infra.Metrics.UpdateDeadLinksFound(int64(len(result.DeadLinks)))
-			c.infra.Metrics.UpdateDeadDomainsFound(int64(len(result.DeadDomains)))
-		} else {
-			// Skip dead link checking for performance when not explicitly requested
-			result.DeadLinks = []string{}   // Empty - no dead link checking
-			result.DeadDomains = []string{} // Empty - no dead link checking
+	// Error pages are not audited, they are rarely the site's own markup
+	if c.options.Accessibility && result.StatusCode < 400 {
+		if auditor, ok := c.infra.ContentExtractor.(domain.AccessibilityAuditor); ok {
+			report := auditor.AuditAccessibility(content)
+			result.Accessibility = &report
+			c.infra.Metrics.UpdateAccessibility(int64(report.IssueCount()))
 		}
+	}
 
-		c.infra.Metrics.UpdateEmailsFound(int64(len(result.Emails)))
-		keywordCount := int64(0)
-		for _, count := range result.Keywords {
-			keywordCount += int64(count)
+	// Robots directives of the page, from its meta tags and X-Robots-Tag header
+	respectNoIndex := c.options.RespectNoIndex || c.options.Robots == domain.RobotsStrict
+	if respectNoIndex || c.options.RespectNoFollow {
+		directives := robotsTag + "," + c.infra.ContentExtractor.ExtractMetaRobots(content)
+		result.NoIndex = respectNoIndex && domain.HasNoIndex(directives)
+		if c.options.RespectNoFollow && domain.HasNoFollow(directives) {
+			result.NoFollow = true
+			c.infra.Metrics.UpdateNoFollow(1, 0)
 		}
-		c.infra.Metrics.UpdateKeywordsFound(keywordCount)
+	}
+	if c.options.RespectNoFollow && !result.NoFollow {
+		result.NoFollowLinks = c.infra.ContentExtractor.ExtractNoFollowLinks(content, result.URL)
+		c.infra.Metrics.UpdateNoFollow(0, int64(len(result.NoFollowLinks)))
 	}
 
-	// Extract new URLs for crawling if not at max depth)
-	if task.Depth < maxDepth {
-		newURLs := c.infra.ContentExtractor.ExtractLinks(content, task.URL)
-		result.NewURLs = c.addNewURLs(newURLs, task.Depth+1)
+	// Noindex pages keep no findings, their links are still followed unless nofollow
+	if result.NoIndex {
+		c.infra.Metrics.UpdatePagesNoIndex(1)
+	} else {
+		c.extractFindings(result, content, result.URL)
+
+		// Titles and descriptions are indexed by domain to find the pages sharing them
+		if metaStore, ok := c.infra.Storage.(domain.PageMetaStore); ok && result.StatusCode < 400 {
+			meta := domain.PageMeta{URL: result.URL, Title: result.Title, Description: result.Description}
+			if err := metaStore.StorePageMeta(meta); err != nil {
+				logging.Debugf("Failed to index page meta of %s: %v", result.URL, err)
+			}
+		}
+	}
+
+	if result.StatusCode < 400 {
+		c.detectDuplicate(result, content)
+	}
+}
+
+// retryAfterRobots puts a task back until its host's robots.txt is fetched again, false once it waited too often
+func (c *CrawlerService) retryAfterRobots(task domain.URLTask) bool {
+	if task.RobotsWaits >= MaxRobotsRetries {
+		return false
 	}
+
+	if scheduler, ok := c.infra.URLQueue.(domain.HostScheduler); ok {
+		scheduler.DeferHost(domain.GetDomain(task.URL), time.Now().Add(infrastructure.RobotsRetryInterval))
+	}
+
+	task.RobotsWaits++
+	c.infra.URLQueue.PushOrSpill(task)
+	return true
 }
 
-// fetches content from a URL
-func (c *CrawlerService) fetchURL(url string) (string, int, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// pageStored releases the findings of background checks held back until the page's result
+// was stored
+func (c *CrawlerService) pageStored(pageURL string, stored bool) {
+	if holder, ok := c.infra.ContentExtractor.(domain.FindingsHolder); ok {
+		holder.PageStored(pageURL, stored)
+	}
+}
+
+// extractFindings runs the extractors of the crawl on a page, see builtinExtractors
+func (c *CrawlerService) extractFindings(result *domain.CrawlResult, content, pageURL string) {
+	for _, extractor := range c.extractors {
+		extractor.Extract(content, pageURL, result)
+	}
+}
+
+// detectDuplicate flags a page whose content was already crawled under another URL, or
+// whose text is nearly the same as another page's with near duplicates on
+func (c *CrawlerService) detectDuplicate(result *domain.CrawlResult, content string) {
+	var fingerprint uint64
+	if fingerprinter, ok := c.infra.ContentExtractor.(domain.PageFingerprinter); ok {
+		fingerprint = fingerprinter.Fingerprint(content)
+		result.SimHash = fmt.Sprintf("%016x", fingerprint)
+	}
+
+	original, near := c.infra.Duplicates.Observe(result.URL, result.ContentHash, fingerprint)
+	if original == "" {
+		return
+	}
+	result.DuplicateOf = original
+	result.NearDuplicate = near
+	c.infra.Metrics.UpdateDuplicatePages(near)
+}
+
+// hashContent fingerprints a page body so unchanged pages can be recognised
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// scoreLinks computes relevance scores for the links of a page when focused crawling is on
+func (c *CrawlerService) scoreLinks(content, pageURL string, pageKeywords map[string]int) map[string]float64 {
+	keywords := c.currentKeywords()
+	if !c.options.Focused || len(keywords) == 0 {
+		return nil
+	}
+
+	// Links found on a page that already matched are worth following a bit sooner
+	pageBonus := 0.0
+	if len(pageKeywords) > 0 {
+		pageBonus = 0.5
+	}
+
+	scores := make(map[string]float64)
+	for _, link := range c.infra.ContentExtractor.ExtractAnchors(content, pageURL) {
+		if score := domain.RelevanceScore(link, keywords); score > 0 {
+			scores[link.URL] = score + pageBonus
+		}
+	}
+
+	return scores
+}
+
+// fetches content from a URL, previous makes the request conditional when known
+func (c *CrawlerService) fetchURL(ctx context.Context, url string, previous *domain.PageState) (fetchResponse, error) {
+	if c.infra.Renderer != nil {
+		return c.renderURL(ctx, url)
+	}
+
+	// Fetched moments ago, before the URL came around again
+	if cached, ok := c.infra.Responses.Get(url); ok && cached.HasBody {
+		c.infra.Metrics.UpdateResponseCacheHits(1)
+		result := newFetchResponse(cached.StatusCode, cached.Header)
+		result.content = cached.Body
+		result.finalURL = cached.FinalURL
+		result.cached = true
+		result.challenge = domain.DetectChallenge(cached.StatusCode, cached.Header, cached.Body)
+		return result, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", 0, err
+		return fetchResponse{}, err
 	}
 
-	req.Header.Set("User-Agent", "GolamV2-Crawler/1.0")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	c.infra.Identity.Apply(req)
+	if previous != nil {
+		if previous.ETag != "" {
+			req.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			req.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
 
+	req, trace := metrics.TraceRequest(req)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", 0, err
+		return fetchResponse{}, err
 	}
 	defer resp.Body.Close()
+	defer func() { c.infra.Metrics.RecordFetch(trace.Timing()) }()
+
+	result := newFetchResponse(resp.StatusCode, resp.Header)
+	if final := resp.Request.URL.String(); final != url {
+		result.finalURL = final
+	}
+	cached := infrastructure.CachedResponse{StatusCode: resp.StatusCode, FinalURL: result.finalURL, Header: resp.Header}
+
+	if resp.StatusCode == http.StatusNotModified && previous != nil {
+		result.notModified = true
+		return result, nil
+	}
 
 	// Check Content-Type header - only process HTML content for performance
-	contentType := resp.Header.Get("Content-Type")
-	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "text/html") &&
-		!strings.Contains(strings.ToLower(contentType), "application/xhtml") {
+	contentType := result.contentType
+	if !isHTML(contentType) {
 		// Skip non-HTML content (images, PDFs, videos, etc.)
-		return "", resp.StatusCode, fmt.Errorf("skipped non-HTML content: %s", contentType)
+		c.infra.Responses.Add(url, cached)
+		return result, fmt.Errorf("skipped non-HTML content: %s", contentType)
 	}
 
 	// Reduced response size limit to prevent memory issues (max 2MB) - Not Guaranteed to be enough for all pages, but just better than 10MB
 	// This prevents 50 workers * 2MB = 100MB max instead of 500MB
 	limitedReader := io.LimitReader(resp.Body, 2*1024*1024)
-	content, err := io.ReadAll(limitedReader)
+	content, err := bufpool.ReadString(limitedReader, resp.ContentLength)
 	if err != nil {
-		return "", resp.StatusCode, err
+		return result, err
+	}
+
+	result.content = content
+	result.challenge = domain.DetectChallenge(resp.StatusCode, resp.Header, result.content)
+	cached.Body, cached.HasBody = result.content, true
+	c.infra.Responses.Add(url, cached)
+	return result, nil
+}
+
+// renderURL loads a page in the headless browser, the response of its navigation stands in
+// for the HTTP fetch. Pages the browser loaded but could not render keep the raw body
+func (c *CrawlerService) renderURL(ctx context.Context, url string) (fetchResponse, error) {
+	page, err := c.infra.Renderer.Render(ctx, url)
+	if page.StatusCode == 0 {
+		return fetchResponse{}, err
+	}
+
+	result := newFetchResponse(page.StatusCode, page.Header)
+	result.finalURL = page.FinalURL
+	if !isHTML(result.contentType) {
+		return result, fmt.Errorf("skipped non-HTML content: %s", result.contentType)
+	}
+	if err != nil {
+		if page.Body == "" {
+			return result, err
+		}
+		logging.Debugf("Rendering %s failed, keeping the page as received: %v", url, err)
+	}
+
+	result.content = page.Body
+	result.rendered = page.HTML
+	result.screenshot = page.Screenshot
+	result.challenge = domain.DetectChallenge(page.StatusCode, page.Header, page.Body)
+	return result, nil
+}
+
+// isHTML reports whether a Content-Type is HTML, pages without one are taken as HTML
+func isHTML(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "" || strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml")
+}
+
+func newFetchResponse(statusCode int, header http.Header) fetchResponse {
+	return fetchResponse{
+		statusCode:   statusCode,
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+		contentType:  header.Get("Content-Type"),
+		robotsTag:    strings.Join(header.Values("X-Robots-Tag"), ","),
+		headers:      recordedHeaders(header),
 	}
+}
 
-	return string(content), resp.StatusCode, nil
+// recordedHeaders picks the headers worth keeping with the result
+func recordedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range domain.RecordedHeaders {
+		if value := header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
 }
 
-// addNewURLs adds new URLs to the crawling queue
-func (c *CrawlerService) addNewURLs(urls []string, depth int) []string {
+// addNewURLs adds new URLs to the crawling queue, scores is optional and labels are those
+// of the page they were found on
+func (c *CrawlerService) addNewURLs(urls []string, depth int, scores map[string]float64, labels []string) []string {
 	var newURLs []string
 
-	for _, url := range urls {
+	for _, link := range urls {
 		// Check if URL is valid
-		if !domain.IsValidURL(url) {
+		if !domain.IsValidURL(link) {
 			continue
 		}
 
-		// Check Bloom filter for duplicates
-		if c.infra.BloomFilter.Test(url) {
-			continue // Likely already seen by bloom
+		// Generated URL traps can produce megabyte long URLs, keep them out of the queue
+		if err := c.options.URLLimits.Check(link); err != nil {
+			c.infra.Metrics.UpdateURLsRejected(1)
+			continue
 		}
 
-		// Add to Bloom filter
-		c.infra.BloomFilter.Add(url)
+		// Stay inside the --scope of the crawl and its URL patterns
+		if !c.scope.Allows(link) || !c.options.URLFilter.Allows(link) {
+			if c.discovery != nil {
+				c.discovery.recordOutOfScope(link)
+			}
+			continue
+		}
+
+		// Tracking parameters (utm_*, fbclid) and the ones --query-rule strips
+		url := c.options.QueryRules.Apply(link)
+
+		// URLs differing only in learned volatile params (sort=, sessionid=) are one URL,
+		// fetched over https once their host redirected there
+		url = c.normalizeURL(c.infra.URLCollapser.Canonical(url))
+
+		// Skip URLs already seen (bloom filter and/or stored keys, see --dedup),
+		// http and https variants are the same entry
+		if !c.infra.Dedup.MarkSeen(domain.URLKey(url)) {
+			continue
+		}
 
 		// Create URL task
 		task := domain.URLTask{
@@ -280,11 +958,14 @@ func (c *CrawlerService) addNewURLs(urls []string, depth int) []string {
 			Depth:     depth,
 			Timestamp: time.Now(),
 			Retries:   0,
+			Score:     scores[link],
+			Labels:    labels,
 		}
 
 		// Try to add to queue, if full, store in database
-		if err := c.infra.URLQueue.Push(task); err != nil {
-			c.infra.Storage.StoreURL(task)
+		c.infra.URLQueue.PushOrSpill(task)
+		if c.discovery != nil {
+			c.discovery.recordQueued(url)
 		}
 
 		newURLs = append(newURLs, url)
@@ -293,6 +974,42 @@ func (c *CrawlerService) addNewURLs(urls []string, depth int) []string {
 	return newURLs
 }
 
+// normalizeURL is the form of a URL in the frontier: its host in lowercase ACE form, and
+// https for hosts that redirected us there permanently
+func (c *CrawlerService) normalizeURL(link string) string {
+	return c.infra.HTTPSUpgrades.Upgrade(domain.NormalizeURL(link))
+}
+
+// enqueueSitemaps feeds the URLs from a host's sitemaps into the frontier
+func (c *CrawlerService) enqueueSitemaps(host string, sitemaps []string) {
+	// Keeps StopWhenIdle from ending the crawl while sitemaps download
+	atomic.AddInt64(&c.inFlight, 1)
+	defer atomic.AddInt64(&c.inFlight, -1)
+
+	if len(sitemaps) == 0 && host == c.seedHost {
+		sitemaps = []string{"https://" + host + "/sitemap.xml"}
+	}
+
+	for _, sitemap := range sitemaps {
+		entries, err := c.sitemaps.Fetch(sitemap)
+		if err != nil {
+			continue
+		}
+
+		// Pages the site ranks above the default priority are fetched a little sooner, by up
+		// to half a depth level, the ones it ranks below a little later
+		urls := make([]string, 0, len(entries))
+		scores := make(map[string]float64, len(entries))
+		for _, entry := range entries {
+			urls = append(urls, entry.Loc)
+			if score := entry.Priority - infrastructure.DefaultSitemapPriority; score != 0 {
+				scores[entry.Loc] = score
+			}
+		}
+		c.addNewURLs(urls, SitemapDepth, scores, nil)
+	}
+}
+
 // periodically updates metrics
 func (c *CrawlerService) updateMetrics(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Second)
@@ -317,8 +1034,248 @@ func (c *CrawlerService) updateMetrics(ctx context.Context) {
 	}
 }
 
+// watchIdle cancels the crawl once there has been nothing to do for IdleTimeout
+func (c *CrawlerService) watchIdle(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var idleSince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.isIdle() {
+				idleSince = time.Time{}
+				continue
+			}
+
+			if idleSince.IsZero() {
+				idleSince = time.Now()
+			} else if time.Since(idleSince) >= IdleTimeout {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// isIdle reports whether no URL is being processed or waiting anywhere
+func (c *CrawlerService) isIdle() bool {
+	if atomic.LoadInt64(&c.inFlight) > 0 || !c.infra.URLQueue.IsEmpty() || c.retries.Len() > 0 {
+		return false
+	}
+
+	storageMetrics, err := c.infra.Storage.GetMetrics()
+	return err == nil && storageMetrics.URLsInDB == 0
+}
+
+// followedLinks extracts the links of a page, without the hidden ones with SkipHiddenLinks
+// and without the nofollow ones, or any on a nofollow page, with RespectNoFollow
+func (c *CrawlerService) followedLinks(content string, result *domain.CrawlResult) []string {
+	if result.NoFollow {
+		return nil
+	}
+
+	links := c.infra.ContentExtractor.ExtractLinks(content, result.URL)
+	skip := make(map[string]bool)
+	if c.options.SkipHiddenLinks {
+		for _, link := range result.HiddenLinks {
+			skip[link] = true
+		}
+	}
+	for _, link := range result.NoFollowLinks {
+		skip[link] = true
+	}
+	if len(skip) == 0 {
+		return links
+	}
+
+	followed := links[:0]
+	for _, link := range links {
+		if !skip[link] {
+			followed = append(followed, link)
+		}
+	}
+	return followed
+}
+
+// withAnchorText pairs links with their anchor text so dead link reports can show it,
+// src links (images, scripts) have none
+func (c *CrawlerService) withAnchorText(content, pageURL string, links []string) []domain.Link {
+	texts := make(map[string]string)
+	for _, anchor := range c.infra.ContentExtractor.ExtractAnchors(content, pageURL) {
+		texts[anchor.URL] = anchor.Text
+	}
+
+	paired := make([]domain.Link, len(links))
+	for i, link := range links {
+		paired[i] = domain.Link{URL: link, Text: texts[link]}
+	}
+	return paired
+}
+
+// allowsLinkCheck keeps dead link checks as polite as the crawl: no excluded domains and
+// nothing robots.txt disallows. Hosts whose robots.txt is unreachable are still checked,
+// that is how dead domains get found
+func (c *CrawlerService) allowsLinkCheck(link string) bool {
+	if c.scope.Excludes(link) || c.infra.Challenges.Quarantined(domain.GetDomain(link)) {
+		return false
+	}
+	if c.options.Robots == domain.RobotsOff {
+		return true
+	}
+	return c.infra.RobotsChecker.CheckLink(c.infra.Identity.Agent(), link) != domain.RobotsBlocked
+}
+
+// retryLater schedules a transiently failed task with exponential backoff, false once it is out of retries
+func (c *CrawlerService) retryLater(task domain.URLTask) bool {
+	if task.Retries >= c.options.MaxRetries {
+		return false
+	}
+
+	delay := min(RetryBaseDelay<<task.Retries, MaxRetryDelay)
+	logging.Debugf("Retrying %s in %s", task.URL, delay)
+	task.Retries++
+	c.retries.Add(task, time.Now().Add(delay))
+	c.infra.Metrics.UpdateURLsRetried(1)
+	return true
+}
+
+// challenged marks the result of a page answered by a bot challenge and backs its host off.
+// The page is tried again after the backoff, true when it was requeued. Hosts challenging
+// QuarantineAfter times in a row are quarantined
+func (c *CrawlerService) challenged(task domain.URLTask, result *domain.CrawlResult, vendor string) bool {
+	result.Challenge = vendor
+	result.Error = fmt.Sprintf("answered by a %s bot challenge", vendor)
+
+	host := domain.GetDomain(task.URL)
+	strikes := c.infra.Challenges.Record(host)
+	if strikes >= infrastructure.QuarantineAfter {
+		quarantined := int64(0)
+		if strikes == infrastructure.QuarantineAfter {
+			logging.Warnf("Quarantined %s after %d bot challenges in a row, its URLs are skipped", host, strikes)
+			quarantined = 1
+		}
+		c.infra.Metrics.UpdateChallenges(1, quarantined)
+		return false
+	}
+	c.infra.Metrics.UpdateChallenges(1, 0)
+
+	backoff := infrastructure.ChallengeBackoffAfter(strikes)
+	logging.Debugf("%s answered %s with a %s challenge, backing off for %s", host, task.URL, vendor, backoff)
+	if scheduler, ok := c.infra.URLQueue.(domain.HostScheduler); ok {
+		scheduler.DeferHost(host, time.Now().Add(backoff))
+	}
+
+	if task.ChallengeWaits >= c.options.MaxRetries {
+		return false
+	}
+	task.ChallengeWaits++
+	c.infra.URLQueue.PushOrSpill(task)
+	return true
+}
+
+// recordFetch writes a fetch to the request log, and keeps it when it took longer than
+// SlowThreshold. Responses served by the response cache are never slow
+func (c *CrawlerService) recordFetch(workerID int, task domain.URLTask, resp fetchResponse, err error, took time.Duration) {
+	slow := c.options.SlowThreshold > 0 && took >= c.options.SlowThreshold && !resp.cached
+	if c.infra.RequestLog == nil && !slow {
+		return
+	}
+
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+	if c.infra.RequestLog != nil {
+		c.infra.RequestLog.Fetch(logging.FetchRecord{
+			Worker:   workerID,
+			URL:      task.URL,
+			Status:   resp.statusCode,
+			Bytes:    int64(len(resp.content)),
+			Duration: took,
+			Attempt:  task.Retries,
+			Error:    message,
+			Slow:     slow,
+			Cached:   resp.cached,
+		})
+	}
+	if !slow {
+		return
+	}
+
+	logging.Debugf("Slow fetch of %s took %s", task.URL, took.Round(time.Millisecond))
+	c.infra.Metrics.UpdateSlowRequests(1)
+	if store, ok := c.infra.Storage.(domain.SlowRequestLog); ok {
+		err := store.StoreSlowRequest(domain.SlowRequest{
+			URL:        task.URL,
+			Worker:     workerID,
+			Duration:   took,
+			StatusCode: resp.statusCode,
+			Bytes:      int64(len(resp.content)),
+			Error:      message,
+			FetchedAt:  time.Now().Add(-took),
+		})
+		if err != nil {
+			logging.Warnf("Failed to record the slow fetch of %s: %v", task.URL, err)
+		}
+	}
+}
+
+// deadLetter keeps a task that failed all its retries for inspection (explore: deadletter list),
+// and un-marks it so a later rediscovery can try it again
+func (c *CrawlerService) deadLetter(task domain.URLTask, statusCode int, err error) {
+	logging.Debugf("Giving up on %s after %d retries: %v", task.URL, task.Retries, err)
+	if store, ok := c.infra.Storage.(domain.DeadLetterStore); ok {
+		store.StoreDeadLetter(domain.DeadLetter{
+			Task:       task,
+			Error:      err.Error(),
+			StatusCode: statusCode,
+			FailedAt:   time.Now(),
+		})
+	}
+	c.infra.Metrics.UpdateURLsDeadLettered(1)
+	c.infra.Dedup.Forget(domain.URLKey(task.URL))
+}
+
+// pumpRetries moves retried tasks back into the frontier once their backoff has passed
+func (c *CrawlerService) pumpRetries(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, task := range c.retries.PopDue(now) {
+				c.infra.URLQueue.PushOrSpill(task)
+			}
+		}
+	}
+}
+
+// MaxSchemeLinksPerPage caps the ftp, mailto and tel links checked on one page
+const MaxSchemeLinksPerPage = 50
+
+// checkSchemeLinks queues the ftp, mailto and tel links of a page on the dead link checks,
+// links to excluded domains are left alone
+func (c *CrawlerService) checkSchemeLinks(content, pageURL string, labels []string) {
+	var links []domain.Link
+	for _, link := range c.infra.ContentExtractor.ExtractSchemeLinks(content) {
+		if len(links) == MaxSchemeLinksPerPage {
+			break
+		}
+		if !c.scope.Excludes(link.URL) {
+			links = append(links, link)
+		}
+	}
+	c.infra.ContentExtractor.CheckSchemeLinks(links, pageURL, labels)
+}
+
 // shouldCheckDeadLinks determines if dead link checking should be enabled
 // This checks if the --domains flag was explicitly passed, even in "all" mode
 func (c *CrawlerService) shouldCheckDeadLinks() bool {
-	return c.checkDeadDomains || c.mode == "domains"
+	return !c.options.Offline && (c.checkDeadDomains || c.mode == "domains")
 }
@@ -0,0 +1,104 @@
+package application
+
+import (
+	"sort"
+	"sync"
+
+	"golamv2/internal/domain"
+)
+
+// MaxDiscoverySamples is how many URLs of each host a discovery report lists
+const MaxDiscoverySamples = 3
+
+// DiscoveryReport is what a dry run found: the URLs that would be crawled and the links
+// left out by the scope and URL patterns, per host with the busiest hosts first
+type DiscoveryReport struct {
+	Queued     []HostDiscovery
+	OutOfScope []HostDiscovery
+}
+
+// HostDiscovery counts the URLs found on one host
+type HostDiscovery struct {
+	Host    string
+	URLs    int
+	Samples []string // The first URLs found
+}
+
+// discoveryRecorder tallies discovered URLs per host for dry runs
+type discoveryRecorder struct {
+	mu         sync.Mutex
+	queued     map[string]*HostDiscovery
+	outOfScope map[string]*HostDiscovery
+	seen       map[string]bool // Out of scope links are found on many pages, count them once
+}
+
+func newDiscoveryRecorder() *discoveryRecorder {
+	return &discoveryRecorder{
+		queued:     make(map[string]*HostDiscovery),
+		outOfScope: make(map[string]*HostDiscovery),
+		seen:       make(map[string]bool),
+	}
+}
+
+// recordQueued counts a URL that was queued, queued URLs are already deduplicated
+func (r *discoveryRecorder) recordQueued(url string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record(r.queued, url)
+}
+
+// recordOutOfScope counts a link the scope or URL patterns kept out of the crawl
+func (r *discoveryRecorder) recordOutOfScope(url string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.seen[url] {
+		return
+	}
+	r.seen[url] = true
+	record(r.outOfScope, url)
+}
+
+func record(hosts map[string]*HostDiscovery, url string) {
+	host := domain.GetDomain(url)
+	entry, ok := hosts[host]
+	if !ok {
+		entry = &HostDiscovery{Host: host}
+		hosts[host] = entry
+	}
+
+	entry.URLs++
+	if len(entry.Samples) < MaxDiscoverySamples {
+		entry.Samples = append(entry.Samples, url)
+	}
+}
+
+// report returns a copy of the tallies, busiest hosts first
+func (r *discoveryRecorder) report() DiscoveryReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return DiscoveryReport{
+		Queued:     sortedHosts(r.queued),
+		OutOfScope: sortedHosts(r.outOfScope),
+	}
+}
+
+func sortedHosts(hosts map[string]*HostDiscovery) []HostDiscovery {
+	sorted := make([]HostDiscovery, 0, len(hosts))
+	for _, entry := range hosts {
+		sorted = append(sorted, HostDiscovery{
+			Host:    entry.Host,
+			URLs:    entry.URLs,
+			Samples: append([]string(nil), entry.Samples...),
+		})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].URLs != sorted[j].URLs {
+			return sorted[i].URLs > sorted[j].URLs
+		}
+		return sorted[i].Host < sorted[j].Host
+	})
+	return sorted
+}
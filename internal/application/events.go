@@ -0,0 +1,58 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// EventBufferSize is how many events a subscriber may fall behind before it misses some
+const EventBufferSize = 256
+
+// EventBus fans the events of a crawl out to its subscribers. The storage publishes stored
+// results into it, the content extractor dead links and the crawler fetch errors
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[chan domain.CrawlEvent]struct{}
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan domain.CrawlEvent]struct{})}
+}
+
+// Publish hands the event to every subscriber, the ones falling behind miss it rather
+// than slowing the crawl down
+func (b *EventBus) Publish(event domain.CrawlEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the events published from now on, unsubscribe closes the channel
+func (b *EventBus) Subscribe() (<-chan domain.CrawlEvent, func()) {
+	events := make(chan domain.CrawlEvent, EventBufferSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[events] = struct{}{}
+
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[events]; ok {
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
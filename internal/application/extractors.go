@@ -0,0 +1,126 @@
+package application
+
+import (
+	"sync"
+
+	"golamv2/internal/domain"
+)
+
+// Names of the built-in extractors
+const (
+	ExtractorTitle     = "title"
+	ExtractorEmails    = "emails"
+	ExtractorKeywords  = "keywords"
+	ExtractorDeadLinks = "deadlinks"
+)
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   []domain.Extractor
+)
+
+// RegisterExtractor adds an extractor to every crawl started afterwards, after the built-in
+// ones of its mode. Meant to be called from the init function of the package providing it
+func RegisterExtractor(extractor domain.Extractor) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	plugins = append(plugins, extractor)
+}
+
+// registeredExtractors returns the extractors added with RegisterExtractor
+func registeredExtractors() []domain.Extractor {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	return append([]domain.Extractor(nil), plugins...)
+}
+
+// builtinExtractors returns the extractors a crawl mode hunts with, the title is always extracted
+func (c *CrawlerService) builtinExtractors() []domain.Extractor {
+	extractors := []domain.Extractor{titleExtractor{c}}
+
+	switch c.mode {
+	case domain.ModeEmail:
+		extractors = append(extractors, emailExtractor{c})
+	case domain.ModeKeywords:
+		extractors = append(extractors, keywordExtractor{c})
+	case domain.ModeDomains:
+		if c.shouldCheckDeadLinks() {
+			extractors = append(extractors, deadLinkExtractor{c})
+		}
+	case domain.ModeAll:
+		extractors = append(extractors, emailExtractor{c}, keywordExtractor{c})
+		// Dead links only when --domains was given, checking them is slow
+		if c.shouldCheckDeadLinks() {
+			extractors = append(extractors, deadLinkExtractor{c})
+		}
+	}
+
+	return extractors
+}
+
+// AddExtractor adds an extractor to this crawl only, call it before StartCrawling
+func (c *CrawlerService) AddExtractor(extractor domain.Extractor) {
+	c.extractors = append(c.extractors, extractor)
+}
+
+// Extractors returns the names of the extractors run on every page, in order
+func (c *CrawlerService) Extractors() []string {
+	names := make([]string, len(c.extractors))
+	for i, extractor := range c.extractors {
+		names[i] = extractor.Name()
+	}
+	return names
+}
+
+// titleExtractor keeps the title and meta description of every page
+type titleExtractor struct{ c *CrawlerService }
+
+func (titleExtractor) Name() string { return ExtractorTitle }
+
+func (e titleExtractor) Extract(content, pageURL string, result *domain.CrawlResult) {
+	result.Title = e.c.infra.ContentExtractor.ExtractTitle(content)
+	result.Description = e.c.infra.ContentExtractor.ExtractMetaDescription(content)
+}
+
+// emailExtractor hunts for email addresses
+type emailExtractor struct{ c *CrawlerService }
+
+func (emailExtractor) Name() string { return ExtractorEmails }
+
+func (e emailExtractor) Extract(content, pageURL string, result *domain.CrawlResult) {
+	result.Emails = e.c.infra.ContentExtractor.ExtractEmails(content)
+	e.c.infra.Metrics.UpdateEmailsFound(int64(len(result.Emails)))
+}
+
+// keywordExtractor counts the keywords of the crawl, they can be reloaded while crawling
+type keywordExtractor struct{ c *CrawlerService }
+
+func (keywordExtractor) Name() string { return ExtractorKeywords }
+
+func (e keywordExtractor) Extract(content, pageURL string, result *domain.CrawlResult) {
+	result.Keywords = e.c.infra.ContentExtractor.ExtractKeywords(content, e.c.currentKeywords())
+	if len(result.Keywords) > 0 {
+		result.KeywordMatch = e.c.options.KeywordMatching.Strategy()
+	}
+
+	keywordCount := int64(0)
+	for _, count := range result.Keywords {
+		keywordCount += int64(count)
+	}
+	e.c.infra.Metrics.UpdateKeywordsFound(keywordCount)
+}
+
+// deadLinkExtractor checks the links of the page and its ftp, mailto and tel links
+type deadLinkExtractor struct{ c *CrawlerService }
+
+func (deadLinkExtractor) Name() string { return ExtractorDeadLinks }
+
+func (e deadLinkExtractor) Extract(content, pageURL string, result *domain.CrawlResult) {
+	c := e.c
+	links := c.withAnchorText(content, pageURL, c.followedLinks(content, result))
+	result.DeadLinks, result.DeadDomains = c.infra.ContentExtractor.CheckDeadLinks(links, pageURL, result.Labels)
+	c.infra.Metrics.UpdateLinksChecked(int64(len(links)))
+	c.infra.Metrics.UpdateDeadLinksFound(int64(len(result.DeadLinks)))
+	c.infra.Metrics.UpdateDeadDomainsFound(int64(len(result.DeadDomains)))
+	c.checkSchemeLinks(content, pageURL, result.Labels)
+}
@@ -0,0 +1,126 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MaxWorkers caps the workers a running crawl can be resized to
+const MaxWorkers = 1000
+
+// workerPool runs the workers of a crawl, a Controller pauses and resizes it while crawling
+type workerPool struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	run     func(quit <-chan struct{}, workerID int) // nil until start
+	quits   []chan struct{}                          // One per running worker, closed to retire it
+	nextID  int                                      // Retired workers' IDs are not reused
+	size    int
+	closed  bool          // The crawl is stopping, no workers are started anymore
+	resumed chan struct{} // Open while paused, nil otherwise
+}
+
+// start runs size workers until ctx is done, a size set earlier by resize wins
+func (p *workerPool) start(ctx context.Context, size int, run func(quit <-chan struct{}, workerID int)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.run = run
+	if p.size == 0 {
+		p.size = size
+	}
+	for len(p.quits) < p.size {
+		p.spawn()
+	}
+
+	// Holds wait until no worker can be added anymore
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		<-ctx.Done()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.closed = true
+	}()
+}
+
+func (p *workerPool) spawn() {
+	quit := make(chan struct{})
+	workerID := p.nextID
+	p.nextID++
+	p.quits = append(p.quits, quit)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.run(quit, workerID)
+	}()
+}
+
+// wait blocks until every worker returned
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
+
+// resize starts or retires workers, retired ones finish the page they are on
+func (p *workerPool) resize(size int) error {
+	if size < 1 {
+		return fmt.Errorf("a crawl needs at least one worker")
+	}
+	if size > MaxWorkers {
+		return fmt.Errorf("a crawl runs at most %d workers", MaxWorkers)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("the crawl is stopping")
+	}
+	p.size = size
+	if p.run == nil {
+		return nil
+	}
+	for len(p.quits) < size {
+		p.spawn()
+	}
+	for len(p.quits) > size {
+		last := len(p.quits) - 1
+		close(p.quits[last])
+		p.quits = p.quits[:last]
+	}
+	return nil
+}
+
+// workers returns how many workers run, or will once the crawl starts
+func (p *workerPool) workers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// pause holds the workers before their next URL, pages in flight are finished
+func (p *workerPool) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+	}
+}
+
+func (p *workerPool) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
+
+// paused returns a channel closed on resume while the pool is paused, nil otherwise
+func (p *workerPool) paused() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resumed
+}
@@ -0,0 +1,104 @@
+package application
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+// ReprocessReport counts what Reprocess went through
+type ReprocessReport struct {
+	Pages    int // Results stored
+	Older    int // Earlier copies of a page archived again later, skipped
+	Skipped  int // Pages of other content types and redirects
+	Duration time.Duration
+}
+
+// Reprocess runs the extractors of the crawl again over the pages of an archive and stores a
+// fresh result for each, without a single request. Pages archived more than once are only
+// reprocessed from their newest copy. The service must be created with Offline so dead links
+// are not checked, and no links are queued
+func (c *CrawlerService) Reprocess(ctx context.Context, archive domain.PageArchive) (ReprocessReport, error) {
+	start := time.Now()
+	var report ReprocessReport
+
+	// Copies of every page, a page is reprocessed once its last copy comes by
+	copies := make(map[string]int)
+	err := archive.ForEachArchivedPage(func(page domain.ArchivedPage) error {
+		copies[page.URL]++
+		return ctx.Err()
+	})
+	if err != nil {
+		return report, err
+	}
+
+	pages := make(chan domain.ArchivedPage)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				c.reprocessPage(page)
+			}
+		}()
+	}
+
+	err = archive.ForEachArchivedPage(func(page domain.ArchivedPage) error {
+		if copies[page.URL]--; copies[page.URL] > 0 {
+			report.Older++
+			return nil
+		}
+		if !isHTML(page.ContentType) || (page.StatusCode >= 300 && page.StatusCode < 400) {
+			report.Skipped++
+			return nil
+		}
+
+		select {
+		case pages <- page:
+			report.Pages++
+			if report.Pages%1000 == 0 {
+				logging.Infof("Reprocessed %d pages", report.Pages)
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(pages)
+	wg.Wait()
+
+	report.Duration = time.Since(start)
+	return report, err
+}
+
+// reprocessPage extracts and stores the result of one archived page
+func (c *CrawlerService) reprocessPage(page domain.ArchivedPage) {
+	startTime := time.Now()
+	result := domain.CrawlResult{
+		URL:           page.URL,
+		StatusCode:    page.StatusCode,
+		ProcessedAt:   startTime,
+		Labels:        c.options.Labels,
+		ContentHash:   hashContent(page.Body),
+		ContentLength: int64(len(page.Body)),
+	}
+
+	if documents, ok := c.infra.ContentExtractor.(domain.DocumentCache); ok {
+		defer documents.ReleaseDocument(page.Body)
+	}
+	c.analyzePage(&result, page.Body, "")
+	c.infra.Metrics.RecordExtract(time.Since(startTime))
+
+	result.ProcessTime = time.Since(startTime)
+	err := c.infra.Storage.StoreResult(result)
+	if err != nil {
+		logging.Warnf("Failed to store the result of %s: %v", page.URL, err)
+	}
+	c.pageStored(result.URL, err == nil)
+	c.infra.Metrics.UpdateURLsProcessed(1)
+}
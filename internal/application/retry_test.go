@@ -0,0 +1,58 @@
+package application
+
+import (
+	"testing"
+
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/metrics"
+	"golamv2/pkg/queue"
+)
+
+// recordingQueue keeps the tasks put back in the frontier
+type recordingQueue struct {
+	domain.URLQueue
+	pushed []domain.URLTask
+}
+
+func (q *recordingQueue) PushOrSpill(task domain.URLTask) error {
+	q.pushed = append(q.pushed, task)
+	return nil
+}
+
+func TestDeferralsCountedApart(t *testing.T) {
+	frontier := &recordingQueue{}
+	c := &CrawlerService{
+		infra: &infrastructure.Infrastructure{
+			URLQueue:   frontier,
+			Metrics:    metrics.NewMetricsCollector(),
+			Challenges: infrastructure.NewChallengeTracker(),
+		},
+		options: CrawlOptions{MaxRetries: 2},
+		retries: queue.NewRetryQueue(),
+	}
+
+	// A task that used up its fetch retries still waits for robots.txt and challenges
+	task := domain.URLTask{URL: "https://example.com/", Retries: 2}
+	if c.retryLater(task) {
+		t.Fatal("retryLater requeued a task out of fetch retries")
+	}
+	for i := 0; i < MaxRobotsRetries; i++ {
+		if !c.retryAfterRobots(task) {
+			t.Fatalf("robots wait %d refused", i+1)
+		}
+		task = frontier.pushed[len(frontier.pushed)-1]
+	}
+	if c.retryAfterRobots(task) {
+		t.Fatal("retryAfterRobots requeued a task out of robots waits")
+	}
+
+	var result domain.CrawlResult
+	if !c.challenged(task, &result, "cloudflare") {
+		t.Fatal("challenged did not requeue a task with no challenge waits")
+	}
+	task = frontier.pushed[len(frontier.pushed)-1]
+	if task.Retries != 2 || task.RobotsWaits != MaxRobotsRetries || task.ChallengeWaits != 1 {
+		t.Errorf("counters = %d/%d/%d, want 2/%d/1", task.Retries, task.RobotsWaits, task.ChallengeWaits, MaxRobotsRetries)
+	}
+}
@@ -0,0 +1,208 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+
+	"github.com/robfig/cron/v3"
+)
+
+// MaxRunHistory caps how many past runs the scheduler remembers
+const MaxRunHistory = 100
+
+// RunFunc executes a single scheduled crawl and fills in its statistics
+type RunFunc func(ctx context.Context, run *domain.CrawlRun) error
+
+// Scheduler runs recurring crawls on a cron schedule while the process stays resident
+type Scheduler struct {
+	spec        string
+	schedule    cron.Schedule
+	run         RunFunc
+	historyPath string
+
+	mu      sync.RWMutex
+	history []domain.CrawlRun
+	nextRun time.Time
+}
+
+// NewScheduler parses a standard 5-field cron spec ("0 3 * * *") or a descriptor like "@daily".
+// Run history is persisted to historyPath when it is not empty
+func NewScheduler(spec string, historyPath string, run RunFunc) (*Scheduler, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+
+	s := &Scheduler{
+		spec:        spec,
+		schedule:    schedule,
+		run:         run,
+		historyPath: historyPath,
+	}
+	s.loadHistory()
+
+	return s, nil
+}
+
+// Start blocks running crawls at every scheduled time until the context is cancelled.
+// Runs never overlap - a run that overshoots its slot delays the next one
+func (s *Scheduler) Start(ctx context.Context) error {
+	for {
+		next := s.schedule.Next(time.Now())
+		s.mu.Lock()
+		s.nextRun = next
+		s.mu.Unlock()
+
+		logging.Infof("Next scheduled crawl at %s", next.Format("2006-01-02 15:04:05"))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		s.execute(ctx, next)
+	}
+}
+
+// execute performs one run and records it in the history
+func (s *Scheduler) execute(ctx context.Context, scheduledAt time.Time) {
+	run := domain.CrawlRun{
+		ID:          scheduledAt.Format("20060102-150405"),
+		ScheduledAt: scheduledAt,
+		StartedAt:   time.Now(),
+		Status:      domain.RunRunning,
+	}
+
+	idx := s.record(run)
+
+	err := s.run(ctx, &run)
+	run.FinishedAt = time.Now()
+	if ctx.Err() != nil {
+		run.Status = domain.RunCancelled
+		logging.Infof("Scheduled crawl %s was stopped before it finished", run.ID)
+	} else if err != nil {
+		run.Status = domain.RunFailed
+		run.Error = err.Error()
+		logging.Errorf("Scheduled crawl %s failed: %v", run.ID, err)
+	} else {
+		run.Status = domain.RunCompleted
+		logging.Infof("Scheduled crawl %s completed in %v", run.ID, run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+	}
+
+	s.update(idx, run)
+	s.saveHistory()
+}
+
+// record appends a run and returns its position
+func (s *Scheduler) record(run domain.CrawlRun) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, run)
+	if len(s.history) > MaxRunHistory {
+		s.history = s.history[len(s.history)-MaxRunHistory:]
+	}
+	return len(s.history) - 1
+}
+
+// update replaces a previously recorded run
+func (s *Scheduler) update(idx int, run domain.CrawlRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if idx < len(s.history) && s.history[idx].ID == run.ID {
+		s.history[idx] = run
+	}
+}
+
+// History returns the recorded runs, newest first
+func (s *Scheduler) History() []domain.CrawlRun {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := make([]domain.CrawlRun, len(s.history))
+	for i, run := range s.history {
+		runs[len(s.history)-1-i] = run
+	}
+	return runs
+}
+
+// LastCompleted returns the newest run that completed, or nil before the first one did
+func (s *Scheduler) LastCompleted() *domain.CrawlRun {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].Status == domain.RunCompleted {
+			run := s.history[i]
+			return &run
+		}
+	}
+	return nil
+}
+
+// NextRun returns when the next crawl is due
+func (s *Scheduler) NextRun() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextRun
+}
+
+// Spec returns the cron expression the scheduler was created with
+func (s *Scheduler) Spec() string {
+	return s.spec
+}
+
+// loadHistory restores run history written by a previous scheduler process
+func (s *Scheduler) loadHistory() {
+	if s.historyPath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(s.historyPath)
+	if err != nil {
+		return // No history yet
+	}
+
+	var runs []domain.CrawlRun
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return
+	}
+
+	// Anything still marked running was interrupted by a restart
+	for i := range runs {
+		if runs[i].Status == domain.RunRunning {
+			runs[i].Status = domain.RunFailed
+			runs[i].Error = "interrupted"
+		}
+	}
+	s.history = runs
+}
+
+// saveHistory persists run history so it survives restarts
+func (s *Scheduler) saveHistory() {
+	if s.historyPath == "" {
+		return
+	}
+
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s.history, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return
+	}
+
+	if err := os.WriteFile(s.historyPath, data, 0644); err != nil {
+		logging.Errorf("Failed to save run history: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+func TestSchedulerExecute(t *testing.T) {
+	tests := []struct {
+		name     string
+		cancel   bool
+		err      error
+		want     domain.RunStatus
+		baseline bool
+	}{
+		{"completed", false, nil, domain.RunCompleted, true},
+		{"failed", false, errors.New("boom"), domain.RunFailed, false},
+		{"stopped", true, nil, domain.RunCancelled, false},
+		{"stopped with an error", true, context.Canceled, domain.RunCancelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s, err := NewScheduler("@daily", "", func(context.Context, *domain.CrawlRun) error {
+				if tt.cancel {
+					cancel()
+				}
+				return tt.err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.execute(ctx, time.Now())
+			if got := s.History()[0].Status; got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+			if baseline := s.LastCompleted() != nil; baseline != tt.baseline {
+				t.Errorf("baseline for the next run %v, want %v", baseline, tt.baseline)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package application
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+// DefaultDrainTimeout is how long pages in flight may take to finish once a crawl is stopped
+const DefaultDrainTimeout = 10 * time.Second
+
+// drainAfter gives the pages in flight DrainTimeout to finish once the crawl is stopped,
+// then cancels their requests
+func (c *CrawlerService) drainAfter(ctx, work context.Context, stopWork context.CancelFunc) {
+	select {
+	case <-work.Done():
+		return
+	case <-ctx.Done():
+	}
+
+	timeout := c.options.DrainTimeout
+	if inFlight := atomic.LoadInt64(&c.inFlight); inFlight > 0 && timeout > 0 {
+		logging.Infof("Waiting up to %s for %d pages in flight", timeout, inFlight)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-work.Done():
+	case <-timer.C:
+		if atomic.LoadInt64(&c.inFlight) > 0 {
+			logging.Warnf("Pages still in flight after %s are abandoned, the next run fetches them again", timeout)
+		}
+		stopWork()
+	}
+}
+
+// shutdown runs once the workers stopped: the frontier goes back to the storage, where the
+// next run on the same data picks it up, and the metrics are saved
+func (c *CrawlerService) shutdown() {
+	var tasks []domain.URLTask
+	if queue, ok := c.infra.URLQueue.(domain.QueueDrainer); ok {
+		tasks = queue.Drain()
+	}
+
+	c.workingMu.Lock()
+	for workerID, task := range c.working {
+		tasks = append(tasks, task)
+		delete(c.working, workerID)
+	}
+	c.workingMu.Unlock()
+
+	// Retries still waiting are picked up by the next run too
+	tasks = append(tasks, c.retries.Drain()...)
+
+	flushed := 0
+	for _, task := range tasks {
+		if err := c.infra.Storage.StoreURL(task); err != nil {
+			logging.Warnf("Queued URL %s lost at shutdown: %v", task.URL, err)
+			continue
+		}
+		flushed++
+	}
+	if flushed > 0 && !c.options.DryRun {
+		logging.Infof("URLs left for the next run: %d", flushed)
+	}
+
+	// The seen URLs, the frontier is in the storage now
+	c.checkpoint(false)
+
+	if err := c.infra.Storage.UpdateMetrics(c.infra.Metrics.GetMetrics()); err != nil {
+		logging.Warnf("Saving the metrics failed: %v", err)
+	}
+}
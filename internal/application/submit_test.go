@@ -0,0 +1,47 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+
+	"golamv2/internal/domain"
+	"golamv2/internal/infrastructure"
+	"golamv2/pkg/bloom"
+)
+
+func TestSubmit(t *testing.T) {
+	const seen = "https://example.com/"
+	tests := []struct {
+		name string
+		job  domain.CrawlJob
+		want []string
+	}{
+		{"new seed", domain.CrawlJob{URLs: []string{"https://example.org/"}}, []string{"https://example.org/"}},
+		{"seen seed", domain.CrawlJob{URLs: []string{seen}}, nil},
+		{"seen seed recrawled", domain.CrawlJob{URLs: []string{seen}, Recrawl: true}, []string{seen}},
+		{"invalid seed recrawled", domain.CrawlJob{URLs: []string{"mailto:a@b.c"}, Recrawl: true}, nil},
+		{"seed of an upgraded host", domain.CrawlJob{URLs: []string{"http://Example.net/a"}}, []string{"https://example.net/a"}},
+		{"http variant of a seen seed", domain.CrawlJob{URLs: []string{"http://example.com/"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dedup := infrastructure.NewURLDeduper(domain.DedupProbabilistic, bloom.NewURLBloomFilter(), nil)
+			dedup.MarkSeen(domain.URLKey(domain.NormalizeURL(seen)))
+			upgrades := infrastructure.NewHTTPSUpgrades()
+			upgrades.Record("example.net")
+			c := &CrawlerService{
+				infra: &infrastructure.Infrastructure{URLQueue: &recordingQueue{}, Dedup: dedup, HTTPSUpgrades: upgrades},
+				scope: domain.NewScope(domain.ScopeHost, seen),
+			}
+
+			got, err := c.Submit(tt.job)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Submit = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package domain
+
+// Kinds of accessibility issues the audit records
+const (
+	A11yMissingAlt   = "missing_alt"   // img without an alt attribute, alt="" marks decorative images and passes
+	A11yMissingLabel = "missing_label" // Form control without a label, aria-label or aria-labelledby
+	A11yHeadingOrder = "heading_order" // Heading skipping levels, an h4 right after an h2
+	A11yMissingLang  = "missing_lang"  // html element without a lang attribute
+)
+
+// MaxA11yIssues caps the issues listed per page, the counts keep going
+const MaxA11yIssues = 50
+
+// AccessibilityIssue is one problem found on a page
+type AccessibilityIssue struct {
+	Kind    string `json:"kind"`
+	Element string `json:"element"` // Short description of the offending element, img src=/logo.png
+}
+
+// AccessibilityReport is the accessibility audit of one page
+type AccessibilityReport struct {
+	MissingAlt    int                  `json:"missing_alt"`
+	MissingLabels int                  `json:"missing_labels"`
+	HeadingOrder  int                  `json:"heading_order"`
+	MissingLang   bool                 `json:"missing_lang"`
+	Issues        []AccessibilityIssue `json:"issues,omitempty"` // The first MaxA11yIssues
+}
+
+// IssueCount is the number of issues found on the page
+func (r AccessibilityReport) IssueCount() int {
+	count := r.MissingAlt + r.MissingLabels + r.HeadingOrder
+	if r.MissingLang {
+		count++
+	}
+	return count
+}
+
+// Add records an issue, listing it while there is room
+func (r *AccessibilityReport) Add(kind, element string) {
+	switch kind {
+	case A11yMissingAlt:
+		r.MissingAlt++
+	case A11yMissingLabel:
+		r.MissingLabels++
+	case A11yHeadingOrder:
+		r.HeadingOrder++
+	case A11yMissingLang:
+		r.MissingLang = true
+	}
+	if len(r.Issues) < MaxA11yIssues {
+		r.Issues = append(r.Issues, AccessibilityIssue{Kind: kind, Element: element})
+	}
+}
+
+// AccessibilityAuditor is implemented by content extractors that can audit the accessibility of a page
+type AccessibilityAuditor interface {
+	AuditAccessibility(content string) AccessibilityReport
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Vendors of the bot challenges DetectChallenge recognizes
+const (
+	ChallengeCloudflare = "cloudflare"
+	ChallengeAkamai     = "akamai"
+	ChallengeImperva    = "imperva"
+	ChallengeSucuri     = "sucuri"
+	ChallengeDataDome   = "datadome"
+	ChallengePerimeterX = "perimeterx"
+	ChallengeCaptcha    = "captcha" // Any other CAPTCHA interstitial
+)
+
+// challengeMarkers are body snippets of challenge pages. Real pages behind the same WAF load
+// some of them too (Cloudflare injects its challenge platform script into ordinary pages),
+// so they only count on denied responses
+var challengeMarkers = []struct {
+	vendor string
+	marker string
+}{
+	{ChallengeCloudflare, "/cdn-cgi/challenge-platform/"},
+	{ChallengeCloudflare, "cf-chl-"},
+	{ChallengeCloudflare, "<title>just a moment...</title>"},
+	{ChallengeCloudflare, "<title>attention required! | cloudflare</title>"},
+	{ChallengeImperva, "_incapsula_resource"},
+	{ChallengeImperva, "incapsula incident id"},
+	{ChallengeSucuri, "sucuri website firewall"},
+	{ChallengeDataDome, "captcha-delivery.com"},
+	{ChallengePerimeterX, "px-captcha"},
+}
+
+// captchaMarkers are CAPTCHA widgets, on a page that was denied they make it an interstitial.
+// Pages answering 200 use them in ordinary forms
+var captchaMarkers = []string{"g-recaptcha", "hcaptcha.com", "h-captcha", "challenges.cloudflare.com/turnstile", "captcha"}
+
+// DetectChallenge tells whether a response is a WAF or bot challenge instead of the page,
+// and returns its vendor. Empty when it looks like the real page
+func DetectChallenge(statusCode int, header http.Header, body string) string {
+	if strings.EqualFold(header.Get("Cf-Mitigated"), "challenge") {
+		return ChallengeCloudflare
+	}
+
+	denied := statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests ||
+		statusCode == http.StatusServiceUnavailable
+	if !denied {
+		return ""
+	}
+
+	lower := strings.ToLower(body)
+	for _, m := range challengeMarkers {
+		if strings.Contains(lower, m.marker) {
+			return m.vendor
+		}
+	}
+
+	// Akamai denies with a bare "Access Denied" page and a reference number
+	server := strings.ToLower(header.Get("Server"))
+	if strings.Contains(server, "akamaighost") && strings.Contains(lower, "access denied") {
+		return ChallengeAkamai
+	}
+
+	for _, marker := range captchaMarkers {
+		if strings.Contains(lower, marker) {
+			if strings.Contains(server, "cloudflare") {
+				return ChallengeCloudflare
+			}
+			return ChallengeCaptcha
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDetectChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   string
+	}{
+		{"cf-mitigated header", 200, http.Header{"Cf-Mitigated": {"challenge"}}, "<html></html>", ChallengeCloudflare},
+		{"cloudflare interstitial", 403, nil, "<title>Just a moment...</title>", ChallengeCloudflare},
+		{"challenge script on a real page", 200, nil, `<script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script>`, ""},
+		{"challenge script on a denied page", 503, nil, `<script src="/cdn-cgi/challenge-platform/h/b/orchestrate"></script>`, ChallengeCloudflare},
+		{"imperva", 403, nil, "Incapsula incident ID: 123", ChallengeImperva},
+		{"recaptcha form", 200, nil, `<div class="g-recaptcha"></div>`, ""},
+		{"recaptcha on 429", 429, nil, `<div class="g-recaptcha"></div>`, ChallengeCaptcha},
+		{"captcha behind cloudflare", 403, http.Header{"Server": {"cloudflare"}}, "h-captcha", ChallengeCloudflare},
+		{"akamai", 403, http.Header{"Server": {"AkamaiGHost"}}, "<h1>Access Denied</h1>", ChallengeAkamai},
+		{"plain 403", 403, nil, "Forbidden", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			if got := DetectChallenge(tt.status, header, tt.body); got != tt.want {
+				t.Errorf("DetectChallenge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// ChangeReport is what changed between two crawls of the same site, see CompareResults
+type ChangeReport struct {
+	Before      string    `json:"before"` // Run, or data directory and time range, compared against
+	After       string    `json:"after"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	AddedURLs         []string         `json:"added_urls"`
+	RemovedURLs       []string         `json:"removed_urls"`
+	StatusChanges     []StatusChange   `json:"status_changes"`
+	NewDeadLinks      []DeadLinkChange `json:"new_dead_links"`
+	ResolvedDeadLinks []DeadLinkChange `json:"resolved_dead_links"` // Only of pages crawled again
+	NewEmails         []EmailChange    `json:"new_emails"`
+	DisappearedEmails []EmailChange    `json:"disappeared_emails"` // Only of pages crawled again, Pages lists where they were
+	KeywordChanges    []KeywordChange  `json:"keyword_changes"`    // Counted over the pages crawled by both
+}
+
+// StatusChange is a page answering with another status code
+type StatusChange struct {
+	URL    string `json:"url"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// DeadLinkChange is a dead link of a page
+type DeadLinkChange struct {
+	Page string `json:"page"`
+	Link string `json:"link"`
+}
+
+// EmailChange is an email and the pages it was found on
+type EmailChange struct {
+	Email string   `json:"email"`
+	Pages []string `json:"pages"`
+}
+
+// KeywordChange is how often a keyword was found across the pages both crawls reached
+type KeywordChange struct {
+	Keyword string `json:"keyword"`
+	Before  int    `json:"before"`
+	After   int    `json:"after"`
+}
+
+// ChangeSummary counts the entries of a ChangeReport
+type ChangeSummary struct {
+	AddedURLs         int `json:"added_urls"`
+	RemovedURLs       int `json:"removed_urls"`
+	StatusChanges     int `json:"status_changes"`
+	NewDeadLinks      int `json:"new_dead_links"`
+	ResolvedDeadLinks int `json:"resolved_dead_links"`
+	NewEmails         int `json:"new_emails"`
+	DisappearedEmails int `json:"disappeared_emails"`
+	KeywordChanges    int `json:"keyword_changes"`
+}
+
+// Summary counts the changes of the report
+func (r ChangeReport) Summary() ChangeSummary {
+	return ChangeSummary{
+		AddedURLs:         len(r.AddedURLs),
+		RemovedURLs:       len(r.RemovedURLs),
+		StatusChanges:     len(r.StatusChanges),
+		NewDeadLinks:      len(r.NewDeadLinks),
+		ResolvedDeadLinks: len(r.ResolvedDeadLinks),
+		NewEmails:         len(r.NewEmails),
+		DisappearedEmails: len(r.DisappearedEmails),
+		KeywordChanges:    len(r.KeywordChanges),
+	}
+}
+
+// Empty reports whether nothing changed
+func (s ChangeSummary) Empty() bool {
+	return s == ChangeSummary{}
+}
+
+// CompareResults reports the changes from the results of one crawl to those of the next.
+// Only the newest result of a page counts. Pages crawled by one only are added or removed,
+// new emails are looked for across the site, dead links page by page. Disappeared emails and
+// keyword counts only look at the pages crawled by both, a page an unfinished crawl did not
+// reach again loses nothing
+func CompareResults(before, after []CrawlResult) ChangeReport {
+	old, current := latestResults(before), latestResults(after)
+	report := ChangeReport{
+		GeneratedAt:       time.Now(),
+		AddedURLs:         []string{},
+		RemovedURLs:       []string{},
+		StatusChanges:     []StatusChange{},
+		NewDeadLinks:      []DeadLinkChange{},
+		ResolvedDeadLinks: []DeadLinkChange{},
+		NewEmails:         []EmailChange{},
+		DisappearedEmails: []EmailChange{},
+		KeywordChanges:    []KeywordChange{},
+	}
+
+	for url, result := range current {
+		previous, crawled := old[url]
+		for _, link := range result.DeadLinks {
+			if !crawled || !slices.Contains(previous.DeadLinks, link) {
+				report.NewDeadLinks = append(report.NewDeadLinks, DeadLinkChange{Page: url, Link: link})
+			}
+		}
+		if !crawled {
+			report.AddedURLs = append(report.AddedURLs, url)
+			continue
+		}
+		if previous.StatusCode != result.StatusCode {
+			report.StatusChanges = append(report.StatusChanges, StatusChange{URL: url, Before: previous.StatusCode, After: result.StatusCode})
+		}
+		for _, link := range previous.DeadLinks {
+			if !slices.Contains(result.DeadLinks, link) {
+				report.ResolvedDeadLinks = append(report.ResolvedDeadLinks, DeadLinkChange{Page: url, Link: link})
+			}
+		}
+	}
+
+	for url := range old {
+		if _, ok := current[url]; !ok {
+			report.RemovedURLs = append(report.RemovedURLs, url)
+		}
+	}
+
+	oldEmails, currentEmails := emailPages(old), emailPages(current)
+	for email, pages := range currentEmails {
+		if _, ok := oldEmails[email]; !ok {
+			report.NewEmails = append(report.NewEmails, EmailChange{Email: email, Pages: pages})
+		}
+	}
+	for email, pages := range emailPages(crawledBy(old, current)) {
+		if _, ok := currentEmails[email]; !ok {
+			report.DisappearedEmails = append(report.DisappearedEmails, EmailChange{Email: email, Pages: pages})
+		}
+	}
+
+	oldCounts, currentCounts := keywordCounts(crawledBy(old, current)), keywordCounts(crawledBy(current, old))
+	for keyword, count := range currentCounts {
+		if oldCounts[keyword] != count {
+			report.KeywordChanges = append(report.KeywordChanges, KeywordChange{Keyword: keyword, Before: oldCounts[keyword], After: count})
+		}
+	}
+	for keyword, count := range oldCounts {
+		if _, ok := currentCounts[keyword]; !ok {
+			report.KeywordChanges = append(report.KeywordChanges, KeywordChange{Keyword: keyword, Before: count})
+		}
+	}
+
+	sort.Strings(report.AddedURLs)
+	sort.Strings(report.RemovedURLs)
+	sort.Slice(report.StatusChanges, func(i, j int) bool { return report.StatusChanges[i].URL < report.StatusChanges[j].URL })
+	sortDeadLinkChanges(report.NewDeadLinks)
+	sortDeadLinkChanges(report.ResolvedDeadLinks)
+	sort.Slice(report.NewEmails, func(i, j int) bool { return report.NewEmails[i].Email < report.NewEmails[j].Email })
+	sort.Slice(report.DisappearedEmails, func(i, j int) bool {
+		return report.DisappearedEmails[i].Email < report.DisappearedEmails[j].Email
+	})
+	sort.Slice(report.KeywordChanges, func(i, j int) bool {
+		return report.KeywordChanges[i].Keyword < report.KeywordChanges[j].Keyword
+	})
+	return report
+}
+
+// latestResults keeps the newest result of every page, recrawls store one each
+func latestResults(results []CrawlResult) map[string]CrawlResult {
+	latest := make(map[string]CrawlResult, len(results))
+	for _, result := range results {
+		if seen, ok := latest[result.URL]; !ok || result.ProcessedAt.After(seen.ProcessedAt) {
+			latest[result.URL] = result
+		}
+	}
+	return latest
+}
+
+// crawledBy keeps the results of the pages other has results for too
+func crawledBy(results, other map[string]CrawlResult) map[string]CrawlResult {
+	kept := make(map[string]CrawlResult, len(results))
+	for url, result := range results {
+		if _, ok := other[url]; ok {
+			kept[url] = result
+		}
+	}
+	return kept
+}
+
+// emailPages maps every email to the sorted pages it was found on
+func emailPages(results map[string]CrawlResult) map[string][]string {
+	pages := make(map[string][]string)
+	for url, result := range results {
+		for _, email := range result.Emails {
+			pages[email] = append(pages[email], url)
+		}
+	}
+	for _, list := range pages {
+		sort.Strings(list)
+	}
+	return pages
+}
+
+// keywordCounts sums the keyword counts of every page
+func keywordCounts(results map[string]CrawlResult) map[string]int {
+	counts := make(map[string]int)
+	for _, result := range results {
+		for keyword, count := range result.Keywords {
+			counts[keyword] += count
+		}
+	}
+	return counts
+}
+
+func sortDeadLinkChanges(changes []DeadLinkChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Page != changes[j].Page {
+			return changes[i].Page < changes[j].Page
+		}
+		return changes[i].Link < changes[j].Link
+	})
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareResults(t *testing.T) {
+	page := func(url string, status int, emails []string, keywords map[string]int, deadLinks ...string) CrawlResult {
+		return CrawlResult{URL: url, StatusCode: status, Emails: emails, Keywords: keywords, DeadLinks: deadLinks}
+	}
+
+	tests := []struct {
+		name   string
+		before []CrawlResult
+		after  []CrawlResult
+		check  func(t *testing.T, report ChangeReport)
+	}{
+		{
+			name:   "added and removed pages",
+			before: []CrawlResult{page("/a", 200, nil, nil), page("/b", 200, nil, nil)},
+			after:  []CrawlResult{page("/a", 200, nil, nil), page("/c", 200, nil, nil)},
+			check: func(t *testing.T, report ChangeReport) {
+				expect(t, "added", report.AddedURLs, []string{"/c"})
+				expect(t, "removed", report.RemovedURLs, []string{"/b"})
+			},
+		},
+		{
+			name:   "status change",
+			before: []CrawlResult{page("/a", 200, nil, nil)},
+			after:  []CrawlResult{page("/a", 404, nil, nil)},
+			check: func(t *testing.T, report ChangeReport) {
+				expect(t, "status", report.StatusChanges, []StatusChange{{URL: "/a", Before: 200, After: 404}})
+			},
+		},
+		{
+			name:   "dead links page by page",
+			before: []CrawlResult{page("/a", 200, nil, nil, "/x", "/y")},
+			after:  []CrawlResult{page("/a", 200, nil, nil, "/y", "/z"), page("/b", 200, nil, nil, "/x")},
+			check: func(t *testing.T, report ChangeReport) {
+				expect(t, "new", report.NewDeadLinks, []DeadLinkChange{{Page: "/a", Link: "/z"}, {Page: "/b", Link: "/x"}})
+				expect(t, "resolved", report.ResolvedDeadLinks, []DeadLinkChange{{Page: "/a", Link: "/x"}})
+			},
+		},
+		{
+			name:   "email moved to another page",
+			before: []CrawlResult{page("/a", 200, []string{"a@example.com"}, nil)},
+			after:  []CrawlResult{page("/a", 200, nil, nil), page("/b", 200, []string{"a@example.com", "b@example.com"}, nil)},
+			check: func(t *testing.T, report ChangeReport) {
+				expect(t, "new", report.NewEmails, []EmailChange{{Email: "b@example.com", Pages: []string{"/b"}}})
+				expect(t, "disappeared", report.DisappearedEmails, []EmailChange{})
+			},
+		},
+		{
+			name:   "email gone from a page crawled again",
+			before: []CrawlResult{page("/a", 200, []string{"a@example.com"}, nil)},
+			after:  []CrawlResult{page("/a", 200, nil, nil)},
+			check: func(t *testing.T, report ChangeReport) {
+				expect(t, "disappeared", report.DisappearedEmails, []EmailChange{{Email: "a@example.com", Pages: []string{"/a"}}})
+			},
+		},
+		{
+			name: "unfinished crawl loses nothing",
+			before: []CrawlResult{
+				page("/a", 200, []string{"a@example.com"}, map[string]int{"golang": 2}),
+				page("/b", 200, []string{"b@example.com"}, map[string]int{"golang": 3}),
+			},
+			after: []CrawlResult{page("/a", 200, []string{"a@example.com"}, map[string]int{"golang": 2})},
+			check: func(t *testing.T, report ChangeReport) {
+				expect(t, "removed", report.RemovedURLs, []string{"/b"})
+				expect(t, "disappeared", report.DisappearedEmails, []EmailChange{})
+				expect(t, "keywords", report.KeywordChanges, []KeywordChange{})
+			},
+		},
+		{
+			name:   "keyword counts of pages crawled by both",
+			before: []CrawlResult{page("/a", 200, nil, map[string]int{"golang": 2, "rust": 1})},
+			after:  []CrawlResult{page("/a", 200, nil, map[string]int{"golang": 5}), page("/b", 200, nil, map[string]int{"zig": 1})},
+			check: func(t *testing.T, report ChangeReport) {
+				expect(t, "keywords", report.KeywordChanges, []KeywordChange{
+					{Keyword: "golang", Before: 2, After: 5},
+					{Keyword: "rust", Before: 1},
+				})
+			},
+		},
+		{
+			name: "newest result of a page counts",
+			before: []CrawlResult{
+				{URL: "/a", StatusCode: 500, ProcessedAt: time.Unix(1, 0)},
+				{URL: "/a", StatusCode: 200, ProcessedAt: time.Unix(2, 0)},
+			},
+			after: []CrawlResult{page("/a", 200, nil, nil)},
+			check: func(t *testing.T, report ChangeReport) {
+				if !report.Summary().Empty() {
+					t.Errorf("summary = %+v, want no changes", report.Summary())
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, CompareResults(tt.before, tt.after))
+		})
+	}
+}
+
+func expect[T any](t *testing.T, what string, got, want []T) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %+v, want %+v", what, got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Checkpoint is the frontier of a crawl, saved so a killed crawl can resume where it stopped.
+// URLs spilled to the storage are not in it, they are still there when the crawl resumes.
+// A crawl that stopped cleanly moves its whole frontier to the storage, its last checkpoint
+// only keeps the seen URLs
+type Checkpoint struct {
+	Tasks   []URLTask `json:"tasks"` // Queued, in flight and waiting for a retry
+	Bloom   []byte    `json:"-"`     // The seen URLs, nil when the bloom filter can not be saved
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// CheckpointStore is implemented by storages that keep the last checkpoint of a crawl
+type CheckpointStore interface {
+	SaveCheckpoint(checkpoint Checkpoint) error
+	LoadCheckpoint() (*Checkpoint, error) // nil when no crawl was checkpointed
+}
+
+// QueueSnapshot is implemented by URL queues that can list their tasks for a checkpoint
+type QueueSnapshot interface {
+	Snapshot() []URLTask
+}
+
+// QueueDrainer is implemented by URL queues that can hand back every task when the crawl stops
+type QueueDrainer interface {
+	Drain() []URLTask
+}
+
+// PersistentBloomFilter is implemented by bloom filters that can be saved in a checkpoint
+type PersistentBloomFilter interface {
+	BloomFilter
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+}
@@ -0,0 +1,19 @@
+package domain
+
+// ControlStatus is the state of a running crawl as the control API reports it
+type ControlStatus struct {
+	Paused   bool    `json:"paused"`
+	Workers  int     `json:"workers"`
+	Rate     float64 `json:"rate"` // Requests per second of all workers together, 0 means no limit
+	Stopping bool    `json:"stopping"`
+}
+
+// CrawlControl pauses, reconfigures and stops a running crawl
+type CrawlControl interface {
+	Pause()
+	Resume()
+	SetWorkers(workers int) error
+	SetRate(requestsPerSecond float64) error
+	Stop() // Stops the crawl cleanly, like Ctrl-C
+	Status() ControlStatus
+}
@@ -1,7 +1,11 @@
 package domain
 
 import (
+	"errors"
+	"net"
 	"net/url"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -13,6 +17,8 @@ const (
 	ModeDomains  CrawlMode = "domains"
 	ModeKeywords CrawlMode = "keywords"
 	ModeAll      CrawlMode = "all"
+	// ModeA11y only audits accessibility, the audit runs in the other modes too with --a11y
+	ModeA11y CrawlMode = "a11y"
 )
 
 // URLTask represents a URL to be crawled
@@ -21,41 +27,348 @@ type URLTask struct {
 	Depth     int       `json:"depth"`
 	Timestamp time.Time `json:"timestamp"`
 	Retries   int       `json:"retries"`
+	Score     float64   `json:"score,omitempty"` // Relevance score used by focused crawling, or the sitemap priority
+	// Tags of the seed it was found from, passed on to its links
+	Labels []string `json:"labels,omitempty"`
+	// Times it waited for its host's robots.txt and for a bot challenge to pass, counted apart
+	// from the fetch Retries so one kind of wait does not use up the others
+	RobotsWaits    int `json:"robots_waits,omitempty"`
+	ChallengeWaits int `json:"challenge_waits,omitempty"`
+}
+
+// Link represents an anchor discovered on a page
+type Link struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
+}
+
+// CollapseRule lists the query parameters learned to be volatile on a domain
+type CollapseRule struct {
+	Domain    string   `json:"domain"`
+	Params    []string `json:"params"`
+	Collapsed int64    `json:"collapsed"` // URLs collapsed into an already seen family
+}
+
+// DeadLink describes a broken link found on a page
+type DeadLink struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code,omitempty"` // 0 when the server never answered
+	AnchorText string `json:"anchor_text,omitempty"`
+	RedirectTo string `json:"redirect_to,omitempty"` // Final target when the link redirected to a dead page
+	Error      string `json:"error,omitempty"`
+	// Scheme and host its domain answered on when https://domain did not
+	DomainVariant string `json:"domain_variant,omitempty"`
+}
+
+// DeadDomainCause is why a domain was declared dead
+type DeadDomainCause string
+
+const (
+	DeadDomainNXDomain DeadDomainCause = "nxdomain" // The name does not resolve, often an expired domain
+	DeadDomainDNS      DeadDomainCause = "dns"      // Resolution failed for another reason (SERVFAIL, resolver timeout)
+	DeadDomainRefused  DeadDomainCause = "refused"  // Resolved but nothing listens
+	DeadDomainTLS      DeadDomainCause = "tls"      // Handshake or certificate failure
+	DeadDomainTimeout  DeadDomainCause = "timeout"  // No answer in time, often firewalled
+	DeadDomainOther    DeadDomainCause = "other"
+)
+
+// DeadDomain describes an unreachable domain found through a link
+type DeadDomain struct {
+	Domain string          `json:"domain"`
+	Cause  DeadDomainCause `json:"cause"`
+	Error  string          `json:"error,omitempty"`
+	// Whether the domain could be registered, only looked up with --rdap
+	Registration *Registration `json:"registration,omitempty"`
+	// URLs probed before declaring the domain dead, https and http, and www. or apex with --probe-www
+	Probed []string `json:"probed,omitempty"`
+}
+
+// RegistrationStatus tells whether a dead domain is still registered
+type RegistrationStatus string
+
+const (
+	RegistrationAvailable  RegistrationStatus = "available"  // Not registered, anybody can claim it
+	RegistrationExpiring   RegistrationStatus = "expiring"   // Expires within 30 days or is being deleted
+	RegistrationRegistered RegistrationStatus = "registered" // Still owned, it only stopped resolving
+	RegistrationUnknown    RegistrationStatus = "unknown"    // No RDAP server for the TLD, or the lookup failed
+)
+
+// Registration is what RDAP knows about a domain
+type Registration struct {
+	Status    RegistrationStatus `json:"status"`
+	ExpiresAt *time.Time         `json:"expires_at,omitempty"`
+}
+
+// SchemeLinkStatus is the outcome of checking a link that is not a web page
+type SchemeLinkStatus string
+
+const (
+	SchemeLinkOK          SchemeLinkStatus = "ok"
+	SchemeLinkInvalid     SchemeLinkStatus = "invalid"     // Malformed address or phone number
+	SchemeLinkNoMX        SchemeLinkStatus = "no_mx"       // The mail domain takes no mail
+	SchemeLinkUnreachable SchemeLinkStatus = "unreachable" // FTP server down, or the mail domain lookup failed
+)
+
+// SchemeLink is an ftp://, mailto: or tel: link found on a page and how its check went
+type SchemeLink struct {
+	URL        string           `json:"url"`
+	Scheme     string           `json:"scheme"`
+	Status     SchemeLinkStatus `json:"status"`
+	AnchorText string           `json:"anchor_text,omitempty"`
+	Error      string           `json:"error,omitempty"`
 }
 
 // represents the result of crawling a URL
 type CrawlResult struct {
-	URL         string         `json:"url"`
-	StatusCode  int            `json:"status_code"`
-	Title       string         `json:"title"`
-	Emails      []string       `json:"emails,omitempty"`
-	Keywords    map[string]int `json:"keywords,omitempty"`
-	DeadLinks   []string       `json:"dead_links,omitempty"`
-	DeadDomains []string       `json:"dead_domains,omitempty"`
-	NewURLs     []string       `json:"new_urls,omitempty"`
-	ProcessedAt time.Time      `json:"processed_at"`
-	ProcessTime time.Duration  `json:"process_time"`
-	Error       string         `json:"error,omitempty"`
+	URL               string            `json:"url"`
+	StatusCode        int               `json:"status_code"`
+	Title             string            `json:"title"`
+	Description       string            `json:"description,omitempty"` // Meta description
+	Emails            []string          `json:"emails,omitempty"`
+	Keywords          map[string]int    `json:"keywords,omitempty"`
+	KeywordMatch      string            `json:"keyword_match,omitempty"` // How the keywords were matched, see KeywordMatching
+	DeadLinks         []string          `json:"dead_links,omitempty"`
+	DeadDomains       []string          `json:"dead_domains,omitempty"`
+	DeadLinkDetails   []DeadLink        `json:"dead_link_details,omitempty"`
+	DeadDomainDetails []DeadDomain      `json:"dead_domain_details,omitempty"`
+	SchemeLinks       []SchemeLink      `json:"scheme_links,omitempty"` // Broken ftp, mailto and tel links, dead link mode only
+	NewURLs           []string          `json:"new_urls,omitempty"`     // Links queued for the first time
+	Links             []string          `json:"links,omitempty"`        // Every link followed from the page, the edges of the link graph
+	ProcessedAt       time.Time         `json:"processed_at"`
+	ProcessTime       time.Duration     `json:"process_time"`
+	Error             string            `json:"error,omitempty"`
+	Unchanged         bool              `json:"unchanged,omitempty"`      // Skipped by an incremental recrawl
+	Headers           map[string]string `json:"headers,omitempty"`        // Selected response headers, see RecordedHeaders
+	FinalURL          string            `json:"final_url,omitempty"`      // Where redirects ended, empty when there were none
+	ContentLength     int64             `json:"content_length,omitempty"` // Body bytes read, capped by the fetch size limit
+	ContentHash       string            `json:"content_hash,omitempty"`   // Hex SHA-256 of the body
+	Screenshot        string            `json:"screenshot,omitempty"`     // Screenshot file name, rendering mode only
+	NoIndex           bool              `json:"noindex,omitempty"`        // Page asked not to be indexed, nothing was extracted
+	NoFollow          bool              `json:"nofollow,omitempty"`       // Page asked for its links not to be followed, none were queued
+	Labels            []string          `json:"labels,omitempty"`         // Tags of the seed the page was found from
+	HiddenLinks       []string          `json:"hidden_links,omitempty"`   // Links users can not see, likely honeypots
+	Challenge         string            `json:"challenge,omitempty"`      // Vendor of the bot challenge served instead of the page
+
+	// Links only linked with rel="nofollow", not followed with --respect-nofollow
+	NoFollowLinks []string `json:"nofollow_links,omitempty"`
+
+	// Accessibility audit of the page, --a11y only
+	Accessibility *AccessibilityReport `json:"accessibility,omitempty"`
+
+	// Hex SimHash of the page text, near duplicates differ in a few bits
+	SimHash string `json:"simhash,omitempty"`
+	// First page crawled with the same content, or nearly the same with NearDuplicate
+	DuplicateOf   string `json:"duplicate_of,omitempty"`
+	NearDuplicate bool   `json:"near_duplicate,omitempty"`
+
+	// Findings of extractor plugins outside golamv2, by extractor name, see SetExtra
+	Extra map[string]any `json:"extra,omitempty"`
+}
+
+// SetExtra keeps the findings of an extractor plugin with the result
+func (r *CrawlResult) SetExtra(name string, findings any) {
+	if r.Extra == nil {
+		r.Extra = make(map[string]any)
+	}
+	r.Extra[name] = findings
+}
+
+// MergeDeadLinks adds the dead links, dead domains and scheme links of other the result does
+// not have yet, it returns how many dead links and dead domains were new
+func (r *CrawlResult) MergeDeadLinks(other CrawlResult) (int, int) {
+	links, domains := 0, 0
+	for _, link := range other.DeadLinks {
+		if !slices.Contains(r.DeadLinks, link) {
+			r.DeadLinks = append(r.DeadLinks, link)
+			links++
+		}
+	}
+	for _, deadDomain := range other.DeadDomains {
+		if !slices.Contains(r.DeadDomains, deadDomain) {
+			r.DeadDomains = append(r.DeadDomains, deadDomain)
+			domains++
+		}
+	}
+	for _, detail := range other.DeadLinkDetails {
+		if !slices.ContainsFunc(r.DeadLinkDetails, func(known DeadLink) bool { return known.URL == detail.URL }) {
+			r.DeadLinkDetails = append(r.DeadLinkDetails, detail)
+		}
+	}
+	for _, detail := range other.DeadDomainDetails {
+		if !slices.ContainsFunc(r.DeadDomainDetails, func(known DeadDomain) bool { return known.Domain == detail.Domain }) {
+			r.DeadDomainDetails = append(r.DeadDomainDetails, detail)
+		}
+	}
+	for _, link := range other.SchemeLinks {
+		if !slices.ContainsFunc(r.SchemeLinks, func(known SchemeLink) bool { return known.URL == link.URL }) {
+			r.SchemeLinks = append(r.SchemeLinks, link)
+		}
+	}
+	return links, domains
+}
+
+// HasLabel reports whether the result is tagged with label
+func (r CrawlResult) HasLabel(label string) bool {
+	for _, l := range r.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordedHeaders are the response headers kept with each result (lowercase)
+var RecordedHeaders = []string{
+	"server",
+	"content-type",
+	"content-language",
+	"cache-control",
+	"expires",
+	"age",
+	"etag",
+	"last-modified",
+	"x-powered-by",
+	"x-generator",
+	"x-cache",
+	"via",
+	"cf-cache-status",
+	"cf-ray",
+	"x-amz-cf-id",
+	"x-served-by",
+	"strict-transport-security",
+	"content-security-policy",
+	"x-frame-options",
 }
 
 // represents crawler performance metrics
 type CrawlMetrics struct {
-	URLsProcessed    int64     `json:"urls_processed"`
-	URLsInQueue      int64     `json:"urls_in_queue"`
-	URLsInDB         int64     `json:"urls_in_db"`
-	EmailsFound      int64     `json:"emails_found"`
-	KeywordsFound    int64     `json:"keywords_found"`
-	LinksChecked     int64     `json:"links_checked"`
-	DeadLinksFound   int64     `json:"dead_links_found"`
-	DeadDomainsFound int64     `json:"dead_domains_found"`
-	ActiveWorkers    int       `json:"active_workers"`
-	MemoryUsageMB    float64   `json:"memory_usage_mb"`
-	URLsPerSecond    float64   `json:"urls_per_second"`
-	StartTime        time.Time `json:"start_time"`
-	LastUpdateTime   time.Time `json:"last_update_time"`
-	Errors           int64     `json:"errors"`
+	URLsProcessed    int64 `json:"urls_processed"`
+	URLsInQueue      int64 `json:"urls_in_queue"`
+	URLsInDB         int64 `json:"urls_in_db"`
+	EmailsFound      int64 `json:"emails_found"`
+	KeywordsFound    int64 `json:"keywords_found"`
+	LinksChecked     int64 `json:"links_checked"`
+	DeadLinksFound   int64 `json:"dead_links_found"`
+	DeadDomainsFound int64 `json:"dead_domains_found"`
+	// Dead domains broken down by DeadDomainCause
+	DeadDomainsNXDomain int64 `json:"dead_domains_nxdomain"`
+	DeadDomainsDNS      int64 `json:"dead_domains_dns"`
+	DeadDomainsRefused  int64 `json:"dead_domains_refused"`
+	DeadDomainsTLS      int64 `json:"dead_domains_tls"`
+	DeadDomainsTimeout  int64 `json:"dead_domains_timeout"`
+	DeadDomainsOther    int64 `json:"dead_domains_other"`
+	// Dead domains RDAP found unregistered, or expiring
+	DeadDomainsAvailable int64 `json:"dead_domains_available"`
+	DeadDomainsExpiring  int64 `json:"dead_domains_expiring"`
+	// Domains that only answered over http or on their www. or apex variant
+	DomainsAliveViaFallback int64 `json:"domains_alive_via_fallback"`
+	// Links skipped because the dead link check queue was full
+	DeadLinkChecksDropped int64 `json:"dead_link_checks_dropped"`
+	// Links joining an already queued check or known to be alive, not requested again
+	LinkChecksDeduped int64     `json:"link_checks_deduped"`
+	ActiveWorkers     int       `json:"active_workers"`
+	MemoryUsageMB     float64   `json:"memory_usage_mb"`
+	URLsPerSecond     float64   `json:"urls_per_second"`
+	StartTime         time.Time `json:"start_time"`
+	LastUpdateTime    time.Time `json:"last_update_time"`
+	Errors            int64     `json:"errors"`
+	PagesUnchanged    int64     `json:"pages_unchanged"`
+	URLsRejected      int64     `json:"urls_rejected"`      // Dropped for breaking the URL limits
+	URLsRetried       int64     `json:"urls_retried"`       // Fetches retried after a network error, 5xx or 429
+	URLsDeadLettered  int64     `json:"urls_dead_lettered"` // URLs that failed all their retries
+	PagesNoIndex      int64     `json:"pages_noindex"`      // Findings discarded for noindex, --respect-noindex or strict robots mode only
+	// Layers of the URL bloom filter, more than one means the crawl outgrew its first filter
+	BloomFilterLayers int     `json:"bloom_filter_layers"`
+	BloomFPRate       float64 `json:"bloom_fp_rate"` // Rate the last full layer had reached when it grew
+	// robots.txt fetch outcomes other than found
+	RobotsMissing     int64  `json:"robots_missing"`
+	RobotsForbidden   int64  `json:"robots_forbidden"`
+	RobotsUnreachable int64  `json:"robots_unreachable"`
+	RobotsMode        string `json:"robots_mode"`
 	// Memory breakdown by component
 	MemoryBreakdown MemoryBreakdown `json:"memory_breakdown"`
+	// URL flow between the in-memory queue and the database
+	QueueFlow QueueFlow `json:"queue_flow"`
+	// ftp, mailto and tel links checked and the ones that failed
+	SchemeLinksChecked int64 `json:"scheme_links_checked"`
+	SchemeLinksBroken  int64 `json:"scheme_links_broken"`
+	// How much each rate limit level held requests back
+	RateLimits []RateLimitStats `json:"rate_limits,omitempty"`
+	// Links found invisible to users, and the ones not followed for it
+	HiddenLinksFound   int64 `json:"hidden_links_found"`
+	HiddenLinksSkipped int64 `json:"hidden_links_skipped"`
+	// Pages whose links were not followed for a nofollow robots directive, and links not
+	// followed for rel="nofollow", --respect-nofollow only
+	PagesNoFollow int64 `json:"pages_nofollow"`
+	LinksNoFollow int64 `json:"links_nofollow"`
+	// Bot challenges served instead of pages, and hosts quarantined for challenging in a row
+	PagesChallenged    int64 `json:"pages_challenged"`
+	DomainsQuarantined int64 `json:"domains_quarantined"`
+	// Hosts whose DNS records were collected, DNS record mode only
+	HostsDNSRecorded int64 `json:"hosts_dns_recorded"`
+	// Pages audited for accessibility and the issues found on them
+	PagesAudited        int64 `json:"pages_audited"`
+	AccessibilityIssues int64 `json:"accessibility_issues"`
+	// Fetches and link checks answered from the in-run response cache
+	ResponseCacheHits int64 `json:"response_cache_hits"`
+	// Pages with the same content as an earlier page, and with nearly the same text
+	DuplicatePages     int64 `json:"duplicate_pages"`
+	NearDuplicatePages int64 `json:"near_duplicate_pages"`
+	// Fetches slower than --slow-threshold, recorded for the explorer's slow command
+	SlowRequests int64 `json:"slow_requests"`
+	// Where page fetches spend their time, network phases against extraction
+	Transport TransportStats `json:"transport"`
+}
+
+// FetchTiming is how long the phases of one page fetch took, phases that did not
+// happen (DNS and connect on a reused connection) are 0
+type FetchTiming struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration // From the request written to the first response byte
+	Download time.Duration // From the first response byte to the body read
+	Reused   bool          // The connection came from the pool
+}
+
+// TransportStats averages the fetch phases of the crawl. High DNS, connect or TLS times
+// point at the network, a high TTFB at slow servers and a high extract time at parsing.
+// Each average only counts the fetches that went through that phase
+type TransportStats struct {
+	Fetches       int64   `json:"fetches"`
+	ConnsReused   int64   `json:"conns_reused"`
+	ConnReuseRate float64 `json:"conn_reuse_rate"` // Share of fetches on a pooled connection
+	AvgDNSMs      float64 `json:"avg_dns_ms"`
+	AvgConnectMs  float64 `json:"avg_connect_ms"`
+	AvgTLSMs      float64 `json:"avg_tls_ms"`
+	AvgTTFBMs     float64 `json:"avg_ttfb_ms"`
+	AvgDownloadMs float64 `json:"avg_download_ms"`
+	PagesParsed   int64   `json:"pages_parsed"`
+	AvgExtractMs  float64 `json:"avg_extract_ms"` // Extracting findings and links from a page
+}
+
+// RateLimitStats shows how often one level of the rate limiter made requests wait,
+// a saturation near 1 means that level sets the pace of the crawl
+type RateLimitStats struct {
+	Level       string  `json:"level"`        // global, ip or domain
+	Limit       float64 `json:"limit"`        // Requests per second, per bucket below global. 0 means no limit
+	Buckets     int     `json:"buckets"`      // Server addresses or hosts tracked
+	Requests    int64   `json:"requests"`     // Requests that passed the level
+	Waits       int64   `json:"waits"`        // Requests that had to wait
+	WaitSeconds float64 `json:"wait_seconds"` // Total time spent waiting
+	Saturation  float64 `json:"saturation"`   // Share of requests that had to wait
+}
+
+// QueueFlow shows how URLs move between the queue and its database overflow, lots of spills
+// and refills means thrash, empty refills with an empty queue means starvation
+type QueueFlow struct {
+	PushesRejected     int64   `json:"pushes_rejected"` // Pushes refused because the queue was full
+	URLsSpilled        int64   `json:"urls_spilled"`    // Rejected URLs stored in the database instead
+	RefillBatches      int64   `json:"refill_batches"`  // Database reads that brought URLs back
+	EmptyRefills       int64   `json:"empty_refills"`   // Database reads that found nothing
+	URLsRefilled       int64   `json:"urls_refilled"`
+	LastRefillMs       float64 `json:"last_refill_ms"`
+	AvgRefillLatencyMs float64 `json:"avg_refill_latency_ms"`
 }
 
 // MemoryBreakdown represents memory usage by component -- Something is off though not much of a breakdown-may cause an iinflated memory usage in the dashboard
@@ -73,6 +386,7 @@ type MemoryBreakdown struct {
 // interface for the efficient URL queue
 type URLQueue interface {
 	Push(task URLTask) error
+	PushOrSpill(task URLTask) error // Stores the task in the database when the queue is full
 	Pop() (URLTask, error)
 	Size() int
 	IsFull() bool
@@ -80,6 +394,19 @@ type URLQueue interface {
 	Close() error
 }
 
+// HostScheduler is implemented by queues that can space out the fetches of a host,
+// crawl delays are enforced this way so workers never sleep
+type HostScheduler interface {
+	SetHostDelay(host string, delay time.Duration)
+	DeferHost(host string, until time.Time) // Hold the host's tasks back once, until the given time
+}
+
+// HostLimiter is implemented by queues capping the tasks of a host processed at once,
+// Done hands the host's slot back once a popped task was processed
+type HostLimiter interface {
+	Done(task URLTask)
+}
+
 // BloomFilter
 type BloomFilter interface {
 	Add(url string)
@@ -88,12 +415,76 @@ type BloomFilter interface {
 	Reset()
 }
 
+// RemovableBloomFilter is implemented by bloom filters that can forget URLs again
+type RemovableBloomFilter interface {
+	BloomFilter
+	Remove(url string)
+}
+
+// KnownURLs is implemented by storages that can list every URL queued or crawled so far
+type KnownURLs interface {
+	ForEachKnownURL(fn func(url string)) error
+}
+
+// LabeledResults is implemented by storages that can filter results by label while reading them
+type LabeledResults interface {
+	GetLabeledResults(label, cursor string, limit int) ([]CrawlResult, string, error)
+}
+
+// DeadLinkResults is implemented by storages that can skip results without dead links while reading them
+type DeadLinkResults interface {
+	GetDeadLinkResults(cursor string, limit int) ([]CrawlResult, string, error)
+}
+
+// IndexedResults is implemented by storages indexing results by email, keyword and host, so
+// the pages with one are read without scanning every result. Paged like GetResults
+type IndexedResults interface {
+	ResultsByEmail(email, cursor string, limit int) ([]CrawlResult, string, error)
+	ResultsByKeyword(keyword, cursor string, limit int) ([]CrawlResult, string, error)
+	ResultsByDomain(host, cursor string, limit int) ([]CrawlResult, string, error)
+}
+
+// ErrInvalidCursor is returned for a cursor no page of results handed out
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// DatabaseStats is the on-disk size of one database of a storage and its keys, counted
+// per collection (the key prefix, like url: or result:, or the table)
+type DatabaseStats struct {
+	Name        string           `json:"name"`
+	LSMBytes    int64            `json:"lsm_bytes"`  // Tables of keys and small values, the whole file without a value log
+	VLogBytes   int64            `json:"vlog_bytes"` // Values kept apart from the keys
+	Tables      int              `json:"tables"`
+	TableKeys   int64            `json:"table_keys"` // Keys in the tables, old versions and deletions included
+	Collections map[string]int64 `json:"collections"`
+}
+
+// StorageStats is implemented by storages that can report their databases as stored on disk
+type StorageStats interface {
+	CollectionStats() ([]DatabaseStats, error)
+}
+
+// ResultSink is a database results are copied to next to the primary storage
+type ResultSink interface {
+	WriteResults(results []CrawlResult) error
+	Close() error
+}
+
 // Storage interface for persistent storage
 type Storage interface {
 	StoreURL(task URLTask) error
 	GetURLs(limit int) ([]URLTask, error)
 	StoreResult(result CrawlResult) error
-	GetResults(mode CrawlMode, limit int) ([]CrawlResult, error)
+	// MergeResult adds the dead links of result to the latest result stored for its URL,
+	// result is stored as is when there is none yet
+	MergeResult(result CrawlResult) error
+	// GetResults returns up to limit results after cursor, the first ones for an empty
+	// cursor, and the cursor of the next page. It is empty once there are no more results
+	GetResults(mode CrawlMode, cursor string, limit int) ([]CrawlResult, string, error)
+	// SearchResults pages through the results matching query and filters like GetResults,
+	// see MatchesResult. Filters must be validated
+	SearchResults(query string, filters ResultFilters, cursor string, limit int) ([]CrawlResult, string, error)
+	// GetResultsByTimeRange pages through the results processed within r like GetResults
+	GetResultsByTimeRange(r TimeRange, cursor string, limit int) ([]CrawlResult, string, error)
 	GetMetrics() (*CrawlMetrics, error)
 	UpdateMetrics(metrics *CrawlMetrics) error
 	Close() error
@@ -102,6 +493,9 @@ type Storage interface {
 // RobotsChecker interface for robots.txt compliance
 type RobotsChecker interface {
 	CanFetch(userAgent, urlStr string) bool
+	Check(userAgent, urlStr string) RobotsVerdict
+	// Check for a link that is only checked for being dead, its host is not crawled
+	CheckLink(userAgent, urlStr string) RobotsVerdict
 	GetSitemaps(domain string) []string
 	GetCrawlDelay(userAgent, domain string) time.Duration
 }
@@ -111,8 +505,47 @@ type ContentExtractor interface {
 	ExtractEmails(content string) []string
 	ExtractKeywords(content string, keywords []string) map[string]int
 	ExtractLinks(content, baseURL string) []string
+	ExtractAnchors(content, baseURL string) []Link
+	ExtractSchemeLinks(content string) []Link // ftp, mailto and tel links
 	ExtractTitle(content string) string
-	CheckDeadLinks(links []string, sourceURL string) ([]string, []string) // deadLinks, deadDomains
+	ExtractMetaDescription(content string) string
+	// Links users can not see, likely honeypots
+	ExtractHiddenLinks(content, baseURL string) []string
+	// Links only linked with rel="nofollow"
+	ExtractNoFollowLinks(content, baseURL string) []string
+	ExtractMetaRobots(content string) string                                             // Content of the robots meta tags, comma-joined
+	CheckDeadLinks(links []Link, sourceURL string, labels []string) ([]string, []string) // deadLinks, deadDomains
+	// Queues ftp, mailto and tel links, the broken ones are merged into the page's result
+	CheckSchemeLinks(links []Link, sourceURL string, labels []string)
+}
+
+// Extractor is a plugin pulling findings out of crawled pages. Every extractor of a crawl runs
+// on every page that keeps its findings (not the noindex ones in strict robots mode), in the
+// order they were registered, and writes what it found into the page's result
+type Extractor interface {
+	Name() string
+	Extract(content, pageURL string, result *CrawlResult)
+}
+
+// DocumentCache is implemented by content extractors parsing a page once for all their
+// extraction steps, ReleaseDocument frees the parsed page when the crawler is done with it
+type DocumentCache interface {
+	ReleaseDocument(content string)
+}
+
+// FindingsHolder is implemented by content extractors whose background checks add findings to
+// the result of a page, they hold them back until PageStored so no finding arrives before the
+// page's result exists
+type FindingsHolder interface {
+	// PageStored adds the findings held back to the stored result, or drops them when the
+	// result of the page was not stored
+	PageStored(pageURL string, stored bool)
+}
+
+// PageFingerprinter is implemented by content extractors computing the SimHash of a page's
+// text, pages with nearly the same text get fingerprints a few bits apart
+type PageFingerprinter interface {
+	Fingerprint(content string) uint64
 }
 
 // IsValidURL checks if a URL is valid
@@ -126,14 +559,46 @@ func IsValidURL(urlStr string) bool {
 		return false
 	}
 
-	return u.Scheme == "http" || u.Scheme == "https"
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	// Internationalized hosts must have a valid punycode form
+	_, err = ASCIIHost(u.Hostname())
+	return err == nil
 }
 
-// GetDomain extracts domain from URL
+// URLKey is the deduplication key of a URL, http:// and https:// variants of a page share it,
+// so do the Unicode and punycode spellings of its host
+func URLKey(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return urlStr
+	}
+
+	host := hostname(u)
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	u.Scheme = ""
+	u.Host = host
+	u.Fragment = ""
+	return u.String()
+}
+
+// GetDomain extracts domain from URL, in ACE form for internationalized domains
 func GetDomain(urlStr string) string {
 	u, err := url.Parse(urlStr)
 	if err != nil {
 		return ""
 	}
-	return u.Host
+	host := hostname(u)
+	if u.Port() != "" {
+		return net.JoinHostPort(host, u.Port())
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]" // IPv6
+	}
+	return host
 }
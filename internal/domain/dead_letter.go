@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// DeadLetter is a URL that kept failing after all its retries, kept so it can be inspected and requeued
+type DeadLetter struct {
+	Task       URLTask   `json:"task"`
+	Error      string    `json:"error"`
+	StatusCode int       `json:"status_code,omitempty"` // 0 when the server never answered
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// DeadLetterStore is implemented by storages that keep dead letters
+type DeadLetterStore interface {
+	StoreDeadLetter(letter DeadLetter) error
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DedupMode picks how discovered URLs are checked against the ones already seen
+type DedupMode string
+
+const (
+	DedupProbabilistic DedupMode = "probabilistic" // Bloom filter only, a false positive drops a new URL
+	DedupExact         DedupMode = "exact"         // Stored keys only, never wrong but a disk lookup per URL
+	DedupHybrid        DedupMode = "hybrid"        // Bloom filter first, its hits confirmed against the stored keys
+)
+
+// ParseDedupMode validates a --dedup value
+func ParseDedupMode(value string) (DedupMode, error) {
+	switch mode := DedupMode(strings.ToLower(value)); mode {
+	case DedupProbabilistic, DedupExact, DedupHybrid:
+		return mode, nil
+	case "":
+		return DedupProbabilistic, nil
+	default:
+		return "", fmt.Errorf("invalid dedup mode %q: must be probabilistic, exact or hybrid", value)
+	}
+}
+
+// SeenURLStore is implemented by storages keeping an exact set of seen URL keys
+type SeenURLStore interface {
+	MarkURLSeen(key string) (bool, error) // True when the key was not seen before
+	ForgetURL(key string) error
+	ResetSeenURLs() error
+}
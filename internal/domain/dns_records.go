@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// DNSRecords are the DNS records of a crawled host. Addresses are the host's own,
+// mail, name server and TXT records belong to its registrable domain where they live
+type DNSRecords struct {
+	Host       string    `json:"host"`
+	Domain     string    `json:"domain"` // Registrable domain MX, NS and TXT were looked up on
+	A          []string  `json:"a,omitempty"`
+	AAAA       []string  `json:"aaaa,omitempty"`
+	MX         []string  `json:"mx,omitempty"` // Mail hosts by preference
+	NS         []string  `json:"ns,omitempty"`
+	TXT        []string  `json:"txt,omitempty"`
+	Errors     []string  `json:"errors,omitempty"` // Lookups that failed, other than missing records
+	LookedUpAt time.Time `json:"looked_up_at"`
+}
+
+// DNSRecordStore is implemented by storages that keep the DNS records of crawled hosts
+type DNSRecordStore interface {
+	StoreDNSRecords(records DNSRecords) error
+	GetDNSRecords(host string) (*DNSRecords, error)
+}
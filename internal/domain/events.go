@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// EventType tells what a CrawlEvent is about
+type EventType string
+
+const (
+	EventResult   EventType = "result"    // A result of URL was stored, Result is set
+	EventDeadLink EventType = "dead_link" // Link on the page URL is dead, Message says why
+	EventError    EventType = "error"     // Fetching URL failed, Message says why
+)
+
+// CrawlEvent is something that happened during a crawl, pushed live to the dashboard
+type CrawlEvent struct {
+	Type    EventType    `json:"type"`
+	URL     string       `json:"url"`
+	Link    string       `json:"link,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Result  *CrawlResult `json:"result,omitempty"`
+	Time    time.Time    `json:"time"`
+}
+
+// EventPublisher takes the events of a crawl, publishing must never block the crawl
+type EventPublisher interface {
+	Publish(event CrawlEvent)
+}
+
+// EventSource hands out the events of a crawl, unsubscribe closes the channel
+type EventSource interface {
+	Subscribe() (events <-chan CrawlEvent, unsubscribe func())
+}
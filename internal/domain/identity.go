@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// DefaultUserAgent is sent when no user agent is configured
+const DefaultUserAgent = "GolamV2-Crawler/1.0"
+
+// Identity is what every request of a crawl says about the crawler: page fetches, robots.txt,
+// sitemaps, dead link checks and rendered pages. The zero value is DefaultUserAgent alone
+type Identity struct {
+	UserAgent string
+	Headers   map[string]string // Extra headers in canonical form, see ParseHeader
+}
+
+// Agent returns the user agent, DefaultUserAgent when none is set. robots.txt groups are
+// matched against it too
+func (i Identity) Agent() string {
+	if i.UserAgent == "" {
+		return DefaultUserAgent
+	}
+	return i.UserAgent
+}
+
+// Apply sets the extra headers and the user agent on a request
+func (i Identity) Apply(req *http.Request) {
+	for name, value := range i.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("User-Agent", i.Agent())
+}
+
+// ParseHeader splits a "Name: value" header, the name is returned in canonical form
+func ParseHeader(header string) (string, string, error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q: expected Name: value", header)
+	}
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	if name == "User-Agent" {
+		return "", "", fmt.Errorf("User-Agent can not be set as a header, it has its own setting")
+	}
+	return name, strings.TrimSpace(value), nil
+}
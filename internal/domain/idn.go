@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// hostProfile maps hosts the way browsers look them up, without the strict hostname
+// rules that reject the underscores real sites use
+var hostProfile = idna.New(idna.MapForLookup(), idna.Transitional(false), idna.StrictDomainName(false))
+
+// ASCIIHost returns the lowercase ACE (punycode) form of a host, bücher.de and
+// xn--bcher-kva.de are the same host. IP addresses are returned as they are
+func ASCIIHost(host string) (string, error) {
+	if host == "" || net.ParseIP(host) != nil {
+		return strings.ToLower(host), nil
+	}
+
+	ascii, err := hostProfile.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %v", host, err)
+	}
+	return ascii, nil
+}
+
+// UnicodeHost returns the readable form of an ACE host, for reports
+func UnicodeHost(host string) string {
+	unicode, err := hostProfile.ToUnicode(host)
+	if err != nil {
+		return host
+	}
+	return unicode
+}
+
+// hostname returns the host of u in ACE form, hosts that do not convert are only lowercased
+func hostname(u *url.URL) string {
+	host, err := ASCIIHost(u.Hostname())
+	if err != nil {
+		return strings.ToLower(u.Hostname())
+	}
+	return host
+}
+
+// NormalizeURL writes the host of an http(s) URL in lowercase ACE form, so links spelling
+// a domain in Unicode, punycode or percent-encoded form queue the same page
+func NormalizeURL(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return urlStr
+	}
+
+	host, err := ASCIIHost(u.Hostname())
+	if err != nil {
+		return urlStr
+	}
+	if port := u.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6
+	}
+
+	if host == u.Host {
+		return urlStr
+	}
+	u.Host = host
+	return u.String()
+}
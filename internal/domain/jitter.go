@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Jitter is a random pause added between two requests to the same host, on top of the
+// rate limits and crawl delays, so the traffic looks less like a bot's
+type Jitter struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// ParseJitter parses a --jitter value: a range like 200ms-1s, or a single duration for
+// 0 up to it. Empty means no jitter
+func ParseJitter(value string) (Jitter, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Jitter{}, nil
+	}
+
+	low, high, isRange := strings.Cut(value, "-")
+	if !isRange {
+		low, high = "0s", value
+	}
+
+	var jitter Jitter
+	var err error
+	if jitter.Min, err = time.ParseDuration(strings.TrimSpace(low)); err != nil {
+		return Jitter{}, fmt.Errorf("invalid jitter %q: %v", value, err)
+	}
+	if jitter.Max, err = time.ParseDuration(strings.TrimSpace(high)); err != nil {
+		return Jitter{}, fmt.Errorf("invalid jitter %q: %v", value, err)
+	}
+	if jitter.Min < 0 || jitter.Max < jitter.Min {
+		return Jitter{}, fmt.Errorf("invalid jitter %q: expected min-max with 0 <= min <= max", value)
+	}
+	return jitter, nil
+}
+
+// Draw returns a random pause between Min and Max
+func (j Jitter) Draw() time.Duration {
+	if j.Max <= j.Min {
+		return j.Min
+	}
+	return j.Min + time.Duration(rand.Int63n(int64(j.Max-j.Min)+1))
+}
+
+// String formats the jitter the way ParseJitter reads it
+func (j Jitter) String() string {
+	return j.Min.String() + "-" + j.Max.String()
+}
@@ -0,0 +1,14 @@
+package domain
+
+// CrawlJob is a batch of seed URLs handed to a running crawl, over POST /api/jobs or a
+// message queue
+type CrawlJob struct {
+	URLs   []string `json:"urls"`
+	Labels []string `json:"labels,omitempty"` // Tag everything found from the seeds
+	// Namespace the job is meant for, a crawl only takes jobs of the namespace it stores
+	// into. Empty for whichever it is
+	Namespace string `json:"namespace,omitempty"`
+	// Recrawl fetches the seeds again even when the crawl saw them already, the pages they
+	// link to are still only crawled once
+	Recrawl bool `json:"recrawl,omitempty"`
+}
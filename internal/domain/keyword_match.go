@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Keyword matching strategies, stored with the keywords of every result. Synonyms, diacritics
+// folding and per keyword rules are added to exact or stem with a +, as in stem+synonyms
+const (
+	KeywordMatchExact      = "exact" // Case-folded substring
+	KeywordMatchStem       = "stem"
+	KeywordMatchSynonyms   = "synonyms"
+	KeywordMatchDiacritics = "diacritics"
+	KeywordMatchRules      = "rules"
+)
+
+// KeywordMatching widens what counts as an occurrence of a keyword
+type KeywordMatching struct {
+	// Stem matches whole words by their stems, "running" counts for "run"
+	Stem bool
+	// Synonyms count toward the keyword they are listed under, keyed by lower-cased keyword
+	Synonyms map[string][]string
+	// FoldDiacritics ignores accents and other combining marks, café matches cafe
+	FoldDiacritics bool
+	// Rules match some keywords their own way, keyed by lower-cased keyword
+	Rules map[string]KeywordRule
+}
+
+// KeywordRule is how one keyword of a --keywords-file matches. Stem, Synonyms and
+// FoldDiacritics of the crawl apply on top
+type KeywordRule struct {
+	Keyword       string   `yaml:"keyword"`                  // Name the count is stored under, and the term matched without Regex
+	WholeWord     bool     `yaml:"whole_word,omitempty"`     // Only whole words count, the words of a phrase in a row
+	CaseSensitive bool     `yaml:"case_sensitive,omitempty"` // Case must match too
+	Stem          bool     `yaml:"stem,omitempty"`           // Whole words compared by their stems, like --stem
+	Regex         string   `yaml:"regex,omitempty"`          // RE2 pattern whose matches are counted instead of the keyword
+	Synonyms      []string `yaml:"synonyms,omitempty"`       // More terms counted toward the keyword
+}
+
+// Validate checks a rule, the regex must compile
+func (r KeywordRule) Validate() error {
+	if strings.TrimSpace(r.Keyword) == "" {
+		return fmt.Errorf("keyword rule without a keyword")
+	}
+	if r.Regex != "" {
+		if r.WholeWord || r.Stem || len(r.Synonyms) > 0 {
+			return fmt.Errorf("keyword %q: regex can not be combined with whole_word, stem or synonyms", r.Keyword)
+		}
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("keyword %q: invalid regex: %v", r.Keyword, err)
+		}
+	}
+	if r.Stem && r.CaseSensitive {
+		return fmt.Errorf("keyword %q: stems are compared case-folded, stem can not be case_sensitive", r.Keyword)
+	}
+	return nil
+}
+
+// AddRule sets the rule of its keyword, replacing an earlier one
+func (k *KeywordMatching) AddRule(rule KeywordRule) {
+	if k.Rules == nil {
+		k.Rules = make(map[string]KeywordRule)
+	}
+	k.Rules[strings.ToLower(rule.Keyword)] = rule
+}
+
+// Rule returns how keyword matches, the settings of the crawl when it has no rule of its own
+func (k KeywordMatching) Rule(keyword string) KeywordRule {
+	rule, ok := k.Rules[strings.ToLower(keyword)]
+	if !ok {
+		rule = KeywordRule{Keyword: keyword}
+	}
+	rule.Stem = rule.Stem || k.Stem
+	return rule
+}
+
+// ParseSynonyms parses a --synonyms value: keyword=synonym,synonym
+func ParseSynonyms(value string) (string, []string, error) {
+	keyword, list, ok := strings.Cut(value, "=")
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+
+	var synonyms []string
+	for _, synonym := range strings.Split(list, ",") {
+		if synonym = strings.ToLower(strings.TrimSpace(synonym)); synonym != "" {
+			synonyms = append(synonyms, synonym)
+		}
+	}
+	if !ok || keyword == "" || len(synonyms) == 0 {
+		return "", nil, fmt.Errorf("invalid synonyms %q: expected keyword=synonym,synonym", value)
+	}
+	return keyword, synonyms, nil
+}
+
+// AddSynonyms lists synonyms under keyword, next to the ones it already has
+func (k *KeywordMatching) AddSynonyms(keyword string, synonyms []string) {
+	if k.Synonyms == nil {
+		k.Synonyms = make(map[string][]string)
+	}
+	keyword = strings.ToLower(keyword)
+	k.Synonyms[keyword] = append(k.Synonyms[keyword], synonyms...)
+}
+
+// Terms returns keyword and its synonyms, those of its rule included, the terms whose
+// occurrences count for it
+func (k KeywordMatching) Terms(keyword string) []string {
+	terms := append([]string{keyword}, k.Synonyms[strings.ToLower(keyword)]...)
+	return append(terms, k.Rules[strings.ToLower(keyword)].Synonyms...)
+}
+
+// Strategy names how keywords are matched
+func (k KeywordMatching) Strategy() string {
+	strategy := KeywordMatchExact
+	if k.Stem {
+		strategy = KeywordMatchStem
+	}
+	if len(k.Synonyms) > 0 {
+		strategy += "+" + KeywordMatchSynonyms
+	}
+	if k.FoldDiacritics {
+		strategy += "+" + KeywordMatchDiacritics
+	}
+	if len(k.Rules) > 0 {
+		strategy += "+" + KeywordMatchRules
+	}
+	return strategy
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "testing"
+
+func TestKeywordRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    KeywordRule
+		wantErr bool
+	}{
+		{"plain", KeywordRule{Keyword: "api"}, false},
+		{"no keyword", KeywordRule{Keyword: " ", WholeWord: true}, true},
+		{"case sensitive whole word", KeywordRule{Keyword: "API", WholeWord: true, CaseSensitive: true}, false},
+		{"stem", KeywordRule{Keyword: "run", Stem: true}, false},
+		{"stem case sensitive", KeywordRule{Keyword: "Run", Stem: true, CaseSensitive: true}, true},
+		{"case sensitive regex", KeywordRule{Keyword: "order", Regex: `ORD-\d+`, CaseSensitive: true}, false},
+		{"regex with stem", KeywordRule{Keyword: "order", Regex: `order`, Stem: true}, true},
+		{"invalid regex", KeywordRule{Keyword: "order", Regex: `(`}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "strings"
+
+// Page metadata compared across the pages of a domain
+const (
+	MetaTitle       = "title"
+	MetaDescription = "description"
+)
+
+// PageMeta is the title and meta description of a crawled page
+type PageMeta struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Value returns the title or the description
+func (m PageMeta) Value(kind string) string {
+	if kind == MetaDescription {
+		return m.Description
+	}
+	return m.Title
+}
+
+// DuplicateMeta is a title or description shared by several pages of a domain
+type DuplicateMeta struct {
+	Domain string   `json:"domain"`
+	Kind   string   `json:"kind"`
+	Value  string   `json:"value"`
+	URLs   []string `json:"urls"`
+}
+
+// NormalizeMeta is what titles and descriptions are compared on, case and spacing aside
+func NormalizeMeta(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// PageMetaStore is implemented by storages that index page titles and descriptions by domain
+type PageMetaStore interface {
+	StorePageMeta(meta PageMeta) error
+	// Groups of pages sharing a value, largest first, an empty domain lists every domain
+	GetDuplicateMeta(kind, domain string, limit int) ([]DuplicateMeta, error)
+}
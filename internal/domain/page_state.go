@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PageState remembers what a page looked like the last time it was fetched,
+// incremental recrawls use it to skip pages that have not changed
+type PageState struct {
+	URL           string    `json:"url"`
+	ETag          string    `json:"etag,omitempty"`
+	LastModified  string    `json:"last_modified,omitempty"`
+	ContentHash   string    `json:"content_hash"`
+	ContentLength int64     `json:"content_length,omitempty"`
+	Links         []string  `json:"links,omitempty"` // Outgoing links, re-queued when the page is skipped
+	FetchedAt     time.Time `json:"fetched_at"`
+}
+
+// PageStateStore is implemented by storages that can persist page state between crawls
+type PageStateStore interface {
+	GetPageState(url string) (*PageState, error)
+	StorePageState(state PageState) error
+}
+
+// ArchivedPage is the raw body of a crawled page, kept so it can be re-extracted without refetching
+type ArchivedPage struct {
+	URL         string    `json:"url"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        string    `json:"body"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// PageArchive is implemented by storages that can archive raw page bodies
+type PageArchive interface {
+	ArchivePage(page ArchivedPage) error
+	GetArchivedPage(url string) (*ArchivedPage, error)
+	ForEachArchivedPage(fn func(page ArchivedPage) error) error
+}
+
+// ArchiveMode picks where the raw bodies of fetched pages are kept, empty keeps none
+type ArchiveMode string
+
+const (
+	ArchiveBadger ArchiveMode = "badger" // Snappy compressed in the archive database of the Badger storage
+	ArchiveWARC   ArchiveMode = "warc"   // Gzipped WARC files any web archive tool reads
+)
+
+// ParseArchiveMode validates an --archive value
+func ParseArchiveMode(value string) (ArchiveMode, error) {
+	switch mode := ArchiveMode(strings.ToLower(value)); mode {
+	case ArchiveBadger, ArchiveWARC:
+		return mode, nil
+	case "", "off", "none":
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid archive mode %q: must be badger or warc", value)
+	}
+}
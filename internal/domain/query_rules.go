@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultStripParams are tracking parameters that never change a page
+var DefaultStripParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi"}
+
+// QueryRule strips query parameters of one domain and its subdomains, or of every domain
+// for *. Parameter names ending in * match a prefix (utm_*)
+type QueryRule struct {
+	Domain string
+	Strip  []string // Parameters removed
+	Keep   []string // Parameters kept, when set every other parameter is removed
+}
+
+// QueryRules strips query parameters from discovered URLs before they are deduplicated,
+// so tracking variants of a page are crawled once
+type QueryRules struct {
+	Strip []string    // Removed on every domain unless a keep rule lists them
+	Rules []QueryRule // Per domain, the most specific one applies
+}
+
+// ParseQueryRule parses a --query-rule value: domain:strip=a,b or domain:keep=id,page
+func ParseQueryRule(value string) (QueryRule, error) {
+	host, spec, ok := strings.Cut(value, ":")
+	host = strings.Trim(strings.ToLower(strings.TrimSpace(host)), ".")
+	if !ok || host == "" {
+		return QueryRule{}, fmt.Errorf("invalid query rule %q: expected domain:strip=params or domain:keep=params", value)
+	}
+	if ascii, err := ASCIIHost(host); err == nil && host != "*" {
+		host = ascii
+	}
+
+	action, params, ok := strings.Cut(strings.TrimSpace(spec), "=")
+	names := splitParams(params)
+	if !ok || len(names) == 0 {
+		return QueryRule{}, fmt.Errorf("invalid query rule %q: expected strip= or keep= with parameter names", value)
+	}
+
+	rule := QueryRule{Domain: host}
+	switch strings.ToLower(action) {
+	case "strip":
+		rule.Strip = names
+	case "keep":
+		rule.Keep = names
+	default:
+		return QueryRule{}, fmt.Errorf("invalid query rule %q: unknown action %q, must be strip or keep", value, action)
+	}
+	return rule, nil
+}
+
+func splitParams(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Apply removes the stripped parameters from a URL, the other parameters keep their
+// order and encoding
+func (q QueryRules) Apply(urlStr string) string {
+	if len(q.Strip) == 0 && len(q.Rules) == 0 {
+		return urlStr
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil || u.RawQuery == "" {
+		return urlStr
+	}
+
+	rule := q.ruleFor(hostname(u))
+
+	var kept []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if pair != "" && !q.strips(rule, name) {
+			kept = append(kept, pair)
+		}
+	}
+
+	query := strings.Join(kept, "&")
+	if query == u.RawQuery {
+		return urlStr
+	}
+	u.RawQuery = query
+	u.ForceQuery = false
+	return u.String()
+}
+
+// ruleFor returns the rule of the most specific domain matching host, or nil
+func (q QueryRules) ruleFor(host string) *QueryRule {
+	var best *QueryRule
+	for i, rule := range q.Rules {
+		if rule.Domain != "*" && host != rule.Domain && !strings.HasSuffix(host, "."+rule.Domain) {
+			continue
+		}
+		if best == nil || len(rule.Domain) > len(best.Domain) {
+			best = &q.Rules[i]
+		}
+	}
+	return best
+}
+
+// strips reports whether a parameter is removed, a keep rule replaces the global list
+func (q QueryRules) strips(rule *QueryRule, name string) bool {
+	if rule != nil && len(rule.Keep) > 0 {
+		return !matchParam(rule.Keep, name)
+	}
+	if rule != nil && matchParam(rule.Strip, name) {
+		return true
+	}
+	return matchParam(q.Strip, name)
+}
+
+func matchParam(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	// Weights applied when a keyword shows up in a link
+	AnchorMatchWeight = 2.0
+	URLMatchWeight    = 1.0
+)
+
+// RelevanceScore scores a link against the hunted keywords using its anchor text and URL.
+// A higher score means the link is more likely to lead to a relevant page
+func RelevanceScore(link Link, keywords []string) float64 {
+	if len(keywords) == 0 {
+		return 0
+	}
+
+	anchor := strings.ToLower(link.Text)
+	target := strings.ToLower(link.URL)
+	if u, err := url.Parse(link.URL); err == nil {
+		// Ignore the host, nearly every link on a site shares it
+		target = strings.ToLower(u.Path + " " + u.RawQuery)
+		if decoded, err := url.PathUnescape(target); err == nil {
+			target = decoded
+		}
+	}
+
+	score := 0.0
+	for _, keyword := range keywords {
+		keywordLower := strings.ToLower(strings.TrimSpace(keyword))
+		if keywordLower == "" {
+			continue
+		}
+		if strings.Contains(anchor, keywordLower) {
+			score += AnchorMatchWeight
+		}
+		if strings.Contains(target, keywordLower) {
+			score += URLMatchWeight
+		}
+	}
+
+	return score
+}
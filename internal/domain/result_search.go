@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ResultFilters narrows a result search down by field, empty fields match every result
+type ResultFilters struct {
+	Domain string // Host of the page, its subdomains included
+	Status string // HTTP status of the page, 404 or a class like 4xx
+	Type   string // Findings the page must have: email, keyword, dead_link, dead_domain or error
+	Label  string // Tag of the seed the page was found from
+}
+
+// ResultTypes are the values of the type: filter
+var ResultTypes = []string{"email", "keyword", "dead_link", "dead_domain", "error"}
+
+// ParseResultQuery splits a search like `acme domain:example.com status:4xx` into the free
+// text and the field filters. Filters given twice keep the last value
+func ParseResultQuery(search string) (string, ResultFilters, error) {
+	var words []string
+	var filters ResultFilters
+	for _, word := range strings.Fields(search) {
+		field, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			words = append(words, word)
+			continue
+		}
+
+		switch strings.ToLower(field) {
+		case "domain":
+			filters.Domain = value
+		case "status":
+			filters.Status = value
+		case "type":
+			filters.Type = value
+		case "label":
+			filters.Label = value
+		default:
+			words = append(words, word) // Text with a colon, like a URL
+			continue
+		}
+	}
+
+	if err := filters.Validate(); err != nil {
+		return "", ResultFilters{}, err
+	}
+	return strings.Join(words, " "), filters, nil
+}
+
+// Validate checks the filters and normalizes them for MatchesResult
+func (f *ResultFilters) Validate() error {
+	if f.Domain != "" {
+		host, err := ASCIIHost(strings.TrimSuffix(strings.ToLower(f.Domain), "."))
+		if err != nil {
+			return fmt.Errorf("invalid domain filter %q: %v", f.Domain, err)
+		}
+		f.Domain = host
+	}
+
+	if f.Status != "" {
+		status := strings.ToLower(f.Status)
+		if class, ok := strings.CutSuffix(status, "xx"); ok && len(class) == 1 && class[0] >= '1' && class[0] <= '5' {
+			f.Status = status
+		} else if code, err := strconv.Atoi(status); err == nil && code >= 100 && code <= 599 {
+			f.Status = status
+		} else {
+			return fmt.Errorf("invalid status filter %q: use a code like 404 or a class like 4xx", f.Status)
+		}
+	}
+
+	if f.Type != "" {
+		f.Type = strings.ToLower(f.Type)
+		if !slices.Contains(ResultTypes, f.Type) {
+			return fmt.Errorf("invalid type filter %q: must be %s", f.Type, strings.Join(ResultTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// MatchesResult reports whether a result has query in its URL, title, description or
+// findings, case insensitive, and passes the filters. The filters must be validated
+func MatchesResult(result CrawlResult, query string, filters ResultFilters) bool {
+	if filters.Label != "" && !result.HasLabel(filters.Label) {
+		return false
+	}
+
+	if filters.Domain != "" {
+		u, err := url.Parse(result.URL)
+		if err != nil {
+			return false
+		}
+		host := hostname(u)
+		if host != filters.Domain && !strings.HasSuffix(host, "."+filters.Domain) {
+			return false
+		}
+	}
+
+	if filters.Status != "" {
+		status := strconv.Itoa(result.StatusCode)
+		if class, ok := strings.CutSuffix(filters.Status, "xx"); ok {
+			if !strings.HasPrefix(status, class) || len(status) != 3 {
+				return false
+			}
+		} else if status != filters.Status {
+			return false
+		}
+	}
+
+	switch filters.Type {
+	case "email":
+		if len(result.Emails) == 0 {
+			return false
+		}
+	case "keyword":
+		if len(result.Keywords) == 0 {
+			return false
+		}
+	case "dead_link":
+		if len(result.DeadLinks) == 0 {
+			return false
+		}
+	case "dead_domain":
+		if len(result.DeadDomains) == 0 {
+			return false
+		}
+	case "error":
+		if result.Error == "" {
+			return false
+		}
+	}
+
+	if query == "" {
+		return true
+	}
+	query = strings.ToLower(query)
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), query)
+	}
+
+	if contains(result.URL) || contains(result.Title) || contains(result.Description) {
+		return true
+	}
+	for _, email := range result.Emails {
+		if contains(email) {
+			return true
+		}
+	}
+	for keyword := range result.Keywords {
+		if contains(keyword) {
+			return true
+		}
+	}
+	for _, link := range result.DeadLinks {
+		if contains(link) {
+			return true
+		}
+	}
+	for _, deadDomain := range result.DeadDomains {
+		if contains(deadDomain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RobotsMode controls how closely the crawler follows robots rules
+type RobotsMode string
+
+const (
+	RobotsStrict   RobotsMode = "strict"   // Standard plus noindex
+	RobotsStandard RobotsMode = "standard" // robots.txt allow and disallow rules and Crawl-delay
+	RobotsOff      RobotsMode = "off"      // Ignore robots.txt, for crawling one's own properties
+)
+
+// ParseRobotsMode validates a --robots value
+func ParseRobotsMode(value string) (RobotsMode, error) {
+	switch mode := RobotsMode(strings.ToLower(value)); mode {
+	case RobotsStrict, RobotsStandard, RobotsOff:
+		return mode, nil
+	case "":
+		return RobotsStandard, nil
+	default:
+		return "", fmt.Errorf("invalid robots mode %q: must be strict, standard or off", value)
+	}
+}
+
+// RobotsStatus is how fetching a host's robots.txt went
+type RobotsStatus string
+
+const (
+	RobotsFound       RobotsStatus = "found"
+	RobotsMissing     RobotsStatus = "missing"     // 404 and other 4xx, crawling is allowed
+	RobotsForbidden   RobotsStatus = "forbidden"   // 401 or 403
+	RobotsUnreachable RobotsStatus = "unreachable" // 5xx, timeouts and connection errors
+)
+
+// RobotsStatusOf classifies the HTTP status a robots.txt was served with
+func RobotsStatusOf(statusCode int) RobotsStatus {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return RobotsFound
+	case statusCode == 401 || statusCode == 403:
+		return RobotsForbidden
+	case statusCode >= 400 && statusCode < 500:
+		return RobotsMissing
+	default:
+		return RobotsUnreachable
+	}
+}
+
+// RobotsAction is what to do with the URLs of a host whose robots.txt could not be read
+type RobotsAction string
+
+const (
+	RobotsAllow    RobotsAction = "allow"
+	RobotsDisallow RobotsAction = "disallow"
+	RobotsRetry    RobotsAction = "retry" // Hold the URLs back and fetch robots.txt again later
+)
+
+// ParseRobotsAction validates a robots policy action
+func ParseRobotsAction(value string) (RobotsAction, error) {
+	switch action := RobotsAction(strings.ToLower(value)); action {
+	case RobotsAllow, RobotsDisallow, RobotsRetry:
+		return action, nil
+	default:
+		return "", fmt.Errorf("invalid robots action %q: must be allow, disallow or retry", value)
+	}
+}
+
+// RobotsPolicy decides the cases where robots.txt could not be read, missing files always allow crawling
+type RobotsPolicy struct {
+	Forbidden   RobotsAction
+	Unreachable RobotsAction
+}
+
+// DefaultRobotsPolicy stays off hosts forbidding robots.txt and waits for unreachable ones
+var DefaultRobotsPolicy = RobotsPolicy{Forbidden: RobotsDisallow, Unreachable: RobotsRetry}
+
+// RobotsVerdict is the robots answer for one URL
+type RobotsVerdict int
+
+const (
+	RobotsAllowed RobotsVerdict = iota
+	RobotsBlocked
+	RobotsRetryLater // robots.txt is unreachable for now
+)
+
+// HasNoIndex reports whether robots directives from a meta tag or X-Robots-Tag header forbid indexing
+func HasNoIndex(directives string) bool {
+	return hasRobotsDirective(directives, "noindex")
+}
+
+// HasNoFollow reports whether robots directives from a meta tag or X-Robots-Tag header forbid
+// following the links of the page
+func HasNoFollow(directives string) bool {
+	return hasRobotsDirective(directives, "nofollow")
+}
+
+// hasRobotsDirective reports whether directives hold name, or none which implies every one
+func hasRobotsDirective(directives, name string) bool {
+	for _, directive := range strings.FieldsFunc(strings.ToLower(directives), func(r rune) bool {
+		return r == ',' || r == ':' || r == ' ' || r == '\t'
+	}) {
+		if directive == name || directive == "none" {
+			return true
+		}
+	}
+	return false
+}
+
+// RobotsFile is a fetched robots.txt, kept so resumed crawls do not fetch it again
+type RobotsFile struct {
+	Host       string    `json:"host"`
+	StatusCode int       `json:"status_code"`
+	Body       string    `json:"body,omitempty"`
+	FetchedAt  time.Time `json:"fetched_at"`
+}
+
+// RobotsStore is implemented by storages that can persist robots.txt files between runs
+type RobotsStore interface {
+	GetRobots(host string) (*RobotsFile, error)
+	StoreRobots(file RobotsFile) error
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// RunStatus is the state of a scheduled crawl run
+type RunStatus string
+
+const (
+	RunPending   RunStatus = "pending"
+	RunRunning   RunStatus = "running"
+	RunCompleted RunStatus = "completed"
+	RunFailed    RunStatus = "failed"
+)
+
+// RunCancelled is a run stopped before it finished, it is never the baseline of a comparison
+const RunCancelled RunStatus = "cancelled"
+
+// ChangesFileName is the file in the directory of a scheduled run its ChangeReport is written to
+const ChangesFileName = "changes.json"
+
+// CrawlRun records one execution of a scheduled crawl
+type CrawlRun struct {
+	ID            string    `json:"id"`
+	Session       string    `json:"session"`
+	DataDir       string    `json:"data_dir"`
+	ScheduledAt   time.Time `json:"scheduled_at"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at,omitempty"`
+	Status        RunStatus `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	URLsProcessed int64     `json:"urls_processed"`
+	Findings      int64     `json:"findings"`
+
+	// Changes since the run ComparedTo, the report is in ChangesFileName of the run's directory
+	ComparedTo string         `json:"compared_to,omitempty"`
+	Changes    *ChangeSummary `json:"changes,omitempty"`
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ScopePolicy controls which discovered links the crawler follows
+type ScopePolicy string
+
+const (
+	ScopeHost   ScopePolicy = "host"   // Only the exact host of the start URL
+	ScopeDomain ScopePolicy = "domain" // Any subdomain of the start URL's registrable domain
+	ScopeAny    ScopePolicy = "any"    // Everything, the original behaviour
+)
+
+// ParseScopePolicy validates a --scope value
+func ParseScopePolicy(value string) (ScopePolicy, error) {
+	switch policy := ScopePolicy(strings.ToLower(value)); policy {
+	case ScopeHost, ScopeDomain, ScopeAny:
+		return policy, nil
+	case "":
+		return ScopeAny, nil
+	default:
+		return "", fmt.Errorf("invalid scope %q: must be host, domain or any", value)
+	}
+}
+
+// Scope decides whether a URL is inside the crawl, relative to the seed URLs
+type Scope struct {
+	policy   ScopePolicy
+	mu       sync.RWMutex    // Seeds and exclusions can change while crawling
+	allowed  map[string]bool // Hosts or registrable domains depending on the policy
+	excluded map[string]bool // Blocked domains, their subdomains included, whatever the policy
+}
+
+// NewScope builds a scope around the hosts of the seed URLs
+func NewScope(policy ScopePolicy, seeds ...string) *Scope {
+	s := &Scope{
+		policy:   policy,
+		allowed:  make(map[string]bool),
+		excluded: make(map[string]bool),
+	}
+	s.AddSeeds(seeds...)
+
+	return s
+}
+
+// AddSeeds widens the scope to the hosts of more seed URLs
+func (s *Scope) AddSeeds(seeds ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, seed := range seeds {
+		if key := s.key(seed); key != "" {
+			s.allowed[key] = true
+		}
+	}
+}
+
+// Exclude blocks domains and all of their subdomains, for following links and checking them alike
+func (s *Scope) Exclude(domains ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	excluded := excludedDomains(domains)
+	for d := range s.excluded {
+		excluded[d] = true
+	}
+	s.excluded = excluded
+}
+
+// SetExcluded replaces the blocked domains, for settings reloaded during a crawl. The new set
+// is swapped in at once, no URL is checked against a half-built one
+func (s *Scope) SetExcluded(domains ...string) {
+	excluded := excludedDomains(domains)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.excluded = excluded
+}
+
+// excludedDomains builds a set of blocked domains, the set of a Scope is never changed once
+// swapped in
+func excludedDomains(domains []string) map[string]bool {
+	excluded := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), ".")
+		if ascii, err := ASCIIHost(d); err == nil {
+			d = ascii
+		}
+		if d != "" {
+			excluded[d] = true
+		}
+	}
+	return excluded
+}
+
+// Excludes reports whether a URL is on a blocked domain and must not be requested at all
+func (s *Scope) Excludes(urlStr string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.RLock()
+	excluded := s.excluded
+	s.mu.RUnlock()
+	if len(excluded) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	// shop.example.com is blocked by example.com, walk up the labels
+	host := hostname(u)
+	for host != "" {
+		if excluded[host] {
+			return true
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			break
+		}
+		host = host[dot+1:]
+	}
+	return false
+}
+
+// Allows reports whether a link may be followed
+func (s *Scope) Allows(urlStr string) bool {
+	if s.Excludes(urlStr) {
+		return false
+	}
+	if s == nil || s.policy == ScopeAny || s.policy == "" {
+		return true
+	}
+
+	key := s.key(urlStr)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return key != "" && s.allowed[key]
+}
+
+// Policy returns the scope policy
+func (s *Scope) Policy() ScopePolicy {
+	if s == nil || s.policy == "" {
+		return ScopeAny
+	}
+	return s.policy
+}
+
+// key is what two URLs must share to be in the same scope
+func (s *Scope) key(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+
+	host := hostname(u)
+	if s.policy != ScopeDomain {
+		return host
+	}
+
+	// example.co.uk for shop.example.co.uk, IPs and local hosts are kept as they are
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return registrable
+}
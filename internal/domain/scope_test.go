@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestScopeExcludes(t *testing.T) {
+	scope := NewScope(ScopeAny)
+	scope.Exclude("Example.com.", " ads.test ")
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/", true},
+		{"https://shop.example.com/cart", true},
+		{"https://notexample.com/", false},
+		{"https://ads.test/x", true},
+		{"https://example.org/", false},
+	}
+	for _, tt := range tests {
+		if got := scope.Excludes(tt.url); got != tt.want {
+			t.Errorf("Excludes(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	scope.SetExcluded("example.org")
+	if scope.Excludes("https://example.com/") || !scope.Excludes("https://example.org/") {
+		t.Error("SetExcluded did not replace the blocked domains")
+	}
+}
+
+// A domain blocked before and after a reload stays blocked while the reload happens
+func TestScopeSetExcludedIsAtomic(t *testing.T) {
+	scope := NewScope(ScopeAny)
+	scope.Exclude("example.com")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			scope.SetExcluded("example.com", "example.org")
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if !scope.Excludes("https://example.com/") {
+			t.Fatal("example.com was let through during a reload")
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// SlowRequest is a page fetch that took longer than --slow-threshold
+type SlowRequest struct {
+	URL        string        `json:"url"`
+	Worker     int           `json:"worker"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"status_code,omitempty"` // 0 when the server never answered
+	Bytes      int64         `json:"bytes"`
+	Error      string        `json:"error,omitempty"`
+	FetchedAt  time.Time     `json:"fetched_at"`
+}
+
+// SlowRequestLog is implemented by storages that keep slow fetches
+type SlowRequestLog interface {
+	StoreSlowRequest(request SlowRequest) error
+	// GetSlowRequests returns up to limit slow fetches, slowest first
+	GetSlowRequests(limit int) ([]SlowRequest, error)
+}
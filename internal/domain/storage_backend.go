@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StorageBackend is where a crawl keeps its results and the URLs its queue spills
+type StorageBackend string
+
+const (
+	StorageBadger StorageBackend = "badger" // Badger databases, read by the explorer, exports, the dashboard and --resume
+	StorageJSONL  StorageBackend = "jsonl"  // Append-only JSON Lines files, the lightest for huge single-pass crawls
+	StorageMemory StorageBackend = "memory" // Badger kept in memory, nothing is written to disk
+	// SQL databases, results are queryable with plain SQL and shared with other tools
+	StorageSQLite   StorageBackend = "sqlite"
+	StoragePostgres StorageBackend = "postgres"
+)
+
+// Badger reports whether the backend is one of the Badger ones, the only ones with
+// checkpoints, page state, namespaces and the indexes the explorer reads
+func (b StorageBackend) Badger() bool {
+	return b == StorageBadger || b == StorageMemory || b == ""
+}
+
+// ParseStorageBackend validates a --storage value
+func ParseStorageBackend(value string) (StorageBackend, error) {
+	switch backend := StorageBackend(strings.ToLower(value)); backend {
+	case StorageBadger, StorageJSONL, StorageMemory, StorageSQLite, StoragePostgres:
+		return backend, nil
+	case "":
+		return StorageBadger, nil
+	default:
+		return "", fmt.Errorf("invalid storage %q: must be badger, jsonl, memory, sqlite or postgres", value)
+	}
+}
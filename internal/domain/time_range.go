@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeRange selects the results processed from From up to, not including, To. A zero end
+// leaves that side open
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// timeLayouts are the accepted forms of a range end, those without a zone are local time
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// ParseTimeRange parses the ends of a range, each empty or a date like 2024-05-01, a local
+// time like 2024-05-01T14:30 or an RFC 3339 time. A date as the end includes that whole day
+func ParseTimeRange(from, to string) (TimeRange, error) {
+	var r TimeRange
+	var err error
+	if r.From, err = parseTimeBound(from, false); err != nil {
+		return TimeRange{}, err
+	}
+	if r.To, err = parseTimeBound(to, true); err != nil {
+		return TimeRange{}, err
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return TimeRange{}, fmt.Errorf("invalid time range: %s is not before %s", from, to)
+	}
+	return r, nil
+}
+
+func parseTimeBound(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range timeLayouts[1:] {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			if end && len(value) == len("2006-01-02") {
+				t = t.AddDate(0, 0, 1)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use 2006-01-02, 2006-01-02T15:04 or RFC 3339", value)
+}
+
+// IsZero reports whether the range is open on both sides
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// Contains reports whether t is within the range
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TraversalOrder picks the order queued URLs are crawled in
+type TraversalOrder string
+
+const (
+	TraversalPriority TraversalOrder = "priority" // Shallow pages first, mixed with relevance scores and domain diversity
+	TraversalBFS      TraversalOrder = "bfs"      // Level by level, each level in discovery order
+	TraversalDFS      TraversalOrder = "dfs"      // Deepest and most recently found first
+)
+
+// ParseTraversalOrder validates a --traversal value
+func ParseTraversalOrder(value string) (TraversalOrder, error) {
+	switch order := TraversalOrder(strings.ToLower(value)); order {
+	case TraversalPriority, TraversalBFS, TraversalDFS:
+		return order, nil
+	case "":
+		return TraversalPriority, nil
+	default:
+		return "", fmt.Errorf("invalid traversal %q: must be bfs, dfs or priority", value)
+	}
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// URLFilter keeps discovered URLs matching regular expressions, the zero value keeps everything
+type URLFilter struct {
+	Include []*regexp.Regexp // A URL must match one of them, if any
+	Exclude []*regexp.Regexp // A URL matching any of them is dropped, includes or not
+}
+
+// ParseURLFilter compiles the include and exclude patterns of a filter
+func ParseURLFilter(include, exclude []string) (URLFilter, error) {
+	var filter URLFilter
+	for _, pattern := range include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return URLFilter{}, fmt.Errorf("invalid include pattern %q: %v", pattern, err)
+		}
+		filter.Include = append(filter.Include, re)
+	}
+	for _, pattern := range exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return URLFilter{}, fmt.Errorf("invalid exclude pattern %q: %v", pattern, err)
+		}
+		filter.Exclude = append(filter.Exclude, re)
+	}
+	return filter, nil
+}
+
+// Allows reports whether a URL passes the filter
+func (f URLFilter) Allows(urlStr string) bool {
+	for _, re := range f.Exclude {
+		if re.MatchString(urlStr) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, re := range f.Include {
+		if re.MatchString(urlStr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLLimits caps the shape of URLs accepted into the frontier, a zero field means no limit
+type URLLimits struct {
+	MaxLength      int // Characters in the whole URL
+	MaxQueryParams int // Number of query parameters
+	MaxPathDepth   int // Number of path segments
+}
+
+// DefaultURLLimits are generous enough for real sites but stop generated URL traps
+var DefaultURLLimits = URLLimits{
+	MaxLength:      2048,
+	MaxQueryParams: 20,
+	MaxPathDepth:   16,
+}
+
+// Check returns why a URL breaks the limits, or nil
+func (l URLLimits) Check(urlStr string) error {
+	// Length first so megabyte long URLs are never parsed
+	if l.MaxLength > 0 && len(urlStr) > l.MaxLength {
+		return fmt.Errorf("URL length %d exceeds %d", len(urlStr), l.MaxLength)
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return err
+	}
+
+	if l.MaxQueryParams > 0 && u.RawQuery != "" {
+		if params := strings.Count(u.RawQuery, "&") + 1; params > l.MaxQueryParams {
+			return fmt.Errorf("%d query parameters exceed %d", params, l.MaxQueryParams)
+		}
+	}
+
+	if l.MaxPathDepth > 0 {
+		if depth := len(strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })); depth > l.MaxPathDepth {
+			return fmt.Errorf("path depth %d exceeds %d", depth, l.MaxPathDepth)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strings"
+
+	"golamv2/internal/domain"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// unlabeledInputTypes are the input types that need no label, their value or purpose says it
+var unlabeledInputTypes = map[string]bool{"hidden": true, "submit": true, "reset": true, "button": true, "image": true}
+
+// AuditAccessibility checks a page for images without alt text, form controls without
+// a label, headings skipping levels and a missing lang attribute
+func (e *ContentExtractor) AuditAccessibility(content string) domain.AccessibilityReport {
+	var report domain.AccessibilityReport
+
+	doc, err := e.document(content)
+	if err != nil {
+		return report
+	}
+
+	if lang := strings.TrimSpace(doc.Find("html").First().AttrOr("lang", "")); lang == "" {
+		report.Add(domain.A11yMissingLang, "html")
+	}
+
+	// Images hidden from assistive technology need no text
+	doc.Find(`img, input[type="image"]`).Each(func(i int, s *goquery.Selection) {
+		if _, ok := s.Attr("alt"); ok || s.AttrOr("aria-hidden", "") == "true" || s.AttrOr("role", "") == "presentation" {
+			return
+		}
+		if hasAccessibleName(s) {
+			return
+		}
+		report.Add(domain.A11yMissingAlt, describeElement(s, "src"))
+	})
+
+	labelled := make(map[string]bool)
+	doc.Find("label[for]").Each(func(i int, s *goquery.Selection) {
+		labelled[s.AttrOr("for", "")] = true
+	})
+	doc.Find("input, select, textarea").Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "input" && unlabeledInputTypes[strings.ToLower(s.AttrOr("type", "text"))] {
+			return
+		}
+		if id := s.AttrOr("id", ""); (id != "" && labelled[id]) || s.ParentsFiltered("label").Length() > 0 || hasAccessibleName(s) {
+			return
+		}
+		report.Add(domain.A11yMissingLabel, describeElement(s, "type", "name", "id"))
+	})
+
+	// Headings may go down any number of levels but only up one at a time
+	previous := 0
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		level := int(goquery.NodeName(s)[1] - '0')
+		if previous > 0 && level > previous+1 {
+			report.Add(domain.A11yHeadingOrder, fmt.Sprintf("%s %q after h%d", goquery.NodeName(s), shorten(strings.TrimSpace(s.Text()), 40), previous))
+		}
+		previous = level
+	})
+
+	return report
+}
+
+// hasAccessibleName tells elements named by ARIA attributes or a title
+func hasAccessibleName(s *goquery.Selection) bool {
+	for _, attr := range []string{"aria-label", "aria-labelledby", "title"} {
+		if strings.TrimSpace(s.AttrOr(attr, "")) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// describeElement names an element by its tag and the given attributes it has, input type=email name=contact
+func describeElement(s *goquery.Selection, attrs ...string) string {
+	parts := []string{goquery.NodeName(s)}
+	for _, attr := range attrs {
+		if value, ok := s.Attr(attr); ok && value != "" {
+			parts = append(parts, attr+"="+shorten(value, 80))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// shorten cuts text to at most limit runes
+func shorten(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
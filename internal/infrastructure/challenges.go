@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ChallengeBackoff is how long a host is left alone after its first bot challenge,
+	// doubled for every further one in a row
+	ChallengeBackoff    = 2 * time.Minute
+	MaxChallengeBackoff = 30 * time.Minute
+	// QuarantineAfter is how many challenges in a row quarantine a host for the rest of the run
+	QuarantineAfter = 4
+)
+
+// ChallengeTracker counts the bot challenges of each host in a row. Hosts that keep
+// challenging are quarantined, requesting them more only gets the crawler blocklisted
+type ChallengeTracker struct {
+	mu      sync.RWMutex
+	strikes map[string]int
+}
+
+// NewChallengeTracker creates an empty challenge tracker
+func NewChallengeTracker() *ChallengeTracker {
+	return &ChallengeTracker{
+		strikes: make(map[string]int),
+	}
+}
+
+// Record counts a challenge of host and returns how many came in a row
+func (t *ChallengeTracker) Record(host string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	host = strings.ToLower(host)
+	t.strikes[host]++
+	return t.strikes[host]
+}
+
+// Clear forgets the challenges of host once it served a page, quarantine is kept
+func (t *ChallengeTracker) Clear(host string) {
+	host = strings.ToLower(host)
+
+	t.mu.RLock()
+	strikes, ok := t.strikes[host]
+	t.mu.RUnlock()
+	if !ok || strikes >= QuarantineAfter {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.strikes[host] < QuarantineAfter {
+		delete(t.strikes, host)
+	}
+}
+
+// Quarantined tells whether host challenged QuarantineAfter times in a row
+func (t *ChallengeTracker) Quarantined(host string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.strikes[strings.ToLower(host)] >= QuarantineAfter
+}
+
+// QuarantinedHosts lists the quarantined hosts
+func (t *ChallengeTracker) QuarantinedHosts() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var hosts []string
+	for host, strikes := range t.strikes {
+		if strikes >= QuarantineAfter {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// ChallengeBackoffAfter is how long to leave a host alone after strikes challenges in a row
+func ChallengeBackoffAfter(strikes int) time.Duration {
+	if strikes < 1 {
+		return 0
+	}
+	return min(ChallengeBackoff<<(strikes-1), MaxChallengeBackoff)
+}
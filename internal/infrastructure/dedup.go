@@ -0,0 +1,73 @@
+package infrastructure
+
+import "golamv2/internal/domain"
+
+// URLDeduper decides whether a discovered URL is new, using the bloom filter, the exact
+// keyspace of the storage, or both depending on the dedup mode
+type URLDeduper struct {
+	mode  domain.DedupMode
+	bloom domain.BloomFilter
+	store domain.SeenURLStore // nil in probabilistic mode
+}
+
+// NewURLDeduper creates a deduper, storages without an exact keyspace fall back to the bloom filter
+func NewURLDeduper(mode domain.DedupMode, bloom domain.BloomFilter, storage domain.Storage) *URLDeduper {
+	d := &URLDeduper{mode: domain.DedupProbabilistic, bloom: bloom}
+
+	if store, ok := storage.(domain.SeenURLStore); ok && mode != domain.DedupProbabilistic && mode != "" {
+		d.mode = mode
+		d.store = store
+	}
+	return d
+}
+
+// Mode returns the dedup mode in use
+func (d *URLDeduper) Mode() domain.DedupMode {
+	return d.mode
+}
+
+// MarkSeen records a URL key and reports whether it was new
+func (d *URLDeduper) MarkSeen(key string) bool {
+	switch d.mode {
+	case domain.DedupExact:
+		added, err := d.store.MarkURLSeen(key)
+		return err == nil && added
+
+	case domain.DedupHybrid:
+		// The bloom filter has no false negatives, a miss is new for sure
+		if !d.bloom.Test(key) {
+			d.bloom.Add(key)
+			d.store.MarkURLSeen(key)
+			return true
+		}
+		// A hit may be a false positive, the stored keys have the final word
+		added, err := d.store.MarkURLSeen(key)
+		return err == nil && added
+
+	default:
+		if d.bloom.Test(key) {
+			return false // Likely already seen by bloom
+		}
+		d.bloom.Add(key)
+		return true
+	}
+}
+
+// Forget un-marks a URL key so it is new again when rediscovered. In probabilistic mode
+// this only works with a counting bloom filter, exact and hybrid modes can always forget
+func (d *URLDeduper) Forget(key string) {
+	if filter, ok := d.bloom.(domain.RemovableBloomFilter); ok {
+		filter.Remove(key)
+	}
+	if d.store != nil {
+		d.store.ForgetURL(key)
+	}
+}
+
+// Reset empties the exact keyspace so every URL is new again, for recrawls of the same data
+func (d *URLDeduper) Reset() error {
+	if d.store == nil {
+		return nil
+	}
+	return d.store.ResetSeenURLs()
+}
@@ -0,0 +1,180 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+	"golamv2/pkg/metrics"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// DNSLookupWorkers bounds the hosts looked up at the same time
+	DNSLookupWorkers = 4
+	// DNSLookupQueue bounds the hosts waiting for a worker, hosts found with a full queue are
+	// tried again the next time one of their pages is fetched
+	DNSLookupQueue = 1000
+	// DNSLookupTimeout covers all the lookups of one host
+	DNSLookupTimeout = 10 * time.Second
+	// DNSRecordsMaxAge is how long records stored by an earlier run are kept before looking up again
+	DNSRecordsMaxAge = 24 * time.Hour
+)
+
+// DNSCollector records the A, AAAA, MX, NS and TXT records of every host the crawl fetches,
+// once per host and in the background, so shared hosting and mail providers can be mapped
+// from the stored records after a single crawl
+type DNSCollector struct {
+	resolver *net.Resolver
+	store    domain.DNSRecordStore
+	metrics  *metrics.MetricsCollector
+	seen     sync.Map
+	hosts    chan string // Waiting for a lookup worker
+
+	mu      sync.Mutex
+	closed  bool
+	lookups sync.WaitGroup
+}
+
+// NewDNSCollector creates a collector keeping the records in store
+func NewDNSCollector(store domain.DNSRecordStore, metrics *metrics.MetricsCollector) *DNSCollector {
+	d := &DNSCollector{
+		resolver: net.DefaultResolver,
+		store:    store,
+		metrics:  metrics,
+		hosts:    make(chan string, DNSLookupQueue),
+	}
+	for i := 0; i < DNSLookupWorkers; i++ {
+		d.lookups.Add(1)
+		go func() {
+			defer d.lookups.Done()
+			for host := range d.hosts {
+				d.record(host)
+			}
+		}()
+	}
+	return d
+}
+
+// Collect looks up the records of the host of rawURL unless it was already, without blocking.
+// IP addresses have no records to collect
+func (d *DNSCollector) Collect(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return
+	}
+	host, err := domain.ASCIIHost(strings.ToLower(u.Hostname()))
+	if err != nil {
+		return
+	}
+	if _, loaded := d.seen.LoadOrStore(host, true); loaded {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	select {
+	case d.hosts <- host:
+	default:
+		d.seen.Delete(host)
+	}
+}
+
+// record looks up and stores the records of host, unless an earlier run stored them recently
+func (d *DNSCollector) record(host string) {
+	if stored, err := d.store.GetDNSRecords(host); err == nil && stored != nil &&
+		time.Since(stored.LookedUpAt) < DNSRecordsMaxAge {
+		return
+	}
+
+	records := d.Lookup(host)
+	if err := d.store.StoreDNSRecords(records); err != nil {
+		logging.Debugf("Storing the DNS records of %s failed: %v", host, err)
+		return
+	}
+	if d.metrics != nil {
+		d.metrics.UpdateHostsDNSRecorded(1)
+	}
+}
+
+// Lookup resolves the records of host. Mail, name server and TXT records are looked up
+// on the registrable domain, www.example.com gets its mail at example.com
+func (d *DNSCollector) Lookup(host string) domain.DNSRecords {
+	ctx, cancel := context.WithTimeout(context.Background(), DNSLookupTimeout)
+	defer cancel()
+
+	zone, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		zone = host
+	}
+	records := domain.DNSRecords{Host: host, Domain: zone, LookedUpAt: time.Now()}
+	failed := func(kind string, err error) {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return
+		}
+		records.Errors = append(records.Errors, kind+": "+err.Error())
+	}
+
+	if addrs, err := d.resolver.LookupIPAddr(ctx, host); err != nil {
+		failed("A", err)
+	} else {
+		for _, addr := range addrs {
+			if addr.IP.To4() != nil {
+				records.A = append(records.A, addr.IP.String())
+			} else {
+				records.AAAA = append(records.AAAA, addr.IP.String())
+			}
+		}
+		sort.Strings(records.A)
+		sort.Strings(records.AAAA)
+	}
+
+	// LookupMX already sorts by preference
+	if mxs, err := d.resolver.LookupMX(ctx, zone); err != nil {
+		failed("MX", err)
+	} else {
+		for _, mx := range mxs {
+			records.MX = append(records.MX, strings.TrimSuffix(strings.ToLower(mx.Host), "."))
+		}
+	}
+
+	if nss, err := d.resolver.LookupNS(ctx, zone); err != nil {
+		failed("NS", err)
+	} else {
+		for _, ns := range nss {
+			records.NS = append(records.NS, strings.TrimSuffix(strings.ToLower(ns.Host), "."))
+		}
+		sort.Strings(records.NS)
+	}
+
+	if txts, err := d.resolver.LookupTXT(ctx, zone); err != nil {
+		failed("TXT", err)
+	} else {
+		records.TXT = txts
+	}
+
+	return records
+}
+
+// Close waits for the lookups in flight and the queued ones, call it before closing the storage
+func (d *DNSCollector) Close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.hosts)
+	}
+	d.mu.Unlock()
+	d.lookups.Wait()
+}
@@ -0,0 +1,35 @@
+package infrastructure
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// DocumentCacheSize bounds the parsed pages kept, the crawler releases them once a page is done
+	DocumentCacheSize = 256
+	// DocumentCacheTTL drops parsed pages nobody released
+	DocumentCacheTTL = time.Minute
+)
+
+// document returns the parsed document of content, a page is parsed once for all its
+// extraction steps. Documents are only read, never changed, so workers may share them
+func (e *ContentExtractor) document(content string) (*goquery.Document, error) {
+	if doc, ok := e.documents.Get(content); ok {
+		return doc, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	e.documents.Add(content, doc)
+	return doc, nil
+}
+
+// ReleaseDocument drops the parsed document of a page once its extraction is done
+func (e *ContentExtractor) ReleaseDocument(content string) {
+	e.documents.Remove(content)
+}
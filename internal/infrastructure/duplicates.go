@@ -0,0 +1,174 @@
+package infrastructure
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+)
+
+const (
+	// Words hashed together into a feature of the SimHash, so reordered pages still differ
+	simHashShingle = 3
+	// MaxNearDuplicateDistance is the largest SimHash bit distance near duplicates are looked up at
+	MaxNearDuplicateDistance = 15
+	// Fingerprints are wiped when they grow past this, like the URL collapser observations
+	MaxFingerprints = 200000
+)
+
+// Fingerprint returns the SimHash of the visible text of a page, pages sharing most of their
+// text get fingerprints a few bits apart. Scripts and styles are left out
+func (e *ContentExtractor) Fingerprint(content string) uint64 {
+	doc, err := e.document(content)
+	if err != nil {
+		return 0
+	}
+	// The cached document is shared with the other extraction steps, work on a copy
+	body := doc.Find("body").Clone()
+	body.Find("script, style, noscript, template").Remove()
+	return simHash(splitWords(normalizeText(body.Text(), false)))
+}
+
+// simHash folds the hashes of the word shingles into one fingerprint, each bit is set
+// when most shingles had it set
+func simHash(words []string) uint64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	hasher := fnv.New64a()
+	for i := 0; i+simHashShingle <= len(words) || i == 0; i++ {
+		hasher.Reset()
+		hasher.Write([]byte(strings.Join(words[i:min(i+simHashShingle, len(words))], " ")))
+		sum := hasher.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// fingerprintedPage is the first page seen with a fingerprint
+type fingerprintedPage struct {
+	url         string
+	fingerprint uint64
+}
+
+// DuplicateDetector remembers the content hash and SimHash of every crawled page and tells
+// which earlier page a new one duplicates. Near duplicates are found by splitting fingerprints
+// into one fixed band more than the distance allowed, of 64/(distance+1) bits give or take
+// one: by the pigeonhole principle two fingerprints that close are equal in at least one band
+type DuplicateDetector struct {
+	mu       sync.Mutex
+	distance int
+	hashes   map[string]string                // content hash -> first URL
+	bands    []map[uint64][]fingerprintedPage // band value -> pages, one map per band
+
+	// Bit offset and mask of every band, set once by NewDuplicateDetector
+	shifts []int
+	masks  []uint64
+	pages  int // Fingerprints recorded since the last reset
+}
+
+// NewDuplicateDetector creates a detector, distance is the SimHash bits near duplicates
+// may differ in, 0 only finds identical pages
+func NewDuplicateDetector(distance int) *DuplicateDetector {
+	d := &DuplicateDetector{distance: min(distance, MaxNearDuplicateDistance)}
+	if d.distance > 0 {
+		// 64 bits rarely split evenly, the first bands take one bit of the remainder each
+		count := d.distance + 1
+		width, wider := 64/count, 64%count
+		shift := 0
+		for i := 0; i < count; i++ {
+			bandWidth := width
+			if i < wider {
+				bandWidth++
+			}
+			d.shifts = append(d.shifts, shift)
+			d.masks = append(d.masks, 1<<bandWidth-1)
+			shift += bandWidth
+		}
+	}
+	d.reset()
+	return d
+}
+
+func (d *DuplicateDetector) reset() {
+	d.hashes = make(map[string]string)
+	d.bands = nil
+	d.pages = 0
+	if d.distance > 0 {
+		d.bands = make([]map[uint64][]fingerprintedPage, len(d.shifts))
+		for i := range d.bands {
+			d.bands[i] = make(map[uint64][]fingerprintedPage)
+		}
+	}
+}
+
+// band returns the bits of a fingerprint falling in band i
+func (d *DuplicateDetector) band(fingerprint uint64, i int) uint64 {
+	return (fingerprint >> d.shifts[i]) & d.masks[i]
+}
+
+// Observe records a page and returns the earlier page it duplicates, and whether the
+// match is only a near one. An empty URL means the page is new
+func (d *DuplicateDetector) Observe(pageURL, contentHash string, fingerprint uint64) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.hashes) >= MaxFingerprints || d.pages >= MaxFingerprints {
+		d.reset()
+	}
+
+	if contentHash != "" {
+		if original, ok := d.hashes[contentHash]; ok && original != pageURL {
+			return original, false
+		} else if !ok {
+			d.hashes[contentHash] = pageURL
+		}
+	}
+
+	// Pages without text all fingerprint to 0, they are not near anything
+	if d.bands == nil || fingerprint == 0 {
+		return "", false
+	}
+
+	// A page sharing several bands is only compared once
+	known := false
+	compared := make(map[string]bool)
+	for i, band := range d.bands {
+		for _, page := range band[d.band(fingerprint, i)] {
+			if compared[page.url] {
+				continue
+			}
+			compared[page.url] = true
+			if page.url == pageURL {
+				known = true
+			} else if bits.OnesCount64(page.fingerprint^fingerprint) <= d.distance {
+				return page.url, true
+			}
+		}
+	}
+	if known {
+		return "", false
+	}
+	d.pages++
+	page := fingerprintedPage{url: pageURL, fingerprint: fingerprint}
+	for i, band := range d.bands {
+		key := d.band(fingerprint, i)
+		band[key] = append(band[key], page)
+	}
+	return "", false
+}
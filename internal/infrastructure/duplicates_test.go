@@ -0,0 +1,64 @@
+package infrastructure
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// flipBits flips n distinct bits of a fingerprint picked by rng
+func flipBits(fingerprint uint64, n int, rng *rand.Rand) uint64 {
+	for _, bit := range rng.Perm(64)[:n] {
+		fingerprint ^= 1 << bit
+	}
+	return fingerprint
+}
+
+func TestDuplicateDetectorBands(t *testing.T) {
+	for distance := 1; distance <= MaxNearDuplicateDistance; distance++ {
+		d := NewDuplicateDetector(distance)
+		width := 0
+		for i, mask := range d.masks {
+			bits := 0
+			for m := mask; m != 0; m >>= 1 {
+				bits++
+			}
+			if bits < 64/(distance+1) || bits > 64/(distance+1)+1 {
+				t.Errorf("distance %d: band %d is %d bits wide", distance, i, bits)
+			}
+			if d.shifts[i] != width {
+				t.Errorf("distance %d: band %d starts at bit %d, want %d", distance, i, d.shifts[i], width)
+			}
+			width += bits
+		}
+		if width != 64 {
+			t.Errorf("distance %d: bands cover %d bits", distance, width)
+		}
+	}
+}
+
+func TestDuplicateDetectorObserve(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, distance := range []int{1, 3, 6, 15} {
+		t.Run(fmt.Sprintf("distance %d", distance), func(t *testing.T) {
+			for round := 0; round < 200; round++ {
+				d := NewDuplicateDetector(distance)
+				original := rng.Uint64() | 1
+				d.Observe("https://example.com/a", "a", original)
+
+				if got, near := d.Observe("https://example.com/a", "a", original); got != "" || near {
+					t.Fatalf("page observed again matched %q", got)
+				}
+				if got, _ := d.Observe("https://example.com/copy", "a", original); got != "https://example.com/a" {
+					t.Fatalf("exact copy matched %q", got)
+				}
+				if got, near := d.Observe("https://example.com/near", "b", flipBits(original, distance, rng)); got != "https://example.com/a" || !near {
+					t.Fatalf("page %d bits away matched %q, near %v", distance, got, near)
+				}
+				if got, _ := d.Observe("https://example.com/far", "c", flipBits(original, distance+1, rng)); got == "https://example.com/a" {
+					t.Fatalf("page %d bits away matched", distance+1)
+				}
+			}
+		})
+	}
+}
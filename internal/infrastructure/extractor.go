@@ -2,16 +2,24 @@ package infrastructure
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"golamv2/internal/domain"
+	"golamv2/pkg/cache"
 	"golamv2/pkg/metrics"
+	"golamv2/pkg/ratelimit"
 
 	"github.com/PuerkitoBio/goquery"
 )
@@ -21,26 +29,110 @@ type ContentExtractor struct {
 	emailRegex      *regexp.Regexp
 	httpClient      *http.Client
 	deadLinkClient  *http.Client // Separate client with aggressive timeout for dead link checking
-	mu              sync.RWMutex
-	deadLinkCache   map[string]bool
-	deadDomainCache map[string]bool // Cache for domain-level checks
+	deadLinkCache   *cache.LRU[string, linkStatus]
+	deadDomainCache *cache.LRU[string, domainStatus] // Cache for domain-level checks
 
 	// Async dead link checking - results go directly to storage
-	linkQueue chan linkCheckRequest
-	storage   domain.Storage            // Direct access to storage for async updates
-	metrics   *metrics.MetricsCollector // Direct access to metrics for updates
-	ctx       context.Context
-	cancel    context.CancelFunc
-	wg        sync.WaitGroup
+	config     DeadLinkCheckerConfig
+	linkQueue  chan string // Target URLs, each queued once however many pages link to it
+	pendingMu  sync.Mutex
+	pending    map[string][]linkSource   // Pages waiting on the check of a queued target
+	storage    domain.Storage            // Direct access to storage for async updates
+	metrics    *metrics.MetricsCollector // Direct access to metrics for updates
+	linkFilter func(link string) bool    // Links it rejects are never requested
+	limiter    *ratelimit.Limiter        // Spaces out the checks, optional
+	responses  *ResponseCache            // Pages the crawler fetched, optional
+	registry   *RDAPClient               // Looks up dead domains, optional
+	events     domain.EventPublisher     // Told about every dead link found, optional
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+
+	// How keywords are matched, exact substrings unless set
+	keywords *KeywordMatcher
+
+	// Findings of pages whose result is not stored yet, see PageStored
+	heldMu sync.Mutex
+	held   map[string][]domain.CrawlResult
+
+	// Parsed documents of the pages being extracted, keyed by their content
+	documents *cache.LRU[string, *goquery.Document]
+
+	// Checks the ftp, mailto and tel links queued with CheckSchemeLinks
+	schemeLinks *SchemeLinkChecker
+
+	// User agent and headers of the link checks, see SetIdentity
+	identity domain.Identity
 }
 
-type linkCheckRequest struct {
-	url       string
-	sourceURL string
+// linkSource is a page linking to a checked URL
+type linkSource struct {
+	sourceURL  string
+	anchorText string
+	labels     []string // Labels of the source page, copied to its dead link results
 }
 
+// linkStatus is the outcome of checking one link
+type linkStatus struct {
+	dead       bool
+	statusCode int
+	redirectTo string // Final URL when the link redirected
+	err        string
+}
+
+// deadLinkReason says why a link is dead, its error or status code
+func deadLinkReason(status linkStatus) string {
+	if status.err != "" {
+		return status.err
+	}
+	return fmt.Sprintf("status %d", status.statusCode)
+}
+
+// domainStatus is the outcome of checking a whole domain
+type domainStatus struct {
+	dead         bool
+	cause        domain.DeadDomainCause
+	err          string
+	registration *domain.Registration // Only looked up for domains that do not resolve
+	variant      string               // Probe that answered when it was not https://domain
+	probed       []string             // Probes tried before declaring the domain dead
+}
+
+// MaxDeadLinkRedirects is how many redirects a dead link check follows
+const MaxDeadLinkRedirects = 3
+
+const (
+	// Link and domain check results are kept in LRU caches, hot entries survive long runs
+	DeadLinkCacheSize   = 50000
+	DeadDomainCacheSize = 5000
+
+	// Cached results go stale so links that recover (or break) mid-run get rechecked
+	DeadLinkCacheTTL   = 6 * time.Hour
+	DeadDomainCacheTTL = time.Hour
+)
+
+// DeadLinkCheckerConfig sizes the async dead link checker
+type DeadLinkCheckerConfig struct {
+	Workers   int
+	QueueSize int
+	// EnqueueTimeout is how long a page waits for room in a full queue, 0 drops the links right away
+	EnqueueTimeout time.Duration
+	// ProbeWWW also tries the www. or apex variant of a domain before declaring it dead
+	ProbeWWW bool
+}
+
+// DefaultDeadLinkCheckerConfig is the original sizing
+var DefaultDeadLinkCheckerConfig = DeadLinkCheckerConfig{Workers: 3, QueueSize: 1000}
+
 // NewContentExtractor creates a new content extractor
-func NewContentExtractor() *ContentExtractor {
+func NewContentExtractor(config DeadLinkCheckerConfig) *ContentExtractor {
+	if config.Workers <= 0 {
+		config.Workers = DefaultDeadLinkCheckerConfig.Workers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultDeadLinkCheckerConfig.QueueSize
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	extractor := &ContentExtractor{
@@ -61,16 +153,21 @@ func NewContentExtractor() *ContentExtractor {
 				return http.ErrUseLastResponse // Don't follow redirects for speed
 			},
 		},
-		deadLinkCache:   make(map[string]bool),
-		deadDomainCache: make(map[string]bool),
-		linkQueue:       make(chan linkCheckRequest, 1000), // Buffered queue
+		deadLinkCache:   cache.NewLRU[string, linkStatus](DeadLinkCacheSize, DeadLinkCacheTTL),
+		deadDomainCache: cache.NewLRU[string, domainStatus](DeadDomainCacheSize, DeadDomainCacheTTL),
+		config:          config,
+		keywords:        NewKeywordMatcher(domain.KeywordMatching{}),
+		linkQueue:       make(chan string, config.QueueSize), // Buffered queue
+		pending:         make(map[string][]linkSource),
+		held:            make(map[string][]domain.CrawlResult),
+		documents:       cache.NewLRU[string, *goquery.Document](DocumentCacheSize, DocumentCacheTTL),
+		schemeLinks:     NewSchemeLinkChecker(),
 		ctx:             ctx,
 		cancel:          cancel,
 	}
 
 	// Start background workers for async dead link checking
-	numWorkers := 3 // Reduced from 10 workers per page
-	for i := 0; i < numWorkers; i++ {
+	for i := 0; i < config.Workers; i++ {
 		extractor.wg.Add(1)
 		go extractor.asyncDeadLinkWorker()
 	}
@@ -83,18 +180,68 @@ func (e *ContentExtractor) SetStorage(storage domain.Storage) {
 	e.storage = storage
 }
 
+// SetLinkFilter sets the check links must pass before being requested (robots, excluded domains)
+func (e *ContentExtractor) SetLinkFilter(filter func(link string) bool) {
+	e.linkFilter = filter
+}
+
+// SetRateLimiter makes the checks wait for the crawl's rate limits
+func (e *ContentExtractor) SetRateLimiter(limiter *ratelimit.Limiter) {
+	e.limiter = limiter
+}
+
+// SetResponseCache lets the checks reuse responses of pages the crawler fetched
+func (e *ContentExtractor) SetResponseCache(responses *ResponseCache) {
+	e.responses = responses
+}
+
+// SetRegistryLookup makes dead domains that do not resolve get their registration looked up
+func (e *ContentExtractor) SetRegistryLookup(registry *RDAPClient) {
+	e.registry = registry
+}
+
+// SetIdentity sets the user agent and headers of the link checks, set it before the crawl starts
+func (e *ContentExtractor) SetIdentity(identity domain.Identity) {
+	e.identity = identity
+}
+
+// SetKeywordMatching sets how keywords are matched, set it before the crawl starts
+func (e *ContentExtractor) SetKeywordMatching(matching domain.KeywordMatching) {
+	e.keywords = NewKeywordMatcher(matching)
+}
+
+// waitForRate waits until target may be requested, false when the extractor is closing
+func (e *ContentExtractor) waitForRate(target string) bool {
+	if e.limiter == nil {
+		return true
+	}
+	return e.limiter.Wait(e.ctx, target) == nil
+}
+
 // SetMetrics allows setting the metrics collector reference after creation
 func (e *ContentExtractor) SetMetrics(metrics *metrics.MetricsCollector) {
 	e.metrics = metrics
 }
 
+// SetEvents sets where the dead links found are published
+func (e *ContentExtractor) SetEvents(events domain.EventPublisher) {
+	e.events = events
+}
+
+// publishDeadLink tells the events about a dead link on a page
+func (e *ContentExtractor) publishDeadLink(sourceURL, target, reason string) {
+	if e.events != nil {
+		e.events.Publish(domain.CrawlEvent{Type: domain.EventDeadLink, URL: sourceURL, Link: target, Message: reason})
+	}
+}
+
 // extracts email addresses
 func (e *ContentExtractor) ExtractEmails(content string) []string {
 	matches := e.emailRegex.FindAllString(content, -1)
 
 	// Deduplicate emails
-	emailMap := make(map[string]bool)
-	var emails []string
+	emailMap := make(map[string]bool, len(matches))
+	emails := make([]string, 0, len(matches))
 
 	for _, email := range matches {
 		email = strings.ToLower(email)
@@ -107,25 +254,15 @@ func (e *ContentExtractor) ExtractEmails(content string) []string {
 	return emails
 }
 
-// searches for specific keywords in content and counts occurrences
+// searches for specific keywords in content and counts occurrences, see KeywordMatcher.
+// Content and keywords are compared in NFC with their case folded, unless a rule says otherwise
 func (e *ContentExtractor) ExtractKeywords(content string, keywords []string) map[string]int {
-	results := make(map[string]int)
-	contentLower := strings.ToLower(content)
-
-	for _, keyword := range keywords {
-		keywordLower := strings.ToLower(keyword)
-		count := strings.Count(contentLower, keywordLower)
-		if count > 0 {
-			results[keyword] = count
-		}
-	}
-
-	return results
+	return e.keywords.Count(content, keywords)
 }
 
 // extracts all links from HTML content
 func (e *ContentExtractor) ExtractLinks(content, baseURL string) []string {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	doc, err := e.document(content)
 	if err != nil {
 		return nil
 	}
@@ -135,10 +272,12 @@ func (e *ContentExtractor) ExtractLinks(content, baseURL string) []string {
 		return nil
 	}
 
-	var links []string
-	linkMap := make(map[string]bool)
+	// Sized for every candidate, most pages have few duplicates
+	anchors, sources := doc.Find("a[href]"), doc.Find("[src]")
+	links := make([]string, 0, anchors.Length()+sources.Length())
+	linkMap := make(map[string]bool, anchors.Length()+sources.Length())
 
-	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+	anchors.Each(func(i int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
 		if !exists {
 			return
@@ -151,7 +290,7 @@ func (e *ContentExtractor) ExtractLinks(content, baseURL string) []string {
 		}
 
 		absoluteURL := baseU.ResolveReference(linkURL)
-		urlStr := absoluteURL.String()
+		urlStr := domain.NormalizeURL(absoluteURL.String())
 
 		// Filter valid URLs and deduplicate
 		if domain.IsValidURL(urlStr) && !linkMap[urlStr] {
@@ -161,7 +300,7 @@ func (e *ContentExtractor) ExtractLinks(content, baseURL string) []string {
 	})
 
 	// Extract links from src attributes (images, scripts, etc.)
-	doc.Find("[src]").Each(func(i int, s *goquery.Selection) {
+	sources.Each(func(i int, s *goquery.Selection) {
 		src, exists := s.Attr("src")
 		if !exists {
 			return
@@ -173,7 +312,7 @@ func (e *ContentExtractor) ExtractLinks(content, baseURL string) []string {
 		}
 
 		absoluteURL := baseU.ResolveReference(srcURL)
-		urlStr := absoluteURL.String()
+		urlStr := domain.NormalizeURL(absoluteURL.String())
 
 		if domain.IsValidURL(urlStr) && !linkMap[urlStr] {
 			linkMap[urlStr] = true
@@ -184,9 +323,87 @@ func (e *ContentExtractor) ExtractLinks(content, baseURL string) []string {
 	return links
 }
 
+// ExtractAnchors extracts a[href] links together with their anchor text
+func (e *ContentExtractor) ExtractAnchors(content, baseURL string) []domain.Link {
+	doc, err := e.document(content)
+	if err != nil {
+		return nil
+	}
+
+	baseU, err := url.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+
+	var anchors []domain.Link
+	anchorIndex := make(map[string]int)
+
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		linkURL, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+
+		urlStr := domain.NormalizeURL(baseU.ResolveReference(linkURL).String())
+		if !domain.IsValidURL(urlStr) {
+			return
+		}
+
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			// Fall back to image alt / title attributes for icon links
+			text, _ = s.Attr("title")
+			if alt, exists := s.Find("img[alt]").First().Attr("alt"); exists && text == "" {
+				text = alt
+			}
+		}
+
+		// Same target linked several times - merge the anchor texts
+		if idx, exists := anchorIndex[urlStr]; exists {
+			if text != "" {
+				anchors[idx].Text = strings.TrimSpace(anchors[idx].Text + " " + text)
+			}
+			return
+		}
+
+		anchorIndex[urlStr] = len(anchors)
+		anchors = append(anchors, domain.Link{URL: urlStr, Text: text})
+	})
+
+	return anchors
+}
+
+// ExtractSchemeLinks extracts the ftp, mailto and tel links of a page with their anchor text
+func (e *ContentExtractor) ExtractSchemeLinks(content string) []domain.Link {
+	doc, err := e.document(content)
+	if err != nil {
+		return nil
+	}
+
+	var links []domain.Link
+	seen := make(map[string]bool)
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		u, err := url.Parse(href)
+		if err != nil || seen[href] {
+			return
+		}
+		for _, scheme := range SchemeLinkSchemes {
+			if strings.EqualFold(u.Scheme, scheme) {
+				seen[href] = true
+				links = append(links, domain.Link{URL: href, Text: strings.Join(strings.Fields(s.Text()), " ")})
+				return
+			}
+		}
+	})
+
+	return links
+}
+
 // extracts the page title from HTML content
 func (e *ContentExtractor) ExtractTitle(content string) string {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	doc, err := e.document(content)
 	if err != nil {
 		return ""
 	}
@@ -195,20 +412,80 @@ func (e *ContentExtractor) ExtractTitle(content string) string {
 	return strings.TrimSpace(title)
 }
 
+// ExtractMetaDescription returns the content of the description meta tag
+func (e *ContentExtractor) ExtractMetaDescription(content string) string {
+	doc, err := e.document(content)
+	if err != nil {
+		return ""
+	}
+
+	description := ""
+	doc.Find("meta[name]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if strings.EqualFold(s.AttrOr("name", ""), "description") {
+			description = strings.TrimSpace(s.AttrOr("content", ""))
+			return false
+		}
+		return true
+	})
+	return description
+}
+
+// ExtractMetaRobots returns the directives of the robots meta tags meant for us or every crawler
+func (e *ContentExtractor) ExtractMetaRobots(content string) string {
+	doc, err := e.document(content)
+	if err != nil {
+		return ""
+	}
+
+	var directives []string
+	doc.Find("meta[name]").Each(func(i int, s *goquery.Selection) {
+		name := strings.ToLower(s.AttrOr("name", ""))
+		if name == "robots" || name == "golamv2" {
+			directives = append(directives, s.AttrOr("content", ""))
+		}
+	})
+
+	return strings.Join(directives, ",")
+}
+
 // CheckDeadLinks queues links for async checking and returns empty results immediately
-func (e *ContentExtractor) CheckDeadLinks(links []string, sourceURL string) ([]string, []string) {
+func (e *ContentExtractor) CheckDeadLinks(links []domain.Link, sourceURL string, labels []string) ([]string, []string) {
 	// Sample 20% of links for async processing
 	sampledLinks := e.sampleLinks(links, 0.2)
 
-	// Queue all sampled links for background processing
-	e.queueLinksForChecking(sampledLinks, sourceURL)
+	// Queue all sampled links for background processing, what they find waits for the page's result
+	if len(sampledLinks) > 0 {
+		e.holdFindings(sourceURL)
+	}
+	e.queueLinksForChecking(sampledLinks, linkSource{sourceURL: sourceURL, labels: labels})
 
 	// Return empty results immediately - dead links will be stored in DB by async workers
 	return []string{}, []string{}
 }
 
+// CheckSchemeLinks queues ftp, mailto and tel links on the dead link workers, the broken
+// ones are added to the page's stored result like its dead links
+func (e *ContentExtractor) CheckSchemeLinks(links []domain.Link, sourceURL string, labels []string) {
+	if len(links) > 0 {
+		e.holdFindings(sourceURL)
+	}
+	e.queueLinksForChecking(links, linkSource{sourceURL: sourceURL, labels: labels})
+}
+
+// holdFindings makes the findings for a page wait until its result is stored, see PageStored
+func (e *ContentExtractor) holdFindings(sourceURL string) {
+	if e.storage == nil {
+		return
+	}
+	e.heldMu.Lock()
+	if _, held := e.held[sourceURL]; !held {
+		e.held[sourceURL] = nil
+	}
+	e.heldMu.Unlock()
+}
+
 // sampleLinks randomly selects a percentage of links
-func (e *ContentExtractor) sampleLinks(links []string, percentage float64) []string {
+func (e *ContentExtractor) sampleLinks(links []domain.Link, percentage float64) []domain.Link {
 	if percentage >= 1.0 {
 		return links
 	}
@@ -219,7 +496,7 @@ func (e *ContentExtractor) sampleLinks(links []string, percentage float64) []str
 	}
 
 	// Shuffle and take first N
-	shuffled := make([]string, len(links))
+	shuffled := make([]domain.Link, len(links))
 	copy(shuffled, links)
 
 	// Simple Fisher-Yates shuffle
@@ -231,18 +508,90 @@ func (e *ContentExtractor) sampleLinks(links []string, percentage float64) []str
 	return shuffled[:numToSample]
 }
 
-// queueLinksForChecking adds links to the async checking queue
-func (e *ContentExtractor) queueLinksForChecking(links []string, sourceURL string) {
-	for _, link := range links {
+// queueLinksForChecking adds links to the async checking queue, links that do not fit are counted as dropped.
+// A target is queued once: pages linking to an already queued target join its check,
+// and targets cached as alive are not checked again
+func (e *ContentExtractor) queueLinksForChecking(links []domain.Link, source linkSource) {
+	// One deadline for the whole page so a full queue stalls a crawl worker at most EnqueueTimeout
+	var timeout <-chan time.Time
+	if e.config.EnqueueTimeout > 0 {
+		timer := time.NewTimer(e.config.EnqueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	seen := make(map[string]bool, len(links))
+	for i, link := range links {
+		if seen[link.URL] {
+			continue
+		}
+		seen[link.URL] = true
+
+		source.anchorText = link.Text
+		if !e.addPendingSource(link.URL, source) {
+			e.dedupeLinkChecks(1)
+			continue
+		}
+
+		if timeout == nil {
+			select {
+			case e.linkQueue <- link.URL:
+				// Successfully queued
+			default:
+				// Queue is full, skip this link
+				e.dropLinkChecks(int64(len(e.takePendingSources(link.URL))))
+			}
+			continue
+		}
+
 		select {
-		case e.linkQueue <- linkCheckRequest{url: link, sourceURL: sourceURL}:
-			// Successfully queued
-		default:
-			// Queue is full, skip this link
+		case e.linkQueue <- link.URL:
+		case <-timeout:
+			// The rest of the page was never registered, only this target is pending
+			e.dropLinkChecks(int64(len(e.takePendingSources(link.URL)) + len(links) - i - 1))
+			return
+		case <-e.ctx.Done():
+			return
 		}
 	}
 }
 
+// addPendingSource records a page waiting on a link check, true when the target still has to be queued
+func (e *ContentExtractor) addPendingSource(target string, source linkSource) bool {
+	if cached, exists := e.deadLinkCache.Get(target); exists && !cached.dead {
+		return false
+	}
+
+	e.pendingMu.Lock()
+	defer e.pendingMu.Unlock()
+
+	sources, queued := e.pending[target]
+	e.pending[target] = append(sources, source)
+	return !queued
+}
+
+// takePendingSources removes and returns the pages waiting on a target
+func (e *ContentExtractor) takePendingSources(target string) []linkSource {
+	e.pendingMu.Lock()
+	defer e.pendingMu.Unlock()
+
+	sources := e.pending[target]
+	delete(e.pending, target)
+	return sources
+}
+
+func (e *ContentExtractor) dedupeLinkChecks(count int64) {
+	if e.metrics != nil {
+		e.metrics.UpdateLinkChecksDeduped(count)
+	}
+}
+
+func (e *ContentExtractor) dropLinkChecks(count int64) {
+	if e.metrics != nil {
+		e.metrics.UpdateDeadLinkChecksDropped(count)
+	}
+}
+
 // asyncDeadLinkWorker processes links in the background
 func (e *ContentExtractor) asyncDeadLinkWorker() {
 	defer e.wg.Done()
@@ -251,44 +600,86 @@ func (e *ContentExtractor) asyncDeadLinkWorker() {
 		select {
 		case <-e.ctx.Done():
 			return
-		case req := <-e.linkQueue:
-			e.processLinkAsync(req)
+		case target := <-e.linkQueue:
+			e.processLinkAsync(target)
 		}
 	}
 }
 
-// isDeadLinkFast checks if a link is dead with aggressive timeout (URL-level check)
-func (e *ContentExtractor) isDeadLinkFast(urlStr string) bool {
+// checkLinkFast checks if a link is dead with aggressive timeout (URL-level check),
+// redirects are followed a few hops so links redirecting to dead pages are caught too
+func (e *ContentExtractor) checkLinkFast(urlStr string) linkStatus {
 	// Check cache first
-	e.mu.RLock()
-	if cached, exists := e.deadLinkCache[urlStr]; exists {
-		e.mu.RUnlock()
+	if cached, exists := e.deadLinkCache.Get(urlStr); exists {
 		return cached
 	}
-	e.mu.RUnlock()
 
-	// Use HEAD request only (no GET fallback for speed)
-	req, err := http.NewRequest("HEAD", urlStr, nil)
-	if err != nil {
-		e.cacheDeadLink(urlStr, false)
-		return false
+	// The crawler fetched the page moments ago
+	if cached, ok := e.responses.Get(urlStr); ok {
+		status := linkStatus{
+			dead:       isDeadStatus(cached.StatusCode),
+			statusCode: cached.StatusCode,
+			redirectTo: cached.FinalURL,
+		}
+		e.deadLinkCache.Add(urlStr, status)
+		if e.metrics != nil {
+			e.metrics.UpdateResponseCacheHits(1)
+		}
+		return status
 	}
-	req.Header.Set("User-Agent", "GolamV2-Crawler/1.0")
 
-	resp, err := e.deadLinkClient.Do(req)
-	if err != nil {
-		// This could be domain-level or URL-level issue
-		// We'll let the domain check handle domain-level issues
-		e.cacheDeadLink(urlStr, true)
-		return true
+	status := linkStatus{}
+	target := urlStr
+	for hop := 0; hop <= MaxDeadLinkRedirects; hop++ {
+		// Closing, the link is not known to be dead and nothing is cached
+		if !e.waitForRate(target) {
+			return linkStatus{}
+		}
+
+		// Use HEAD request only (no GET fallback for speed)
+		req, err := http.NewRequest("HEAD", target, nil)
+		if err != nil {
+			status = linkStatus{}
+			break
+		}
+		e.identity.Apply(req)
+
+		resp, err := e.deadLinkClient.Do(req)
+		if err != nil {
+			// This could be domain-level or URL-level issue
+			// We'll let the domain check handle domain-level issues
+			status.dead = true
+			status.err = err.Error()
+			break
+		}
+		resp.Body.Close()
+		status.statusCode = resp.StatusCode
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			// Only consider HTTP error status codes as dead (not connection issues)
+			status.dead = isDeadStatus(resp.StatusCode)
+			break
+		}
+
+		next, err := req.URL.Parse(location)
+		if err != nil {
+			break
+		}
+		target = next.String()
+		status.redirectTo = target
 	}
-	defer resp.Body.Close()
 
-	// Only consider HTTP error status codes as dead (not connection issues)
-	isDead := resp.StatusCode == 404 || resp.StatusCode == 410 || resp.StatusCode >= 500
-	e.cacheDeadLink(urlStr, isDead)
+	e.deadLinkCache.Add(urlStr, status)
+	if status.statusCode != 0 && status.err == "" {
+		e.responses.Add(urlStr, CachedResponse{StatusCode: status.statusCode, FinalURL: status.redirectTo})
+	}
+	return status
+}
 
-	return isDead
+// isDeadStatus tells the status codes that make a link dead
+func isDeadStatus(statusCode int) bool {
+	return statusCode == 404 || statusCode == 410 || statusCode >= 500
 }
 
 // Close shuts down the async workers
@@ -298,51 +689,101 @@ func (e *ContentExtractor) Close() {
 	e.wg.Wait()
 }
 
-// processLinkAsync checks if a link is dead and stores result directly in database
-func (e *ContentExtractor) processLinkAsync(req linkCheckRequest) {
+// processLinkAsync checks if a link is dead and adds it to the stored result of every page linking to it
+func (e *ContentExtractor) processLinkAsync(target string) {
 	if e.storage == nil {
+		e.takePendingSources(target)
 		return // No storage available
 	}
 
+	if isSchemeLink(target) {
+		e.processSchemeLinkAsync(target)
+		return
+	}
+
+	// Links the crawl may not request are not verified either
+	if e.linkFilter != nil && !e.linkFilter(target) {
+		e.takePendingSources(target)
+		return
+	}
+
 	// Extract domain first
-	domainName := domain.GetDomain(req.url)
+	domainName := domain.GetDomain(target)
 	if domainName == "" {
+		e.takePendingSources(target)
 		return // Invalid URL
 	}
 
-	// Check if domain is dead first (optimization)
-	isDomainDead := e.isDomainDead(domainName)
-	if isDomainDead {
-		// Domain is dead, so URL is automatically dead too
-		result := domain.CrawlResult{
-			URL:         req.sourceURL,
-			ProcessedAt: time.Now(),
-			DeadLinks:   []string{req.url},
-			DeadDomains: []string{domainName},
-		}
-
-		e.storage.StoreResult(result)
-
-		// Update metrics if available
-		if e.metrics != nil {
-			e.metrics.UpdateDeadLinksFound(1)
-			e.metrics.UpdateDeadDomainsFound(1)
+	// Check if domain is dead first (optimization), a page fetched moments ago is on a live one
+	domainCheck := domainStatus{}
+	if _, fetched := e.responses.Get(target); !fetched {
+		domainCheck = e.checkDomain(domainName)
+	}
+	if domainCheck.dead {
+		// Domain is dead, so URL is automatically dead too.
+		// Sources are taken after the check so pages found meanwhile share it
+		for _, source := range e.takePendingSources(target) {
+			result := domain.CrawlResult{
+				URL:         source.sourceURL,
+				Labels:      source.labels,
+				ProcessedAt: time.Now(),
+				DeadLinks:   []string{target},
+				DeadDomains: []string{domainName},
+				DeadLinkDetails: []domain.DeadLink{{
+					URL:        target,
+					AnchorText: source.anchorText,
+					Error:      "domain unreachable",
+				}},
+				DeadDomainDetails: []domain.DeadDomain{{
+					Domain:       domainName,
+					Cause:        domainCheck.cause,
+					Error:        domainCheck.err,
+					Registration: domainCheck.registration,
+					Probed:       domainCheck.probed,
+				}},
+			}
+
+			e.storeFinding(result)
+			e.publishDeadLink(source.sourceURL, target, "domain unreachable")
+
+			// Update metrics if available
+			if e.metrics != nil {
+				e.metrics.UpdateDeadLinksFound(1)
+				e.metrics.UpdateDeadDomainsFound(1)
+				e.metrics.UpdateDeadDomainCause(domainCheck.cause)
+				e.metrics.UpdateDeadDomainRegistration(domainCheck.registration)
+			}
 		}
 		return
 	}
 
 	// Domain is alive, check specific URL
-	isURLDead := e.isDeadLinkFast(req.url)
-	if isURLDead {
-		// URL is dead but domain is alive
+	status := e.checkLinkFast(target)
+	sources := e.takePendingSources(target)
+	if !status.dead {
+		return
+	}
+
+	// URL is dead but domain is alive
+	for _, source := range sources {
 		result := domain.CrawlResult{
-			URL:         req.sourceURL,
+			URL:         source.sourceURL,
+			Labels:      source.labels,
 			ProcessedAt: time.Now(),
-			DeadLinks:   []string{req.url},
+			DeadLinks:   []string{target},
 			DeadDomains: []string{}, // Domain is NOT dead
+			DeadLinkDetails: []domain.DeadLink{{
+				URL:           target,
+				StatusCode:    status.statusCode,
+				AnchorText:    source.anchorText,
+				RedirectTo:    status.redirectTo,
+				DomainVariant: domainCheck.variant,
+				Error:         status.err,
+			}},
 		}
 
-		e.storage.StoreResult(result)
+		e.storeFinding(result)
+		e.publishDeadLink(source.sourceURL, target, deadLinkReason(status))
 
 		// Update metrics if available
 		if e.metrics != nil {
@@ -352,57 +793,198 @@ func (e *ContentExtractor) processLinkAsync(req linkCheckRequest) {
 	}
 }
 
-// isDomainDead checks if an entire domain is unreachable (DNS/connection level)
-func (e *ContentExtractor) isDomainDead(domainName string) bool {
+// processSchemeLinkAsync checks an ftp, mailto or tel link and adds it to the stored result
+// of every page linking to it when it is broken
+func (e *ContentExtractor) processSchemeLinkAsync(target string) {
+	link := e.schemeLinks.Check(domain.Link{URL: target})
+	sources := e.takePendingSources(target)
+
+	broken := link.Status != domain.SchemeLinkOK
+	if e.metrics != nil {
+		brokenCount := int64(0)
+		if broken {
+			brokenCount = int64(len(sources))
+		}
+		e.metrics.UpdateSchemeLinks(int64(len(sources)), brokenCount)
+	}
+	if !broken {
+		return
+	}
+
+	for _, source := range sources {
+		link.AnchorText = source.anchorText
+		e.storeFinding(domain.CrawlResult{
+			URL:         source.sourceURL,
+			Labels:      source.labels,
+			ProcessedAt: time.Now(),
+			SchemeLinks: []domain.SchemeLink{link},
+		})
+	}
+}
+
+// storeFinding adds the dead links found for a page to its stored result, or holds them
+// until the result is stored
+func (e *ContentExtractor) storeFinding(result domain.CrawlResult) {
+	e.heldMu.Lock()
+	if findings, held := e.held[result.URL]; held {
+		e.held[result.URL] = append(findings, result)
+		e.heldMu.Unlock()
+		return
+	}
+	e.heldMu.Unlock()
+
+	e.storage.MergeResult(result)
+}
+
+// PageStored merges the findings held back for a page now that its result is stored, they
+// are dropped when it was not stored, the page is checked again when it is crawled
+func (e *ContentExtractor) PageStored(pageURL string, stored bool) {
+	e.heldMu.Lock()
+	findings, held := e.held[pageURL]
+	delete(e.held, pageURL)
+	e.heldMu.Unlock()
+
+	if !held || !stored {
+		return
+	}
+	for _, finding := range findings {
+		e.storage.MergeResult(finding)
+	}
+}
+
+// checkDomain checks if an entire domain is unreachable (DNS/connection level) and why.
+// Older sites often only serve plain HTTP, so both schemes are probed, and the www. or apex
+// variant with ProbeWWW, before the domain is declared dead
+func (e *ContentExtractor) checkDomain(domainName string) domainStatus {
 	// Check cache first
-	e.mu.RLock()
-	if cached, exists := e.deadDomainCache[domainName]; exists {
-		e.mu.RUnlock()
+	if cached, exists := e.deadDomainCache.Get(domainName); exists {
 		return cached
 	}
-	e.mu.RUnlock()
 
-	// Try to connect to domain root
-	testURL := "https://" + domainName
-	req, err := http.NewRequest("HEAD", testURL, nil)
-	if err != nil {
-		e.cacheDomainStatus(domainName, true)
-		return true
+	status := domainStatus{}
+	var failures []string
+	unresolved := "" // A name that did not resolve is not tried again with the other scheme
+	for _, probe := range e.domainProbes(domainName) {
+		host := probe[strings.Index(probe, "://")+3:]
+		if host == unresolved {
+			continue
+		}
+		if !e.waitForRate(probe) {
+			return domainStatus{}
+		}
+
+		status.probed = append(status.probed, probe)
+		err := e.probeDomain(probe)
+		if err == nil {
+			// If we get any HTTP response, domain is alive
+			alive := domainStatus{}
+			if len(status.probed) > 1 {
+				alive.variant = probe
+				if e.metrics != nil {
+					e.metrics.UpdateDomainsAliveViaFallback(1)
+				}
+			}
+			e.deadDomainCache.Add(domainName, alive)
+			return alive
+		}
+
+		// The cause of the first probe, on the domain itself, is the one reported
+		cause := classifyDomainError(err)
+		if !status.dead {
+			status.dead, status.cause = true, cause
+		}
+		if cause == domain.DeadDomainNXDomain || cause == domain.DeadDomainDNS {
+			unresolved = host
+		}
+		failures = append(failures, err.Error())
 	}
-	req.Header.Set("User-Agent", "GolamV2-Crawler/1.0")
+	status.err = strings.Join(failures, "; ")
 
-	resp, err := e.deadLinkClient.Do(req)
-	if err != nil {
-		// Connection failed - domain is likely dead
-		e.cacheDomainStatus(domainName, true)
-		return true
+	// A domain that does not resolve may not be registered at all
+	unregistered := status.cause == domain.DeadDomainNXDomain || status.cause == domain.DeadDomainDNS
+	if status.dead && unregistered && e.registry != nil {
+		registration := e.registry.Lookup(domainName)
+		status.registration = &registration
 	}
-	defer resp.Body.Close()
 
-	// If we get any HTTP response, domain is alive
-	e.cacheDomainStatus(domainName, false)
-	return false
+	e.deadDomainCache.Add(domainName, status)
+	return status
 }
 
-// cacheDomainStatus caches the domain alive/dead status
-func (e *ContentExtractor) cacheDomainStatus(domainName string, isDead bool) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+// domainProbes lists the URLs tried to tell whether a domain is alive, in order
+func (e *ContentExtractor) domainProbes(domainName string) []string {
+	probes := []string{"https://" + domainName, "http://" + domainName}
+	if !e.config.ProbeWWW {
+		return probes
+	}
+
+	// IP addresses have no www. variant
+	host := domainName
+	if h, _, err := net.SplitHostPort(domainName); err == nil {
+		host = h
+	}
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return probes
+	}
+
+	variant := "www." + domainName
+	if apex, ok := strings.CutPrefix(domainName, "www."); ok {
+		variant = apex
+	}
+	return append(probes, "https://"+variant, "http://"+variant)
+}
 
-	if len(e.deadDomainCache) > 1000 {
-		e.deadDomainCache = make(map[string]bool)
+// probeDomain requests the root of a domain, any HTTP response means it is alive
+func (e *ContentExtractor) probeDomain(probe string) error {
+	req, err := http.NewRequest("HEAD", probe, nil)
+	if err != nil {
+		return err
 	}
+	e.identity.Apply(req)
 
-	e.deadDomainCache[domainName] = isDead
+	resp, err := e.deadLinkClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
 }
 
-func (e *ContentExtractor) cacheDeadLink(urlStr string, isDead bool) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+// classifyDomainError tells an expired domain from a firewalled or misconfigured one
+func classifyDomainError(err error) domain.DeadDomainCause {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsNotFound {
+			return domain.DeadDomainNXDomain
+		}
+		if dnsErr.IsTimeout {
+			return domain.DeadDomainTimeout
+		}
+		return domain.DeadDomainDNS
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return domain.DeadDomainRefused
+	}
 
-	if len(e.deadLinkCache) > 5000 {
-		e.deadLinkCache = make(map[string]bool)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return domain.DeadDomainTimeout
+	}
+
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	if errors.As(err, &recordErr) || errors.As(err, &certErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidCert) {
+		return domain.DeadDomainTLS
+	}
+	// Handshake alerts are plain errors, only their text gives them away
+	if strings.Contains(err.Error(), "tls: ") {
+		return domain.DeadDomainTLS
 	}
 
-	e.deadLinkCache[urlStr] = isDead
+	return domain.DeadDomainOther
 }
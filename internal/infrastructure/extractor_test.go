@@ -0,0 +1,65 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchmarkPage is an article sized page with emails and links, like golamv2 bench uses
+func benchmarkPage() string {
+	var b strings.Builder
+	b.WriteString("<html><head><title>Benchmark page</title><meta name=\"description\" content=\"A page\"></head><body>")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, `<p>Paragraph %d with some text to scan, write to contact%d@example.com. `, i, i)
+		fmt.Fprintf(&b, `<a href="/article/%d?ref=bench">Article %d</a> and <a href="https://other%d.example.org/">another site</a></p>`, i, i, i%20)
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func BenchmarkExtractEmails(b *testing.B) {
+	extractor := NewContentExtractor(DefaultDeadLinkCheckerConfig)
+	defer extractor.Close()
+	page := benchmarkPage()
+
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		extractor.ExtractEmails(page)
+	}
+}
+
+// Parses the page every time, nothing keeps the document between pages
+func BenchmarkExtractLinks(b *testing.B) {
+	extractor := NewContentExtractor(DefaultDeadLinkCheckerConfig)
+	defer extractor.Close()
+	page := benchmarkPage()
+
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		extractor.ExtractLinks(page, "https://example.com/")
+		extractor.ReleaseDocument(page)
+	}
+}
+
+// Every extraction step of a page on one parsed document, as the crawler runs them
+func BenchmarkExtractPage(b *testing.B) {
+	extractor := NewContentExtractor(DefaultDeadLinkCheckerConfig)
+	defer extractor.Close()
+	page := benchmarkPage()
+
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		extractor.ExtractTitle(page)
+		extractor.ExtractMetaDescription(page)
+		extractor.ExtractMetaRobots(page)
+		extractor.ExtractHiddenLinks(page, "https://example.com/")
+		extractor.ExtractEmails(page)
+		extractor.ExtractLinks(page, "https://example.com/")
+		extractor.ExtractAnchors(page, "https://example.com/")
+		extractor.ReleaseDocument(page)
+	}
+}
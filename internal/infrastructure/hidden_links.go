@@ -0,0 +1,215 @@
+package infrastructure
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golamv2/internal/domain"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// OffscreenOffset is how far left or up an absolutely placed element must be pushed to count
+// as moved off the screen
+const OffscreenOffset = 1000
+
+// namedColors are the color names worth telling apart, honeypots mostly hide white on white
+var namedColors = map[string]string{
+	"white":  "#ffffff",
+	"black":  "#000000",
+	"red":    "#ff0000",
+	"green":  "#008000",
+	"blue":   "#0000ff",
+	"yellow": "#ffff00",
+	"gray":   "#808080",
+	"grey":   "#808080",
+	"silver": "#c0c0c0",
+}
+
+// ExtractHiddenLinks returns the links of the page users can not see: inside hidden or
+// display:none elements, zero-size, pushed off the screen, empty or in the color of their
+// background. A link also found in an element users can see is left out. Only the page's own
+// markup and inline styles are looked at, stylesheets are not
+func (e *ContentExtractor) ExtractHiddenLinks(content, baseURL string) []string {
+	doc, err := e.document(content)
+	if err != nil {
+		return nil
+	}
+
+	baseU, err := url.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	hidden := make(map[string]bool) // False once found in an element users can see
+	record := func(ref string, hiddenElement bool) {
+		linkURL, err := url.Parse(ref)
+		if err != nil {
+			return
+		}
+
+		urlStr := domain.NormalizeURL(baseU.ResolveReference(linkURL).String())
+		if !domain.IsValidURL(urlStr) {
+			return
+		}
+		if seen, ok := hidden[urlStr]; ok {
+			hidden[urlStr] = seen && hiddenElement
+			return
+		}
+		hidden[urlStr] = hiddenElement
+		links = append(links, urlStr)
+	}
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		record(s.AttrOr("href", ""), isHiddenLink(s))
+	})
+	// Images, scripts and frames are not judged, they count as seen
+	doc.Find("[src]").Each(func(i int, s *goquery.Selection) {
+		record(s.AttrOr("src", ""), false)
+	})
+
+	flagged := links[:0]
+	for _, link := range links {
+		if hidden[link] {
+			flagged = append(flagged, link)
+		}
+	}
+	return flagged
+}
+
+// isHiddenLink tells whether a link or one of its ancestors hides it
+func isHiddenLink(link *goquery.Selection) bool {
+	if strings.TrimSpace(link.Text()) == "" && link.Children().Length() == 0 {
+		return true
+	}
+
+	color, background := "", ""
+	hidden := false
+	link.AddSelection(link.Parents()).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if _, ok := s.Attr("hidden"); ok {
+			hidden = true
+			return false
+		}
+
+		style := parseInlineStyle(s.AttrOr("style", ""))
+		if hidesElement(style) {
+			hidden = true
+			return false
+		}
+
+		// The nearest declared text color and background win, like in the cascade
+		if color == "" {
+			color = normalizeColor(style["color"])
+		}
+		if background == "" {
+			background = normalizeColor(style["background-color"])
+			if background == "" {
+				background = normalizeColor(style["background"])
+			}
+		}
+		return true
+	})
+
+	return hidden || (color != "" && color == background)
+}
+
+// hidesElement tells the inline styles that make an element and its content invisible
+func hidesElement(style map[string]string) bool {
+	switch {
+	case style["display"] == "none":
+		return true
+	case style["visibility"] == "hidden" || style["visibility"] == "collapse":
+		return true
+	case isZero(style["opacity"]) || isZero(style["font-size"]):
+		return true
+	}
+
+	// Zero-size boxes only hide their content when it does not overflow
+	zeroWidth, zeroHeight := isZero(style["width"]), isZero(style["height"])
+	if (zeroWidth && zeroHeight) || ((zeroWidth || zeroHeight) && style["overflow"] == "hidden") {
+		return true
+	}
+
+	if position := style["position"]; position == "absolute" || position == "fixed" {
+		return offscreen(style["left"]) || offscreen(style["top"])
+	}
+	return false
+}
+
+// parseInlineStyle splits a style attribute into lower-cased properties
+func parseInlineStyle(style string) map[string]string {
+	properties := make(map[string]string)
+	for _, declaration := range strings.Split(style, ";") {
+		name, value, ok := strings.Cut(declaration, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.ToLower(value)), "!important"))
+		properties[strings.TrimSpace(strings.ToLower(name))] = value
+	}
+	return properties
+}
+
+// isZero tells lengths and numbers that are zero whatever their unit
+func isZero(value string) bool {
+	if value == "" {
+		return false
+	}
+	number, ok := cssNumber(value)
+	return ok && number == 0
+}
+
+func offscreen(value string) bool {
+	number, ok := cssNumber(value)
+	return ok && number <= -OffscreenOffset
+}
+
+// cssNumber reads the number in front of a CSS unit
+func cssNumber(value string) (float64, bool) {
+	end := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if end == -1 {
+		end = len(value)
+	}
+	number, err := strconv.ParseFloat(value[:end], 64)
+	return number, err == nil
+}
+
+// normalizeColor turns hex, rgb() and a few named colors into #rrggbb, anything else
+// (gradients, images, transparency) is left out as empty
+func normalizeColor(value string) string {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if hex, ok := namedColors[value]; ok {
+		return hex
+	}
+
+	if strings.HasPrefix(value, "#") {
+		switch len(value) {
+		case 4:
+			return fmt.Sprintf("#%c%c%c%c%c%c", value[1], value[1], value[2], value[2], value[3], value[3])
+		case 7:
+			return value
+		}
+		return ""
+	}
+
+	if inner, ok := strings.CutPrefix(value, "rgb("); ok {
+		parts := strings.Split(strings.TrimSuffix(inner, ")"), ",")
+		if len(parts) != 3 {
+			return ""
+		}
+		var rgb [3]int
+		for i, part := range parts {
+			channel, err := strconv.Atoi(part)
+			if err != nil || channel < 0 || channel > 255 {
+				return ""
+			}
+			rgb[i] = channel
+		}
+		return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package infrastructure
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractHiddenLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{
+			name: "display none",
+			page: `<a href="/trap" style="display:none">x</a><a href="/page">page</a>`,
+			want: []string{"https://example.com/trap"},
+		},
+		{
+			name: "hidden ancestor",
+			page: `<div hidden><a href="/trap">x</a></div>`,
+			want: []string{"https://example.com/trap"},
+		},
+		{
+			name: "also linked visibly",
+			page: `<a href="/page" style="display:none">x</a><a href="/page">page</a>`,
+			want: nil,
+		},
+		{
+			name: "visible link first",
+			page: `<a href="/page">page</a><a href="/page" style="color:#fff;background:#fff">x</a>`,
+			want: nil,
+		},
+		{
+			name: "empty anchor next to an image source",
+			page: `<a href="/logo.png"></a><img src="/logo.png">`,
+			want: nil,
+		},
+	}
+
+	extractor := NewContentExtractor(DefaultDeadLinkCheckerConfig)
+	defer extractor.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractor.ExtractHiddenLinks("<html><body>"+tt.page+"</body></html>", "https://example.com/")
+			if len(got) == 0 {
+				got = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractHiddenLinks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package infrastructure
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaxRedirects matches the net/http default
+const MaxRedirects = 10
+
+// HTTPSUpgrades records hosts that permanently redirect http:// to https://,
+// later http links to those hosts are crawled over https straight away
+type HTTPSUpgrades struct {
+	mu    sync.RWMutex
+	hosts map[string]bool
+}
+
+// NewHTTPSUpgrades creates an empty upgrade record
+func NewHTTPSUpgrades() *HTTPSUpgrades {
+	return &HTTPSUpgrades{
+		hosts: make(map[string]bool),
+	}
+}
+
+// CheckRedirect is used as the http.Client redirect policy to spot upgrades
+func (h *HTTPSUpgrades) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= MaxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	previous := via[len(via)-1]
+	permanent := req.Response != nil &&
+		(req.Response.StatusCode == http.StatusMovedPermanently || req.Response.StatusCode == http.StatusPermanentRedirect)
+
+	if permanent && previous.URL.Scheme == "http" && req.URL.Scheme == "https" &&
+		strings.EqualFold(previous.URL.Hostname(), req.URL.Hostname()) {
+		h.Record(req.URL.Hostname())
+	}
+
+	return nil
+}
+
+// Record marks a host as https-only
+func (h *HTTPSUpgrades) Record(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hosts[strings.ToLower(host)] = true
+}
+
+// Upgrade rewrites http:// URLs of upgraded hosts to https://
+func (h *HTTPSUpgrades) Upgrade(rawURL string) string {
+	if !strings.HasPrefix(rawURL, "http://") {
+		return rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	h.mu.RLock()
+	upgraded := h.hosts[strings.ToLower(u.Hostname())]
+	h.mu.RUnlock()
+
+	if !upgraded {
+		return rawURL
+	}
+
+	u.Scheme = "https"
+	if u.Port() == "80" {
+		u.Host = u.Hostname()
+	}
+	return u.String()
+}
+
+// Hosts lists the hosts recorded as upgraded
+func (h *HTTPSUpgrades) Hosts() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	hosts := make([]string, 0, len(h.hosts))
+	for host := range h.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -2,68 +2,375 @@ package infrastructure
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"golamv2/internal/domain"
 	"golamv2/pkg/bloom"
+	"golamv2/pkg/logging"
 	"golamv2/pkg/metrics"
 	"golamv2/pkg/queue"
+	"golamv2/pkg/ratelimit"
+	"golamv2/pkg/sink"
 	"golamv2/pkg/storage"
+	"golamv2/pkg/warc"
 )
 
+// DefaultDataDir is where crawl data lives when no session is given
+const DefaultDataDir = "golamv2_data"
+
+// SQLiteFileName is the database of --storage sqlite under the data directory, when no DSN is given
+const SQLiteFileName = "golamv2.db"
+
+// WARCDirName is the folder under the data directory holding the files of --archive warc
+const WARCDirName = "warc"
+
 // Infrastructure holds all infrastructure components
 type Infrastructure struct {
 	URLQueue         domain.URLQueue
 	BloomFilter      domain.BloomFilter
+	Dedup            *URLDeduper
 	Storage          domain.Storage
 	RobotsChecker    domain.RobotsChecker
 	ContentExtractor domain.ContentExtractor
 	Metrics          *metrics.MetricsCollector
+	URLCollapser     *URLCollapser
+	HTTPSUpgrades    *HTTPSUpgrades
+	RateLimiter      *ratelimit.Limiter
+	Responses        *ResponseCache
+	Challenges       *ChallengeTracker
+	Duplicates       *DuplicateDetector
+	Renderer         *Renderer           // Only set in rendering mode
+	Sinks            *sink.Dispatcher    // Only set with result sinks
+	DNS              *DNSCollector       // Only set in DNS record mode
+	Archive          domain.PageArchive  // Only set when raw pages are archived
+	RequestLog       *logging.RequestLog // Only set with a request log
+	Identity         domain.Identity     // User agent and headers of every request
+	Namespace        string              // Namespace of Storage, empty for the default one
+
+	// Databases behind a namespaced Storage, closed after it
+	shared *storage.BadgerStorage
+}
+
+// Options configures NewInfrastructure
+type Options struct {
+	MaxMemoryMB int
+	DeadLinks   DeadLinkCheckerConfig
+	// CountingBloom lets URLs be removed from the bloom filter again, at 4x its memory
+	CountingBloom bool
+	// Dedup picks probabilistic (bloom filter), exact (stored keys) or hybrid URL dedup
+	Dedup domain.DedupMode
+	// InMemory keeps the databases in memory and writes nothing under dataDir, for dry runs.
+	// It overrides Storage
+	InMemory bool
+	// Storage is the backend results and spilled URLs are kept in, badger when empty
+	Storage domain.StorageBackend
+	// DSN of the SQL storages, the SQLite one defaults to SQLiteFileName under dataDir
+	DSN string
+	// Sinks get a copy of every stored result, keyed by name. Closed with the infrastructure
+	Sinks map[string]domain.ResultSink
+	// Namespace keeps the crawl apart from the others sharing dataDir, empty for the default one
+	Namespace string
+	// DNSRecords collects the DNS records of every crawled host
+	DNSRecords bool
+	// RDAP looks up the registration of dead domains that do not resolve
+	RDAP bool
+	// Traversal is the order queued URLs are crawled in
+	Traversal domain.TraversalOrder
+	// Jitter spaces out the fetches of each host by a random pause
+	Jitter domain.Jitter
+	// RateLimits of the fetches and dead link checks, the global rate is set by the crawler
+	RateLimits ratelimit.Config
+	// Identity is the user agent and extra headers of every request, robots.txt and link checks included
+	Identity domain.Identity
+	// MaxPerHost caps the pages of one host fetched at once, 0 means no cap
+	MaxPerHost int
+	// NearDuplicates is the SimHash bits pages may differ in to be near duplicates, 0 only flags identical pages
+	NearDuplicates int
+	// Archive keeps the raw body of every fetched page, in the Badger storage or as WARC files
+	Archive domain.ArchiveMode
+	// RequestLog is the file every fetch is logged to as a JSON line, "-" for stderr
+	RequestLog string
+}
+
+// resultPublisher is implemented by storages copying the results they store elsewhere
+type resultPublisher interface {
+	SetResultPublisher(publish func(result domain.CrawlResult))
+}
+
+// openStorage opens the storage backend of the options, the databases shared by the
+// namespaces are returned too when the crawl has one
+func openStorage(dataDir string, options Options) (domain.Storage, *storage.BadgerStorage, error) {
+	backend := options.Storage
+	if options.InMemory {
+		backend = domain.StorageMemory
+	}
+
+	var store *storage.BadgerStorage
+	var err error
+	switch backend {
+	case domain.StorageJSONL:
+		fileStore, err := storage.NewFastFileStorage(dataDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create storage: %v", err)
+		}
+		return fileStore, nil, nil
+	case domain.StorageSQLite, domain.StoragePostgres:
+		driver, dsn := storage.DriverPostgres, options.DSN
+		if backend == domain.StorageSQLite {
+			driver = storage.DriverSQLite
+			if dsn == "" {
+				if err := os.MkdirAll(dataDir, 0755); err != nil {
+					return nil, nil, fmt.Errorf("failed to create data directory: %v", err)
+				}
+				dsn = filepath.Join(dataDir, SQLiteFileName)
+			}
+		}
+		sqlStore, err := storage.NewSQLStorage(driver, dsn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create storage: %v", err)
+		}
+		return sqlStore, nil, nil
+	case domain.StorageMemory:
+		store, err = storage.NewMemoryBadgerStorage(domain.ModeAll, options.MaxMemoryMB)
+	default:
+		store, err = storage.NewBadgerStorage(dataDir, domain.ModeAll, options.MaxMemoryMB)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create storage: %v", err)
+	}
+
+	if options.Namespace == "" {
+		return store, nil, nil
+	}
+	namespaced, err := store.Namespace(options.Namespace)
+	if err != nil {
+		store.Close()
+		return nil, nil, err
+	}
+	return namespaced, store, nil
 }
 
-// NewInfrastructure creates a new infrastructure instance
-func NewInfrastructure(maxMemoryMB int) (*Infrastructure, error) {
+// WARCDir is where --archive warc writes the pages of a namespace, or of the default one
+func WARCDir(dataDir, namespace string) string {
+	return filepath.Join(dataDir, WARCDirName, namespace)
+}
+
+// openArchive opens the page archive of the options, nil when pages are not archived
+func openArchive(dataDir string, store domain.Storage, options Options) (domain.PageArchive, error) {
+	switch options.Archive {
+	case domain.ArchiveBadger:
+		archive, ok := store.(domain.PageArchive)
+		if !ok {
+			return nil, fmt.Errorf("--archive badger needs the badger storage")
+		}
+		return archive, nil
+	case domain.ArchiveWARC:
+		if options.InMemory {
+			return nil, nil // Dry runs write nothing under dataDir
+		}
+		return warc.NewArchive(WARCDir(dataDir, options.Namespace))
+	default:
+		return nil, nil
+	}
+}
+
+// NewInfrastructure creates a new infrastructure instance storing its data under dataDir
+func NewInfrastructure(dataDir string, options Options) (*Infrastructure, error) {
 	// Create metrics collector
 	metricsCollector := metrics.NewMetricsCollector()
 
 	// Create Bloom filter for URL deduplication
-	bloomFilter := bloom.NewURLBloomFilter()
+	var bloomFilter bloom.Filter = bloom.NewURLBloomFilter()
+	if options.CountingBloom {
+		bloomFilter = bloom.NewCountingURLBloomFilter()
+	}
+
+	// Crawls past the filter's capacity get a new layer instead of silently dropping URLs
+	metricsCollector.UpdateBloomFilter(1, 0)
+	bloomFilter.SetGrowHandler(func(layers int, falsePositiveRate float64) {
+		logging.Warnf("URL bloom filter is full at %d URLs (false positive rate %.2f%%), added layer %d",
+			bloomFilter.EstimateCount(), falsePositiveRate*100, layers)
+		metricsCollector.UpdateBloomFilter(layers, falsePositiveRate)
+	})
+
+	// Create storage
+	store, shared, err := openStorage(dataDir, options)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create storage (default path in current directory)
-	dbPath := filepath.Join(".", "golamv2_data")
-	storage, err := storage.NewBadgerStorage(dbPath, domain.ModeAll, maxMemoryMB)
+	archive, err := openArchive(dataDir, store, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create storage: %v", err)
+		store.Close()
+		if shared != nil {
+			shared.Close()
+		}
+		return nil, err
+	}
+
+	var requestLog *logging.RequestLog
+	if options.RequestLog != "" {
+		if requestLog, err = logging.NewRequestLog(options.RequestLog); err != nil {
+			if files, ok := archive.(*warc.Archive); ok {
+				files.Close()
+			}
+			store.Close()
+			if shared != nil {
+				shared.Close()
+			}
+			return nil, err
+		}
+	}
+
+	var sinks *sink.Dispatcher
+	if len(options.Sinks) > 0 {
+		sinks = sink.NewDispatcher(options.Sinks)
+		if publisher, ok := store.(resultPublisher); ok {
+			publisher.SetResultPublisher(sinks.Publish)
+		}
 	}
 
 	// Create URL queue
-	urlQueue := queue.NewPriorityURLQueue(storage)
+	urlQueue := queue.NewPriorityURLQueue(store)
+	urlQueue.SetPriorityFunc(queue.PriorityFor(options.Traversal))
+	urlQueue.SetJitter(options.Jitter)
+	urlQueue.SetMaxPerHost(options.MaxPerHost)
 
 	// Create robots checker
-	robotsChecker := NewRobotsChecker("GolamV2-Crawler/1.0")
+	robotsChecker := NewRobotsChecker(options.Identity)
+
+	// Reuse robots.txt files fetched by earlier runs on the same data
+	if robotsStore, ok := store.(domain.RobotsStore); ok {
+		robotsChecker.SetStore(robotsStore)
+	}
+	robotsChecker.SetMetrics(metricsCollector)
 
 	// Create content extractor
-	contentExtractor := NewContentExtractor()
+	contentExtractor := NewContentExtractor(options.DeadLinks)
+	contentExtractor.SetIdentity(options.Identity)
 
 	// Set storage reference for async dead link processing
-	contentExtractor.SetStorage(storage)
+	contentExtractor.SetStorage(store)
 
 	// Set metrics reference for updating dead link counters
 	contentExtractor.SetMetrics(metricsCollector)
 
+	// Dead link checks count against the same limits as the crawl's own fetches
+	rateLimiter := ratelimit.New(options.RateLimits)
+	contentExtractor.SetRateLimiter(rateLimiter)
+	metricsCollector.SetRateLimitReporter(rateLimiter)
+
+	if options.RDAP {
+		contentExtractor.SetRegistryLookup(NewRDAPClient(options.Identity.Agent()))
+	}
+
+	// Pages the crawler fetched are not requested again by the dead link checker
+	responses := NewResponseCache()
+	contentExtractor.SetResponseCache(responses)
+
+	var dnsCollector *DNSCollector
+	if recordStore, ok := store.(domain.DNSRecordStore); ok && options.DNSRecords {
+		dnsCollector = NewDNSCollector(recordStore, metricsCollector)
+	}
+
 	// Set up memory tracking components
-	metricsCollector.SetComponentMemoryTrackers(bloomFilter, storage, urlQueue)
+	if memory, ok := store.(metrics.StorageMemory); ok {
+		metricsCollector.SetComponentMemoryTrackers(bloomFilter, memory, urlQueue)
+	}
 
 	return &Infrastructure{
 		URLQueue:         urlQueue,
 		BloomFilter:      bloomFilter,
-		Storage:          storage,
+		Dedup:            NewURLDeduper(options.Dedup, bloomFilter, store),
+		Storage:          store,
 		RobotsChecker:    robotsChecker,
 		ContentExtractor: contentExtractor,
 		Metrics:          metricsCollector,
+		URLCollapser:     NewURLCollapser(),
+		HTTPSUpgrades:    NewHTTPSUpgrades(),
+		RateLimiter:      rateLimiter,
+		Responses:        responses,
+		Challenges:       NewChallengeTracker(),
+		Duplicates:       NewDuplicateDetector(options.NearDuplicates),
+		Sinks:            sinks,
+		DNS:              dnsCollector,
+		Archive:          archive,
+		RequestLog:       requestLog,
+		Identity:         options.Identity,
+		Namespace:        options.Namespace,
+		shared:           shared,
 	}, nil
 }
 
+// RestoreSeenURLs marks the URLs queued or crawled by earlier runs on the same data as seen,
+// so a resumed crawl does not queue known pages again. Returns how many were restored
+func (i *Infrastructure) RestoreSeenURLs() (int, error) {
+	known, ok := i.Storage.(domain.KnownURLs)
+	if !ok {
+		return 0, nil
+	}
+
+	restored := 0
+	err := known.ForEachKnownURL(func(url string) {
+		// Pages crawled several times have several results, count them once
+		if i.Dedup.MarkSeen(domain.URLKey(url)) {
+			restored++
+		}
+	})
+	return restored, err
+}
+
+// RestoreCheckpoint queues the tasks of the last checkpoint and restores the seen URLs saved
+// with them. Returns how many tasks were queued and whether the seen URLs were restored,
+// RestoreSeenURLs rebuilds them from the storage otherwise
+func (i *Infrastructure) RestoreCheckpoint() (int, bool, error) {
+	store, ok := i.Storage.(domain.CheckpointStore)
+	if !ok {
+		return 0, false, nil
+	}
+	checkpoint, err := store.LoadCheckpoint()
+	if err != nil || checkpoint == nil {
+		return 0, false, err
+	}
+
+	seen := false
+	if filter, ok := i.BloomFilter.(domain.PersistentBloomFilter); ok && len(checkpoint.Bloom) > 0 {
+		if err := filter.UnmarshalBinary(checkpoint.Bloom); err != nil {
+			logging.Warnf("Seen URLs of the checkpoint not restored: %v", err)
+		} else {
+			seen = true
+		}
+	}
+
+	queued := 0
+	for _, task := range checkpoint.Tasks {
+		i.Dedup.MarkSeen(domain.URLKey(task.URL))
+		if err := i.URLQueue.PushOrSpill(task); err != nil {
+			return queued, seen, fmt.Errorf("failed to queue checkpointed URL: %v", err)
+		}
+		queued++
+	}
+	return queued, seen, nil
+}
+
+// SetEvents publishes the results the storage takes and the dead links the content
+// extractor finds, call it before the crawl starts
+func (i *Infrastructure) SetEvents(events domain.EventPublisher) {
+	if store, ok := i.Storage.(resultPublisher); ok {
+		sinks := i.Sinks
+		store.SetResultPublisher(func(result domain.CrawlResult) {
+			if sinks != nil {
+				sinks.Publish(result)
+			}
+			events.Publish(domain.CrawlEvent{Type: domain.EventResult, URL: result.URL, Result: &result})
+		})
+	}
+	if extractor, ok := i.ContentExtractor.(*ContentExtractor); ok {
+		extractor.SetEvents(events)
+	}
+}
+
 // GetMetrics returns the metrics collector
 func (i *Infrastructure) GetMetrics() *metrics.MetricsCollector {
 	return i.Metrics
@@ -73,17 +380,52 @@ func (i *Infrastructure) GetMetrics() *metrics.MetricsCollector {
 func (i *Infrastructure) Close() error {
 	var errors []error
 
+	// Close the content extractor first, its async workers still store dead link results
+	if extractor, ok := i.ContentExtractor.(*ContentExtractor); ok {
+		extractor.Close()
+	}
+
+	if i.Renderer != nil {
+		i.Renderer.Close()
+	}
+
+	// DNS lookups in flight still store their records
+	if i.DNS != nil {
+		i.DNS.Close()
+	}
+
 	if err := i.URLQueue.Close(); err != nil {
 		errors = append(errors, fmt.Errorf("failed to close URL queue: %v", err))
 	}
 
+	// The WARC files of the archive, a Badger archive is closed with the storage
+	if archive, ok := i.Archive.(*warc.Archive); ok {
+		if err := archive.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("failed to close WARC archive: %v", err))
+		}
+	}
+
+	if i.RequestLog != nil {
+		if err := i.RequestLog.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("failed to close request log: %v", err))
+		}
+	}
+
+	// Storage goes last, it waits for writes in flight and flushes before closing
 	if err := i.Storage.Close(); err != nil {
 		errors = append(errors, fmt.Errorf("failed to close storage: %v", err))
 	}
+	if i.shared != nil {
+		if err := i.shared.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("failed to close storage: %v", err))
+		}
+	}
 
-	// Close the content extractor to shut down async workers
-	if extractor, ok := i.ContentExtractor.(*ContentExtractor); ok {
-		extractor.Close()
+	// Sinks get the last results the storage took before closing
+	if i.Sinks != nil {
+		if err := i.Sinks.Close(); err != nil {
+			errors = append(errors, err)
+		}
 	}
 
 	if len(errors) > 0 {
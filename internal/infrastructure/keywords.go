@@ -0,0 +1,260 @@
+package infrastructure
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/stem"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// unspacedScripts are written without spaces between words, each of their letters is
+// a word of its own so keywords in them match as runs of letters
+var unspacedScripts = []*unicode.RangeTable{
+	unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+}
+
+// normalizeText composes text to NFC and folds its case, so the spellings of a word
+// compare equal in any script. Folding diacritics also matches café with cafe
+func normalizeText(text string, foldDiacritics bool) string {
+	// Casers keep state, each call gets its own
+	return cases.Fold().String(composeText(text, foldDiacritics))
+}
+
+// composeText composes text to NFC without folding its case, for case sensitive matching
+func composeText(text string, foldDiacritics bool) string {
+	if foldDiacritics {
+		text, _, _ = transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+		return text
+	}
+	return norm.NFC.String(text)
+}
+
+// KeywordMatcher counts the keywords of a page the way the KeywordMatching of the crawl
+// says, with the regex rules compiled once
+type KeywordMatcher struct {
+	matching domain.KeywordMatching
+	patterns map[string]*regexp.Regexp // Keyed by lower-cased keyword
+}
+
+// NewKeywordMatcher compiles the rules of matching, rules failing Validate never match
+func NewKeywordMatcher(matching domain.KeywordMatching) *KeywordMatcher {
+	m := &KeywordMatcher{matching: matching, patterns: make(map[string]*regexp.Regexp)}
+	for key, rule := range matching.Rules {
+		if rule.Regex == "" {
+			continue
+		}
+		pattern := rule.Regex
+		if !rule.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		if re, err := regexp.Compile(pattern); err == nil {
+			m.patterns[key] = re
+		}
+	}
+	return m
+}
+
+// Count returns how often each keyword occurs in content, keywords that do not are left out
+func (m *KeywordMatcher) Count(content string, keywords []string) map[string]int {
+	text := &keywordText{content: content, foldDiacritics: m.matching.FoldDiacritics}
+	results := make(map[string]int)
+
+	for _, keyword := range keywords {
+		if count := m.count(text, keyword); count > 0 {
+			results[keyword] = count
+		}
+	}
+	return results
+}
+
+// count counts one keyword and its synonyms. Terms normalizing to the same words are
+// counted once, "runs" listed as a synonym of "run" does not double its count
+func (m *KeywordMatcher) count(text *keywordText, keyword string) int {
+	rule := m.matching.Rule(keyword)
+	if rule.Regex != "" {
+		re := m.patterns[strings.ToLower(keyword)]
+		if re == nil {
+			return 0
+		}
+		return len(re.FindAllStringIndex(text.composed(), -1))
+	}
+
+	count := 0
+	counted := make(map[string]bool)
+	for _, term := range m.matching.Terms(keyword) {
+		switch {
+		case rule.Stem:
+			// Whole words compared by their stems, phrases match as consecutive words
+			phrase := stemmedWords(normalizeText(term, m.matching.FoldDiacritics))
+			key := strings.Join(phrase, " ")
+			if len(phrase) == 0 || counted[key] {
+				continue
+			}
+			counted[key] = true
+			count += countPhrase(text.stems(), phrase)
+		case rule.WholeWord:
+			phrase := splitWords(text.normalize(term, rule.CaseSensitive))
+			key := strings.Join(phrase, " ")
+			if len(phrase) == 0 || counted[key] {
+				continue
+			}
+			counted[key] = true
+			count += countPhrase(text.words(rule.CaseSensitive), phrase)
+		default:
+			// Substrings, the words of a phrase may be apart by any whitespace
+			term = strings.Join(strings.Fields(text.normalize(term, rule.CaseSensitive)), " ")
+			if term == "" || counted[term] {
+				continue
+			}
+			counted[term] = true
+			count += strings.Count(text.flat(rule.CaseSensitive), term)
+		}
+	}
+	return count
+}
+
+// keywordText is the content of a page in the forms keywords are matched against, each
+// made on first use
+type keywordText struct {
+	content        string
+	foldDiacritics bool
+
+	forms map[string]string   // Normalized text, by form
+	split map[string][]string // Words, by form
+}
+
+func (t *keywordText) normalize(text string, caseSensitive bool) string {
+	if caseSensitive {
+		return composeText(text, t.foldDiacritics)
+	}
+	return normalizeText(text, t.foldDiacritics)
+}
+
+func (t *keywordText) form(name string, build func() string) string {
+	if t.forms == nil {
+		t.forms = make(map[string]string)
+	}
+	if text, ok := t.forms[name]; ok {
+		return text
+	}
+	text := build()
+	t.forms[name] = text
+	return text
+}
+
+// composed is the content in NFC with its case kept, what regexes run on
+func (t *keywordText) composed() string {
+	return t.form("composed", func() string { return composeText(t.content, t.foldDiacritics) })
+}
+
+// normalized is the content in NFC, case folded unless caseSensitive
+func (t *keywordText) normalized(caseSensitive bool) string {
+	if caseSensitive {
+		return t.composed()
+	}
+	return t.form("folded", func() string { return normalizeText(t.content, t.foldDiacritics) })
+}
+
+// flat is normalized with every run of whitespace made a single space
+func (t *keywordText) flat(caseSensitive bool) string {
+	name := "flat"
+	if caseSensitive {
+		name = "flat-cased"
+	}
+	return t.form(name, func() string { return strings.Join(strings.Fields(t.normalized(caseSensitive)), " ") })
+}
+
+func (t *keywordText) words(caseSensitive bool) []string {
+	name := "words"
+	if caseSensitive {
+		name = "words-cased"
+	}
+	return t.wordForm(name, func() []string { return splitWords(t.normalized(caseSensitive)) })
+}
+
+func (t *keywordText) stems() []string {
+	return t.wordForm("stems", func() []string { return stemmedWords(t.normalized(false)) })
+}
+
+func (t *keywordText) wordForm(name string, build func() []string) []string {
+	if t.split == nil {
+		t.split = make(map[string][]string)
+	}
+	if words, ok := t.split[name]; ok {
+		return words
+	}
+	words := build()
+	t.split[name] = words
+	return words
+}
+
+// stemmedWords splits normalized text into words and stems them
+func stemmedWords(text string) []string {
+	words := splitWords(text)
+
+	stems := make(map[string]string)
+	for i, word := range words {
+		stemmed, ok := stems[word]
+		if !ok {
+			stemmed = stem.Porter(word)
+			stems[word] = stemmed
+		}
+		words[i] = stemmed
+	}
+	return words
+}
+
+// splitWords splits text into words of letters, digits and the marks combining with them,
+// which many scripts write vowels with. Letters of unspaced scripts are words on their own
+func splitWords(text string) []string {
+	var words []string
+	start := -1
+	for i, r := range text {
+		switch {
+		case unicode.In(r, unspacedScripts...):
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+			words = append(words, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+// countPhrase counts the places words holds phrase
+func countPhrase(words, phrase []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,65 @@
+package infrastructure
+
+import (
+	"net/url"
+	"strings"
+
+	"golamv2/internal/domain"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ExtractNoFollowLinks returns the links of the page only linked with rel="nofollow", a link
+// also found without it elsewhere on the page is left out
+func (e *ContentExtractor) ExtractNoFollowLinks(content, baseURL string) []string {
+	doc, err := e.document(content)
+	if err != nil {
+		return nil
+	}
+
+	baseU, err := url.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	nofollow := make(map[string]bool) // False once linked without it
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		linkURL, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+
+		urlStr := domain.NormalizeURL(baseU.ResolveReference(linkURL).String())
+		if !domain.IsValidURL(urlStr) {
+			return
+		}
+
+		flagged := hasRelNoFollow(s.AttrOr("rel", ""))
+		if seen, ok := nofollow[urlStr]; ok {
+			nofollow[urlStr] = seen && flagged
+			return
+		}
+		nofollow[urlStr] = flagged
+		links = append(links, urlStr)
+	})
+
+	flagged := links[:0]
+	for _, link := range links {
+		if nofollow[link] {
+			flagged = append(flagged, link)
+		}
+	}
+	return flagged
+}
+
+// hasRelNoFollow reports whether a rel attribute holds the nofollow token
+func hasRelNoFollow(rel string) bool {
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		if token == "nofollow" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/cache"
+	"golamv2/pkg/logging"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// RDAPBootstrapURL lists the RDAP servers of every TLD that has one
+	RDAPBootstrapURL = "https://data.iana.org/rdap/dns.json"
+	// ExpiringWithin is how close to its expiry date a registered domain counts as expiring
+	ExpiringWithin = 30 * 24 * time.Hour
+	// Registrations are looked up once per run and registrable domain
+	RDAPCacheSize = 5000
+)
+
+// expiringStatuses are EPP statuses of domains on their way out of the registry
+var expiringStatuses = []string{"pending delete", "redemption period", "pending restore"}
+
+// RDAPClient looks up the registration of dead domains over RDAP, the successor of WHOIS,
+// to tell the ones anybody could register from the ones that merely stopped resolving
+type RDAPClient struct {
+	client    *http.Client
+	userAgent string
+	loadOnce  sync.Once
+	servers   map[string]string // TLD -> base URL of its RDAP server
+	loadErr   error
+	lookups   *cache.LRU[string, domain.Registration]
+}
+
+// NewRDAPClient creates a client, the IANA server list is fetched on the first lookup.
+// Registries only get the user agent of the crawl, not its extra headers
+func NewRDAPClient(userAgent string) *RDAPClient {
+	return &RDAPClient{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		userAgent: userAgent,
+		lookups:   cache.NewLRU[string, domain.Registration](RDAPCacheSize, 0),
+	}
+}
+
+// rdapBootstrap is the IANA file mapping TLDs to RDAP servers
+type rdapBootstrap struct {
+	Services [][][]string `json:"services"` // [[tlds...], [urls...]]
+}
+
+// rdapDomain is the part of an RDAP domain answer the lookup needs
+type rdapDomain struct {
+	Status []string `json:"status"`
+	Events []struct {
+		Action string    `json:"eventAction"`
+		Date   time.Time `json:"eventDate"`
+	} `json:"events"`
+}
+
+// Lookup returns the registration of the registrable domain host belongs to
+func (r *RDAPClient) Lookup(host string) domain.Registration {
+	name, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(strings.TrimSuffix(host, ".")))
+	if err != nil {
+		return domain.Registration{Status: domain.RegistrationUnknown}
+	}
+	if cached, ok := r.lookups.Get(name); ok {
+		return cached
+	}
+
+	registration, err := r.lookup(name)
+	if err != nil {
+		// Not cached, the registry may answer next time
+		return domain.Registration{Status: domain.RegistrationUnknown}
+	}
+	r.lookups.Add(name, registration)
+	return registration
+}
+
+func (r *RDAPClient) lookup(name string) (domain.Registration, error) {
+	r.loadOnce.Do(func() {
+		if r.loadErr = r.loadServers(); r.loadErr != nil {
+			logging.Warnf("Dead domains are not looked up in RDAP: %v", r.loadErr)
+		}
+	})
+	if r.loadErr != nil {
+		return domain.Registration{}, r.loadErr
+	}
+
+	tld := name[strings.LastIndex(name, ".")+1:]
+	server, ok := r.servers[tld]
+	if !ok {
+		// No RDAP for this TLD, there is nothing more to learn this run
+		return domain.Registration{Status: domain.RegistrationUnknown}, nil
+	}
+
+	req, err := http.NewRequest("GET", server+"domain/"+name, nil)
+	if err != nil {
+		return domain.Registration{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return domain.Registration{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return domain.Registration{Status: domain.RegistrationAvailable}, nil
+	case resp.StatusCode != http.StatusOK:
+		return domain.Registration{}, fmt.Errorf("RDAP lookup of %s answered %d", name, resp.StatusCode)
+	}
+
+	var answer rdapDomain
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return domain.Registration{}, fmt.Errorf("invalid RDAP answer for %s: %v", name, err)
+	}
+	return r.classify(answer), nil
+}
+
+// classify tells a registered domain from one that expires soon or is being deleted
+func (r *RDAPClient) classify(answer rdapDomain) domain.Registration {
+	registration := domain.Registration{Status: domain.RegistrationRegistered}
+	for _, event := range answer.Events {
+		if strings.EqualFold(event.Action, "expiration") && !event.Date.IsZero() {
+			expiresAt := event.Date
+			registration.ExpiresAt = &expiresAt
+			if time.Until(expiresAt) < ExpiringWithin {
+				registration.Status = domain.RegistrationExpiring
+			}
+		}
+	}
+
+	for _, status := range answer.Status {
+		for _, expiring := range expiringStatuses {
+			if strings.EqualFold(status, expiring) {
+				registration.Status = domain.RegistrationExpiring
+			}
+		}
+	}
+	return registration
+}
+
+// loadServers reads the IANA bootstrap file
+func (r *RDAPClient) loadServers() error {
+	resp, err := r.client.Get(RDAPBootstrapURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the RDAP server list: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch the RDAP server list: %d", resp.StatusCode)
+	}
+
+	var bootstrap rdapBootstrap
+	if err := json.NewDecoder(resp.Body).Decode(&bootstrap); err != nil {
+		return fmt.Errorf("invalid RDAP server list: %v", err)
+	}
+
+	r.servers = make(map[string]string)
+	for _, service := range bootstrap.Services {
+		if len(service) < 2 || len(service[1]) == 0 {
+			continue
+		}
+		// Prefer https, servers are listed with a trailing slash but not always
+		base := service[1][0]
+		for _, candidate := range service[1] {
+			if strings.HasPrefix(candidate, "https://") {
+				base = candidate
+				break
+			}
+		}
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		for _, tld := range service[0] {
+			r.servers[strings.ToLower(tld)] = base
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// Viewport used for rendering and screenshots
+	RenderViewportWidth  = 1280
+	RenderViewportHeight = 800
+
+	// RenderTimeout bounds loading and capturing a single page
+	RenderTimeout = 30 * time.Second
+)
+
+// ScreenshotsDirName is the folder under the data directory holding page screenshots
+const ScreenshotsDirName = "screenshots"
+
+// RenderedPage is a page as the headless browser saw it, the response is the one of the
+// navigation so the page is not fetched a second time over plain HTTP
+type RenderedPage struct {
+	StatusCode int
+	Header     http.Header
+	FinalURL   string // Where redirects ended, empty when there were none
+	Body       string // As received, before scripts ran
+	HTML       string
+	Screenshot string // File name under the screenshot directory, empty when not captured
+}
+
+// Renderer loads pages in a headless Chrome so JavaScript built content gets crawled too,
+// every page gets its own tab in one shared browser
+type Renderer struct {
+	browserCtx    context.Context
+	cancelAlloc   context.CancelFunc
+	cancelBrowser context.CancelFunc
+	screenshotDir string
+	headers       network.Headers // Extra headers of the crawl, sent with every request of a tab
+}
+
+// NewRenderer starts the headless browser, screenshots are only captured when screenshotDir is set.
+// The browser identifies itself with the user agent and extra headers of identity
+func NewRenderer(screenshotDir string, identity domain.Identity) (*Renderer, error) {
+	if screenshotDir != "" {
+		if err := os.MkdirAll(screenshotDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create screenshot directory: %v", err)
+		}
+	}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.UserAgent(identity.Agent()),
+		chromedp.WindowSize(RenderViewportWidth, RenderViewportHeight),
+	)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+
+	// Running with no actions launches the browser so a missing Chrome fails early
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		return nil, fmt.Errorf("failed to start headless browser: %v", err)
+	}
+
+	renderer := &Renderer{
+		browserCtx:    browserCtx,
+		cancelAlloc:   cancelAlloc,
+		cancelBrowser: cancelBrowser,
+		screenshotDir: screenshotDir,
+	}
+	if len(identity.Headers) > 0 {
+		renderer.headers = make(network.Headers, len(identity.Headers))
+		for name, value := range identity.Headers {
+			renderer.headers[name] = value
+		}
+	}
+	return renderer, nil
+}
+
+// Render loads url in a new tab and returns the response and the rendered DOM, with a viewport
+// screenshot if enabled. The DOM is only read from HTML pages
+func (r *Renderer) Render(ctx context.Context, url string) (RenderedPage, error) {
+	tabCtx, cancelTab := chromedp.NewContext(r.browserCtx)
+	defer cancelTab()
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, RenderTimeout)
+	defer cancelTimeout()
+
+	// Stop rendering when the crawl is cancelled
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelTab()
+		case <-tabCtx.Done():
+		}
+	}()
+
+	// The request ids of the documents loaded, to read the raw body of the page from
+	documents := make(map[string]network.RequestID)
+	var documentsMu sync.Mutex
+	chromedp.ListenTarget(tabCtx, func(ev any) {
+		if ev, ok := ev.(*network.EventResponseReceived); ok && ev.Type == network.ResourceTypeDocument {
+			documentsMu.Lock()
+			documents[ev.Response.URL] = ev.RequestID
+			documentsMu.Unlock()
+		}
+	})
+
+	setup := []chromedp.Action{
+		chromedp.EmulateViewport(RenderViewportWidth, RenderViewportHeight),
+	}
+	if r.headers != nil {
+		setup = append(setup, network.SetExtraHTTPHeaders(r.headers))
+	}
+	if err := chromedp.Run(tabCtx, setup...); err != nil {
+		return RenderedPage{}, fmt.Errorf("failed to open tab: %v", err)
+	}
+
+	response, err := chromedp.RunResponse(tabCtx, chromedp.Navigate(url))
+	if err != nil {
+		return RenderedPage{}, fmt.Errorf("failed to render page: %v", err)
+	}
+	if response == nil {
+		return RenderedPage{}, fmt.Errorf("failed to render page: no response")
+	}
+
+	page := RenderedPage{StatusCode: int(response.Status), Header: make(http.Header, len(response.Headers))}
+	for name, value := range response.Headers {
+		// Repeated headers arrive joined by newlines
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			page.Header.Add(name, v)
+		}
+	}
+	if response.URL != url {
+		page.FinalURL = response.URL
+	}
+
+	documentsMu.Lock()
+	requestID, ok := documents[response.URL]
+	documentsMu.Unlock()
+	if ok {
+		err := chromedp.Run(tabCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+			body, err := network.GetResponseBody(requestID).Do(ctx)
+			page.Body = string(body)
+			return err
+		}))
+		if err != nil {
+			return page, fmt.Errorf("failed to read page body: %v", err)
+		}
+	}
+
+	// Downloads and images have no DOM worth reading
+	contentType := strings.ToLower(page.Header.Get("Content-Type"))
+	if contentType != "" && !strings.Contains(contentType, "text/html") && !strings.Contains(contentType, "application/xhtml") {
+		return page, nil
+	}
+
+	var screenshot []byte
+	actions := []chromedp.Action{chromedp.OuterHTML("html", &page.HTML, chromedp.ByQuery)}
+	if r.screenshotDir != "" {
+		actions = append(actions, chromedp.CaptureScreenshot(&screenshot))
+	}
+	if err := chromedp.Run(tabCtx, actions...); err != nil {
+		return page, fmt.Errorf("failed to render page: %v", err)
+	}
+
+	if len(screenshot) > 0 {
+		name := ScreenshotName(url)
+		if err := os.WriteFile(filepath.Join(r.screenshotDir, name), screenshot, 0644); err != nil {
+			return page, fmt.Errorf("failed to save screenshot: %v", err)
+		}
+		page.Screenshot = name
+	}
+
+	return page, nil
+}
+
+// Close shuts the browser down
+func (r *Renderer) Close() {
+	r.cancelBrowser()
+	r.cancelAlloc()
+}
+
+// ScreenshotName is the file name of the screenshot of url, keyed by the URL hash
+func ScreenshotName(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:]) + ".png"
+}
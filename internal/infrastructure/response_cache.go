@@ -0,0 +1,68 @@
+package infrastructure
+
+import (
+	"net/http"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/cache"
+)
+
+const (
+	// ResponseCacheSize bounds the cached responses, with MaxCachedBody that is at most 128MB of bodies
+	ResponseCacheSize = 500
+	// ResponseCacheTTL keeps responses only for the moment, this is not an HTTP cache
+	ResponseCacheTTL = 5 * time.Minute
+	// MaxCachedBody is the largest body kept, bigger responses are cached without it
+	MaxCachedBody = 256 * 1024
+)
+
+// CachedResponse is a response fetched earlier in the run
+type CachedResponse struct {
+	StatusCode int
+	FinalURL   string // Where redirects ended, empty when there were none
+	Header     http.Header
+	Body       string
+	HasBody    bool // False for HEAD requests, skipped content and bodies over MaxCachedBody
+}
+
+// ResponseCache keeps the responses of the run for a few minutes, keyed by domain.URLKey,
+// so a page the crawler fetched is not requested again by the dead link checker or fetched
+// twice. Link checks only cache the status, the crawler needs the body. Server errors and
+// 429s are never cached, they are worth retrying
+type ResponseCache struct {
+	entries *cache.LRU[string, CachedResponse]
+}
+
+// NewResponseCache creates an empty response cache
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{
+		entries: cache.NewLRU[string, CachedResponse](ResponseCacheSize, ResponseCacheTTL),
+	}
+}
+
+// Get returns the response cached for rawURL
+func (r *ResponseCache) Get(rawURL string) (CachedResponse, bool) {
+	if r == nil {
+		return CachedResponse{}, false
+	}
+	return r.entries.Get(domain.URLKey(rawURL))
+}
+
+// Add caches a response, a response without body does not replace one with
+func (r *ResponseCache) Add(rawURL string, response CachedResponse) {
+	if r == nil || response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests {
+		return
+	}
+	if len(response.Body) > MaxCachedBody {
+		response.Body, response.HasBody = "", false
+	}
+
+	key := domain.URLKey(rawURL)
+	if !response.HasBody {
+		if cached, ok := r.entries.Get(key); ok && cached.HasBody {
+			return
+		}
+	}
+	r.entries.Add(key, response)
+}
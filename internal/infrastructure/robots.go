@@ -2,27 +2,58 @@ package infrastructure
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sync"
 	"time"
 
+	"golamv2/internal/domain"
+	"golamv2/pkg/bufpool"
+	"golamv2/pkg/metrics"
+
 	"github.com/temoto/robotstxt"
 )
 
+// RobotsCacheTTL is how long a stored robots.txt is trusted before fetching it again
+const RobotsCacheTTL = 24 * time.Hour
+
+// MaxRobotsSize caps the robots.txt body read
+const MaxRobotsSize = 512 << 10
+
+// RobotsRetryInterval is how long an unreachable robots.txt is remembered before fetching it again
+const RobotsRetryInterval = 5 * time.Minute
+
+// robotsEntry is the cached outcome of fetching one host's robots.txt
+type robotsEntry struct {
+	robots  *robotstxt.RobotsData // Only set when a file was found
+	status  domain.RobotsStatus
+	expires time.Time // Unreachable entries expire so robots.txt gets fetched again
+
+	// Sitemaps were handed to onSitemaps, only done once a URL of the host is crawled
+	announced bool
+}
+
 // RobotsChecker implements domain.RobotsChecker
 type RobotsChecker struct {
-	mu        sync.RWMutex
-	cache     map[string]*robotstxt.RobotsData
-	client    *http.Client
-	userAgent string
+	mu       sync.RWMutex
+	cache    map[string]*robotsEntry
+	client   *http.Client
+	identity domain.Identity
+	policy   domain.RobotsPolicy
+	metrics  *metrics.MetricsCollector
+	// Called with the Sitemap: entries of the robots.txt of every host crawled
+	onSitemaps func(host string, sitemaps []string)
+	// Persists robots.txt files between runs, optional
+	store domain.RobotsStore
 }
 
-// NewRobotsChecker creates a new robots.txt checker
-func NewRobotsChecker(userAgent string) *RobotsChecker {
+// NewRobotsChecker creates a new robots.txt checker, robots.txt is requested with identity
+func NewRobotsChecker(identity domain.Identity) *RobotsChecker {
 	return &RobotsChecker{
-		cache:     make(map[string]*robotstxt.RobotsData),
-		userAgent: userAgent,
+		cache:    make(map[string]*robotsEntry),
+		identity: identity,
+		policy:   domain.DefaultRobotsPolicy,
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -31,32 +62,66 @@ func NewRobotsChecker(userAgent string) *RobotsChecker {
 
 // CanFetch checks if the given URL can be fetched according to robots.txt
 func (r *RobotsChecker) CanFetch(userAgent, urlStr string) bool {
+	return r.Check(userAgent, urlStr) == domain.RobotsAllowed
+}
+
+// Check decides whether the given URL can be fetched now, applying the policy when robots.txt could not be read
+func (r *RobotsChecker) Check(userAgent, urlStr string) domain.RobotsVerdict {
+	return r.check(userAgent, urlStr, true)
+}
+
+// CheckLink is Check for a link that is only checked, not crawled, the sitemaps of its host
+// are not handed to the sitemap handler
+func (r *RobotsChecker) CheckLink(userAgent, urlStr string) domain.RobotsVerdict {
+	return r.check(userAgent, urlStr, false)
+}
+
+func (r *RobotsChecker) check(userAgent, urlStr string, crawled bool) domain.RobotsVerdict {
 	u, err := url.Parse(urlStr)
 	if err != nil {
-		return false
+		return domain.RobotsBlocked
 	}
 
-	domain := u.Host
-	robots := r.getRobots(domain)
-	if robots == nil {
-		return true // If we can't get robots.txt, assume we good!
+	entry := r.getRobots(u.Host, crawled)
+	switch entry.status {
+	case domain.RobotsMissing:
+		return domain.RobotsAllowed
+	case domain.RobotsForbidden:
+		return r.verdict(r.policy.Forbidden)
+	case domain.RobotsUnreachable:
+		return r.verdict(r.policy.Unreachable)
 	}
 
-	group := robots.FindGroup(userAgent)
+	group := entry.robots.FindGroup(userAgent)
 	if group == nil {
-		group = robots.FindGroup("*")
+		group = entry.robots.FindGroup("*")
 	}
 
-	if group == nil {
-		return true
+	if group == nil || group.Test(u.Path) {
+		return domain.RobotsAllowed
 	}
+	return domain.RobotsBlocked
+}
 
-	return group.Test(u.Path)
+// Status returns how fetching the robots.txt of host went, fetching it if needed
+func (r *RobotsChecker) Status(host string) domain.RobotsStatus {
+	return r.getRobots(host, true).status
+}
+
+func (r *RobotsChecker) verdict(action domain.RobotsAction) domain.RobotsVerdict {
+	switch action {
+	case domain.RobotsAllow:
+		return domain.RobotsAllowed
+	case domain.RobotsRetry:
+		return domain.RobotsRetryLater
+	default:
+		return domain.RobotsBlocked
+	}
 }
 
 // GetSitemaps returns sitemap URLs from robots.txt
 func (r *RobotsChecker) GetSitemaps(domain string) []string {
-	robots := r.getRobots(domain)
+	robots := r.getRobots(domain, true).robots
 	if robots == nil {
 		return nil
 	}
@@ -69,9 +134,39 @@ func (r *RobotsChecker) GetSitemaps(domain string) []string {
 	return sitemaps
 }
 
+// SetSitemapHandler sets a callback run in the background once per robots.txt fetched for a
+// crawled host, it is also called for hosts without robots.txt, with no sitemaps. Hosts only
+// reached by CheckLink are left out
+func (r *RobotsChecker) SetSitemapHandler(handler func(host string, sitemaps []string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onSitemaps = handler
+}
+
+// SetStore sets where fetched robots.txt files are persisted and looked up before fetching
+func (r *RobotsChecker) SetStore(store domain.RobotsStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store = store
+}
+
+// SetPolicy sets how hosts with a forbidden or unreachable robots.txt are treated
+func (r *RobotsChecker) SetPolicy(policy domain.RobotsPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = policy
+}
+
+// SetMetrics sets the collector counting robots.txt fetch outcomes
+func (r *RobotsChecker) SetMetrics(metrics *metrics.MetricsCollector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = metrics
+}
+
 // GetCrawlDelay returns the crawl delay for the given user agent and domain
 func (r *RobotsChecker) GetCrawlDelay(userAgent, domain string) time.Duration {
-	robots := r.getRobots(domain)
+	robots := r.getRobots(domain, true).robots
 	if robots == nil {
 		return 0
 	}
@@ -88,47 +183,135 @@ func (r *RobotsChecker) GetCrawlDelay(userAgent, domain string) time.Duration {
 	return time.Duration(group.CrawlDelay) * time.Second
 }
 
-// getRobots fetches and caches robots.txt for a domain
-func (r *RobotsChecker) getRobots(domain string) *robotstxt.RobotsData {
+// getRobots fetches and caches robots.txt for a host, the sitemaps of crawled hosts are
+// announced
+func (r *RobotsChecker) getRobots(host string, crawled bool) *robotsEntry {
 	r.mu.RLock()
-	if robots, exists := r.cache[domain]; exists {
+	if entry, exists := r.cache[host]; exists && (entry.expires.IsZero() || time.Now().Before(entry.expires)) {
 		r.mu.RUnlock()
-		return robots
+		if crawled {
+			r.announceSitemaps(host, entry)
+		}
+		return entry
 	}
+	store := r.store
+	collector := r.metrics
 	r.mu.RUnlock()
 
-	// Fetch robots.txt
-	robotsURL := fmt.Sprintf("https://%s/robots.txt", domain)
-	resp, err := r.client.Get(robotsURL)
+	entry := r.loadRobots(store, host)
+	r.cacheRobots(host, entry)
+
+	if collector != nil {
+		collector.UpdateRobotsStatus(entry.status)
+	}
+
+	if crawled {
+		r.announceSitemaps(host, entry)
+	}
+	return entry
+}
+
+// announceSitemaps hands the sitemaps of a host to the sitemap handler, once per fetched robots.txt
+func (r *RobotsChecker) announceSitemaps(host string, entry *robotsEntry) {
+	r.mu.Lock()
+	onSitemaps := r.onSitemaps
+	if onSitemaps == nil || entry.announced || entry.status == domain.RobotsUnreachable {
+		r.mu.Unlock()
+		return
+	}
+	entry.announced = true
+	r.mu.Unlock()
+
+	var sitemaps []string
+	if entry.robots != nil {
+		sitemaps = entry.robots.Sitemaps
+	}
+	go onSitemaps(host, sitemaps)
+}
+
+// loadRobots returns the stored robots.txt of a domain while fresh, fetching and storing it otherwise
+func (r *RobotsChecker) loadRobots(store domain.RobotsStore, host string) *robotsEntry {
+	if store != nil {
+		if file, err := store.GetRobots(host); err == nil && file != nil && time.Since(file.FetchedAt) < RobotsCacheTTL {
+			return parseRobots(file)
+		}
+	}
+
+	file := r.fetchRobots(host)
+	entry := parseRobots(file)
+	if entry.status == domain.RobotsUnreachable {
+		// Not worth keeping, robots.txt is fetched again after a while
+		entry.expires = time.Now().Add(RobotsRetryInterval)
+		return entry
+	}
+
+	if store != nil {
+		store.StoreRobots(*file)
+	}
+	return entry
+}
+
+// get requests a robots.txt with the crawler's identity
+func (r *RobotsChecker) get(robotsURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.identity.Apply(req)
+	return r.client.Do(req)
+}
+
+// fetchRobots downloads robots.txt, a connection failure is reported as status 0
+func (r *RobotsChecker) fetchRobots(host string) *domain.RobotsFile {
+	file := &domain.RobotsFile{
+		Host:      host,
+		FetchedAt: time.Now(),
+	}
+
+	robotsURL := fmt.Sprintf("https://%s/robots.txt", host)
+	resp, err := r.get(robotsURL)
 	if err != nil {
 		// Try HTTP if HTTPS fails
-		robotsURL = fmt.Sprintf("http://%s/robots.txt", domain)
-		resp, err = r.client.Get(robotsURL)
+		robotsURL = fmt.Sprintf("http://%s/robots.txt", host)
+		resp, err = r.get(robotsURL)
 		if err != nil {
-			r.cacheRobots(domain, nil)
-			return nil
+			return file
 		}
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		r.cacheRobots(domain, nil)
-		return nil
+	if resp.StatusCode == http.StatusOK {
+		body, err := bufpool.ReadString(io.LimitReader(resp.Body, MaxRobotsSize), resp.ContentLength)
+		if err != nil {
+			return file // Timed out halfway, same as unreachable
+		}
+		file.Body = body
 	}
 
-	robots, err := robotstxt.FromResponse(resp)
-	if err != nil {
-		r.cacheRobots(domain, nil)
-		return nil
+	file.StatusCode = resp.StatusCode
+	return file
+}
+
+// parseRobots turns a fetched robots.txt into a cache entry
+func parseRobots(file *domain.RobotsFile) *robotsEntry {
+	entry := &robotsEntry{status: domain.RobotsStatusOf(file.StatusCode)}
+	if entry.status != domain.RobotsFound {
+		return entry
 	}
 
-	r.cacheRobots(domain, robots)
-	return robots
+	robots, err := robotstxt.FromString(file.Body)
+	if err != nil {
+		// Unparseable files are treated as no rules at all
+		entry.status = domain.RobotsMissing
+		return entry
+	}
+	entry.robots = robots
+	return entry
 }
 
 // cacheRobots caches robots.txt data for a domain
-func (r *RobotsChecker) cacheRobots(domain string, robots *robotstxt.RobotsData) {
+func (r *RobotsChecker) cacheRobots(domain string, entry *robotsEntry) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.cache[domain] = robots
+	r.cache[domain] = entry
 }
@@ -0,0 +1,59 @@
+package infrastructure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+func TestSitemapsOnlyAnnouncedForCrawledHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "User-agent: *\nDisallow: /private\nSitemap: http://example.com/sitemap.xml")
+	}))
+	defer server.Close()
+	host := mustHost(t, server.URL)
+
+	announced := make(chan string, 4)
+	robots := NewRobotsChecker(domain.Identity{})
+	robots.SetSitemapHandler(func(host string, sitemaps []string) { announced <- host })
+
+	if verdict := robots.CheckLink("golamv2", server.URL+"/private/x"); verdict != domain.RobotsBlocked {
+		t.Fatalf("CheckLink = %v, want blocked", verdict)
+	}
+	select {
+	case <-announced:
+		t.Fatal("sitemaps announced for a host only link-checked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Crawling the host later announces the cached robots.txt, once
+	robots.Check("golamv2", server.URL+"/")
+	robots.Check("golamv2", server.URL+"/other")
+	select {
+	case got := <-announced:
+		if got != host {
+			t.Errorf("announced %s, want %s", got, host)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sitemaps of a crawled host never announced")
+	}
+	select {
+	case <-announced:
+		t.Fatal("sitemaps announced twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
@@ -0,0 +1,213 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/cache"
+)
+
+// Bounds of the ftp, mailto and tel link checks
+const (
+	SchemeCheckTimeout = 5 * time.Second
+	SchemeCacheSize    = 10000
+	SchemeCacheTTL     = time.Hour
+)
+
+// SchemeLinkSchemes are the link schemes SchemeLinkChecker handles
+var SchemeLinkSchemes = []string{"ftp", "mailto", "tel"}
+
+// isSchemeLink tells the links SchemeLinkChecker handles from web pages
+func isSchemeLink(link string) bool {
+	scheme, _, found := strings.Cut(link, ":")
+	return found && slices.Contains(SchemeLinkSchemes, strings.ToLower(scheme))
+}
+
+// schemeStatus is a cached check of an FTP server or mail domain
+type schemeStatus struct {
+	status domain.SchemeLinkStatus
+	err    string
+}
+
+// SchemeLinkChecker checks the links dead link mode can not request over HTTP: ftp:// servers
+// get a connection probe, mailto: addresses a syntax check plus an MX lookup and tel: numbers a
+// syntax check. FTP servers and mail domains are cached
+type SchemeLinkChecker struct {
+	hosts *cache.LRU[string, schemeStatus]
+}
+
+// NewSchemeLinkChecker creates a checker with an empty cache
+func NewSchemeLinkChecker() *SchemeLinkChecker {
+	return &SchemeLinkChecker{hosts: cache.NewLRU[string, schemeStatus](SchemeCacheSize, SchemeCacheTTL)}
+}
+
+// Check returns the status of an ftp, mailto or tel link
+func (c *SchemeLinkChecker) Check(link domain.Link) domain.SchemeLink {
+	result := domain.SchemeLink{URL: link.URL, AnchorText: link.Text}
+
+	u, err := url.Parse(link.URL)
+	if err != nil {
+		result.Status, result.Error = domain.SchemeLinkInvalid, err.Error()
+		return result
+	}
+	result.Scheme = strings.ToLower(u.Scheme)
+
+	var status schemeStatus
+	switch result.Scheme {
+	case "mailto":
+		status = c.checkMailto(u)
+	case "tel":
+		status = checkTel(u)
+	case "ftp":
+		status = c.checkFTP(u)
+	default:
+		status = schemeStatus{domain.SchemeLinkInvalid, "unsupported scheme"}
+	}
+
+	result.Status, result.Error = status.status, status.err
+	return result
+}
+
+// opaque returns what follows the scheme of mailto: and tel: links, without parameters
+func opaque(u *url.URL, separators string) string {
+	value := u.Opaque
+	if value == "" {
+		value = u.Path
+	}
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		value = unescaped
+	}
+	if i := strings.IndexAny(value, separators); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// checkMailto checks every address of the link, the first failure is its status
+func (c *SchemeLinkChecker) checkMailto(u *url.URL) schemeStatus {
+	addresses := opaque(u, "?")
+	if addresses == "" {
+		return schemeStatus{domain.SchemeLinkInvalid, "no address"}
+	}
+
+	for _, address := range strings.Split(addresses, ",") {
+		parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+		if err != nil {
+			return schemeStatus{domain.SchemeLinkInvalid, err.Error()}
+		}
+
+		at := strings.LastIndex(parsed.Address, "@")
+		mailDomain := strings.ToLower(parsed.Address[at+1:])
+		if !strings.Contains(mailDomain, ".") {
+			return schemeStatus{domain.SchemeLinkInvalid, "no mail domain"}
+		}
+
+		if status := c.checkMailDomain(mailDomain); status.status != domain.SchemeLinkOK {
+			return status
+		}
+	}
+	return schemeStatus{status: domain.SchemeLinkOK}
+}
+
+// checkMailDomain looks up the mail servers of a domain, without MX records its address
+// records take mail (RFC 5321) unless it publishes a null MX
+func (c *SchemeLinkChecker) checkMailDomain(mailDomain string) schemeStatus {
+	key := "mx:" + mailDomain
+	if cached, ok := c.hosts.Get(key); ok {
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), SchemeCheckTimeout)
+	defer cancel()
+
+	status := schemeStatus{status: domain.SchemeLinkOK}
+	records, err := net.DefaultResolver.LookupMX(ctx, mailDomain)
+	switch {
+	case err == nil && len(records) == 1 && records[0].Host == ".":
+		status = schemeStatus{domain.SchemeLinkNoMX, "null MX, the domain takes no mail"}
+	case err == nil && len(records) > 0:
+	case isNotFound(err) || err == nil:
+		if _, hostErr := net.DefaultResolver.LookupHost(ctx, mailDomain); hostErr != nil {
+			status = schemeStatus{domain.SchemeLinkNoMX, "no MX or address records"}
+		}
+	default:
+		status = schemeStatus{domain.SchemeLinkUnreachable, err.Error()}
+	}
+
+	// Lookup failures are retried by the next link
+	if status.status != domain.SchemeLinkUnreachable {
+		c.hosts.Add(key, status)
+	}
+	return status
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// checkTel accepts numbers of 3 to 15 digits (E.164), with an optional leading + and
+// the usual visual separators
+func checkTel(u *url.URL) schemeStatus {
+	number := opaque(u, ";")
+	number = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(number)
+	number = strings.TrimPrefix(number, "+")
+
+	if len(number) < 3 || len(number) > 15 {
+		return schemeStatus{domain.SchemeLinkInvalid, "not a phone number"}
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return schemeStatus{domain.SchemeLinkInvalid, "not a phone number"}
+		}
+	}
+	return schemeStatus{status: domain.SchemeLinkOK}
+}
+
+// checkFTP connects to the server and expects its 220 greeting
+func (c *SchemeLinkChecker) checkFTP(u *url.URL) schemeStatus {
+	if u.Hostname() == "" {
+		return schemeStatus{domain.SchemeLinkInvalid, "no host"}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "21"
+	}
+	address := net.JoinHostPort(u.Hostname(), port)
+
+	key := "ftp:" + strings.ToLower(address)
+	if cached, ok := c.hosts.Get(key); ok {
+		return cached
+	}
+
+	status := probeFTP(address)
+	c.hosts.Add(key, status)
+	return status
+}
+
+func probeFTP(address string) schemeStatus {
+	conn, err := net.DialTimeout("tcp", address, SchemeCheckTimeout)
+	if err != nil {
+		return schemeStatus{domain.SchemeLinkUnreachable, err.Error()}
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(SchemeCheckTimeout))
+	greeting, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return schemeStatus{domain.SchemeLinkUnreachable, fmt.Sprintf("no greeting: %v", err)}
+	}
+	if !strings.HasPrefix(greeting, "220") {
+		return schemeStatus{domain.SchemeLinkUnreachable, strings.TrimSpace(greeting)}
+	}
+	return schemeStatus{status: domain.SchemeLinkOK}
+}
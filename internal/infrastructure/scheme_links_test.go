@@ -0,0 +1,49 @@
+package infrastructure
+
+import (
+	"testing"
+
+	"golamv2/internal/domain"
+)
+
+func TestIsSchemeLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"mailto:a@example.com", true},
+		{"MAILTO:a@example.com", true},
+		{"tel:+1-555-0100", true},
+		{"ftp://ftp.example.com/file", true},
+		{"https://example.com/mailto:a", false},
+		{"http://example.com/", false},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := isSchemeLink(tt.link); got != tt.want {
+			t.Errorf("isSchemeLink(%s) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+// Links that need no lookup
+func TestSchemeLinkCheckerSyntax(t *testing.T) {
+	tests := []struct {
+		link string
+		want domain.SchemeLinkStatus
+	}{
+		{"tel:+1 (555) 010-0100", domain.SchemeLinkOK},
+		{"tel:12", domain.SchemeLinkInvalid},
+		{"tel:call-me", domain.SchemeLinkInvalid},
+		{"mailto:", domain.SchemeLinkInvalid},
+		{"mailto:nobody", domain.SchemeLinkInvalid},
+		{"mailto:a@localhost", domain.SchemeLinkInvalid},
+		{"ftp:///file", domain.SchemeLinkInvalid},
+	}
+	checker := NewSchemeLinkChecker()
+	for _, tt := range tests {
+		if got := checker.Check(domain.Link{URL: tt.link}); got.Status != tt.want {
+			t.Errorf("Check(%s) = %s (%s), want %s", tt.link, got.Status, got.Error, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package infrastructure
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+const (
+	// MaxSitemapURLs caps the URLs taken from one sitemap, index files included
+	MaxSitemapURLs = 50000
+
+	// MaxSitemapSize is the largest sitemap read, after decompression (the sitemaps.org limit)
+	MaxSitemapSize = 50 << 20
+
+	// MaxSitemapNesting is how deep sitemap index files are followed
+	MaxSitemapNesting = 3
+
+	// DefaultSitemapPriority is the priority of URLs their sitemap gives none, as sitemaps.org defines it
+	DefaultSitemapPriority = 0.5
+)
+
+// SitemapURL is a page listed in a sitemap
+type SitemapURL struct {
+	Loc      string
+	Priority float64 // From 0.0 to 1.0, relative to the other pages of the site
+}
+
+// sitemapDocument matches both <urlset> sitemaps and <sitemapindex> files
+type sitemapDocument struct {
+	URLs []struct {
+		Loc      string `xml:"loc"`
+		Priority string `xml:"priority"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// SitemapFetcher downloads and parses sitemaps, each sitemap is only fetched once
+type SitemapFetcher struct {
+	mu       sync.Mutex
+	fetched  map[string]bool
+	client   *http.Client
+	identity domain.Identity
+}
+
+// NewSitemapFetcher creates a new sitemap fetcher, sitemaps are requested with identity
+func NewSitemapFetcher(identity domain.Identity) *SitemapFetcher {
+	return &SitemapFetcher{
+		fetched:  make(map[string]bool),
+		identity: identity,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Fetch returns the pages listed in a sitemap with their priority, following sitemap index files
+func (f *SitemapFetcher) Fetch(sitemapURL string) ([]SitemapURL, error) {
+	var urls []SitemapURL
+	err := f.fetch(sitemapURL, 0, &urls)
+	return urls, err
+}
+
+func (f *SitemapFetcher) fetch(sitemapURL string, nesting int, urls *[]SitemapURL) error {
+	if nesting > MaxSitemapNesting || len(*urls) >= MaxSitemapURLs || !f.markFetched(sitemapURL) {
+		return nil
+	}
+
+	doc, err := f.download(sitemapURL)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range doc.URLs {
+		if len(*urls) >= MaxSitemapURLs {
+			return nil
+		}
+		if loc := strings.TrimSpace(entry.Loc); loc != "" {
+			*urls = append(*urls, SitemapURL{Loc: loc, Priority: parseSitemapPriority(entry.Priority)})
+		}
+	}
+
+	// Index files point at more sitemaps, a broken one should not lose the rest
+	for _, entry := range doc.Sitemaps {
+		if loc := strings.TrimSpace(entry.Loc); loc != "" {
+			f.fetch(loc, nesting+1, urls)
+		}
+	}
+
+	return nil
+}
+
+// parseSitemapPriority reads a <priority>, missing or invalid ones get the default
+func parseSitemapPriority(value string) float64 {
+	priority, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || priority < 0 || priority > 1 {
+		return DefaultSitemapPriority
+	}
+	return priority
+}
+
+// download fetches and decodes one sitemap, gzipped or not
+func (f *SitemapFetcher) download(sitemapURL string) (*sitemapDocument, error) {
+	req, err := http.NewRequest("GET", sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	f.identity.Apply(req)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap %s: %v", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch sitemap %s: status %d", sitemapURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxSitemapSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap %s: %v", sitemapURL, err)
+	}
+
+	// .xml.gz files are served as-is, recognise them by the gzip magic bytes
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap %s: %v", sitemapURL, err)
+		}
+		body, err = io.ReadAll(io.LimitReader(reader, MaxSitemapSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap %s: %v", sitemapURL, err)
+		}
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap %s: %v", sitemapURL, err)
+	}
+	return &doc, nil
+}
+
+// markFetched records a sitemap, false when it was already fetched
+func (f *SitemapFetcher) markFetched(sitemapURL string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fetched[sitemapURL] {
+		return false
+	}
+	f.fetched[sitemapURL] = true
+	return true
+}
@@ -0,0 +1,189 @@
+package infrastructure
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"golamv2/internal/domain"
+)
+
+const (
+	// A parameter is volatile once this many of its variants served identical content
+	CollapseMinDuplicates = 3
+	// Observations are wiped when they grow past this, like the extractor caches
+	MaxCollapseObservations = 50000
+)
+
+// paramVariant is the first value of a parameter seen for one URL family, with its page hash
+type paramVariant struct {
+	value string
+	hash  string
+}
+
+// paramStats counts how often changing a parameter changed the page
+type paramStats struct {
+	duplicates int
+	distinct   int
+}
+
+// URLCollapser learns which query parameters never change a page (sort=, sessionid=, ...)
+// from the content hashes of crawled pages, and strips them so URL families collapse into one
+type URLCollapser struct {
+	mu           sync.RWMutex
+	observations map[string]paramVariant           // domain|param|url-without-param -> first variant
+	stats        map[string]map[string]*paramStats // domain -> param -> stats
+	volatile     map[string]map[string]bool        // domain -> learned volatile params
+	collapsed    map[string]int64                  // domain -> URLs collapsed so far
+}
+
+// NewURLCollapser creates an empty collapser
+func NewURLCollapser() *URLCollapser {
+	return &URLCollapser{
+		observations: make(map[string]paramVariant),
+		stats:        make(map[string]map[string]*paramStats),
+		volatile:     make(map[string]map[string]bool),
+		collapsed:    make(map[string]int64),
+	}
+}
+
+// Observe records the content hash of a crawled URL and learns from URLs
+// that differ from it in exactly one query parameter
+func (u *URLCollapser) Observe(rawURL, contentHash string) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" || contentHash == "" {
+		return
+	}
+
+	query := parsed.Query()
+	host := strings.ToLower(parsed.Hostname())
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.observations) > MaxCollapseObservations {
+		u.observations = make(map[string]paramVariant)
+	}
+
+	for param, values := range query {
+		value := strings.Join(values, ",")
+		key := host + "|" + param + "|" + familyKey(parsed, query, param)
+
+		first, seen := u.observations[key]
+		if !seen {
+			u.observations[key] = paramVariant{value: value, hash: contentHash}
+			continue
+		}
+		if first.value == value {
+			continue
+		}
+
+		stats := u.paramStats(host, param)
+		if first.hash == contentHash {
+			stats.duplicates++
+		} else {
+			stats.distinct++
+		}
+
+		// One real difference is enough to never treat the parameter as volatile
+		if stats.distinct == 0 && stats.duplicates >= CollapseMinDuplicates {
+			if u.volatile[host] == nil {
+				u.volatile[host] = make(map[string]bool)
+			}
+			u.volatile[host][param] = true
+		} else if stats.distinct > 0 && u.volatile[host][param] {
+			delete(u.volatile[host], param)
+		}
+	}
+}
+
+// paramStats returns the stats of a parameter, the caller holds the lock
+func (u *URLCollapser) paramStats(host, param string) *paramStats {
+	params := u.stats[host]
+	if params == nil {
+		params = make(map[string]*paramStats)
+		u.stats[host] = params
+	}
+
+	stats := params[param]
+	if stats == nil {
+		stats = &paramStats{}
+		params[param] = stats
+	}
+	return stats
+}
+
+// Canonical strips the learned volatile parameters from a URL, URLs of one family share it
+func (u *URLCollapser) Canonical(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" {
+		return rawURL
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+
+	u.mu.RLock()
+	volatile := u.volatile[host]
+	if len(volatile) == 0 {
+		u.mu.RUnlock()
+		return rawURL
+	}
+
+	query := parsed.Query()
+	removed := false
+	for param := range query {
+		if volatile[param] {
+			query.Del(param)
+			removed = true
+		}
+	}
+	u.mu.RUnlock()
+
+	if !removed {
+		return rawURL
+	}
+
+	u.mu.Lock()
+	u.collapsed[host]++
+	u.mu.Unlock()
+
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// Rules reports the learned volatile parameters per domain
+func (u *URLCollapser) Rules() []domain.CollapseRule {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	var rules []domain.CollapseRule
+	for host, params := range u.volatile {
+		if len(params) == 0 {
+			continue
+		}
+
+		rule := domain.CollapseRule{Domain: host, Collapsed: u.collapsed[host]}
+		for param := range params {
+			rule.Params = append(rule.Params, param)
+		}
+		sort.Strings(rule.Params)
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Domain < rules[j].Domain
+	})
+	return rules
+}
+
+// familyKey is the URL without one parameter, with the rest of the query sorted
+func familyKey(parsed *url.URL, query url.Values, skip string) string {
+	rest := url.Values{}
+	for param, values := range query {
+		if param != skip {
+			rest[param] = values
+		}
+	}
+	return parsed.Host + parsed.Path + "?" + rest.Encode()
+}
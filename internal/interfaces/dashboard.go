@@ -1,17 +1,24 @@
 package interfaces
 
 import (
+	"crypto/subtle"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golamv2/internal/domain"
+	"golamv2/pkg/export"
+	"golamv2/pkg/logging"
 	"golamv2/pkg/metrics"
 
 	"github.com/gorilla/mux"
@@ -20,12 +27,27 @@ import (
 
 // Dashboard implements the web interface for monitoring
 type Dashboard struct {
-	metrics  *metrics.MetricsCollector
-	storage  domain.Storage
-	urlQueue domain.URLQueue
-	port     int
-	upgrader websocket.Upgrader
-	clients  map[*websocket.Conn]bool
+	mu         sync.RWMutex
+	metrics    *metrics.MetricsCollector
+	storage    domain.Storage
+	urlQueue   domain.URLQueue
+	port       int
+	upgrader   websocket.Upgrader
+	clientsMu  sync.Mutex
+	clients    map[*websocket.Conn]bool
+	runHistory func() []domain.CrawlRun
+	rules      func() []domain.CollapseRule
+	quarantine func() []string                             // Hosts quarantined for bot challenges
+	submit     func(job domain.CrawlJob) ([]string, error) // Queues crawl job seeds, only set by serve
+	reload     func() error                                // Re-reads the config file of the crawl
+	control    domain.CrawlControl                         // Pauses, resizes and stops the crawl
+	// Needed by POST /api/control, without one only local clients may control the crawl
+	controlToken string
+	// Pushed to the WebSocket clients as they happen, see SetEvents
+	events      <-chan domain.CrawlEvent
+	unsubscribe func()
+	// Where rendering mode saved page screenshots
+	screenshotDir string
 }
 
 // NewDashboard creates a new dashboard
@@ -44,6 +66,121 @@ func NewDashboard(metrics *metrics.MetricsCollector, storage domain.Storage, url
 	}
 }
 
+// Attach points the dashboard at a new crawl, used when one process runs several crawls.
+// A dashboard created without one serves the crawl data once a crawl is attached
+func (d *Dashboard) Attach(metrics *metrics.MetricsCollector, storage domain.Storage, urlQueue domain.URLQueue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.metrics = metrics
+	d.storage = storage
+	d.urlQueue = urlQueue
+}
+
+// Detach lets go of the crawl attached, until the next one the crawl data is unavailable
+func (d *Dashboard) Detach() {
+	d.mu.Lock()
+	unsubscribe := d.unsubscribe
+	d.metrics, d.storage, d.urlQueue = nil, nil, nil
+	d.events, d.unsubscribe = nil, nil
+	d.rules, d.quarantine, d.control = nil, nil, nil
+	d.screenshotDir = ""
+	d.mu.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+}
+
+// SetRunHistory sets the provider behind /api/runs
+func (d *Dashboard) SetRunHistory(history func() []domain.CrawlRun) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.runHistory = history
+}
+
+// SetCollapseRules sets the provider behind /api/collapse-rules
+func (d *Dashboard) SetCollapseRules(rules func() []domain.CollapseRule) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rules = rules
+}
+
+// SetQuarantinedHosts sets the provider behind /api/quarantined
+func (d *Dashboard) SetQuarantinedHosts(hosts func() []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.quarantine = hosts
+}
+
+// SetJobSubmitter sets the function behind POST /api/jobs
+func (d *Dashboard) SetJobSubmitter(submit func(job domain.CrawlJob) ([]string, error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.submit = submit
+}
+
+// SetReloader sets the function behind POST /api/reload
+func (d *Dashboard) SetReloader(reload func() error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reload = reload
+}
+
+// SetController sets the crawl controlled by /api/control
+func (d *Dashboard) SetController(control domain.CrawlControl) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.control = control
+}
+
+// SetControlToken makes POST /api/control need an "Authorization: Bearer <token>" header
+func (d *Dashboard) SetControlToken(token string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.controlToken = token
+}
+
+// SetEvents subscribes to the events of a crawl, they are pushed to the WebSocket clients
+// next to the metrics. The events of the crawl attached before are not pushed anymore
+func (d *Dashboard) SetEvents(source domain.EventSource) {
+	events, unsubscribe := source.Subscribe()
+
+	d.mu.Lock()
+	previous := d.unsubscribe
+	d.events, d.unsubscribe = events, unsubscribe
+	d.mu.Unlock()
+
+	if previous != nil {
+		previous()
+	}
+}
+
+// SetScreenshotDir sets the directory screenshots are served from
+func (d *Dashboard) SetScreenshotDir(dir string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.screenshotDir = dir
+}
+
+// backend returns the components of the currently attached crawl
+func (d *Dashboard) backend() (*metrics.MetricsCollector, domain.Storage, domain.URLQueue) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.metrics, d.storage, d.urlQueue
+}
+
+// attached answers 503 instead of calling handler while no crawl is attached
+func (d *Dashboard) attached(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, storage, _ := d.backend(); storage == nil {
+			http.Error(w, "No crawl is running, the data comes back with the next one", http.StatusServiceUnavailable)
+			return
+		}
+		handler(w, r)
+	}
+}
+
 // Start starts the dashboard web server //Works but not the display---problem with JS
 func (d *Dashboard) Start() {
 	r := mux.NewRouter()
@@ -54,22 +191,35 @@ func (d *Dashboard) Start() {
 	// API routes
 	r.HandleFunc("/api/metrics", d.handleMetrics).Methods("GET")
 	r.HandleFunc("/api/ws", d.handleWebSocket)
-	r.HandleFunc("/api/results", d.handleResults).Methods("GET")
-	r.HandleFunc("/api/add-urls", d.handleAddURLs).Methods("POST")
-	r.HandleFunc("/api/db-view", d.handleDBView).Methods("GET") // New route for database view
+	r.HandleFunc("/api/results", d.attached(d.handleResults)).Methods("GET")
+	r.HandleFunc("/api/add-urls", d.attached(d.handleAddURLs)).Methods("POST")
+	r.HandleFunc("/api/db-view", d.attached(d.handleDBView)).Methods("GET") // New route for database view
+	r.HandleFunc("/api/search", d.attached(d.handleSearch)).Methods("GET")
+	r.HandleFunc("/api/db-stats", d.attached(d.handleDBStats)).Methods("GET")
+	r.HandleFunc("/api/runs", d.handleRuns).Methods("GET")
+	r.HandleFunc("/api/jobs", d.handleJobs).Methods("POST")
+	r.HandleFunc("/api/reload", d.handleReload).Methods("POST")
+	r.HandleFunc("/api/control", d.handleControl).Methods("GET")
+	r.HandleFunc("/api/control/{action}", d.handleControl).Methods("POST")
+	r.HandleFunc("/api/collapse-rules", d.handleCollapseRules).Methods("GET")
+	r.HandleFunc("/api/quarantined", d.handleQuarantined).Methods("GET")
+	r.HandleFunc("/api/duplicates", d.attached(d.handleDuplicates)).Methods("GET")
+	r.HandleFunc("/api/slow", d.attached(d.handleSlowRequests)).Methods("GET")
+	r.HandleFunc("/api/report/deadlinks", d.attached(d.handleDeadLinkReport)).Methods("GET")
+	r.HandleFunc("/screenshots/{name}", d.handleScreenshot).Methods("GET")
 
 	// Main dashboard pages
 	r.HandleFunc("/", d.handleDashboard).Methods("GET")
 	r.HandleFunc("/db", d.handleDBDashboard).Methods("GET") // New route for database dashboard
 
-	// Start broadcasting metrics to WebSocket clients
-	go d.broadcastMetrics()
+	// Start broadcasting metrics and events to WebSocket clients
+	go d.broadcast()
 
 	addr := fmt.Sprintf(":%d", d.port)
-	log.Printf("Dashboard server starting on http://localhost%s", addr)
+	logging.Infof("Dashboard server starting on http://localhost%s", addr)
 
 	if err := http.ListenAndServe(addr, r); err != nil {
-		log.Printf("Dashboard server error: %v", err)
+		logging.Errorf("Dashboard server error: %v", err)
 	}
 }
 
@@ -493,6 +643,9 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
             <button class="tab-button" onclick="switchTab('results')">
                  Results
             </button>
+            <button class="tab-button" onclick="switchTab('duplicates')">
+                 Duplicates
+            </button>
             <a href="/db" style="text-decoration: none;" class="tab-button">
                 🗄️ Database Viewer
             </a>
@@ -568,6 +721,46 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
                     <span class="metric-label"> Dead Domains</span>
                     <span class="metric-value error" id="dead-domains">0</span>
                 </div>
+                <div class="metric">
+                    <span class="metric-label"> Dead Domain Causes</span>
+                    <span class="metric-value" id="dead-domain-causes">-</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Available / Expiring Domains</span>
+                    <span class="metric-value success" id="claimable-domains">0 / 0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Accessibility Issues / Pages Audited</span>
+                    <span class="metric-value warning" id="a11y-issues">0 / 0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Domains Alive Only via Fallback</span>
+                    <span class="metric-value" id="fallback-domains">0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Link Checks Dropped</span>
+                    <span class="metric-value" id="link-checks-dropped">0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Link Checks Deduped</span>
+                    <span class="metric-value" id="link-checks-deduped">0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Hidden Links (skipped)</span>
+                    <span class="metric-value" id="hidden-links">0 (0)</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Noindex Pages / Nofollow Pages / Nofollow Links</span>
+                    <span class="metric-value" id="robots-skipped">0 / 0 / 0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Broken ftp/mailto/tel</span>
+                    <span class="metric-value" id="scheme-links-broken">0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label"> Duplicate Pages (near)</span>
+                    <span class="metric-value" id="duplicate-pages">0 (0)</span>
+                </div>
             </div>
             
             <!-- Performance Card -->
@@ -585,6 +778,63 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
                     <span class="metric-label">Avg Processing Time</span>
                     <span class="metric-value" id="avg-processing-time">0ms</span>
                 </div>
+                <div class="metric">
+                    <span class="metric-label">Robots Mode</span>
+                    <span class="metric-value" id="robots-mode">standard</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Retries / Dead Letters</span>
+                    <span class="metric-value" id="retries">0 / 0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Slow Fetches</span>
+                    <span class="metric-value warning" id="slow-requests">0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Queue Spilled / Refilled</span>
+                    <span class="metric-value" id="queue-flow">0 / 0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Queue Refills (avg)</span>
+                    <span class="metric-value" id="queue-refills">0 (0 ms)</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Challenged / Quarantined</span>
+                    <span class="metric-value" id="challenges">0 / 0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Fetches Saved by Cache</span>
+                    <span class="metric-value" id="response-cache-hits">0</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Bloom Filter Layers</span>
+                    <span class="metric-value" id="bloom-layers">1</span>
+                </div>
+            </div>
+            
+            <!-- Network Card -->
+            <div class="card">
+                <h3>🌐 Network</h3>
+                <div class="metric">
+                    <span class="metric-label">DNS / Connect / TLS</span>
+                    <span class="metric-value" id="net-setup">0 / 0 / 0 ms</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Time to First Byte</span>
+                    <span class="metric-value" id="net-ttfb">0 ms</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Download</span>
+                    <span class="metric-value" id="net-download">0 ms</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Extraction</span>
+                    <span class="metric-value" id="net-extract">0 ms</span>
+                </div>
+                <div class="metric">
+                    <span class="metric-label">Connections Reused</span>
+                    <span class="metric-value" id="net-reuse">0%</span>
+                </div>
             </div>
             
             <!-- Memory Breakdown Card -->
@@ -696,6 +946,9 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
                         <tbody id="results-tbody">
                         </tbody>
                     </table>
+                    <button id="results-more" class="btn btn-secondary" style="display: none;" onclick="loadResults(true)">
+                         Load more
+                    </button>
                 </div>
                 <div id="results-empty" class="no-results" style="display: none;">
                     No results found matching your criteria.
@@ -703,6 +956,49 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
             </div>
         </div>
         
+        <!-- Duplicates Tab -->
+        <div id="duplicates" class="tab-content">
+            <div class="results-controls">
+                <div class="filter-group">
+                    <label for="duplicate-kind">Shared:</label>
+                    <select id="duplicate-kind" onchange="loadDuplicates()">
+                        <option value="title">Titles</option>
+                        <option value="description">Descriptions</option>
+                    </select>
+                </div>
+                <div class="filter-group">
+                    <label for="duplicate-domain">Domain:</label>
+                    <input type="text" id="duplicate-domain" placeholder="all domains" onchange="loadDuplicates()">
+                </div>
+                <button class="btn btn-primary" onclick="loadDuplicates()">
+                     Refresh
+                </button>
+            </div>
+            
+            <div class="results-table">
+                <div id="duplicates-loading" class="loading">
+                    Loading duplicates...
+                </div>
+                <div id="duplicates-content" style="display: none;">
+                    <table class="table">
+                        <thead>
+                            <tr>
+                                <th>Domain</th>
+                                <th>Value</th>
+                                <th>Pages</th>
+                                <th>URLs</th>
+                            </tr>
+                        </thead>
+                        <tbody id="duplicates-tbody">
+                        </tbody>
+                    </table>
+                </div>
+                <div id="duplicates-empty" class="no-results" style="display: none;">
+                    No pages share a title or description.
+                </div>
+            </div>
+        </div>
+        
         <!-- Database Tab -->
         <div id="db" class="tab-content">
             <h3>🗄️ Database Information</h3>
@@ -739,10 +1035,42 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
         const ws = new WebSocket('ws://localhost:' + window.location.port + '/api/ws');
         
         ws.onmessage = function(event) {
-            const metrics = JSON.parse(event.data);
-            updateMetrics(metrics);
+            const message = JSON.parse(event.data);
+            if (message.event) {
+                handleCrawlEvent(message);
+            } else {
+                updateMetrics(message);
+            }
+        };
+        
+        // Result rows of the types each Results filter shows
+        const liveResultTypes = {
+            emails: ['email'],
+            keywords: ['keyword'],
+            dead_links: ['dead_link', 'dead_domain']
         };
         
+        // Stored results show up at the top of the Results tab once it was loaded
+        function handleCrawlEvent(message) {
+            if (!message.rows || document.getElementById('results-content').style.display !== 'block') {
+                return;
+            }
+            
+            const type = document.getElementById('result-type').value;
+            const tbody = document.getElementById('results-tbody');
+            // Pages loaded with "Load more" stay, only the live rows on top of them are trimmed
+            const limit = Math.max(parseInt(document.getElementById('result-limit').value), resultRowsLoaded);
+            message.rows.forEach(result => {
+                if (type !== 'all' && !(liveResultTypes[type] || []).includes(result.type)) {
+                    return;
+                }
+                tbody.insertBefore(resultRow(result), tbody.firstChild);
+            });
+            while (tbody.children.length > limit) {
+                tbody.removeChild(tbody.lastChild);
+            }
+        }
+        
         ws.onerror = function(error) {
             console.error('WebSocket error:', error);
             document.getElementById('status').textContent = 'Disconnected';
@@ -778,6 +1106,8 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
             // Load data for specific tabs
             if (tabName === 'results') {
                 loadResults();
+            } else if (tabName === 'duplicates') {
+                loadDuplicates();
             } else if (tabName === 'db') {
                 loadDBInfo();
             }
@@ -889,24 +1219,40 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
         }
         
         // Results Management
-        async function loadResults() {
+        let resultsCursor = '';
+        let resultRowsLoaded = 0;
+        
+        // loadResults shows the first page of results, or appends the next one when more is set
+        async function loadResults(more) {
             const type = document.getElementById('result-type').value;
             const limit = document.getElementById('result-limit').value;
-            
-            document.getElementById('results-loading').style.display = 'block';
-            document.getElementById('results-content').style.display = 'none';
-            document.getElementById('results-empty').style.display = 'none';
+            let url = '/api/results?type=' + type + '&limit=' + limit;
+            if (more === true) {
+                url += '&cursor=' + encodeURIComponent(resultsCursor);
+            } else {
+                resultsCursor = '';
+                document.getElementById('results-loading').style.display = 'block';
+                document.getElementById('results-content').style.display = 'none';
+                document.getElementById('results-empty').style.display = 'none';
+            }
             
             try {
-                const response = await fetch('/api/results?type=' + type + '&limit=' + limit);
-                const results = await response.json();
+                const response = await fetch(url);
+                const results = (await response.json()) || [];
+                resultsCursor = response.headers.get('X-Next-Cursor') || '';
+                document.getElementById('results-more').style.display = resultsCursor ? 'inline-block' : 'none';
                 
                 document.getElementById('results-loading').style.display = 'none';
                 
-                if (results.length === 0) {
+                if (more === true) {
+                    const tbody = document.getElementById('results-tbody');
+                    results.forEach(result => tbody.appendChild(resultRow(result)));
+                    resultRowsLoaded = tbody.children.length;
+                } else if (results.length === 0) {
                     document.getElementById('results-empty').style.display = 'block';
                 } else {
                     displayResults(results);
+                    resultRowsLoaded = results.length;
                     document.getElementById('results-content').style.display = 'block';
                 }
             } catch (error) {
@@ -921,16 +1267,54 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
             tbody.innerHTML = '';
             
             results.forEach(result => {
-                const row = document.createElement('tr');
-                row.innerHTML = 
-                    '<td><span class="status-badge status-success">' + result.type + '</span></td>' +
-                    '<td class="url-cell"><a href="' + result.source_url + '" target="_blank">' + result.source_url + '</a></td>' +
-                    '<td>' + result.data + '</td>' +
-                    '<td>' + new Date(result.found_at).toLocaleString() + '</td>';
-                tbody.appendChild(row);
+                tbody.appendChild(resultRow(result));
             });
         }
         
+        // Crawled text goes in as text, never as markup
+        function resultRow(result) {
+            const row = document.createElement('tr');
+            row.appendChild(tableCell(badge('status-success', result.type)));
+            const source = tableCell(newTabLink(result.source_url, result.source_url));
+            source.className = 'url-cell';
+            row.appendChild(source);
+            const data = tableCell(result.data);
+            if (result.cause) {
+                const cause = document.createElement('span');
+                cause.className = 'metric-label';
+                cause.textContent = '(' + result.cause + ')';
+                data.append(' ', cause);
+            }
+            if (result.screenshot) {
+                data.append(' ', newTabLink(result.screenshot, '[screenshot]'));
+            }
+            row.appendChild(data);
+            row.appendChild(tableCell(new Date(result.found_at).toLocaleString()));
+            return row;
+        }
+        
+        // tableCell wraps nodes in a cell, strings become text nodes
+        function tableCell(...nodes) {
+            const cell = document.createElement('td');
+            cell.append(...nodes);
+            return cell;
+        }
+        
+        function badge(className, text) {
+            const span = document.createElement('span');
+            span.className = 'status-badge ' + className;
+            span.textContent = text;
+            return span;
+        }
+        
+        function newTabLink(href, text) {
+            const link = document.createElement('a');
+            link.href = href;
+            link.target = '_blank';
+            link.textContent = text;
+            return link;
+        }
+        
         function exportResults() {
             const type = document.getElementById('result-type').value;
             const limit = document.getElementById('result-limit').value;
@@ -945,6 +1329,57 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
             document.body.removeChild(a);
         }
         
+        // Titles and descriptions shared by several pages of a domain
+        async function loadDuplicates() {
+            const kind = document.getElementById('duplicate-kind').value;
+            const host = document.getElementById('duplicate-domain').value.trim();
+            
+            document.getElementById('duplicates-loading').style.display = 'block';
+            document.getElementById('duplicates-content').style.display = 'none';
+            document.getElementById('duplicates-empty').style.display = 'none';
+            
+            try {
+                const response = await fetch('/api/duplicates?kind=' + kind + '&domain=' + encodeURIComponent(host));
+                const groups = await response.json();
+                
+                document.getElementById('duplicates-loading').style.display = 'none';
+                
+                if (groups.length === 0) {
+                    document.getElementById('duplicates-empty').style.display = 'block';
+                    return;
+                }
+                
+                // Page text goes in as text, never as markup
+                const tbody = document.getElementById('duplicates-tbody');
+                tbody.innerHTML = '';
+                groups.forEach(group => {
+                    const row = document.createElement('tr');
+                    [group.domain, group.value, group.urls.length].forEach(text => {
+                        const cell = document.createElement('td');
+                        cell.textContent = text;
+                        row.appendChild(cell);
+                    });
+                    const urls = document.createElement('td');
+                    urls.className = 'url-cell';
+                    group.urls.forEach(url => {
+                        const link = document.createElement('a');
+                        link.href = url;
+                        link.target = '_blank';
+                        link.textContent = url;
+                        urls.appendChild(link);
+                        urls.appendChild(document.createElement('br'));
+                    });
+                    row.appendChild(urls);
+                    tbody.appendChild(row);
+                });
+                document.getElementById('duplicates-content').style.display = 'block';
+            } catch (error) {
+                console.error('Error loading duplicates:', error);
+                document.getElementById('duplicates-loading').style.display = 'none';
+                document.getElementById('duplicates-empty').style.display = 'block';
+            }
+        }
+        
         // New function to load database information
         async function loadDBInfo() {
             document.getElementById('db-loading').style.display = 'block';
@@ -977,10 +1412,9 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
             
             dbInfo.forEach(info => {
                 const row = document.createElement('tr');
-                row.innerHTML = 
-                    '<td>' + info.collection + '</td>' +
-                    '<td>' + info.document_count.toLocaleString() + '</td>' +
-                    '<td>' + info.size_mb.toFixed(1) + ' MB</td>';
+                row.appendChild(tableCell(info.collection));
+                row.appendChild(tableCell(info.document_count.toLocaleString()));
+                row.appendChild(tableCell(info.size_mb.toFixed(1) + ' MB'));
                 tbody.appendChild(row);
             });
         }
@@ -1011,12 +1445,47 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
             document.getElementById('keywords-found').textContent = metrics.keywords_found.toLocaleString();
             document.getElementById('dead-links').textContent = metrics.dead_links_found.toLocaleString();
             document.getElementById('dead-domains').textContent = metrics.dead_domains_found.toLocaleString();
+            const causes = [
+                ['nxdomain', metrics.dead_domains_nxdomain], ['dns', metrics.dead_domains_dns],
+                ['refused', metrics.dead_domains_refused], ['tls', metrics.dead_domains_tls],
+                ['timeout', metrics.dead_domains_timeout], ['other', metrics.dead_domains_other]
+            ].filter(cause => cause[1] > 0).map(cause => cause[0] + ' ' + cause[1]);
+            document.getElementById('dead-domain-causes').textContent = causes.length ? causes.join(' · ') : '-';
+            document.getElementById('claimable-domains').textContent = (metrics.dead_domains_available || 0).toLocaleString() + ' / ' + (metrics.dead_domains_expiring || 0).toLocaleString();
+            document.getElementById('a11y-issues').textContent = (metrics.accessibility_issues || 0).toLocaleString() + ' / ' + (metrics.pages_audited || 0).toLocaleString();
+            document.getElementById('fallback-domains').textContent = (metrics.domains_alive_via_fallback || 0).toLocaleString();
+            document.getElementById('link-checks-dropped').textContent = (metrics.dead_link_checks_dropped || 0).toLocaleString();
+            document.getElementById('link-checks-deduped').textContent = (metrics.link_checks_deduped || 0).toLocaleString();
+            document.getElementById('hidden-links').textContent = (metrics.hidden_links_found || 0).toLocaleString() + ' (' + (metrics.hidden_links_skipped || 0).toLocaleString() + ')';
+            document.getElementById('robots-skipped').textContent = (metrics.pages_noindex || 0).toLocaleString() + ' / ' + (metrics.pages_nofollow || 0).toLocaleString() + ' / ' + (metrics.links_nofollow || 0).toLocaleString();
+            document.getElementById('scheme-links-broken').textContent = (metrics.scheme_links_broken || 0).toLocaleString();
+            document.getElementById('duplicate-pages').textContent = (metrics.duplicate_pages || 0).toLocaleString() + ' (' + (metrics.near_duplicate_pages || 0).toLocaleString() + ')';
             
             // Performance
             const successRate = metrics.urls_processed > 0 ? 
                 ((metrics.urls_processed - metrics.errors) / metrics.urls_processed * 100).toFixed(1) : 100;
             document.getElementById('success-rate').textContent = successRate + '%';
+            document.getElementById('slow-requests').textContent = (metrics.slow_requests || 0).toLocaleString();
             document.getElementById('total-errors').textContent = metrics.errors.toLocaleString();
+            document.getElementById('robots-mode').textContent = metrics.robots_mode || 'standard';
+            document.getElementById('retries').textContent = (metrics.urls_retried || 0).toLocaleString() + ' / ' + (metrics.urls_dead_lettered || 0).toLocaleString();
+            const flow = metrics.queue_flow || {};
+            document.getElementById('queue-flow').textContent = (flow.urls_spilled || 0).toLocaleString() + ' / ' + (flow.urls_refilled || 0).toLocaleString();
+            document.getElementById('queue-refills').textContent = (flow.refill_batches || 0).toLocaleString() + ' (' + (flow.avg_refill_latency_ms || 0).toFixed(1) + ' ms)';
+            document.getElementById('challenges').textContent = (metrics.pages_challenged || 0).toLocaleString() + ' / ' + (metrics.domains_quarantined || 0).toLocaleString();
+            document.getElementById('response-cache-hits').textContent = (metrics.response_cache_hits || 0).toLocaleString();
+            const bloomLayers = document.getElementById('bloom-layers');
+            bloomLayers.textContent = metrics.bloom_filter_layers || 1;
+            bloomLayers.className = 'metric-value' + (metrics.bloom_filter_layers > 1 ? ' error' : '');
+            
+            // Network
+            const net = metrics.transport || {};
+            const ms = value => (value || 0).toFixed(1);
+            document.getElementById('net-setup').textContent = ms(net.avg_dns_ms) + ' / ' + ms(net.avg_connect_ms) + ' / ' + ms(net.avg_tls_ms) + ' ms';
+            document.getElementById('net-ttfb').textContent = ms(net.avg_ttfb_ms) + ' ms';
+            document.getElementById('net-download').textContent = ms(net.avg_download_ms) + ' ms';
+            document.getElementById('net-extract').textContent = ms(net.avg_extract_ms) + ' ms';
+            document.getElementById('net-reuse').textContent = ((net.conn_reuse_rate || 0) * 100).toFixed(0) + '% of ' + (net.fetches || 0).toLocaleString();
             
             // Memory Breakdown
             if (metrics.memory_breakdown) {
@@ -1065,7 +1534,10 @@ func (d *Dashboard) handleDashboard(w http.ResponseWriter, r *http.Request) {
 
 // handleMetrics serves current metrics as JSON
 func (d *Dashboard) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	metrics := d.metrics.GetMetrics()
+	metrics := &domain.CrawlMetrics{}
+	if collector, _, _ := d.backend(); collector != nil {
+		metrics = collector.GetMetrics()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
@@ -1075,17 +1547,21 @@ func (d *Dashboard) handleMetrics(w http.ResponseWriter, r *http.Request) {
 func (d *Dashboard) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := d.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		logging.Warnf("WebSocket upgrade error: %v", err)
 		return
 	}
 	defer conn.Close()
 
 	// Register client
+	d.clientsMu.Lock()
 	d.clients[conn] = true
+	d.clientsMu.Unlock()
 
 	// Remove client when connection closes
 	defer func() {
+		d.clientsMu.Lock()
 		delete(d.clients, conn)
+		d.clientsMu.Unlock()
 	}()
 
 	// Keep connection alive
@@ -1097,37 +1573,185 @@ func (d *Dashboard) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// broadcastMetrics sends metrics to all connected WebSocket clients
-func (d *Dashboard) broadcastMetrics() {
+// broadcast sends the metrics every 2 seconds and the crawl events as they happen to all
+// connected WebSocket clients
+func (d *Dashboard) broadcast() {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		metrics := d.metrics.GetMetrics()
-		data, err := json.Marshal(metrics)
+	for {
+		d.mu.RLock()
+		events := d.events
+		d.mu.RUnlock()
+
+		var message interface{}
+		select {
+		case <-ticker.C:
+			collector, _, _ := d.backend()
+			if collector == nil {
+				continue // Between crawls
+			}
+			message = collector.GetMetrics()
+		case event, ok := <-events:
+			if !ok {
+				continue // SetEvents subscribed to another crawl
+			}
+			message = eventMessage(event)
+		}
+
+		data, err := json.Marshal(message)
 		if err != nil {
 			continue
 		}
+		d.send(data)
+	}
+}
+
+// eventMessage is what WebSocket clients get for an event, metrics have no "event" key.
+// Stored results come with the rows /api/results lists for them
+func eventMessage(event domain.CrawlEvent) map[string]interface{} {
+	message := map[string]interface{}{"event": event}
+	if event.Result != nil {
+		message["rows"] = resultRows(*event.Result)
+	}
+	return message
+}
+
+// send writes a message to all connected WebSocket clients, slow ones are dropped
+func (d *Dashboard) send(data []byte) {
+	d.clientsMu.Lock()
+	defer d.clientsMu.Unlock()
+
+	for client := range d.clients {
+		client.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := client.WriteMessage(websocket.TextMessage, data); err != nil {
+			// Remove disconnected client
+			delete(d.clients, client)
+			client.Close()
+		}
+	}
+}
 
-		// Send to all connected clients
-		for client := range d.clients {
-			err := client.WriteMessage(websocket.TextMessage, data)
-			if err != nil {
-				// Remove disconnected client
-				delete(d.clients, client)
-				client.Close()
+// resultRows are the rows of the results table for one result: one per finding, or the
+// page itself when nothing was found
+func resultRows(result domain.CrawlResult) []map[string]interface{} {
+	var rows []map[string]interface{}
+
+	// Create entries based on what was found in this result
+	if len(result.Emails) > 0 {
+		for _, email := range result.Emails {
+			rows = append(rows, map[string]interface{}{
+				"type":       "email",
+				"source_url": result.URL,
+				"data":       email,
+				"found_at":   result.ProcessedAt,
+			})
+		}
+	}
+
+	if len(result.Keywords) > 0 {
+		for keyword, count := range result.Keywords {
+			rows = append(rows, map[string]interface{}{
+				"type":       "keyword",
+				"source_url": result.URL,
+				"data":       fmt.Sprintf("%s (found %d times)", keyword, count),
+				"found_at":   result.ProcessedAt,
+			})
+		}
+	}
+
+	if len(result.DeadLinks) > 0 {
+		for _, deadLink := range result.DeadLinks {
+			rows = append(rows, map[string]interface{}{
+				"type":       "dead_link",
+				"source_url": result.URL,
+				"data":       deadLink,
+				"found_at":   result.ProcessedAt,
+			})
+		}
+	}
+
+	if len(result.DeadDomains) > 0 {
+		details := make(map[string]domain.DeadDomain, len(result.DeadDomainDetails))
+		for _, detail := range result.DeadDomainDetails {
+			details[detail.Domain] = detail
+		}
+		for _, deadDomain := range result.DeadDomains {
+			entry := map[string]interface{}{
+				"type":       "dead_domain",
+				"source_url": result.URL,
+				"data":       deadDomain,
+				"found_at":   result.ProcessedAt,
+			}
+			if detail, ok := details[deadDomain]; ok {
+				entry["cause"] = detail.Cause
+				if detail.Registration != nil {
+					entry["registration"] = detail.Registration.Status
+					if detail.Registration.ExpiresAt != nil {
+						entry["expires_at"] = detail.Registration.ExpiresAt
+					}
+				}
 			}
+			if unicode := domain.UnicodeHost(deadDomain); unicode != deadDomain {
+				entry["unicode"] = unicode
+			}
+			rows = append(rows, entry)
+		}
+	}
+
+	// If no specific findings, show the crawl result itself
+	if len(result.Emails) == 0 && len(result.Keywords) == 0 &&
+		len(result.DeadLinks) == 0 && len(result.DeadDomains) == 0 {
+		status := "success"
+		if result.Error != "" {
+			status = "error"
+		}
+		rows = append(rows, map[string]interface{}{
+			"type":       status,
+			"source_url": result.URL,
+			"data":       fmt.Sprintf("Status: %d, Title: %s", result.StatusCode, result.Title),
+			"found_at":   result.ProcessedAt,
+		})
+	}
+
+	// Every row of a rendered page links to its screenshot
+	if result.Screenshot != "" {
+		for _, entry := range rows {
+			entry["screenshot"] = "/screenshots/" + result.Screenshot
 		}
 	}
+	if len(result.Labels) > 0 {
+		for _, entry := range rows {
+			entry["labels"] = result.Labels
+		}
+	}
+
+	return rows
 }
 
 // handleResults serves the results API endpoint
 func (d *Dashboard) handleResults(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	// Get query parameters
 	resultType := r.URL.Query().Get("type")
 	limitStr := r.URL.Query().Get("limit")
+	label := r.URL.Query().Get("label")
+	registration := r.URL.Query().Get("registration") // Only dead domains with this RDAP status
+	format := r.URL.Query().Get("format")             // json (default), csv or ndjson
+	cursor := r.URL.Query().Get("cursor")             // X-Next-Cursor of the previous page
+
+	switch format {
+	case "", "json", "csv", "ndjson":
+	default:
+		http.Error(w, "format must be json, csv or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	// Only results processed within from and to
+	timeRange, err := domain.ParseTimeRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Default values
 	if resultType == "" {
@@ -1141,92 +1765,127 @@ func (d *Dashboard) handleResults(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get results from storage
+	_, storage, _ := d.backend()
 	var results []domain.CrawlResult
-	var err error
-
-	switch resultType {
-	case "emails":
-		results, err = d.storage.GetResults(domain.ModeEmail, limit)
-	case "keywords":
-		results, err = d.storage.GetResults(domain.ModeKeywords, limit)
-	case "dead_links":
-		results, err = d.storage.GetResults(domain.ModeDomains, limit)
-	case "all":
-		results, err = d.storage.GetResults(domain.ModeAll, limit)
+	var next string
+
+	switch {
+	case !timeRange.IsZero():
+		results, next, err = storage.GetResultsByTimeRange(timeRange, cursor, limit)
+	case resultType == "emails":
+		results, next, err = storage.GetResults(domain.ModeEmail, cursor, limit)
+	case resultType == "keywords":
+		results, next, err = storage.GetResults(domain.ModeKeywords, cursor, limit)
+	case resultType == "dead_links":
+		results, next, err = storage.GetResults(domain.ModeDomains, cursor, limit)
 	default:
-		results, err = d.storage.GetResults(domain.ModeAll, limit)
+		results, next, err = storage.GetResults(domain.ModeAll, cursor, limit)
+	}
+
+	// Only results tagged with the label, filtered while reading when the storage can
+	if label != "" && err == nil {
+		if labeled, ok := storage.(domain.LabeledResults); ok && timeRange.IsZero() {
+			results, next, err = labeled.GetLabeledResults(label, cursor, limit)
+		} else {
+			var filtered []domain.CrawlResult
+			for _, result := range results {
+				if result.HasLabel(label) {
+					filtered = append(filtered, result)
+				}
+			}
+			results = filtered
+		}
 	}
 
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor, pass the X-Next-Cursor of the previous page", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error fetching results: %v", err), http.StatusInternalServerError)
 		return
 	}
+	// The cursor of the next page, absent on the last one
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
 
 	// Transform results for frontend
 	var responseResults []map[string]interface{}
 	for _, result := range results {
-		// Create entries based on what was found in this result
-		if len(result.Emails) > 0 {
-			for _, email := range result.Emails {
-				responseResults = append(responseResults, map[string]interface{}{
-					"type":       "email",
-					"source_url": result.URL,
-					"data":       email,
-					"found_at":   result.ProcessedAt,
-				})
-			}
-		}
+		responseResults = append(responseResults, resultRows(result)...)
+	}
 
-		if len(result.Keywords) > 0 {
-			for keyword, count := range result.Keywords {
-				responseResults = append(responseResults, map[string]interface{}{
-					"type":       "keyword",
-					"source_url": result.URL,
-					"data":       fmt.Sprintf("%s (found %d times)", keyword, count),
-					"found_at":   result.ProcessedAt,
-				})
+	if registration != "" {
+		var claimable []map[string]interface{}
+		for _, entry := range responseResults {
+			if status, ok := entry["registration"].(domain.RegistrationStatus); ok && string(status) == registration {
+				claimable = append(claimable, entry)
 			}
 		}
+		responseResults = claimable
+	}
 
-		if len(result.DeadLinks) > 0 {
-			for _, deadLink := range result.DeadLinks {
-				responseResults = append(responseResults, map[string]interface{}{
-					"type":       "dead_link",
-					"source_url": result.URL,
-					"data":       deadLink,
-					"found_at":   result.ProcessedAt,
-				})
-			}
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="crawler-results-%s.csv"`, resultType))
+		writeResultsCSV(w, responseResults)
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		for _, entry := range responseResults {
+			encoder.Encode(entry)
 		}
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(responseResults)
+	}
+}
 
-		if len(result.DeadDomains) > 0 {
-			for _, deadDomain := range result.DeadDomains {
-				responseResults = append(responseResults, map[string]interface{}{
-					"type":       "dead_domain",
-					"source_url": result.URL,
-					"data":       deadDomain,
-					"found_at":   result.ProcessedAt,
-				})
+// resultColumns are the CSV columns of the results API, the ones an entry lacks stay empty
+var resultColumns = []string{"type", "source_url", "data", "found_at", "labels", "cause", "registration", "expires_at", "unicode", "screenshot"}
+
+// writeResultsCSV writes the entries of handleResults as CSV
+func writeResultsCSV(w http.ResponseWriter, entries []map[string]interface{}) {
+	writer := csv.NewWriter(w)
+	writer.Write(resultColumns)
+
+	row := make([]string, len(resultColumns))
+	for _, entry := range entries {
+		for i, column := range resultColumns {
+			switch value := entry[column].(type) {
+			case nil:
+				row[i] = ""
+			case time.Time:
+				row[i] = value.UTC().Format(time.RFC3339)
+			case *time.Time:
+				row[i] = value.UTC().Format(time.RFC3339)
+			case []string:
+				row[i] = strings.Join(value, " ")
+			default:
+				row[i] = fmt.Sprint(value)
 			}
 		}
+		writer.Write(row)
+	}
+	writer.Flush()
+}
 
-		// If no specific findings, show the crawl result itself
-		if len(result.Emails) == 0 && len(result.Keywords) == 0 &&
-			len(result.DeadLinks) == 0 && len(result.DeadDomains) == 0 {
-			status := "success"
-			if result.Error != "" {
-				status = "error"
-			}
-			responseResults = append(responseResults, map[string]interface{}{
-				"type":       status,
-				"source_url": result.URL,
-				"data":       fmt.Sprintf("Status: %d, Title: %s", result.StatusCode, result.Title),
-				"found_at":   result.ProcessedAt,
-			})
-		}
+// handleScreenshot serves a page screenshot saved in rendering mode
+func (d *Dashboard) handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	d.mu.RLock()
+	dir := d.screenshotDir
+	d.mu.RUnlock()
+
+	name := mux.Vars(r)["name"]
+	if dir == "" || filepath.Base(name) != name || filepath.Ext(name) != ".png" {
+		http.NotFound(w, r)
+		return
 	}
 
-	json.NewEncoder(w).Encode(responseResults)
+	w.Header().Set("Content-Type", "image/png")
+	http.ServeFile(w, r, filepath.Join(dir, name))
 }
 
 // handleAddURLs handles adding new URLs to the crawl queue
@@ -1235,7 +1894,8 @@ func (d *Dashboard) handleAddURLs(w http.ResponseWriter, r *http.Request) {
 
 	// Parse JSON request body
 	var request struct {
-		URLs []string `json:"urls"`
+		URLs   []string `json:"urls"`
+		Labels []string `json:"labels"` // Tag the URLs, the pages found from them and their results
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -1267,6 +1927,7 @@ func (d *Dashboard) handleAddURLs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add valid URLs to queue
+	_, _, urlQueue := d.backend()
 	var addedCount int
 	var errors []string
 
@@ -1276,9 +1937,10 @@ func (d *Dashboard) handleAddURLs(w http.ResponseWriter, r *http.Request) {
 			Depth:     0,
 			Timestamp: time.Now(),
 			Retries:   0,
+			Labels:    request.Labels,
 		}
 
-		if err := d.urlQueue.Push(task); err != nil {
+		if err := urlQueue.Push(task); err != nil {
 			errors = append(errors, fmt.Sprintf("Failed to add %s: %v", validURL, err))
 		} else {
 			addedCount++
@@ -1305,6 +1967,8 @@ func (d *Dashboard) handleDBView(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
 	resultType := r.URL.Query().Get("type")
 	limitStr := r.URL.Query().Get("limit")
+	label := r.URL.Query().Get("label")
+	search := r.URL.Query().Get("q") // Searched in the storage, see handleSearch
 
 	// Default values
 	if resultType == "" {
@@ -1318,20 +1982,49 @@ func (d *Dashboard) handleDBView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get results from storage for DB view
+	_, storage, _ := d.backend()
 	var results []domain.CrawlResult
 	var err error
 
-	switch resultType {
-	case "emails":
-		results, err = d.storage.GetResults(domain.ModeEmail, limit)
-	case "keywords":
-		results, err = d.storage.GetResults(domain.ModeKeywords, limit)
-	case "dead_links":
-		results, err = d.storage.GetResults(domain.ModeDomains, limit)
-	case "all":
-		results, err = d.storage.GetResults(domain.ModeAll, limit)
-	default:
-		results, err = d.storage.GetResults(domain.ModeAll, limit)
+	if search != "" {
+		var query string
+		var filters domain.ResultFilters
+		if query, filters, err = domain.ParseResultQuery(search); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if label != "" {
+			filters.Label = label
+		}
+		results, _, err = storage.SearchResults(query, filters, "", limit)
+	} else {
+		switch resultType {
+		case "emails":
+			results, _, err = storage.GetResults(domain.ModeEmail, "", limit)
+		case "keywords":
+			results, _, err = storage.GetResults(domain.ModeKeywords, "", limit)
+		case "dead_links":
+			results, _, err = storage.GetResults(domain.ModeDomains, "", limit)
+		case "all":
+			results, _, err = storage.GetResults(domain.ModeAll, "", limit)
+		default:
+			results, _, err = storage.GetResults(domain.ModeAll, "", limit)
+		}
+	}
+
+	// Only results tagged with the label, filtered while reading when the storage can
+	if label != "" && search == "" && err == nil {
+		if labeled, ok := storage.(domain.LabeledResults); ok {
+			results, _, err = labeled.GetLabeledResults(label, "", limit)
+		} else {
+			var filtered []domain.CrawlResult
+			for _, result := range results {
+				if result.HasLabel(label) {
+					filtered = append(filtered, result)
+				}
+			}
+			results = filtered
+		}
 	}
 
 	if err != nil {
@@ -1420,6 +2113,390 @@ func (d *Dashboard) handleDBView(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(entries)
 }
 
+// handleSearch searches the stored results for the text of q, which may hold field
+// filters like domain:example.com, status:4xx, type:email or label:name. The filters can
+// be passed as parameters too. Results are paged like /api/results
+func (d *Dashboard) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query, filters, err := domain.ParseResultQuery(params.Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for name, filter := range map[string]*string{
+		"domain": &filters.Domain,
+		"status": &filters.Status,
+		"type":   &filters.Type,
+		"label":  &filters.Label,
+	} {
+		if value := params.Get(name); value != "" {
+			*filter = value
+		}
+	}
+	if err := filters.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if l, err := strconv.Atoi(params.Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	_, storage, _ := d.backend()
+	results, next, err := storage.SearchResults(query, filters, params.Get("cursor"), limit)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor, pass the X-Next-Cursor of the previous page", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error searching results: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if results == nil {
+		results = []domain.CrawlResult{}
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+// handleDBStats serves the on-disk size and key counts of every database of the storage,
+// empty for storages that can not tell
+func (d *Dashboard) handleDBStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	databases := []domain.DatabaseStats{}
+	_, storage, _ := d.backend()
+	if statStore, ok := storage.(domain.StorageStats); ok {
+		stats, err := statStore.CollectionStats()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error reading database stats: %v", err), http.StatusInternalServerError)
+			return
+		}
+		databases = append(databases, stats...)
+	}
+
+	json.NewEncoder(w).Encode(databases)
+}
+
+// handleRuns serves the history of scheduled crawl runs
+func (d *Dashboard) handleRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	d.mu.RLock()
+	history := d.runHistory
+	d.mu.RUnlock()
+
+	runs := []domain.CrawlRun{}
+	if history != nil {
+		runs = history()
+	}
+
+	json.NewEncoder(w).Encode(runs)
+}
+
+// handleJobs starts a crawl job from seed URLs on a running serve process
+func (d *Dashboard) handleJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	d.mu.RLock()
+	submit, token := d.submit, d.controlToken
+	d.mu.RUnlock()
+
+	if submit == nil {
+		http.Error(w, "Crawl jobs are only accepted by golamv2 serve", http.StatusNotFound)
+		return
+	}
+	// Jobs make the daemon fetch any URL, they are taken from the same callers as control
+	if status, err := authorizeControl(r, token); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var job domain.CrawlJob
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	var seeds []string
+	for _, rawURL := range job.URLs {
+		if cleanURL := strings.TrimSpace(rawURL); cleanURL != "" {
+			seeds = append(seeds, cleanURL)
+		}
+	}
+	if len(seeds) == 0 {
+		http.Error(w, "No URLs provided", http.StatusBadRequest)
+		return
+	}
+	job.URLs = seeds
+
+	queued, err := submit(job)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if queued == nil {
+		queued = []string{}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"queued":  queued,
+		"skipped": len(seeds) - len(queued), // Invalid, excluded or already crawled
+	})
+}
+
+// handleReload re-reads the config file, like sending the process SIGHUP
+func (d *Dashboard) handleReload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	d.mu.RLock()
+	reload, token := d.reload, d.controlToken
+	d.mu.RUnlock()
+
+	if reload == nil {
+		http.Error(w, "Config reload is not available", http.StatusNotFound)
+		return
+	}
+	if status, err := authorizeControl(r, token); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// handleControl reports the state of the crawl on GET, and pauses, resumes, stops or
+// reconfigures it on POST /api/control/{pause,resume,stop,settings}
+func (d *Dashboard) handleControl(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	d.mu.RLock()
+	control, token := d.control, d.controlToken
+	d.mu.RUnlock()
+
+	if control == nil {
+		http.Error(w, "Crawl control is not available", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodPost {
+		if status, err := authorizeControl(r, token); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	switch action := mux.Vars(r)["action"]; action {
+	case "":
+	case "pause":
+		control.Pause()
+	case "resume":
+		control.Resume()
+	case "stop":
+		control.Stop()
+	case "settings":
+		var request struct {
+			Workers *int     `json:"workers"`
+			Rate    *float64 `json:"rate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+		if request.Workers != nil {
+			if err := control.SetWorkers(*request.Workers); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if request.Rate != nil {
+			if err := control.SetRate(*request.Rate); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	default:
+		http.Error(w, fmt.Sprintf("Unknown control action %q, expected pause, resume, stop or settings", action), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(control.Status())
+}
+
+// authorizeControl refuses control requests sent by other sites' pages, and those without
+// the token, or from other machines when there is none
+func authorizeControl(r *http.Request, token string) (int, error) {
+	// Browsers send Origin with cross-site POSTs, curl and scripts send none
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			return http.StatusForbidden, fmt.Errorf("cross-site control requests are refused")
+		}
+	}
+
+	if token != "" {
+		given := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(given), []byte("Bearer "+token)) != 1 {
+			return http.StatusUnauthorized, fmt.Errorf("missing or wrong control token")
+		}
+		return 0, nil
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+		return http.StatusForbidden, fmt.Errorf("the crawl can only be controlled from this machine, set --control-token to allow others")
+	}
+	return 0, nil
+}
+
+// handleCollapseRules serves the volatile URL parameters learned per domain
+func (d *Dashboard) handleCollapseRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	d.mu.RLock()
+	provider := d.rules
+	d.mu.RUnlock()
+
+	rules := []domain.CollapseRule{}
+	if provider != nil {
+		if learned := provider(); learned != nil {
+			rules = learned
+		}
+	}
+
+	json.NewEncoder(w).Encode(rules)
+}
+
+// handleQuarantined serves the hosts quarantined for serving bot challenges in a row
+func (d *Dashboard) handleQuarantined(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	d.mu.RLock()
+	provider := d.quarantine
+	d.mu.RUnlock()
+
+	hosts := []string{}
+	if provider != nil {
+		if quarantined := provider(); quarantined != nil {
+			hosts = quarantined
+		}
+	}
+
+	json.NewEncoder(w).Encode(hosts)
+}
+
+// handleDuplicates serves the titles or descriptions shared by several pages of a domain
+func (d *Dashboard) handleDuplicates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	kind := r.URL.Query().Get("kind")
+	if kind != domain.MetaDescription {
+		kind = domain.MetaTitle
+	}
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	groups := []domain.DuplicateMeta{}
+	_, storage, _ := d.backend()
+	if metaStore, ok := storage.(domain.PageMetaStore); ok {
+		found, err := metaStore.GetDuplicateMeta(kind, r.URL.Query().Get("domain"), limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching duplicates: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if found != nil {
+			groups = found
+		}
+	}
+
+	json.NewEncoder(w).Encode(groups)
+}
+
+// handleSlowRequests serves the fetches slower than --slow-threshold, slowest first
+func (d *Dashboard) handleSlowRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	requests := []domain.SlowRequest{}
+	_, storage, _ := d.backend()
+	if slowLog, ok := storage.(domain.SlowRequestLog); ok {
+		found, err := slowLog.GetSlowRequests(limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching slow requests: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if found != nil {
+			requests = found
+		}
+	}
+
+	json.NewEncoder(w).Encode(requests)
+}
+
+// DeadLinkReportLimit is how many results with dead links /api/report/deadlinks reads
+// unless the request sets limit
+const DeadLinkReportLimit = 10000
+
+// reportContentTypes are the formats of the report endpoints
+var reportContentTypes = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv; charset=utf-8",
+	"html": "text/html; charset=utf-8",
+}
+
+// handleDeadLinkReport serves the dead links grouped by source page as JSON, CSV or HTML
+func (d *Dashboard) handleDeadLinkReport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := reportContentTypes[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown format %q, expected json, csv or html", format), http.StatusBadRequest)
+		return
+	}
+	limit := DeadLinkReportLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	_, storage, _ := d.backend()
+	var results []domain.CrawlResult
+	var err error
+	if deadLinks, ok := storage.(domain.DeadLinkResults); ok {
+		results, _, err = deadLinks.GetDeadLinkResults("", limit)
+	} else {
+		results, _, err = storage.GetResults(domain.ModeDomains, "", limit)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching results: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if format == "csv" {
+		w.Header().Set("Content-Disposition", `attachment; filename="deadlinks.csv"`)
+	}
+	if err := export.WriteDeadLinkReportAs(w, format, results); err != nil {
+		logging.Warnf("Writing the dead link report failed: %v", err)
+	}
+}
+
 // handleDBDashboard serves the database dashboard page
 func (d *Dashboard) handleDBDashboard(w http.ResponseWriter, r *http.Request) {
 	tmpl := `
@@ -1765,7 +2842,7 @@ func (d *Dashboard) handleDBDashboard(w http.ResponseWriter, r *http.Request) {
             </div>
 
             <div class="card">
-                <input type="text" id="search-box" class="searchbox" placeholder="Search in results..." oninput="filterResults()">
+                <input type="text" id="search-box" class="searchbox" placeholder="Search in results, e.g. acme domain:example.com status:4xx type:email" oninput="filterResults()">
                 <div id="search-stats"></div>
             </div>
             
@@ -1795,6 +2872,13 @@ func (d *Dashboard) handleDBDashboard(w http.ResponseWriter, r *http.Request) {
         
         <!-- Database Stats Tab -->
         <div id="db-stats" class="tab-content">
+            <div class="card">
+                <h3>💾 On-Disk Databases</h3>
+                <div id="db-disk-content">
+                    <div class="loading">Loading database sizes...</div>
+                </div>
+            </div>
+
             <div class="two-column">
                 <div class="card">
                     <h3>📊 Database Statistics</h3>
@@ -1872,20 +2956,26 @@ func (d *Dashboard) handleDBDashboard(w http.ResponseWriter, r *http.Request) {
         async function loadDBData() {
             const type = document.getElementById('data-type').value;
             const limit = document.getElementById('data-limit').value;
+            const search = document.getElementById('search-box').value.trim();
             
             document.getElementById('db-loading').style.display = 'block';
             document.getElementById('db-content').style.display = 'none';
             document.getElementById('db-empty').style.display = 'none';
             
             try {
-                const response = await fetch('/api/db-view?type=' + type + '&limit=' + limit);
-                dbData = await response.json();
+                const response = await fetch('/api/db-view?type=' + type + '&limit=' + limit + '&q=' + encodeURIComponent(search));
+                if (!response.ok) {
+                    throw new Error(await response.text());
+                }
+                dbData = (await response.json()) || [];
                 filteredData = [...dbData]; // Copy for filtering
                 
                 document.getElementById('db-loading').style.display = 'none';
                 
                 if (dbData.length === 0) {
                     document.getElementById('db-empty').style.display = 'block';
+                    document.getElementById('db-empty').innerHTML = 'No database records found matching your criteria.';
+                    document.getElementById('search-stats').innerHTML = '';
                 } else {
                     displayDBData(dbData);
                     document.getElementById('db-content').style.display = 'block';
@@ -1943,53 +3033,29 @@ func (d *Dashboard) handleDBDashboard(w http.ResponseWriter, r *http.Request) {
                 const displayContent = dataContent.length > maxLength ? 
                     dataContent.substring(0, maxLength) + '...' : dataContent;
                 
-                row.innerHTML = 
-                    '<td><span class="status-badge ' + badgeClass + '">' + record.data_type + '</span></td>' +
-                    '<td class="url-cell"><a href="' + record.url + '" target="_blank">' + record.url + '</a></td>' +
-                    '<td>' + displayContent + '</td>' +
-                    '<td>' + 
-                        'Status: ' + record.status_code + '<br>' +
-                        'Time: ' + processTime + '<br>' +
-                        '<a href="#" class="toggle-json" onclick="viewRecord(\'' + record.id + '\')">View Details</a>' +
-                    '</td>';
+                row.appendChild(tableCell(badge(badgeClass, record.data_type)));
+                const url = tableCell(newTabLink(record.url, record.url));
+                url.className = 'url-cell';
+                row.appendChild(url);
+                row.appendChild(tableCell(displayContent));
+                const details = document.createElement('a');
+                details.href = '#';
+                details.className = 'toggle-json';
+                details.textContent = 'View Details';
+                details.onclick = () => viewRecord(record.id);
+                row.appendChild(tableCell(
+                    'Status: ' + record.status_code, document.createElement('br'),
+                    'Time: ' + processTime, document.createElement('br'), details));
                 tbody.appendChild(row);
             });
         }
         
-        // Filter results based on search box
+        // Search the stored results once typing paused, the storage is searched rather
+        // than the records loaded
+        let searchTimer = null;
         function filterResults() {
-            const searchText = document.getElementById('search-box').value.toLowerCase();
-            
-            if (!searchText) {
-                filteredData = [...dbData]; // Reset to full data
-                document.getElementById('search-stats').innerHTML = dbData.length + ' records found';
-                displayDBData(filteredData);
-                return;
-            }
-            
-            filteredData = dbData.filter(record => {
-                // Search in URL
-                if (record.url.toLowerCase().includes(searchText)) return true;
-                
-                // Search in data
-                let dataContent = '';
-                if (record.data_type === 'emails' || record.data_type === 'dead_links' || record.data_type === 'dead_domains') {
-                    dataContent = Array.isArray(record.raw_data) ? record.raw_data.join(' ') : String(record.raw_data);
-                } else if (record.data_type === 'keywords') {
-                    if (typeof record.raw_data === 'object') {
-                        dataContent = Object.keys(record.raw_data).join(' ');
-                    } else {
-                        dataContent = String(record.raw_data);
-                    }
-                } else {
-                    dataContent = JSON.stringify(record.raw_data);
-                }
-                
-                return dataContent.toLowerCase().includes(searchText);
-            });
-            
-            document.getElementById('search-stats').innerHTML = filteredData.length + ' of ' + dbData.length + ' records found';
-            displayDBData(filteredData);
+            clearTimeout(searchTimer);
+            searchTimer = setTimeout(loadDBData, 300);
         }
         
         // View record details in modal
@@ -2004,25 +3070,25 @@ func (d *Dashboard) handleDBDashboard(w http.ResponseWriter, r *http.Request) {
             title.textContent = 'Record Details: ' + record.data_type;
             
             let html = '<div style="margin-bottom: 15px;">';
-            html += '<strong>URL:</strong> <a href="' + record.url + '" target="_blank">' + record.url + '</a><br>';
+            html += '<strong>URL:</strong> <a href="' + escapeHtml(record.url) + '" target="_blank">' + escapeHtml(record.url) + '</a><br>';
             html += '<strong>Processed At:</strong> ' + new Date(record.processed_at).toLocaleString() + '<br>';
             html += '<strong>Status Code:</strong> ' + record.status_code + '<br>';
             html += '<strong>Process Time:</strong> ' + record.process_time_ms.toFixed(2) + ' ms<br>';
             
             if (record.has_error) {
-                html += '<strong>Error:</strong> <span style="color: #f44336;">' + record.error_message + '</span><br>';
+                html += '<strong>Error:</strong> <span style="color: #f44336;">' + escapeHtml(record.error_message) + '</span><br>';
             }
             
             html += '</div>';
             
             html += '<div style="margin-bottom: 15px;">';
-            html += '<strong>Data Type:</strong> ' + record.data_type + '<br>';
+            html += '<strong>Data Type:</strong> ' + escapeHtml(record.data_type) + '<br>';
             html += '<strong>Data Count:</strong> ' + record.data_count + '<br>';
             html += '</div>';
             
             html += '<div>';
             html += '<strong>Raw Data:</strong><br>';
-            html += '<pre>' + JSON.stringify(record.raw_data, null, 2) + '</pre>';
+            html += '<pre>' + escapeHtml(JSON.stringify(record.raw_data, null, 2)) + '</pre>';
             html += '</div>';
             
             content.innerHTML = html;
@@ -2034,8 +3100,54 @@ func (d *Dashboard) handleDBDashboard(w http.ResponseWriter, r *http.Request) {
             document.getElementById('record-modal').style.display = 'none';
         }
         
+        // Load the sizes and key counts of the databases as stored on disk
+        async function loadDiskStats() {
+            const container = document.getElementById('db-disk-content');
+            try {
+                const response = await fetch('/api/db-stats');
+                const databases = await response.json();
+                if (databases.length === 0) {
+                    container.innerHTML = 'This storage does not report database sizes';
+                    return;
+                }
+
+                let html = '<table class="table"><tr><th>Database</th><th>LSM</th><th>Value Log</th><th>Tables</th><th>Keys per Collection</th></tr>';
+                databases.forEach(db => {
+                    const collections = Object.entries(db.collections || {})
+                        .sort((a, b) => b[1] - a[1])
+                        .map(([name, count]) => escapeHtml(name) + ': ' + count.toLocaleString())
+                        .join('<br>');
+                    html += '<tr><td><strong>' + escapeHtml(db.name) + '</strong></td>' +
+                        '<td>' + formatBytes(db.lsm_bytes) + '</td>' +
+                        '<td>' + formatBytes(db.vlog_bytes) + '</td>' +
+                        '<td>' + db.tables + (db.table_keys ? ' (' + db.table_keys.toLocaleString() + ' keys)' : '') + '</td>' +
+                        '<td>' + (collections || '-') + '</td></tr>';
+                });
+                container.innerHTML = html + '</table>';
+            } catch (error) {
+                container.innerHTML = 'Error loading database sizes: ' + error.message;
+            }
+        }
+
+        function formatBytes(bytes) {
+            const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+            let i = 0;
+            while (bytes >= 1024 && i < units.length - 1) {
+                bytes /= 1024;
+                i++;
+            }
+            return bytes.toFixed(i === 0 ? 0 : 1) + ' ' + units[i];
+        }
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text;
+            return div.innerHTML.replace(/"/g, '&quot;');
+        }
+
         // Load database stats
         async function loadDBStats() {
+            loadDiskStats();
             const statsContainer = document.getElementById('db-stats-content');
             statsContainer.innerHTML = '<div class="loading">Loading statistics...</div>';
             
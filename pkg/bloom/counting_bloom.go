@@ -0,0 +1,214 @@
+package bloom
+
+import (
+	"sync"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+// maxCounter is where the 4 bit counters saturate, a saturated counter is never decremented
+// again so removals can not create false negatives for URLs still in the filter
+const maxCounter = 15
+
+// CountingURLBloomFilter is a bloom filter with 4 bit counters instead of bits, URLs can be
+// removed again (a transient fetch failure to retry later, a pruned domain). It takes 4x
+// the memory of URLBloomFilter for the same URL count and false positive rate, and grows
+// layers the same way
+type CountingURLBloomFilter struct {
+	mu     sync.RWMutex
+	layers []*countingLayer
+	count  uint64
+	onGrow GrowHandler
+}
+
+type countingLayer struct {
+	counters []uint8 // Two counters per byte
+	m        uint64  // Number of counters
+	k        uint
+	count    uint64
+}
+
+// NewCountingURLBloomFilter creates a counting Bloom filter sized like NewURLBloomFilter
+func NewCountingURLBloomFilter() *CountingURLBloomFilter {
+	b := &CountingURLBloomFilter{}
+	b.addLayer()
+	return b
+}
+
+// SetGrowHandler sets the callback told about new layers
+func (b *CountingURLBloomFilter) SetGrowHandler(handler GrowHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onGrow = handler
+}
+
+// Add adds an URL to the Bloom filter
+func (b *CountingURLBloomFilter) Add(url string) {
+	b.mu.Lock()
+
+	layer := b.layers[len(b.layers)-1]
+	layer.add(url)
+	b.count++
+
+	// Past the threshold the layer is full, new URLs go to a bigger one
+	rate := falsePositiveRate(layer.count, layer.m, layer.k)
+	grown := rate > MaxFalsePositiveRate
+	if grown {
+		b.addLayer()
+	}
+	layers, onGrow := len(b.layers), b.onGrow
+	b.mu.Unlock()
+
+	if grown && onGrow != nil {
+		onGrow(layers, rate)
+	}
+}
+
+// Test checks if a URL might be in the Bloom filter
+func (b *CountingURLBloomFilter) Test(url string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, layer := range b.layers {
+		if layer.contains(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove forgets an URL, URLs that were never added are left alone
+func (b *CountingURLBloomFilter) Remove(url string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Newest layer first, that is where a recently added URL lives.
+	// Decrementing for a false positive would evict other URLs
+	for i := len(b.layers) - 1; i >= 0; i-- {
+		if b.layers[i].contains(url) {
+			b.layers[i].remove(url)
+			if b.count > 0 {
+				b.count--
+			}
+			return
+		}
+	}
+}
+
+// EstimateCount returns the estimated number of elements added
+func (b *CountingURLBloomFilter) EstimateCount() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.count
+}
+
+// Reset clears the Bloom filter, dropping the extra layers
+func (b *CountingURLBloomFilter) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.layers = nil
+	b.addLayer()
+	b.count = 0
+}
+
+// GetStats about the Bloom filter
+func (b *CountingURLBloomFilter) GetStats() BloomStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := BloomStats{ElementCount: b.count, Layers: len(b.layers)}
+	rates := make([]float64, len(b.layers))
+	used := uint64(0)
+	for i, layer := range b.layers {
+		stats.BitArraySize += layer.m
+		stats.HashFunctions = uint64(layer.k)
+		for location := uint64(0); location < layer.m; location++ {
+			if layer.get(location) > 0 {
+				used++
+			}
+		}
+		rates[i] = falsePositiveRate(layer.count, layer.m, layer.k)
+	}
+	stats.FillRatio = float64(used) / float64(stats.BitArraySize)
+	stats.EstimatedFPRate = compoundFalsePositiveRate(rates)
+	return stats
+}
+
+// GetMemoryUsageMB returns the memory used by the counters in MB
+func (b *CountingURLBloomFilter) GetMemoryUsageMB() float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	total := 0
+	for _, layer := range b.layers {
+		total += len(layer.counters)
+	}
+	return float64(total) / 1024 / 1024
+}
+
+// addLayer appends the next, larger layer, callers hold the lock
+func (b *CountingURLBloomFilter) addLayer() {
+	elements, rate := layerParameters(len(b.layers))
+	m, k := bloom.EstimateParameters(elements, rate)
+	b.layers = append(b.layers, &countingLayer{
+		counters: make([]uint8, (m+1)/2),
+		m:        uint64(m),
+		k:        k,
+	})
+}
+
+func (l *countingLayer) add(url string) {
+	for _, location := range l.locations(url) {
+		if counter := l.get(location); counter < maxCounter {
+			l.set(location, counter+1)
+		}
+	}
+	l.count++
+}
+
+func (l *countingLayer) remove(url string) {
+	for _, location := range l.locations(url) {
+		if counter := l.get(location); counter < maxCounter {
+			l.set(location, counter-1)
+		}
+	}
+	if l.count > 0 {
+		l.count--
+	}
+}
+
+func (l *countingLayer) contains(url string) bool {
+	for _, location := range l.locations(url) {
+		if l.get(location) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *countingLayer) locations(url string) []uint64 {
+	locations := bloom.Locations([]byte(url), l.k)
+	for i := range locations {
+		locations[i] %= l.m
+	}
+	return locations
+}
+
+func (l *countingLayer) get(location uint64) uint8 {
+	value := l.counters[location/2]
+	if location%2 == 1 {
+		return value >> 4
+	}
+	return value & 0x0f
+}
+
+func (l *countingLayer) set(location uint64, counter uint8) {
+	value := l.counters[location/2]
+	if location%2 == 1 {
+		l.counters[location/2] = value&0x0f | counter<<4
+	} else {
+		l.counters[location/2] = value&0xf0 | counter
+	}
+}
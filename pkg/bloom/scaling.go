@@ -0,0 +1,44 @@
+package bloom
+
+import "math"
+
+// MaxFalsePositiveRate is the estimated rate at which the newest layer counts as full and a
+// new layer is added, past it valid frontier URLs would start getting dropped as duplicates
+const MaxFalsePositiveRate = 2 * FalsePositiveRate
+
+// Filter is what both URL bloom filters offer
+type Filter interface {
+	Add(url string)
+	Test(url string) bool
+	EstimateCount() uint64
+	Reset()
+	GetStats() BloomStats
+	GetMemoryUsageMB() float64
+	SetGrowHandler(handler GrowHandler)
+}
+
+// GrowHandler is told when a filter added a layer, with the rate the full layer had reached
+type GrowHandler func(layers int, falsePositiveRate float64)
+
+// layerParameters sizes layer i (0 based): each layer holds twice the URLs of the previous one
+// at half its false positive rate, so the rate of the whole filter stays bounded
+func layerParameters(i int) (elements uint, falsePositiveRate float64) {
+	return ExpectedElements << i, FalsePositiveRate / float64(uint(1)<<i)
+}
+
+// falsePositiveRate estimates the rate of one layer, (1 - e^(-k*n/m))^k
+func falsePositiveRate(n, m uint64, k uint) float64 {
+	if n == 0 || m == 0 {
+		return 0
+	}
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
+
+// compoundFalsePositiveRate is the chance a URL matches any of the layers
+func compoundFalsePositiveRate(rates []float64) float64 {
+	miss := 1.0
+	for _, rate := range rates {
+		miss *= 1 - rate
+	}
+	return 1 - miss
+}
@@ -0,0 +1,123 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+// Kinds of snapshots, a filter only restores its own kind
+const (
+	snapshotBits     = "bits"
+	snapshotCounting = "counting"
+)
+
+// snapshot is the persisted form of a filter, used by crawl checkpoints
+type snapshot struct {
+	Kind   string
+	Count  uint64
+	Layers []layerSnapshot
+}
+
+type layerSnapshot struct {
+	Count    uint64
+	Filter   []byte  // Bit filters, as encoded by bloom.BloomFilter
+	Counters []uint8 // Counting filters
+	M        uint64
+	K        uint
+}
+
+func encodeSnapshot(s snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return nil, fmt.Errorf("failed to encode bloom filter: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSnapshot(data []byte, kind string) (snapshot, error) {
+	var s snapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return s, fmt.Errorf("failed to decode bloom filter: %v", err)
+	}
+	if s.Kind != kind {
+		return s, fmt.Errorf("saved bloom filter is a %s filter, not a %s one", s.Kind, kind)
+	}
+	if len(s.Layers) == 0 {
+		return s, fmt.Errorf("saved bloom filter has no layers")
+	}
+	return s, nil
+}
+
+// MarshalBinary saves the filter with its layers
+func (b *URLBloomFilter) MarshalBinary() ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	s := snapshot{Kind: snapshotBits, Count: b.count}
+	for _, layer := range b.layers {
+		data, err := layer.filter.GobEncode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode bloom filter: %v", err)
+		}
+		s.Layers = append(s.Layers, layerSnapshot{Count: layer.count, Filter: data})
+	}
+	return encodeSnapshot(s)
+}
+
+// UnmarshalBinary replaces the filter with a saved one
+func (b *URLBloomFilter) UnmarshalBinary(data []byte) error {
+	s, err := decodeSnapshot(data, snapshotBits)
+	if err != nil {
+		return err
+	}
+
+	layers := make([]*urlBloomLayer, 0, len(s.Layers))
+	for _, saved := range s.Layers {
+		filter := &bloom.BloomFilter{}
+		if err := filter.GobDecode(saved.Filter); err != nil {
+			return fmt.Errorf("failed to decode bloom filter: %v", err)
+		}
+		layers = append(layers, &urlBloomLayer{filter: filter, count: saved.Count})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.layers, b.count = layers, s.Count
+	return nil
+}
+
+// MarshalBinary saves the filter with its layers
+func (b *CountingURLBloomFilter) MarshalBinary() ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	s := snapshot{Kind: snapshotCounting, Count: b.count}
+	for _, layer := range b.layers {
+		s.Layers = append(s.Layers, layerSnapshot{Count: layer.count, Counters: layer.counters, M: layer.m, K: layer.k})
+	}
+	return encodeSnapshot(s)
+}
+
+// UnmarshalBinary replaces the filter with a saved one
+func (b *CountingURLBloomFilter) UnmarshalBinary(data []byte) error {
+	s, err := decodeSnapshot(data, snapshotCounting)
+	if err != nil {
+		return err
+	}
+
+	layers := make([]*countingLayer, 0, len(s.Layers))
+	for _, saved := range s.Layers {
+		if uint64(len(saved.Counters)) != (saved.M+1)/2 || saved.K == 0 {
+			return fmt.Errorf("saved bloom filter layer is corrupt")
+		}
+		layers = append(layers, &countingLayer{counters: saved.Counters, m: saved.M, k: saved.K, count: saved.Count})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.layers, b.count = layers, s.Count
+	return nil
+}
@@ -13,31 +13,55 @@ const (
 	FalsePositiveRate = 0.01
 )
 
-// URLBloomFilter implements domain.BloomFilter for URL deduplication
+// URLBloomFilter implements domain.BloomFilter for URL deduplication. It starts as one filter
+// for ExpectedElements URLs and adds larger layers as the newest one fills up
 type URLBloomFilter struct {
 	mu     sync.RWMutex
+	layers []*urlBloomLayer
+	count  uint64
+	onGrow GrowHandler
+}
+
+type urlBloomLayer struct {
 	filter *bloom.BloomFilter
 	count  uint64
 }
 
 // NewURLBloomFilter creates a new Bloom filter optimized for URLs
 func NewURLBloomFilter() *URLBloomFilter {
-	// Calculate optimal parameters for expected elements and false positive rate
-	filter := bloom.NewWithEstimates(ExpectedElements, FalsePositiveRate)
+	b := &URLBloomFilter{}
+	b.addLayer()
+	return b
+}
 
-	return &URLBloomFilter{
-		filter: filter,
-		count:  0,
-	}
+// SetGrowHandler sets the callback told about new layers
+func (b *URLBloomFilter) SetGrowHandler(handler GrowHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onGrow = handler
 }
 
 // Add adds an URL to the Bloom filter
 func (b *URLBloomFilter) Add(url string) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
 
-	b.filter.AddString(url)
+	layer := b.layers[len(b.layers)-1]
+	layer.filter.AddString(url)
+	layer.count++
 	b.count++
+
+	// Past the threshold the layer is full, new URLs go to a bigger one
+	rate := falsePositiveRate(layer.count, uint64(layer.filter.Cap()), layer.filter.K())
+	grown := rate > MaxFalsePositiveRate
+	if grown {
+		b.addLayer()
+	}
+	layers, onGrow := len(b.layers), b.onGrow
+	b.mu.Unlock()
+
+	if grown && onGrow != nil {
+		onGrow(layers, rate)
+	}
 }
 
 // Test checks if a URL might be in the Bloom filter
@@ -45,7 +69,12 @@ func (b *URLBloomFilter) Test(url string) bool {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	return b.filter.TestString(url)
+	for _, layer := range b.layers {
+		if layer.filter.TestString(url) {
+			return true
+		}
+	}
+	return false
 }
 
 // EstimateCount returns the estimated number of elements added
@@ -56,12 +85,13 @@ func (b *URLBloomFilter) EstimateCount() uint64 {
 	return b.count
 }
 
-// Reset clears the Bloom filter
+// Reset clears the Bloom filter, dropping the extra layers
 func (b *URLBloomFilter) Reset() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	b.filter.ClearAll()
+	b.layers = nil
+	b.addLayer()
 	b.count = 0
 }
 
@@ -70,39 +100,18 @@ func (b *URLBloomFilter) GetStats() BloomStats {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	return BloomStats{
-		ElementCount:    b.count,
-		BitArraySize:    uint64(b.filter.Cap()),
-		HashFunctions:   uint64(b.filter.K()),
-		FillRatio:       float64(b.filter.BitSet().Count()) / float64(b.filter.Cap()),
-		EstimatedFPRate: b.estimateFalsePositiveRate(),
+	stats := BloomStats{ElementCount: b.count, Layers: len(b.layers)}
+	rates := make([]float64, len(b.layers))
+	setBits := uint64(0)
+	for i, layer := range b.layers {
+		stats.BitArraySize += uint64(layer.filter.Cap())
+		stats.HashFunctions = uint64(layer.filter.K())
+		setBits += uint64(layer.filter.BitSet().Count())
+		rates[i] = falsePositiveRate(layer.count, uint64(layer.filter.Cap()), layer.filter.K())
 	}
-}
-
-// estimateFalsePositiveRate
-func (b *URLBloomFilter) estimateFalsePositiveRate() float64 {
-	if b.count == 0 {
-		return 0
-	}
-
-	// Calculate false positive rate based on current fill ratio
-	// FPR = (1 - e^(-k*n/m))^k
-	// where k = number of hash functions, n = number of elements, m = bit array size
-
-	n := float64(b.count)
-	m := float64(b.filter.Cap())
-
-	if m == 0 {
-		return 1.0
-	}
-
-	// Simplified to avoid math imports
-	fillRatio := n / m
-	if fillRatio > 0.7 { // High fill ratio
-		return 0.1 // Rough estimate
-	}
-
-	return FalsePositiveRate * (fillRatio / 0.1) // Scale based on fill ratio
+	stats.FillRatio = float64(setBits) / float64(stats.BitArraySize)
+	stats.EstimatedFPRate = compoundFalsePositiveRate(rates)
+	return stats
 }
 
 // GetMemoryUsageMB returns the estimated memory usage in MB
@@ -110,12 +119,23 @@ func (bf *URLBloomFilter) GetMemoryUsageMB() float64 {
 	bf.mu.RLock()
 	defer bf.mu.RUnlock()
 
-	if bf.filter == nil {
+	if len(bf.layers) == 0 {
 		return 0
 	}
 
-	// Uses approximately 12MB (calculated From My Tests!)
-	return 12.0
+	// Uses approximately 12MB for the first layer (calculated From My Tests!), later layers scale with their size
+	first := float64(bf.layers[0].filter.Cap())
+	total := 0.0
+	for _, layer := range bf.layers {
+		total += float64(layer.filter.Cap())
+	}
+	return 12.0 * total / first
+}
+
+// addLayer appends the next, larger layer, callers hold the lock
+func (b *URLBloomFilter) addLayer() {
+	elements, rate := layerParameters(len(b.layers))
+	b.layers = append(b.layers, &urlBloomLayer{filter: bloom.NewWithEstimates(elements, rate)})
 }
 
 // BloomStats represents statistics about the Bloom filter
@@ -125,4 +145,5 @@ type BloomStats struct {
 	HashFunctions   uint64  `json:"hash_functions"`
 	FillRatio       float64 `json:"fill_ratio"`
 	EstimatedFPRate float64 `json:"estimated_fp_rate"`
+	Layers          int     `json:"layers"`
 }
@@ -0,0 +1,46 @@
+// Package bufpool hands out reusable byte buffers, so reading response bodies and
+// marshaling results does not grow a fresh buffer for every page
+package bufpool
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// MaxPooledSize caps the buffers put back, one huge page does not keep its memory pinned
+const MaxPooledSize = 4 << 20
+
+// MaxSizeHint caps how much a size hint pre-allocates, Content-Length is not always honest
+const MaxSizeHint = 2 << 20
+
+var pool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Get returns an empty buffer from the pool
+func Get() *bytes.Buffer {
+	return pool.Get().(*bytes.Buffer)
+}
+
+// Put returns a buffer to the pool, its bytes must not be used afterwards
+func Put(b *bytes.Buffer) {
+	if b.Cap() > MaxPooledSize {
+		return
+	}
+	b.Reset()
+	pool.Put(b)
+}
+
+// ReadString reads r to the end through a pooled buffer, sizeHint is the expected length
+// when known (a Content-Length), -1 otherwise. The string is the only copy made
+func ReadString(r io.Reader, sizeHint int64) (string, error) {
+	b := Get()
+	defer Put(b)
+
+	if sizeHint > 0 {
+		b.Grow(int(min(sizeHint, MaxSizeHint)) + bytes.MinRead)
+	}
+	_, err := b.ReadFrom(r)
+	return b.String(), err
+}
@@ -0,0 +1,27 @@
+package bufpool
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+var body = strings.Repeat("<p>Some page content to read</p>\n", 2000)
+
+func BenchmarkReadString(b *testing.B) {
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ReadString(strings.NewReader(body), int64(len(body)))
+	}
+}
+
+// What ReadString replaces
+func BenchmarkReadAll(b *testing.B) {
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		raw, _ := io.ReadAll(strings.NewReader(body))
+		_ = string(raw)
+	}
+}
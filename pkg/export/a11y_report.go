@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+
+	"golamv2/internal/domain"
+)
+
+// AccessibilityDomainReport aggregates the accessibility audits of one domain's pages
+type AccessibilityDomainReport struct {
+	Domain          string `json:"domain"`
+	PagesAudited    int    `json:"pages_audited"`
+	PagesWithIssues int    `json:"pages_with_issues"`
+	MissingAlt      int    `json:"missing_alt"`
+	MissingLabels   int    `json:"missing_labels"`
+	HeadingOrder    int    `json:"heading_order"`
+	MissingLang     int    `json:"missing_lang"` // Pages without a lang attribute
+}
+
+// Issues is the number of issues found on the domain
+func (r AccessibilityDomainReport) Issues() int {
+	return r.MissingAlt + r.MissingLabels + r.HeadingOrder + r.MissingLang
+}
+
+// BuildAccessibilityReport groups the audits of a --a11y crawl by domain, most issues first
+func BuildAccessibilityReport(results []domain.CrawlResult) []AccessibilityDomainReport {
+	reports := make(map[string]*AccessibilityDomainReport)
+	for _, result := range results {
+		audit := result.Accessibility
+		if audit == nil {
+			continue
+		}
+
+		host := domain.GetDomain(result.URL)
+		report := reports[host]
+		if report == nil {
+			report = &AccessibilityDomainReport{Domain: host}
+			reports[host] = report
+		}
+
+		report.PagesAudited++
+		if audit.IssueCount() > 0 {
+			report.PagesWithIssues++
+		}
+		report.MissingAlt += audit.MissingAlt
+		report.MissingLabels += audit.MissingLabels
+		report.HeadingOrder += audit.HeadingOrder
+		if audit.MissingLang {
+			report.MissingLang++
+		}
+	}
+
+	list := make([]AccessibilityDomainReport, 0, len(reports))
+	for _, report := range reports {
+		list = append(list, *report)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Issues() != list[j].Issues() {
+			return list[i].Issues() > list[j].Issues()
+		}
+		return list[i].Domain < list[j].Domain
+	})
+	return list
+}
+
+// WriteAccessibilityReport writes the accessibility report as CSV, one row per domain
+func WriteAccessibilityReport(w io.Writer, results []domain.CrawlResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"domain", "pages_audited", "pages_with_issues", "missing_alt", "missing_labels", "heading_order", "missing_lang"})
+
+	for _, report := range BuildAccessibilityReport(results) {
+		writer.Write([]string{
+			report.Domain,
+			fmt.Sprint(report.PagesAudited),
+			fmt.Sprint(report.PagesWithIssues),
+			fmt.Sprint(report.MissingAlt),
+			fmt.Sprint(report.MissingLabels),
+			fmt.Sprint(report.HeadingOrder),
+			fmt.Sprint(report.MissingLang),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+
+	"golamv2/internal/domain"
+)
+
+// ChangeReportFormats are the formats WriteChangeReportAs writes
+var ChangeReportFormats = []string{"json", "csv", "html"}
+
+// WriteChangeReportAs writes the changes between two crawls as json, csv or html, see
+// ChangeReportFormats
+func WriteChangeReportAs(w io.Writer, format string, report domain.ChangeReport) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case "csv":
+		return writeChangeReportCSV(w, report)
+	case "html":
+		return changeReportTemplate.Execute(w, struct {
+			domain.ChangeReport
+			Summary domain.ChangeSummary
+		}{report, report.Summary()})
+	default:
+		return fmt.Errorf("unknown change report format %q (available: %s)", format, strings.Join(ChangeReportFormats, ", "))
+	}
+}
+
+// writeChangeReportCSV writes one row per change, the values before and after it in the last
+// two columns
+func writeChangeReportCSV(w io.Writer, report domain.ChangeReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"change", "url", "value", "before", "after"})
+
+	for _, url := range report.AddedURLs {
+		writer.Write([]string{"added_url", url, "", "", ""})
+	}
+	for _, url := range report.RemovedURLs {
+		writer.Write([]string{"removed_url", url, "", "", ""})
+	}
+	for _, change := range report.StatusChanges {
+		writer.Write([]string{"status_change", change.URL, "", strconv.Itoa(change.Before), strconv.Itoa(change.After)})
+	}
+	for _, change := range report.NewDeadLinks {
+		writer.Write([]string{"new_dead_link", change.Page, change.Link, "", ""})
+	}
+	for _, change := range report.ResolvedDeadLinks {
+		writer.Write([]string{"resolved_dead_link", change.Page, change.Link, "", ""})
+	}
+	for _, change := range report.NewEmails {
+		for _, page := range change.Pages {
+			writer.Write([]string{"new_email", page, change.Email, "", ""})
+		}
+	}
+	for _, change := range report.DisappearedEmails {
+		for _, page := range change.Pages {
+			writer.Write([]string{"disappeared_email", page, change.Email, "", ""})
+		}
+	}
+	for _, change := range report.KeywordChanges {
+		writer.Write([]string{"keyword_change", "", change.Keyword, strconv.Itoa(change.Before), strconv.Itoa(change.After)})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+var changeReportTemplate = template.Must(template.New("changes").Funcs(template.FuncMap{
+	"delta": func(before, after int) string {
+		return fmt.Sprintf("%+d", after-before)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Change Report</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 2rem auto; max-width: 1100px; padding: 0 1rem; }
+  h1 { margin-bottom: 0.2rem; }
+  .summary { color: #666; margin-bottom: 2rem; }
+  section { margin-bottom: 2rem; }
+  h2 { font-size: 1rem; border-bottom: 2px solid #eee; padding-bottom: 0.3rem; }
+  h2 .count { color: #888; font-weight: normal; }
+  table { border-collapse: collapse; width: 100%; font-size: 0.9rem; }
+  th, td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid #eee; vertical-align: top; }
+  th { background: #fafafa; }
+  td.url { word-break: break-all; }
+  .muted { color: #999; }
+  a { color: #0366d6; text-decoration: none; }
+</style>
+</head>
+<body>
+<h1>Change Report</h1>
+<p class="summary">{{.Before}} &rarr; {{.After}} &middot; generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</p>
+{{if .Summary.Empty}}<p>Nothing changed.</p>{{end}}
+{{if .AddedURLs}}<section>
+<h2>Added pages <span class="count">({{len .AddedURLs}})</span></h2>
+<table>
+{{range .AddedURLs}}<tr><td class="url"><a href="{{.}}">{{.}}</a></td></tr>
+{{end}}</table>
+</section>{{end}}
+{{if .RemovedURLs}}<section>
+<h2>Removed pages <span class="count">({{len .RemovedURLs}})</span></h2>
+<table>
+{{range .RemovedURLs}}<tr><td class="url"><a href="{{.}}">{{.}}</a></td></tr>
+{{end}}</table>
+</section>{{end}}
+{{if .StatusChanges}}<section>
+<h2>Status code changes <span class="count">({{len .StatusChanges}})</span></h2>
+<table>
+<tr><th>Page</th><th>Before</th><th>After</th></tr>
+{{range .StatusChanges}}<tr><td class="url"><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Before}}</td><td>{{.After}}</td></tr>
+{{end}}</table>
+</section>{{end}}
+{{if .NewDeadLinks}}<section>
+<h2>New dead links <span class="count">({{len .NewDeadLinks}})</span></h2>
+<table>
+<tr><th>Page</th><th>Link</th></tr>
+{{range .NewDeadLinks}}<tr><td class="url"><a href="{{.Page}}">{{.Page}}</a></td><td class="url">{{.Link}}</td></tr>
+{{end}}</table>
+</section>{{end}}
+{{if .ResolvedDeadLinks}}<section>
+<h2>Resolved dead links <span class="count">({{len .ResolvedDeadLinks}})</span></h2>
+<table>
+<tr><th>Page</th><th>Link</th></tr>
+{{range .ResolvedDeadLinks}}<tr><td class="url"><a href="{{.Page}}">{{.Page}}</a></td><td class="url">{{.Link}}</td></tr>
+{{end}}</table>
+</section>{{end}}
+{{if .NewEmails}}<section>
+<h2>New emails <span class="count">({{len .NewEmails}})</span></h2>
+<table>
+<tr><th>Email</th><th>Pages</th></tr>
+{{range .NewEmails}}<tr><td>{{.Email}}</td><td class="url">{{range .Pages}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+</section>{{end}}
+{{if .DisappearedEmails}}<section>
+<h2>Disappeared emails <span class="count">({{len .DisappearedEmails}})</span></h2>
+<table>
+<tr><th>Email</th><th>Last seen on</th></tr>
+{{range .DisappearedEmails}}<tr><td>{{.Email}}</td><td class="url">{{range .Pages}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+</section>{{end}}
+{{if .KeywordChanges}}<section>
+<h2>Keyword counts <span class="count">({{len .KeywordChanges}})</span></h2>
+<table>
+<tr><th>Keyword</th><th>Before</th><th>After</th><th>Change</th></tr>
+{{range .KeywordChanges}}<tr><td>{{.Keyword}}</td><td>{{.Before}}</td><td>{{.After}}</td><td>{{delta .Before .After}}</td></tr>
+{{end}}</table>
+</section>{{end}}
+</body>
+</html>
+`))
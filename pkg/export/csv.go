@@ -0,0 +1,61 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// csvHeader are the columns of WriteCSV, lists are joined by spaces
+var csvHeader = []string{
+	"url", "status_code", "title", "description", "emails", "keywords", "dead_links",
+	"dead_domains", "labels", "error", "processed_at",
+}
+
+// WriteCSV writes results as CSV, one row per page. Keywords are written as keyword:count
+func WriteCSV(w io.Writer, results []domain.CrawlResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write(csvHeader)
+
+	for _, result := range results {
+		keywords := make([]string, 0, len(result.Keywords))
+		for keyword, count := range result.Keywords {
+			keywords = append(keywords, fmt.Sprintf("%s:%d", keyword, count))
+		}
+		sort.Strings(keywords)
+
+		writer.Write([]string{
+			result.URL,
+			fmt.Sprint(result.StatusCode),
+			result.Title,
+			result.Description,
+			strings.Join(result.Emails, " "),
+			strings.Join(keywords, " "),
+			strings.Join(result.DeadLinks, " "),
+			strings.Join(result.DeadDomains, " "),
+			strings.Join(result.Labels, " "),
+			result.Error,
+			result.ProcessedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteNDJSON writes results as newline delimited JSON, one result per line
+func WriteNDJSON(w io.Writer, results []domain.CrawlResult) error {
+	encoder := json.NewEncoder(w)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
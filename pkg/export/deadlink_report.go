@@ -0,0 +1,195 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// DeadLinkReportFormats are the formats WriteDeadLinkReportAs writes
+var DeadLinkReportFormats = []string{"json", "csv", "html"}
+
+// DeadLinkPage is one source page of the dead link report with its broken links
+type DeadLinkPage struct {
+	SourceURL string          `json:"source_url"`
+	Links     []DeadLinkEntry `json:"links"`
+}
+
+// DeadLinkEntry is a broken link of a page, with the first time the crawl found it there
+type DeadLinkEntry struct {
+	domain.DeadLink
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// BuildDeadLinkReport groups dead links by the page linking to them, pages with most broken links first
+func BuildDeadLinkReport(results []domain.CrawlResult) []DeadLinkPage {
+	pages := make(map[string]*DeadLinkPage)
+	seen := make(map[string]map[string]int) // Index of each link in its page
+
+	add := func(source string, link domain.DeadLink, found time.Time) {
+		page := pages[source]
+		if page == nil {
+			page = &DeadLinkPage{SourceURL: source}
+			pages[source] = page
+			seen[source] = make(map[string]int)
+		}
+		if i, ok := seen[source][link.URL]; ok {
+			if found.Before(page.Links[i].FirstSeen) {
+				page.Links[i].FirstSeen = found
+			}
+			return
+		}
+		seen[source][link.URL] = len(page.Links)
+		page.Links = append(page.Links, DeadLinkEntry{DeadLink: link, FirstSeen: found})
+	}
+
+	for _, result := range results {
+		for _, link := range result.DeadLinkDetails {
+			add(result.URL, link, result.ProcessedAt)
+		}
+		// Results from before link details were recorded only have the URLs
+		for _, url := range result.DeadLinks {
+			add(result.URL, domain.DeadLink{URL: url}, result.ProcessedAt)
+		}
+	}
+
+	list := make([]DeadLinkPage, 0, len(pages))
+	for _, page := range pages {
+		sort.Slice(page.Links, func(i, j int) bool { return page.Links[i].URL < page.Links[j].URL })
+		list = append(list, *page)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].Links) != len(list[j].Links) {
+			return len(list[i].Links) > len(list[j].Links)
+		}
+		return list[i].SourceURL < list[j].SourceURL
+	})
+	return list
+}
+
+// WriteDeadLinkReport writes a standalone HTML broken link report grouped by source page
+func WriteDeadLinkReport(w io.Writer, results []domain.CrawlResult) error {
+	pages := BuildDeadLinkReport(results)
+
+	total := 0
+	for _, page := range pages {
+		total += len(page.Links)
+	}
+
+	return deadLinkTemplate.Execute(w, struct {
+		Generated string
+		Pages     []DeadLinkPage
+		Total     int
+	}{
+		Generated: time.Now().Format("2006-01-02 15:04 MST"),
+		Pages:     pages,
+		Total:     total,
+	})
+}
+
+// WriteDeadLinkReportAs writes the dead link report as json, csv or html, see DeadLinkReportFormats
+func WriteDeadLinkReportAs(w io.Writer, format string, results []domain.CrawlResult) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(BuildDeadLinkReport(results))
+	case "csv":
+		return writeDeadLinkReportCSV(w, results)
+	case "html":
+		return WriteDeadLinkReport(w, results)
+	default:
+		return fmt.Errorf("unknown dead link report format %q (available: json, csv, html)", format)
+	}
+}
+
+// writeDeadLinkReportCSV writes one row per broken link, the rows of a page follow each other
+func writeDeadLinkReportCSV(w io.Writer, results []domain.CrawlResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"source_url", "link", "status_code", "anchor_text", "redirect_to", "error", "first_seen"})
+
+	for _, page := range BuildDeadLinkReport(results) {
+		for _, link := range page.Links {
+			status := ""
+			if link.StatusCode != 0 {
+				status = fmt.Sprint(link.StatusCode)
+			}
+			writer.Write([]string{
+				page.SourceURL,
+				link.URL,
+				status,
+				link.AnchorText,
+				link.RedirectTo,
+				link.Error,
+				link.FirstSeen.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+var deadLinkTemplate = template.Must(template.New("deadlinks").Funcs(template.FuncMap{
+	"statusClass": func(code int) string {
+		switch {
+		case code == 0:
+			return "none"
+		case code >= 500:
+			return "server"
+		default:
+			return "client"
+		}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Broken Link Report</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 2rem auto; max-width: 1100px; padding: 0 1rem; }
+  h1 { margin-bottom: 0.2rem; }
+  .summary { color: #666; margin-bottom: 2rem; }
+  section { margin-bottom: 2rem; }
+  h2 { font-size: 1rem; word-break: break-all; border-bottom: 2px solid #eee; padding-bottom: 0.3rem; }
+  h2 .count { color: #888; font-weight: normal; }
+  table { border-collapse: collapse; width: 100%; font-size: 0.9rem; }
+  th, td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid #eee; vertical-align: top; }
+  th { background: #fafafa; }
+  td.url { word-break: break-all; }
+  .status { display: inline-block; min-width: 2.5rem; text-align: center; border-radius: 3px; padding: 0.1rem 0.3rem; font-weight: bold; color: #fff; }
+  .status.client { background: #d9534f; }
+  .status.server { background: #8e44ad; }
+  .status.none { background: #999; }
+  .muted { color: #999; }
+  a { color: #0366d6; text-decoration: none; }
+</style>
+</head>
+<body>
+<h1>Broken Link Report</h1>
+<p class="summary">{{.Total}} broken links on {{len .Pages}} pages &middot; generated {{.Generated}}</p>
+{{if not .Pages}}<p>No broken links were found.</p>{{end}}
+{{range .Pages}}<section>
+<h2><a href="{{.SourceURL}}">{{.SourceURL}}</a> <span class="count">({{len .Links}})</span></h2>
+<table>
+<tr><th>Link</th><th>Status</th><th>Anchor text</th><th>Redirects to</th><th>Error</th><th>First seen</th></tr>
+{{range .Links}}<tr>
+<td class="url">{{.URL}}</td>
+<td><span class="status {{statusClass .StatusCode}}">{{if .StatusCode}}{{.StatusCode}}{{else}}&ndash;{{end}}</span></td>
+<td>{{if .AnchorText}}{{.AnchorText}}{{else}}<span class="muted">none</span>{{end}}</td>
+<td class="url">{{.RedirectTo}}</td>
+<td>{{.Error}}</td>
+<td>{{.FirstSeen.Format "2006-01-02 15:04"}}</td>
+</tr>
+{{end}}</table>
+</section>
+{{end}}</body>
+</html>
+`))
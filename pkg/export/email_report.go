@@ -0,0 +1,97 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// EmailDomainReport aggregates the emails found for one mail domain
+type EmailDomainReport struct {
+	Domain      string    `json:"domain"`
+	Emails      []string  `json:"emails"`
+	Occurrences int       `json:"occurrences"`  // Every time an address of the domain was found
+	SourcePages int       `json:"source_pages"` // Distinct pages they were found on
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// BuildEmailReport groups found emails by mail domain, largest domains first
+func BuildEmailReport(results []domain.CrawlResult) []EmailDomainReport {
+	reports := make(map[string]*EmailDomainReport)
+	emails := make(map[string]map[string]bool)
+	pages := make(map[string]map[string]bool)
+
+	for _, result := range results {
+		for _, email := range result.Emails {
+			email = strings.ToLower(email)
+			at := strings.LastIndex(email, "@")
+			if at < 0 {
+				continue
+			}
+			mailDomain := email[at+1:]
+
+			report := reports[mailDomain]
+			if report == nil {
+				report = &EmailDomainReport{Domain: mailDomain, FirstSeen: result.ProcessedAt, LastSeen: result.ProcessedAt}
+				reports[mailDomain] = report
+				emails[mailDomain] = make(map[string]bool)
+				pages[mailDomain] = make(map[string]bool)
+			}
+
+			report.Occurrences++
+			emails[mailDomain][email] = true
+			pages[mailDomain][result.URL] = true
+			if result.ProcessedAt.Before(report.FirstSeen) {
+				report.FirstSeen = result.ProcessedAt
+			}
+			if result.ProcessedAt.After(report.LastSeen) {
+				report.LastSeen = result.ProcessedAt
+			}
+		}
+	}
+
+	list := make([]EmailDomainReport, 0, len(reports))
+	for mailDomain, report := range reports {
+		for email := range emails[mailDomain] {
+			report.Emails = append(report.Emails, email)
+		}
+		sort.Strings(report.Emails)
+		report.SourcePages = len(pages[mailDomain])
+		list = append(list, *report)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].Emails) != len(list[j].Emails) {
+			return len(list[i].Emails) > len(list[j].Emails)
+		}
+		return list[i].Domain < list[j].Domain
+	})
+	return list
+}
+
+// WriteEmailReport writes the email domain report as CSV, one row per mail domain
+func WriteEmailReport(w io.Writer, results []domain.CrawlResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"domain", "email_count", "occurrences", "source_pages", "first_seen", "last_seen", "emails"})
+
+	for _, report := range BuildEmailReport(results) {
+		writer.Write([]string{
+			report.Domain,
+			fmt.Sprint(len(report.Emails)),
+			fmt.Sprint(report.Occurrences),
+			fmt.Sprint(report.SourcePages),
+			report.FirstSeen.UTC().Format(time.RFC3339),
+			report.LastSeen.UTC().Format(time.RFC3339),
+			strings.Join(report.Emails, " "),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,134 @@
+// Package export writes crawl results in formats meant for tools outside golamv2
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golamv2/internal/domain"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatParquet Format = "parquet"
+	FormatSQLite  Format = "sqlite"
+	FormatXLSX    Format = "xlsx"
+
+	// Flat formats, one result per row or line
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+
+	// FormatEmailReport is the per mail domain email report as CSV
+	FormatEmailReport Format = "email-report"
+
+	// FormatDeadLinkReport is the standalone HTML broken link report
+	FormatDeadLinkReport Format = "deadlink-report"
+
+	// FormatA11yReport is the per domain accessibility audit report as CSV
+	FormatA11yReport Format = "a11y-report"
+
+	// Link graph formats, see Options.GraphLevel
+	FormatGraphML Format = "graphml"
+	FormatDOT     Format = "dot"
+)
+
+// errSQLiteUnavailable is returned for the sqlite format by builds without cgo
+var errSQLiteUnavailable = errors.New("the sqlite format needs a build with CGO_ENABLED=1, this one has none")
+
+// Options tunes formats that can be exported in more than one shape
+type Options struct {
+	GraphLevel GraphLevel // Page or domain nodes for graph formats, pages by default
+}
+
+// Formats lists the supported formats
+var Formats = []Format{FormatJSON, FormatCSV, FormatNDJSON, FormatParquet, FormatSQLite, FormatXLSX, FormatEmailReport, FormatDeadLinkReport, FormatA11yReport, FormatGraphML, FormatDOT}
+
+// ParseFormat validates a format name
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	if format == FormatSQLite && !sqliteAvailable {
+		return "", errSQLiteUnavailable
+	}
+	for _, known := range Formats {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q (available: %s)", name, formatList())
+}
+
+// Extension returns the usual file extension of a format
+func (f Format) Extension() string {
+	switch f {
+	case FormatSQLite:
+		return ".db"
+	case FormatEmailReport, FormatA11yReport:
+		return ".csv"
+	case FormatDeadLinkReport:
+		return ".html"
+	default:
+		return "." + string(f)
+	}
+}
+
+// WriteResults writes crawl results to w in one of the streamable formats
+func WriteResults(format Format, w io.Writer, results []domain.CrawlResult, opts Options) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, results)
+	case FormatCSV:
+		return WriteCSV(w, results)
+	case FormatNDJSON:
+		return WriteNDJSON(w, results)
+	case FormatParquet:
+		return WriteParquet(w, results)
+	case FormatXLSX:
+		return WriteXLSX(w, results)
+	case FormatEmailReport:
+		return WriteEmailReport(w, results)
+	case FormatDeadLinkReport:
+		return WriteDeadLinkReport(w, results)
+	case FormatA11yReport:
+		return WriteAccessibilityReport(w, results)
+	case FormatGraphML:
+		return WriteGraphML(w, BuildLinkGraph(results, opts.GraphLevel))
+	case FormatDOT:
+		return WriteDOT(w, BuildLinkGraph(results, opts.GraphLevel))
+	case FormatSQLite:
+		return fmt.Errorf("the sqlite format needs a file, use WriteFile")
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// WriteFile writes crawl results to the file at path in the given format
+func WriteFile(format Format, path string, results []domain.CrawlResult, opts Options) error {
+	if format == FormatSQLite {
+		return WriteSQLite(path, results)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+
+	if err := WriteResults(format, file, results, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func formatList() string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,217 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golamv2/internal/domain"
+)
+
+// GraphLevel decides what the nodes of an exported link graph are
+type GraphLevel string
+
+const (
+	GraphPages   GraphLevel = "page"   // One node per page, one edge per link
+	GraphDomains GraphLevel = "domain" // One node per domain, edges weighted by link count
+)
+
+// ParseGraphLevel validates a graph level name
+func ParseGraphLevel(name string) (GraphLevel, error) {
+	switch level := GraphLevel(strings.ToLower(name)); level {
+	case GraphPages, GraphDomains:
+		return level, nil
+	case "":
+		return GraphPages, nil
+	default:
+		return "", fmt.Errorf("invalid graph level %q: must be page or domain", name)
+	}
+}
+
+// GraphNode is a page or a domain
+type GraphNode struct {
+	ID         string
+	Domain     string
+	StatusCode int  // Page graphs only, 0 when the page was never crawled
+	Crawled    bool // Page graphs only
+	Pages      int  // Domain graphs only
+}
+
+// GraphEdge is a link, Weight counts links between the same domains
+type GraphEdge struct {
+	Source string
+	Target string
+	Weight int
+}
+
+// LinkGraph is the crawled link graph
+type LinkGraph struct {
+	Level GraphLevel
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// BuildLinkGraph builds the graph of the links between pages
+func BuildLinkGraph(results []domain.CrawlResult, level GraphLevel) LinkGraph {
+	if level == GraphDomains {
+		return buildDomainGraph(results)
+	}
+
+	graph := LinkGraph{Level: GraphPages}
+	nodes := make(map[string]*GraphNode)
+	node := func(url string) *GraphNode {
+		n := nodes[url]
+		if n == nil {
+			n = &GraphNode{ID: url, Domain: domain.GetDomain(url)}
+			nodes[url] = n
+		}
+		return n
+	}
+
+	edges := make(map[[2]string]bool)
+	for _, result := range results {
+		source := node(result.URL)
+		source.Crawled = true
+		source.StatusCode = result.StatusCode
+
+		for _, target := range outgoingLinks(result) {
+			node(target)
+			if key := [2]string{result.URL, target}; !edges[key] {
+				edges[key] = true
+				graph.Edges = append(graph.Edges, GraphEdge{Source: result.URL, Target: target, Weight: 1})
+			}
+		}
+	}
+
+	graph.Nodes = sortedNodes(nodes)
+	return graph
+}
+
+// outgoingLinks returns every link of a page, results stored before they were kept only
+// have the links their page discovered
+func outgoingLinks(result domain.CrawlResult) []string {
+	if len(result.Links) > 0 {
+		return result.Links
+	}
+	return result.NewURLs
+}
+
+// buildDomainGraph collapses pages into their domains, links inside a domain are dropped
+func buildDomainGraph(results []domain.CrawlResult) LinkGraph {
+	graph := LinkGraph{Level: GraphDomains}
+	nodes := make(map[string]*GraphNode)
+	node := func(host string) *GraphNode {
+		n := nodes[host]
+		if n == nil {
+			n = &GraphNode{ID: host, Domain: host}
+			nodes[host] = n
+		}
+		return n
+	}
+
+	weights := make(map[[2]string]int)
+	for _, result := range results {
+		source := domain.GetDomain(result.URL)
+		node(source).Pages++
+
+		for _, target := range outgoingLinks(result) {
+			targetDomain := domain.GetDomain(target)
+			node(targetDomain)
+			if targetDomain != source {
+				weights[[2]string{source, targetDomain}]++
+			}
+		}
+	}
+
+	for key, weight := range weights {
+		graph.Edges = append(graph.Edges, GraphEdge{Source: key[0], Target: key[1], Weight: weight})
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Source != graph.Edges[j].Source {
+			return graph.Edges[i].Source < graph.Edges[j].Source
+		}
+		return graph.Edges[i].Target < graph.Edges[j].Target
+	})
+
+	graph.Nodes = sortedNodes(nodes)
+	return graph
+}
+
+func sortedNodes(nodes map[string]*GraphNode) []GraphNode {
+	list := make([]GraphNode, 0, len(nodes))
+	for _, n := range nodes {
+		list = append(list, *n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// WriteGraphML writes the graph as GraphML, which Gephi, yEd and most graph databases import
+func WriteGraphML(w io.Writer, graph LinkGraph) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	bw.WriteString(`  <key id="domain" for="node" attr.name="domain" attr.type="string"/>` + "\n")
+	if graph.Level == GraphDomains {
+		bw.WriteString(`  <key id="pages" for="node" attr.name="pages" attr.type="int"/>` + "\n")
+	} else {
+		bw.WriteString(`  <key id="status" for="node" attr.name="status_code" attr.type="int"/>` + "\n")
+		bw.WriteString(`  <key id="crawled" for="node" attr.name="crawled" attr.type="boolean"/>` + "\n")
+	}
+	bw.WriteString(`  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>` + "\n")
+	bw.WriteString(`  <graph id="links" edgedefault="directed">` + "\n")
+
+	for _, n := range graph.Nodes {
+		fmt.Fprintf(bw, `    <node id="%s"><data key="domain">%s</data>`, xmlEscape(n.ID), xmlEscape(n.Domain))
+		if graph.Level == GraphDomains {
+			fmt.Fprintf(bw, `<data key="pages">%d</data>`, n.Pages)
+		} else {
+			fmt.Fprintf(bw, `<data key="status">%d</data><data key="crawled">%t</data>`, n.StatusCode, n.Crawled)
+		}
+		bw.WriteString("</node>\n")
+	}
+
+	for i, e := range graph.Edges {
+		fmt.Fprintf(bw, `    <edge id="e%d" source="%s" target="%s"><data key="weight">%d</data></edge>`+"\n",
+			i, xmlEscape(e.Source), xmlEscape(e.Target), e.Weight)
+	}
+
+	bw.WriteString("  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// WriteDOT writes the graph in Graphviz DOT
+func WriteDOT(w io.Writer, graph LinkGraph) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("digraph links {\n")
+	for _, n := range graph.Nodes {
+		if graph.Level == GraphDomains {
+			fmt.Fprintf(bw, "  %s [pages=%d];\n", dotQuote(n.ID), n.Pages)
+		} else {
+			fmt.Fprintf(bw, "  %s [domain=%s, status_code=%d, crawled=%t];\n", dotQuote(n.ID), dotQuote(n.Domain), n.StatusCode, n.Crawled)
+		}
+	}
+	for _, e := range graph.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [weight=%d];\n", dotQuote(e.Source), dotQuote(e.Target), e.Weight)
+	}
+	bw.WriteString("}\n")
+
+	return bw.Flush()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
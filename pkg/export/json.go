@@ -0,0 +1,15 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"golamv2/internal/domain"
+)
+
+// WriteJSON writes results as an indented JSON array, like the explorer always did
+func WriteJSON(w io.Writer, results []domain.CrawlResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
@@ -0,0 +1,320 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+
+	"golamv2/internal/domain"
+
+	"github.com/golang/snappy"
+)
+
+// Just enough of the Parquet format to write one row group of crawl results with
+// snappy compressed PLAIN pages, which DuckDB, Spark and Athena all read
+
+// Parquet physical types, repetitions, converted types and codecs from parquet.thrift
+const (
+	parquetInt32     int32 = 1
+	parquetInt64     int32 = 2
+	parquetByteArray int32 = 6
+
+	parquetRequired int32 = 0
+	parquetRepeated int32 = 2
+
+	convertedNone            int32 = -1
+	convertedUTF8            int32 = 0
+	convertedMap             int32 = 1
+	convertedMapKeyValue     int32 = 2
+	convertedList            int32 = 3
+	convertedTimestampMillis int32 = 9
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	codecSnappy int32 = 1
+	pageData    int32 = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// schemaNode is one element of the flattened Parquet schema, groups have no physical type
+type schemaNode struct {
+	name       string
+	physical   int32 // -1 for groups
+	repetition int32 // -1 for the root
+	converted  int32
+	children   int32
+}
+
+// resultSchema describes the columns written for crawl results
+var resultSchema = []schemaNode{
+	{name: "schema", physical: -1, repetition: -1, converted: convertedNone, children: 14},
+	{name: "url", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "domain", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "status_code", physical: parquetInt32, repetition: parquetRequired, converted: convertedNone},
+	{name: "title", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "emails", physical: -1, repetition: parquetRequired, converted: convertedList, children: 1},
+	{name: "list", physical: -1, repetition: parquetRepeated, converted: convertedNone, children: 1},
+	{name: "element", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "keywords", physical: -1, repetition: parquetRequired, converted: convertedMap, children: 1},
+	{name: "key_value", physical: -1, repetition: parquetRepeated, converted: convertedMapKeyValue, children: 2},
+	{name: "key", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "value", physical: parquetInt32, repetition: parquetRequired, converted: convertedNone},
+	{name: "dead_links", physical: -1, repetition: parquetRequired, converted: convertedList, children: 1},
+	{name: "list", physical: -1, repetition: parquetRepeated, converted: convertedNone, children: 1},
+	{name: "element", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "dead_domains", physical: -1, repetition: parquetRequired, converted: convertedList, children: 1},
+	{name: "list", physical: -1, repetition: parquetRepeated, converted: convertedNone, children: 1},
+	{name: "element", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "new_urls", physical: parquetInt32, repetition: parquetRequired, converted: convertedNone},
+	{name: "processed_at", physical: parquetInt64, repetition: parquetRequired, converted: convertedTimestampMillis},
+	{name: "process_time_ms", physical: parquetInt64, repetition: parquetRequired, converted: convertedNone},
+	{name: "error", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+	{name: "content_length", physical: parquetInt64, repetition: parquetRequired, converted: convertedNone},
+	{name: "content_hash", physical: parquetByteArray, repetition: parquetRequired, converted: convertedUTF8},
+}
+
+// parquetColumn buffers the levels and PLAIN encoded values of one leaf column
+type parquetColumn struct {
+	path     []string
+	physical int32
+	maxLevel int // Max repetition and definition level, lists and maps here are one level deep
+	values   bytes.Buffer
+	reps     []int
+	defs     []int
+}
+
+func newColumn(physical int32, maxLevel int, path ...string) *parquetColumn {
+	return &parquetColumn{path: path, physical: physical, maxLevel: maxLevel}
+}
+
+func (c *parquetColumn) level(rep, def int) {
+	if c.maxLevel > 0 {
+		c.reps = append(c.reps, rep)
+		c.defs = append(c.defs, def)
+	}
+}
+
+func (c *parquetColumn) addString(s string, rep, def int) {
+	c.level(rep, def)
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(s)))
+	c.values.WriteString(s)
+}
+
+func (c *parquetColumn) addInt32(v int32, rep, def int) {
+	c.level(rep, def)
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+func (c *parquetColumn) addInt64(v int64, rep, def int) {
+	c.level(rep, def)
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+// addList writes a list, an empty list is a single undefined entry
+func (c *parquetColumn) addList(values []string) {
+	if len(values) == 0 {
+		c.level(0, 0)
+		return
+	}
+	for i, v := range values {
+		c.addString(v, min(i, 1), 1)
+	}
+}
+
+// numValues is the number of entries in the page, empty lists included
+func (c *parquetColumn) numValues(rows int) int {
+	if c.maxLevel > 0 {
+		return len(c.defs)
+	}
+	return rows
+}
+
+// page returns the uncompressed data page body: repetition levels, definition levels, values
+func (c *parquetColumn) page() []byte {
+	var page bytes.Buffer
+	if c.maxLevel > 0 {
+		writeLevels(&page, c.reps, c.maxLevel)
+		writeLevels(&page, c.defs, c.maxLevel)
+	}
+	page.Write(c.values.Bytes())
+	return page.Bytes()
+}
+
+// writeLevels writes levels with the RLE/bit-packing hybrid, using RLE runs only
+func writeLevels(w *bytes.Buffer, levels []int, maxLevel int) {
+	byteWidth := (bits.Len(uint(maxLevel)) + 7) / 8
+
+	var runs bytes.Buffer
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		header := uint64(j-i) << 1
+		for header >= 0x80 {
+			runs.WriteByte(byte(header) | 0x80)
+			header >>= 7
+		}
+		runs.WriteByte(byte(header))
+		for b := 0; b < byteWidth; b++ {
+			runs.WriteByte(byte(levels[i] >> (8 * b)))
+		}
+		i = j
+	}
+
+	binary.Write(w, binary.LittleEndian, uint32(runs.Len()))
+	w.Write(runs.Bytes())
+}
+
+// WriteParquet writes results as a snappy compressed Parquet file
+func WriteParquet(w io.Writer, results []domain.CrawlResult) error {
+	var (
+		urlCol        = newColumn(parquetByteArray, 0, "url")
+		domainCol     = newColumn(parquetByteArray, 0, "domain")
+		statusCol     = newColumn(parquetInt32, 0, "status_code")
+		titleCol      = newColumn(parquetByteArray, 0, "title")
+		emailsCol     = newColumn(parquetByteArray, 1, "emails", "list", "element")
+		keywordCol    = newColumn(parquetByteArray, 1, "keywords", "key_value", "key")
+		countCol      = newColumn(parquetInt32, 1, "keywords", "key_value", "value")
+		deadLinksCol  = newColumn(parquetByteArray, 1, "dead_links", "list", "element")
+		deadDomainCol = newColumn(parquetByteArray, 1, "dead_domains", "list", "element")
+		newURLsCol    = newColumn(parquetInt32, 0, "new_urls")
+		processedCol  = newColumn(parquetInt64, 0, "processed_at")
+		durationCol   = newColumn(parquetInt64, 0, "process_time_ms")
+		errorCol      = newColumn(parquetByteArray, 0, "error")
+		lengthCol     = newColumn(parquetInt64, 0, "content_length")
+		hashCol       = newColumn(parquetByteArray, 0, "content_hash")
+	)
+	columns := []*parquetColumn{
+		urlCol, domainCol, statusCol, titleCol, emailsCol, keywordCol, countCol,
+		deadLinksCol, deadDomainCol, newURLsCol, processedCol, durationCol, errorCol,
+		lengthCol, hashCol,
+	}
+
+	for _, result := range results {
+		urlCol.addString(result.URL, 0, 0)
+		domainCol.addString(domain.GetDomain(result.URL), 0, 0)
+		statusCol.addInt32(int32(result.StatusCode), 0, 0)
+		titleCol.addString(result.Title, 0, 0)
+		emailsCol.addList(result.Emails)
+
+		keywords := make([]string, 0, len(result.Keywords))
+		for keyword := range result.Keywords {
+			keywords = append(keywords, keyword)
+		}
+		sort.Strings(keywords)
+		keywordCol.addList(keywords)
+		if len(keywords) == 0 {
+			countCol.level(0, 0)
+		}
+		for i, keyword := range keywords {
+			countCol.addInt32(int32(result.Keywords[keyword]), min(i, 1), 1)
+		}
+
+		deadLinksCol.addList(result.DeadLinks)
+		deadDomainCol.addList(result.DeadDomains)
+		newURLsCol.addInt32(int32(len(result.NewURLs)), 0, 0)
+		processedCol.addInt64(result.ProcessedAt.UnixMilli(), 0, 0)
+		durationCol.addInt64(result.ProcessTime.Milliseconds(), 0, 0)
+		errorCol.addString(result.Error, 0, 0)
+		lengthCol.addInt64(result.ContentLength, 0, 0)
+		hashCol.addString(result.ContentHash, 0, 0)
+	}
+
+	return writeParquetFile(w, columns, len(results))
+}
+
+// writeParquetFile writes every column as a single page of one row group, then the footer
+func writeParquetFile(w io.Writer, columns []*parquetColumn, rows int) error {
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	footer := newThriftWriter()
+	footer.writeI32(1, 1) // version
+	footer.listHeader(2, thriftStruct, len(resultSchema))
+	for _, node := range resultSchema {
+		footer.beginListStruct()
+		if node.physical >= 0 {
+			footer.writeI32(1, node.physical)
+		}
+		if node.repetition >= 0 {
+			footer.writeI32(3, node.repetition)
+		}
+		footer.writeString(4, node.name)
+		if node.children > 0 {
+			footer.writeI32(5, node.children)
+		}
+		if node.converted >= 0 {
+			footer.writeI32(6, node.converted)
+		}
+		footer.endStruct()
+	}
+	footer.writeI64(3, int64(rows))
+
+	// The row group goes after the schema but its column chunks are written first
+	chunks := newThriftWriter()
+	totalSize := int64(0)
+	for _, column := range columns {
+		offset := int64(file.Len())
+		page := column.page()
+		compressed := snappy.Encode(nil, page)
+
+		header := newThriftWriter()
+		header.writeI32(1, pageData)
+		header.writeI32(2, int32(len(page)))
+		header.writeI32(3, int32(len(compressed)))
+		header.beginStruct(5)
+		header.writeI32(1, int32(column.numValues(rows)))
+		header.writeI32(2, encodingPlain)
+		header.writeI32(3, encodingRLE)
+		header.writeI32(4, encodingRLE)
+		header.endStruct()
+		header.buf.WriteByte(0) // STOP of the page header
+
+		file.Write(header.Bytes())
+		file.Write(compressed)
+
+		uncompressedSize := int64(header.buf.Len() + len(page))
+		compressedSize := int64(header.buf.Len() + len(compressed))
+		totalSize += uncompressedSize
+
+		chunks.beginListStruct()
+		chunks.writeI64(2, offset)
+		chunks.beginStruct(3)
+		chunks.writeI32(1, column.physical)
+		chunks.writeI32List(2, []int32{encodingPlain, encodingRLE})
+		chunks.writeStringList(3, column.path)
+		chunks.writeI32(4, codecSnappy)
+		chunks.writeI64(5, int64(column.numValues(rows)))
+		chunks.writeI64(6, uncompressedSize)
+		chunks.writeI64(7, compressedSize)
+		chunks.writeI64(9, offset)
+		chunks.endStruct()
+		chunks.endStruct()
+	}
+
+	footer.listHeader(4, thriftStruct, 1)
+	footer.beginListStruct()
+	footer.listHeader(1, thriftStruct, len(columns))
+	footer.buf.Write(chunks.Bytes())
+	footer.writeI64(2, totalSize)
+	footer.writeI64(3, int64(rows))
+	footer.endStruct()
+	footer.writeString(6, "golamv2")
+	footer.buf.WriteByte(0) // STOP of the file metadata
+
+	file.Write(footer.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(footer.buf.Len()))
+	file.Write(parquetMagic)
+
+	if _, err := w.Write(file.Bytes()); err != nil {
+		return fmt.Errorf("failed to write parquet file: %v", err)
+	}
+	return nil
+}
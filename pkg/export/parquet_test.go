@@ -0,0 +1,464 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golamv2/internal/domain"
+
+	"github.com/golang/snappy"
+)
+
+// The round trip test reads the files back with a reader of its own, written from the
+// Parquet and Thrift specs and sharing no code with the writer
+
+// thriftReader reads Thrift compact protocol values: integers as int64, binaries as strings,
+// lists as []any and structs as map[int16]any
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) next(n int) []byte {
+	if n < 0 || r.pos+n > len(r.data) {
+		panic(fmt.Sprintf("thrift: %d bytes wanted at %d of %d", n, r.pos, len(r.data)))
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("thrift: bad varint at %d", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1, 2: // Booleans in lists, fields carry theirs in the type
+		return r.next(1)[0] == 1
+	case 3:
+		return int64(int8(r.next(1)[0]))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		return binary.LittleEndian.Uint64(r.next(8))
+	case 8:
+		return string(r.next(int(r.varint())))
+	case 9, 10:
+		header := r.next(1)[0]
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("thrift: type %d at %d", typ, r.pos))
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	last := int16(0)
+	for {
+		header := r.next(1)[0]
+		if header == 0 {
+			return fields
+		}
+		typ, delta := header&0x0f, int16(header>>4)
+		id := last + delta
+		if delta == 0 {
+			id = int16(r.zigzag())
+		}
+		last = id
+		if typ == 1 || typ == 2 {
+			fields[id] = typ == 1
+			continue
+		}
+		fields[id] = r.value(typ)
+	}
+}
+
+// parquetLeaf is a leaf column of the schema with the levels its path allows
+type parquetLeaf struct {
+	path      string
+	physical  int64
+	converted int64
+	maxRep    int
+	maxDef    int
+}
+
+// schemaLeaves walks the flattened schema depth first, counting repeated and
+// non-required ancestors for the levels
+func schemaLeaves(t *testing.T, schema []any) []parquetLeaf {
+	var leaves []parquetLeaf
+	pos := 1
+	var walk func(path []string, rep, def int)
+	walk = func(path []string, rep, def int) {
+		node := schema[pos].(map[int16]any)
+		pos++
+		path = append(path, node[4].(string))
+		switch node[3] {
+		case int64(parquetRepeated):
+			rep, def = rep+1, def+1
+		case int64(parquetRequired):
+		default:
+			def++
+		}
+		converted := int64(-1)
+		if c, ok := node[6]; ok {
+			converted = c.(int64)
+		}
+		if children, ok := node[5]; ok {
+			for i := int64(0); i < children.(int64); i++ {
+				walk(path, rep, def)
+			}
+			return
+		}
+		leaves = append(leaves, parquetLeaf{strings.Join(path, "."), node[1].(int64), converted, rep, def})
+	}
+
+	root := schema[0].(map[int16]any)
+	for i := int64(0); i < root[5].(int64); i++ {
+		walk(nil, 0, 0)
+	}
+	if pos != len(schema) {
+		t.Fatalf("schema has %d elements, the tree %d", len(schema), pos)
+	}
+	return leaves
+}
+
+// readLevels decodes numValues levels of the RLE/bit-packing hybrid, prefixed by their length
+func readLevels(t *testing.T, page []byte, numValues, maxLevel int) ([]int, []byte) {
+	length := int(binary.LittleEndian.Uint32(page))
+	data, rest := page[4:4+length], page[4+length:]
+	width := bits.Len(uint(maxLevel))
+
+	var levels []int
+	for len(data) > 0 {
+		header, n := binary.Uvarint(data)
+		data = data[n:]
+		if header&1 == 0 {
+			count := int(header >> 1)
+			value := 0
+			for b := 0; b < (width+7)/8; b++ {
+				value |= int(data[b]) << (8 * b)
+			}
+			data = data[(width+7)/8:]
+			for i := 0; i < count; i++ {
+				levels = append(levels, value)
+			}
+			continue
+		}
+		groups := int(header >> 1)
+		packed := data[:groups*width]
+		data = data[groups*width:]
+		for i := 0; i < groups*8; i++ {
+			value := 0
+			for b := 0; b < width; b++ {
+				bit := i*width + b
+				value |= int(packed[bit/8]>>(bit%8)&1) << b
+			}
+			levels = append(levels, value)
+		}
+	}
+	if len(levels) < numValues {
+		t.Fatalf("%d levels, want %d", len(levels), numValues)
+	}
+	return levels[:numValues], rest
+}
+
+// readColumn decodes the single data page of a column chunk into the values of every row
+func readColumn(t *testing.T, file []byte, leaf parquetLeaf, chunk map[int16]any, rows int) [][]any {
+	meta := chunk[3].(map[int16]any)
+	if meta[1] != leaf.physical || meta[4] != int64(codecSnappy) {
+		t.Fatalf("%s: type %v, codec %v", leaf.path, meta[1], meta[4])
+	}
+	var path []string
+	for _, part := range meta[3].([]any) {
+		path = append(path, part.(string))
+	}
+	if strings.Join(path, ".") != leaf.path {
+		t.Fatalf("chunk of %s, schema leaf %s", strings.Join(path, "."), leaf.path)
+	}
+
+	offset := int(meta[9].(int64))
+	reader := &thriftReader{data: file, pos: offset}
+	header := reader.readStruct()
+	compressed := file[reader.pos : reader.pos+int(header[3].(int64))]
+	page, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		t.Fatalf("%s: %v", leaf.path, err)
+	}
+	if len(page) != int(header[2].(int64)) {
+		t.Fatalf("%s: page of %d bytes, header says %d", leaf.path, len(page), header[2])
+	}
+	if size := int64(reader.pos - offset + len(compressed)); meta[7] != size {
+		t.Errorf("%s: compressed size %v, chunk is %d bytes", leaf.path, meta[7], size)
+	}
+
+	dataPage := header[5].(map[int16]any)
+	numValues := int(dataPage[1].(int64))
+	if meta[5] != int64(numValues) || dataPage[2] != int64(encodingPlain) {
+		t.Fatalf("%s: %v values in the chunk, %d in the page, encoding %v", leaf.path, meta[5], numValues, dataPage[2])
+	}
+
+	reps, defs := make([]int, numValues), make([]int, numValues)
+	for i := range defs {
+		defs[i] = leaf.maxDef
+	}
+	if leaf.maxRep > 0 {
+		reps, page = readLevels(t, page, numValues, leaf.maxRep)
+	}
+	if leaf.maxDef > 0 {
+		defs, page = readLevels(t, page, numValues, leaf.maxDef)
+	}
+
+	var values [][]any
+	for i := 0; i < numValues; i++ {
+		if reps[i] == 0 {
+			values = append(values, nil)
+		}
+		if defs[i] < leaf.maxDef {
+			continue
+		}
+		var value any
+		switch leaf.physical {
+		case int64(parquetInt32):
+			value, page = int32(binary.LittleEndian.Uint32(page)), page[4:]
+		case int64(parquetInt64):
+			value, page = int64(binary.LittleEndian.Uint64(page)), page[8:]
+		case int64(parquetByteArray):
+			length := binary.LittleEndian.Uint32(page)
+			value, page = string(page[4:4+length]), page[4+length:]
+		}
+		values[len(values)-1] = append(values[len(values)-1], value)
+	}
+	if len(page) != 0 {
+		t.Errorf("%s: %d bytes after the values", leaf.path, len(page))
+	}
+	if len(values) != rows {
+		t.Fatalf("%s: %d rows, want %d", leaf.path, len(values), rows)
+	}
+	return values
+}
+
+// readParquet checks the layout of a file and returns its columns by path
+func readParquet(t *testing.T, file []byte) (map[string]parquetLeaf, map[string][][]any, int) {
+	if !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		t.Fatal("no PAR1 magic")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footerStart := len(file) - 8 - footerLength
+	reader := &thriftReader{data: file[:len(file)-8], pos: footerStart}
+	footer := reader.readStruct()
+	if reader.pos != len(file)-8 {
+		t.Fatalf("footer ends at %d, the length says %d", reader.pos, len(file)-8)
+	}
+
+	rows := int(footer[3].(int64))
+	leaves := schemaLeaves(t, footer[2].([]any))
+	rowGroups := footer[4].([]any)
+	if len(rowGroups) != 1 {
+		t.Fatalf("%d row groups", len(rowGroups))
+	}
+	rowGroup := rowGroups[0].(map[int16]any)
+	chunks := rowGroup[1].([]any)
+	if rowGroup[3] != int64(rows) || len(chunks) != len(leaves) {
+		t.Fatalf("row group of %v rows and %d chunks, file of %d rows and %d leaves", rowGroup[3], len(chunks), rows, len(leaves))
+	}
+
+	byPath := make(map[string]parquetLeaf)
+	columns := make(map[string][][]any)
+	for i, leaf := range leaves {
+		byPath[leaf.path] = leaf
+		columns[leaf.path] = readColumn(t, file[:footerStart], leaf, chunks[i].(map[int16]any), rows)
+	}
+	return byPath, columns, rows
+}
+
+// parquetRow is a crawl result as the columns hold it
+type parquetRow struct {
+	URL, Domain   string
+	StatusCode    int32
+	Title         string
+	Emails        []string
+	Keywords      map[string]int32
+	DeadLinks     []string
+	DeadDomains   []string
+	NewURLs       int32
+	ProcessedAt   int64
+	ProcessTimeMS int64
+	Error         string
+	ContentLength int64
+	ContentHash   string
+}
+
+// rowOf is what the columns should hold for result, empty lists read back as nil
+func rowOf(result domain.CrawlResult) parquetRow {
+	row := parquetRow{
+		URL:           result.URL,
+		Domain:        domain.GetDomain(result.URL),
+		StatusCode:    int32(result.StatusCode),
+		Title:         result.Title,
+		Emails:        result.Emails,
+		DeadLinks:     result.DeadLinks,
+		DeadDomains:   result.DeadDomains,
+		NewURLs:       int32(len(result.NewURLs)),
+		ProcessedAt:   result.ProcessedAt.UnixMilli(),
+		ProcessTimeMS: result.ProcessTime.Milliseconds(),
+		Error:         result.Error,
+		ContentLength: result.ContentLength,
+		ContentHash:   result.ContentHash,
+	}
+	if len(result.Keywords) > 0 {
+		row.Keywords = make(map[string]int32)
+		for keyword, count := range result.Keywords {
+			row.Keywords[keyword] = int32(count)
+		}
+	}
+	return row
+}
+
+// stringValues converts the values of a list column
+func stringValues(values []any) []string {
+	var s []string
+	for _, v := range values {
+		s = append(s, v.(string))
+	}
+	return s
+}
+
+func TestWriteParquetRoundTrip(t *testing.T) {
+	processed := time.UnixMilli(1700000000123)
+	// Long runs of empty lists with a few full ones in between
+	many := make([]domain.CrawlResult, 300)
+	for i := range many {
+		many[i] = domain.CrawlResult{
+			URL:           fmt.Sprintf("https://example.com/page/%d", i),
+			StatusCode:    200,
+			Title:         strings.Repeat("t", i%200),
+			ProcessedAt:   processed,
+			ContentLength: int64(i),
+		}
+		if i%150 == 149 {
+			many[i].Emails = []string{fmt.Sprintf("a%d@example.com", i)}
+			many[i].Keywords = map[string]int{"golang": i}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		results []domain.CrawlResult
+	}{
+		{"no rows", nil},
+		{"flat values", []domain.CrawlResult{{
+			URL:           "https://Example.com/ünïcode?q=1",
+			StatusCode:    404,
+			Title:         "Ünïcode títle",
+			ProcessedAt:   processed,
+			ProcessTime:   1500 * time.Millisecond,
+			Error:         "not found",
+			ContentLength: 1 << 40,
+			ContentHash:   strings.Repeat("ab", 32),
+		}}},
+		{"lists and maps", []domain.CrawlResult{{
+			URL:         "https://example.com/",
+			Emails:      []string{"a@example.com", "b@example.com", "c@example.com"},
+			Keywords:    map[string]int{"rust": 1, "golang": 7, "zig": 2},
+			DeadLinks:   []string{"https://example.com/gone"},
+			DeadDomains: []string{"gone.example", "down.example"},
+			NewURLs:     []string{"https://example.com/a", "https://example.com/b"},
+			ProcessedAt: processed,
+		}}},
+		{"empty and full lists mixed", []domain.CrawlResult{
+			{URL: "https://example.com/1", ProcessedAt: processed},
+			{URL: "https://example.com/2", Emails: []string{"a@example.com"}, Keywords: map[string]int{"go": 1}, ProcessedAt: processed},
+			{URL: "https://example.com/3", ProcessedAt: processed},
+			{URL: "https://example.com/4", DeadLinks: []string{"https://x.example/", "https://y.example/"}, ProcessedAt: processed},
+		}},
+		{"long runs of levels", many},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteParquet(&buf, tt.results); err != nil {
+				t.Fatal(err)
+			}
+			leaves, columns, rows := readParquet(t, buf.Bytes())
+			if rows != len(tt.results) {
+				t.Fatalf("%d rows, want %d", rows, len(tt.results))
+			}
+
+			for path, want := range map[string][2]int32{
+				"url":                    {parquetByteArray, convertedUTF8},
+				"status_code":            {parquetInt32, convertedNone},
+				"emails.list.element":    {parquetByteArray, convertedUTF8},
+				"keywords.key_value.key": {parquetByteArray, convertedUTF8},
+				"processed_at":           {parquetInt64, convertedTimestampMillis},
+			} {
+				if leaf := leaves[path]; leaf.physical != int64(want[0]) || leaf.converted != int64(want[1]) {
+					t.Errorf("%s: type %d converted %d, want %v", path, leaf.physical, leaf.converted, want)
+				}
+			}
+
+			for i, result := range tt.results {
+				value := func(path string) any { return columns[path][i][0] }
+				got := parquetRow{
+					URL:           value("url").(string),
+					Domain:        value("domain").(string),
+					StatusCode:    value("status_code").(int32),
+					Title:         value("title").(string),
+					Emails:        stringValues(columns["emails.list.element"][i]),
+					DeadLinks:     stringValues(columns["dead_links.list.element"][i]),
+					DeadDomains:   stringValues(columns["dead_domains.list.element"][i]),
+					NewURLs:       value("new_urls").(int32),
+					ProcessedAt:   value("processed_at").(int64),
+					ProcessTimeMS: value("process_time_ms").(int64),
+					Error:         value("error").(string),
+					ContentLength: value("content_length").(int64),
+					ContentHash:   value("content_hash").(string),
+				}
+				keys, counts := columns["keywords.key_value.key"][i], columns["keywords.key_value.value"][i]
+				if len(keys) != len(counts) {
+					t.Fatalf("row %d: %d keywords, %d counts", i, len(keys), len(counts))
+				}
+				if !sort.StringsAreSorted(stringValues(keys)) {
+					t.Errorf("row %d: keywords %v not sorted", i, keys)
+				}
+				for j, key := range keys {
+					if got.Keywords == nil {
+						got.Keywords = make(map[string]int32)
+					}
+					got.Keywords[key.(string)] = counts[j].(int32)
+				}
+
+				if want := rowOf(result); !reflect.DeepEqual(got, want) {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
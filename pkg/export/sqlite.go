@@ -0,0 +1,187 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// sqliteSchema normalizes results into one table per kind of finding
+const sqliteSchema = `
+CREATE TABLE results (
+	id              INTEGER PRIMARY KEY,
+	url             TEXT NOT NULL,
+	domain          TEXT NOT NULL,
+	status_code     INTEGER NOT NULL,
+	title           TEXT,
+	new_urls        INTEGER NOT NULL,
+	processed_at    TEXT NOT NULL,
+	process_time_ms INTEGER NOT NULL,
+	error           TEXT,
+	content_length  INTEGER NOT NULL,
+	content_hash    TEXT
+);
+CREATE TABLE emails (
+	result_id INTEGER NOT NULL REFERENCES results(id),
+	email     TEXT NOT NULL
+);
+CREATE TABLE keywords (
+	result_id INTEGER NOT NULL REFERENCES results(id),
+	keyword   TEXT NOT NULL,
+	count     INTEGER NOT NULL,
+	strategy  TEXT NOT NULL
+);
+CREATE TABLE dead_links (
+	result_id INTEGER NOT NULL REFERENCES results(id),
+	url       TEXT NOT NULL
+);
+CREATE TABLE dead_domains (
+	result_id    INTEGER NOT NULL REFERENCES results(id),
+	domain       TEXT NOT NULL,
+	cause        TEXT,
+	error        TEXT,
+	registration TEXT,
+	expires_at   TEXT
+);
+CREATE TABLE headers (
+	result_id INTEGER NOT NULL REFERENCES results(id),
+	name      TEXT NOT NULL,
+	value     TEXT NOT NULL
+);
+
+CREATE INDEX idx_results_url ON results(url);
+CREATE INDEX idx_results_domain ON results(domain);
+CREATE INDEX idx_results_status ON results(status_code);
+CREATE INDEX idx_results_content_hash ON results(content_hash);
+CREATE INDEX idx_emails_email ON emails(email);
+CREATE INDEX idx_emails_result ON emails(result_id);
+CREATE INDEX idx_keywords_keyword ON keywords(keyword);
+CREATE INDEX idx_keywords_result ON keywords(result_id);
+CREATE INDEX idx_dead_links_url ON dead_links(url);
+CREATE INDEX idx_dead_links_result ON dead_links(result_id);
+CREATE INDEX idx_dead_domains_domain ON dead_domains(domain);
+CREATE INDEX idx_dead_domains_result ON dead_domains(result_id);
+CREATE INDEX idx_dead_domains_cause ON dead_domains(cause);
+CREATE INDEX idx_dead_domains_registration ON dead_domains(registration);
+CREATE INDEX idx_headers_name_value ON headers(name, value);
+CREATE INDEX idx_headers_result ON headers(result_id);
+`
+
+// WriteSQLite materializes results into a new SQLite database at path, replacing any existing file
+func WriteSQLite(path string, results []domain.CrawlResult) error {
+	if !sqliteAvailable {
+		return errSQLiteUnavailable
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("failed to create sqlite schema: %v", err)
+	}
+
+	// One transaction keeps large exports fast
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertResults(tx, results); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sqlite export: %v", err)
+	}
+	return nil
+}
+
+// insertResults writes every result and its findings inside tx
+func insertResults(tx *sql.Tx, results []domain.CrawlResult) error {
+	statements := map[string]string{
+		"results":      `INSERT INTO results (id, url, domain, status_code, title, new_urls, processed_at, process_time_ms, error, content_length, content_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		"emails":       `INSERT INTO emails (result_id, email) VALUES (?, ?)`,
+		"keywords":     `INSERT INTO keywords (result_id, keyword, count, strategy) VALUES (?, ?, ?, ?)`,
+		"dead_links":   `INSERT INTO dead_links (result_id, url) VALUES (?, ?)`,
+		"dead_domains": `INSERT INTO dead_domains (result_id, domain, cause, error, registration, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		"headers":      `INSERT INTO headers (result_id, name, value) VALUES (?, ?, ?)`,
+	}
+
+	prepared := make(map[string]*sql.Stmt, len(statements))
+	for table, query := range statements {
+		stmt, err := tx.Prepare(query)
+		if err != nil {
+			return fmt.Errorf("failed to prepare insert into %s: %v", table, err)
+		}
+		defer stmt.Close()
+		prepared[table] = stmt
+	}
+
+	for i, result := range results {
+		id := i + 1
+
+		_, err := prepared["results"].Exec(id, result.URL, domain.GetDomain(result.URL), result.StatusCode, result.Title,
+			len(result.NewURLs), result.ProcessedAt.UTC().Format(time.RFC3339), result.ProcessTime.Milliseconds(), result.Error,
+			result.ContentLength, result.ContentHash)
+		if err != nil {
+			return fmt.Errorf("failed to insert result %s: %v", result.URL, err)
+		}
+
+		for _, email := range result.Emails {
+			if _, err := prepared["emails"].Exec(id, email); err != nil {
+				return fmt.Errorf("failed to insert email: %v", err)
+			}
+		}
+		// Results from before matching strategies were recorded matched exactly
+		match := result.KeywordMatch
+		if match == "" {
+			match = domain.KeywordMatchExact
+		}
+		for keyword, count := range result.Keywords {
+			if _, err := prepared["keywords"].Exec(id, keyword, count, match); err != nil {
+				return fmt.Errorf("failed to insert keyword: %v", err)
+			}
+		}
+		for _, link := range result.DeadLinks {
+			if _, err := prepared["dead_links"].Exec(id, link); err != nil {
+				return fmt.Errorf("failed to insert dead link: %v", err)
+			}
+		}
+		// Results from before causes were recorded leave them empty, registrations are only
+		// there with --rdap
+		details := make(map[string]domain.DeadDomain, len(result.DeadDomainDetails))
+		for _, detail := range result.DeadDomainDetails {
+			details[detail.Domain] = detail
+		}
+		for _, deadDomain := range result.DeadDomains {
+			detail := details[deadDomain]
+			var registration, expiresAt interface{}
+			if detail.Registration != nil {
+				registration = string(detail.Registration.Status)
+				if detail.Registration.ExpiresAt != nil {
+					expiresAt = detail.Registration.ExpiresAt.UTC().Format(time.RFC3339)
+				}
+			}
+			if _, err := prepared["dead_domains"].Exec(id, deadDomain, string(detail.Cause), detail.Error, registration, expiresAt); err != nil {
+				return fmt.Errorf("failed to insert dead domain: %v", err)
+			}
+		}
+		for name, value := range result.Headers {
+			if _, err := prepared["headers"].Exec(id, name, value); err != nil {
+				return fmt.Errorf("failed to insert header: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
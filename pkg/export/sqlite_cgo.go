@@ -0,0 +1,8 @@
+//go:build cgo
+
+package export
+
+import _ "github.com/mattn/go-sqlite3" // sqlite driver, needs cgo
+
+// sqliteAvailable reports whether this build can write the sqlite format
+const sqliteAvailable = true
@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package export
+
+// sqliteAvailable reports whether this build can write the sqlite format
+const sqliteAvailable = false
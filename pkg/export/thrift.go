@@ -0,0 +1,107 @@
+package export
+
+import "bytes"
+
+// Thrift compact protocol type ids, just enough to write Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes Thrift compact protocol structs, Parquet page headers and footers use it
+type thriftWriter struct {
+	buf       bytes.Buffer
+	lastField []int16 // Last field id of every open struct
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastField: []int16{0}}
+}
+
+func (t *thriftWriter) Bytes() []byte {
+	return t.buf.Bytes()
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	for v >= 0x80 {
+		t.buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	t.buf.WriteByte(byte(v))
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+// fieldHeader writes a field header using the short delta form when possible
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.lastField[len(t.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) writeI32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) writeI64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) writeString(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// beginStruct opens a struct field, close it with endStruct
+func (t *thriftWriter) beginStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.lastField = append(t.lastField, 0)
+}
+
+// beginListStruct opens a struct that is an element of a list
+func (t *thriftWriter) beginListStruct() {
+	t.lastField = append(t.lastField, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0) // STOP
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+func (t *thriftWriter) listHeader(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xF0 | elemType)
+		t.varint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) writeI32List(id int16, values []int32) {
+	t.listHeader(id, thriftI32, len(values))
+	for _, v := range values {
+		t.zigzag(int64(v))
+	}
+}
+
+func (t *thriftWriter) writeStringList(id int16, values []string) {
+	t.listHeader(id, thriftBinary, len(values))
+	for _, v := range values {
+		t.varint(uint64(len(v)))
+		t.buf.WriteString(v)
+	}
+}
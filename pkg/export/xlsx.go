@@ -0,0 +1,143 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"golamv2/internal/domain"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// domainSummary is one row of the Domains sheet
+type domainSummary struct {
+	domain      string
+	pages       int
+	errors      int
+	emails      int
+	keywords    int
+	deadLinks   int
+	deadDomains int
+}
+
+// xlsxSheet is a sheet header and its rows
+type xlsxSheet struct {
+	name   string
+	header []string
+	widths []float64
+	rows   [][]interface{}
+}
+
+// WriteXLSX writes a spreadsheet with one sheet per kind of finding plus a per-domain summary
+func WriteXLSX(w io.Writer, results []domain.CrawlResult) error {
+	emails := xlsxSheet{name: "Emails", header: []string{"Email", "Found On"}, widths: []float64{40, 80}}
+	keywords := xlsxSheet{name: "Keywords", header: []string{"Keyword", "Count", "Found On"}, widths: []float64{25, 10, 80}}
+	deadLinks := xlsxSheet{name: "Dead Links", header: []string{"Dead Link", "Found On"}, widths: []float64{60, 80}}
+	domains := xlsxSheet{
+		name:   "Domains",
+		header: []string{"Domain", "Pages", "Errors", "Emails", "Keywords", "Dead Links", "Dead Domains"},
+		widths: []float64{40, 10, 10, 10, 10, 12, 14},
+	}
+
+	summaries := make(map[string]*domainSummary)
+	for _, result := range results {
+		host := domain.GetDomain(result.URL)
+		summary := summaries[host]
+		if summary == nil {
+			summary = &domainSummary{domain: host}
+			summaries[host] = summary
+		}
+
+		summary.pages++
+		if result.Error != "" {
+			summary.errors++
+		}
+		summary.emails += len(result.Emails)
+		summary.deadLinks += len(result.DeadLinks)
+		summary.deadDomains += len(result.DeadDomains)
+
+		for _, email := range result.Emails {
+			emails.rows = append(emails.rows, []interface{}{email, result.URL})
+		}
+		for keyword, count := range result.Keywords {
+			summary.keywords += count
+			keywords.rows = append(keywords.rows, []interface{}{keyword, count, result.URL})
+		}
+		for _, link := range result.DeadLinks {
+			deadLinks.rows = append(deadLinks.rows, []interface{}{link, result.URL})
+		}
+	}
+
+	hosts := make([]string, 0, len(summaries))
+	for host := range summaries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		s := summaries[host]
+		domains.rows = append(domains.rows, []interface{}{s.domain, s.pages, s.errors, s.emails, s.keywords, s.deadLinks, s.deadDomains})
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %v", err)
+	}
+
+	for i, sheet := range []xlsxSheet{emails, keywords, deadLinks, domains} {
+		if i == 0 {
+			err = f.SetSheetName("Sheet1", sheet.name)
+		} else {
+			_, err = f.NewSheet(sheet.name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create sheet %s: %v", sheet.name, err)
+		}
+
+		if err := writeSheet(f, sheet, headerStyle); err != nil {
+			return fmt.Errorf("failed to write sheet %s: %v", sheet.name, err)
+		}
+	}
+
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write xlsx file: %v", err)
+	}
+	return nil
+}
+
+// writeSheet writes a sheet with the stream writer, which is much lighter on large exports
+func writeSheet(f *excelize.File, sheet xlsxSheet, headerStyle int) error {
+	sw, err := f.NewStreamWriter(sheet.name)
+	if err != nil {
+		return err
+	}
+
+	for i, width := range sheet.widths {
+		if err := sw.SetColWidth(i+1, i+1, width); err != nil {
+			return err
+		}
+	}
+
+	header := make([]interface{}, len(sheet.header))
+	for i, title := range sheet.header {
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: title}
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	for i, row := range sheet.rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+
+	return sw.Flush()
+}
@@ -0,0 +1,122 @@
+package intake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golamv2/pkg/logging"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AMQPPrefetch is how many unacknowledged messages the broker hands out at once
+const AMQPPrefetch = 100
+
+// AMQPSource consumes a durable queue, declared if missing. Messages are acknowledged once
+// their URLs are queued, so a crawler stopping in between leaves them to the next consumer
+type AMQPSource struct {
+	url    string
+	queue  string
+	mu     sync.Mutex
+	conn   *amqp.Connection
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewAMQPSource creates a source for queue on the broker at url
+func NewAMQPSource(url, queue string) *AMQPSource {
+	return &AMQPSource{url: url, queue: queue, done: make(chan struct{})}
+}
+
+// Start consumes in the background, connecting again whenever the broker goes away
+func (s *AMQPSource) Start(submit Submitter) error {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			err := s.consume(submit)
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+
+			logging.Warnf("AMQP intake: %v, connecting again in %s", err, ReconnectDelay)
+			select {
+			case <-s.done:
+				return
+			case <-time.After(ReconnectDelay):
+			}
+		}
+	}()
+	return nil
+}
+
+// consume handles deliveries until the connection is lost or the source closed
+func (s *AMQPSource) consume(submit Submitter) error {
+	conn, err := amqp.Dial(s.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return fmt.Errorf("intake closed")
+	}
+	s.conn = conn
+	s.mu.Unlock()
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %v", err)
+	}
+	if _, err := channel.QueueDeclare(s.queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %v", s.queue, err)
+	}
+	if err := channel.Qos(AMQPPrefetch, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %v", err)
+	}
+
+	deliveries, err := channel.Consume(s.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to consume %s: %v", s.queue, err)
+	}
+
+	for delivery := range deliveries {
+		job, err := ParseMessage(delivery.Body)
+		if err != nil {
+			logging.Warnf("AMQP intake: %v", err)
+			delivery.Reject(false)
+			continue
+		}
+
+		queued, err := submit(job)
+		if err != nil {
+			logging.Warnf("AMQP intake: %v", err)
+			delivery.Reject(false)
+			continue
+		}
+		logging.Debugf("AMQP intake queued %d of %d URLs", len(queued), len(job.URLs))
+		delivery.Ack(false)
+	}
+	return fmt.Errorf("connection closed")
+}
+
+// Close disconnects, unacknowledged messages go back to the queue
+func (s *AMQPSource) Close() error {
+	close(s.done)
+
+	s.mu.Lock()
+	s.closed = true
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
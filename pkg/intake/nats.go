@@ -0,0 +1,80 @@
+package intake
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golamv2/pkg/logging"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSource subscribes to a subject in the golamv2 queue group. Requests (messages with a
+// reply subject) are answered with the URLs queued
+type NATSSource struct {
+	url     string
+	subject string
+	conn    *nats.Conn
+}
+
+// NewNATSSource creates a source for subject on the NATS server at url
+func NewNATSSource(url, subject string) *NATSSource {
+	return &NATSSource{url: url, subject: subject}
+}
+
+// Start connects and subscribes, the client keeps reconnecting while the server is away
+func (s *NATSSource) Start(submit Submitter) error {
+	conn, err := nats.Connect(s.url,
+		nats.Name("golamv2"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+		nats.ReconnectWait(ReconnectDelay),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logging.Warnf("NATS intake disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			logging.Infof("NATS intake reconnected")
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+
+	_, err = conn.QueueSubscribe(s.subject, QueueGroup, func(msg *nats.Msg) {
+		job, err := ParseMessage(msg.Data)
+		if err != nil {
+			logging.Warnf("NATS intake: %v", err)
+			return
+		}
+
+		queued, err := submit(job)
+		reply := jobReply{Queued: queued, Skipped: len(job.URLs) - len(queued)}
+		if err != nil {
+			logging.Warnf("NATS intake: %v", err)
+			reply.Error = err.Error()
+		} else {
+			logging.Debugf("NATS intake queued %d of %d URLs", len(queued), len(job.URLs))
+		}
+		if msg.Reply != "" {
+			data, _ := json.Marshal(reply)
+			msg.Respond(data)
+		}
+	})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to subscribe to %s: %v", s.subject, err)
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// Close stops taking messages, the ones already received are handled first
+func (s *NATSSource) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Drain()
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is how much console output to show, each level includes the ones before it
+type Level int32
+
+const (
+	LevelError Level = iota // Failures only, --quiet
+	LevelWarn
+	LevelInfo  // Startup banners and summaries, the default
+	LevelDebug // Every fetch and retry plus database messages, --verbose
+)
+
+var levelNames = map[Level]string{
+	LevelError: "error",
+	LevelWarn:  "warn",
+	LevelInfo:  "info",
+	LevelDebug: "debug",
+}
+
+var current = int32(LevelInfo)
+
+// ParseLevel validates a --log-level value
+func ParseLevel(value string) (Level, error) {
+	name := strings.ToLower(value)
+	if name == "warning" {
+		name = "warn"
+	}
+	for level, levelName := range levelNames {
+		if name == levelName {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("invalid log level %q: must be error, warn, info or debug", value)
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// SetLevel sets the level for the whole process
+func SetLevel(level Level) {
+	atomic.StoreInt32(&current, int32(level))
+}
+
+// Enabled reports whether messages of level are shown
+func Enabled(level Level) bool {
+	return Level(atomic.LoadInt32(&current)) >= level
+}
+
+// Errorf logs a failure, shown at every level
+func Errorf(format string, args ...interface{}) {
+	log.Printf("Error: "+format, args...)
+}
+
+// Warnf logs something the operator should look at
+func Warnf(format string, args ...interface{}) {
+	if Enabled(LevelWarn) {
+		log.Printf("Warning: "+format, args...)
+	}
+}
+
+// Infof prints a console line without timestamp, for banners and summaries
+func Infof(format string, args ...interface{}) {
+	if Enabled(LevelInfo) {
+		fmt.Println(fmt.Sprintf(format, args...))
+	}
+}
+
+// Debugf logs details only wanted when investigating a crawl
+func Debugf(format string, args ...interface{}) {
+	if Enabled(LevelDebug) {
+		log.Printf(format, args...)
+	}
+}
+
+// BadgerLogger passes Badger's messages on at matching levels, its info messages are debug
+// output here and its own debug tracing (a line per write) is dropped. It implements badger.Logger
+type BadgerLogger struct {
+	name string
+}
+
+// NewBadgerLogger creates a logger for the named database
+func NewBadgerLogger(name string) *BadgerLogger {
+	return &BadgerLogger{name: name}
+}
+
+func (b *BadgerLogger) Errorf(format string, args ...interface{}) {
+	Errorf("%s", b.message(format, args))
+}
+
+func (b *BadgerLogger) Warningf(format string, args ...interface{}) {
+	Warnf("%s", b.message(format, args))
+}
+
+func (b *BadgerLogger) Infof(format string, args ...interface{}) {
+	if Enabled(LevelDebug) {
+		Debugf("%s", b.message(format, args))
+	}
+}
+
+func (b *BadgerLogger) Debugf(format string, args ...interface{}) {}
+
+func (b *BadgerLogger) message(format string, args []interface{}) string {
+	return fmt.Sprintf("badger %s: %s", b.name, strings.TrimRight(fmt.Sprintf(format, args...), "\n"))
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// RequestLog writes one JSON line per page fetch, for log pipelines and tracing workers
+type RequestLog struct {
+	logger *slog.Logger
+	file   *os.File // nil for stderr
+}
+
+// FetchRecord is one page fetch of a worker
+type FetchRecord struct {
+	Worker   int
+	URL      string
+	Status   int // 0 when the server never answered
+	Bytes    int64
+	Duration time.Duration
+	Attempt  int // Retries before this fetch
+	Error    string
+	Slow     bool // Took longer than --slow-threshold
+	Cached   bool // Served by the response cache, nothing was requested
+}
+
+// NewRequestLog appends to the file at path, or writes to stderr for "-"
+func NewRequestLog(path string) (*RequestLog, error) {
+	var w io.Writer = os.Stderr
+	var file *os.File
+	if path != "-" {
+		var err error
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open request log: %v", err)
+		}
+		w = file
+	}
+	return &RequestLog{logger: slog.New(slog.NewJSONHandler(w, nil)), file: file}, nil
+}
+
+// Fetch logs a fetch, failed ones at warn level
+func (l *RequestLog) Fetch(record FetchRecord) {
+	attrs := []slog.Attr{
+		slog.Int("worker", record.Worker),
+		slog.String("url", record.URL),
+		slog.Int("status", record.Status),
+		slog.Int64("bytes", record.Bytes),
+		slog.Float64("duration_ms", float64(record.Duration.Microseconds())/1000),
+		slog.Int("attempt", record.Attempt),
+	}
+	level := slog.LevelInfo
+	if record.Error != "" {
+		attrs = append(attrs, slog.String("error", record.Error))
+		level = slog.LevelWarn
+	}
+	if record.Slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	if record.Cached {
+		attrs = append(attrs, slog.Bool("cached", true))
+	}
+	l.logger.LogAttrs(context.Background(), level, "fetch", attrs...)
+}
+
+// Close closes the file of the log
+func (l *RequestLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
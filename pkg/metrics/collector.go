@@ -18,6 +18,10 @@ type MetricsCollector struct {
 	bloomFilter BloomFilterMemory
 	storage     StorageMemory
 	queue       QueueMemory
+	// Rate limiter wait times, optional
+	rateLimits RateLimitReporter
+	// Fetch phase timings, see RecordFetch
+	transport transportCounters
 }
 
 // BloomFilterMemory interface for tracking bloom filter memory
@@ -35,6 +39,16 @@ type QueueMemory interface {
 	GetMemoryUsageMB() float64
 }
 
+// QueueFlowReporter is implemented by queues counting their database overflow traffic
+type QueueFlowReporter interface {
+	GetQueueFlow() domain.QueueFlow
+}
+
+// RateLimitReporter is implemented by rate limiters counting how long requests waited
+type RateLimitReporter interface {
+	GetRateLimitStats() []domain.RateLimitStats
+}
+
 // NewMetricsCollector creates a new metrics collector
 func NewMetricsCollector() *MetricsCollector {
 	now := time.Now()
@@ -87,6 +101,12 @@ func (m *MetricsCollector) UpdateLinksChecked(delta int64) {
 	atomic.AddInt64(&m.metrics.LinksChecked, delta)
 }
 
+// UpdateSchemeLinks counts checked ftp, mailto and tel links and the broken ones among them
+func (m *MetricsCollector) UpdateSchemeLinks(checked, broken int64) {
+	atomic.AddInt64(&m.metrics.SchemeLinksChecked, checked)
+	atomic.AddInt64(&m.metrics.SchemeLinksBroken, broken)
+}
+
 // UpdateDeadLinksFound increments the dead links found counter
 func (m *MetricsCollector) UpdateDeadLinksFound(delta int64) {
 	atomic.AddInt64(&m.metrics.DeadLinksFound, delta)
@@ -97,6 +117,42 @@ func (m *MetricsCollector) UpdateDeadDomainsFound(delta int64) {
 	atomic.AddInt64(&m.metrics.DeadDomainsFound, delta)
 }
 
+// UpdateDeadDomainCause counts a dead domain under the reason it failed
+func (m *MetricsCollector) UpdateDeadDomainCause(cause domain.DeadDomainCause) {
+	switch cause {
+	case domain.DeadDomainNXDomain:
+		atomic.AddInt64(&m.metrics.DeadDomainsNXDomain, 1)
+	case domain.DeadDomainDNS:
+		atomic.AddInt64(&m.metrics.DeadDomainsDNS, 1)
+	case domain.DeadDomainRefused:
+		atomic.AddInt64(&m.metrics.DeadDomainsRefused, 1)
+	case domain.DeadDomainTLS:
+		atomic.AddInt64(&m.metrics.DeadDomainsTLS, 1)
+	case domain.DeadDomainTimeout:
+		atomic.AddInt64(&m.metrics.DeadDomainsTimeout, 1)
+	default:
+		atomic.AddInt64(&m.metrics.DeadDomainsOther, 1)
+	}
+}
+
+// UpdateDeadDomainRegistration counts a dead domain under what RDAP knows of it
+func (m *MetricsCollector) UpdateDeadDomainRegistration(registration *domain.Registration) {
+	if registration == nil {
+		return
+	}
+	switch registration.Status {
+	case domain.RegistrationAvailable:
+		atomic.AddInt64(&m.metrics.DeadDomainsAvailable, 1)
+	case domain.RegistrationExpiring:
+		atomic.AddInt64(&m.metrics.DeadDomainsExpiring, 1)
+	}
+}
+
+// UpdateDomainsAliveViaFallback increments the counter of domains only alive on a fallback probe
+func (m *MetricsCollector) UpdateDomainsAliveViaFallback(delta int64) {
+	atomic.AddInt64(&m.metrics.DomainsAliveViaFallback, delta)
+}
+
 // UpdateActiveWorkers updates the active workers counter
 func (m *MetricsCollector) UpdateActiveWorkers(count int) {
 	m.metrics.ActiveWorkers = count
@@ -107,6 +163,118 @@ func (m *MetricsCollector) UpdateErrors(delta int64) {
 	atomic.AddInt64(&m.metrics.Errors, delta)
 }
 
+// UpdateDeadLinkChecksDropped increments the counter of links the dead link checker had no room for
+func (m *MetricsCollector) UpdateDeadLinkChecksDropped(delta int64) {
+	atomic.AddInt64(&m.metrics.DeadLinkChecksDropped, delta)
+}
+
+// UpdateLinkChecksDeduped increments the counter of links not checked again because they were queued or cached
+func (m *MetricsCollector) UpdateLinkChecksDeduped(delta int64) {
+	atomic.AddInt64(&m.metrics.LinkChecksDeduped, delta)
+}
+
+// UpdateHiddenLinks counts links invisible to users and the ones skipped for it
+func (m *MetricsCollector) UpdateHiddenLinks(found, skipped int64) {
+	atomic.AddInt64(&m.metrics.HiddenLinksFound, found)
+	atomic.AddInt64(&m.metrics.HiddenLinksSkipped, skipped)
+}
+
+// UpdateNoFollow counts a page whose links were not followed for nofollow, or links
+// skipped for rel="nofollow"
+func (m *MetricsCollector) UpdateNoFollow(pages, links int64) {
+	atomic.AddInt64(&m.metrics.PagesNoFollow, pages)
+	atomic.AddInt64(&m.metrics.LinksNoFollow, links)
+}
+
+// UpdateSlowRequests counts fetches slower than the slow threshold
+func (m *MetricsCollector) UpdateSlowRequests(count int64) {
+	atomic.AddInt64(&m.metrics.SlowRequests, count)
+}
+
+// UpdateChallenges counts bot challenges and the hosts quarantined for them
+func (m *MetricsCollector) UpdateChallenges(pages, quarantined int64) {
+	atomic.AddInt64(&m.metrics.PagesChallenged, pages)
+	atomic.AddInt64(&m.metrics.DomainsQuarantined, quarantined)
+}
+
+// UpdateHostsDNSRecorded increments the counter of hosts whose DNS records were collected
+func (m *MetricsCollector) UpdateHostsDNSRecorded(delta int64) {
+	atomic.AddInt64(&m.metrics.HostsDNSRecorded, delta)
+}
+
+// UpdateAccessibility counts an audited page and the accessibility issues found on it
+func (m *MetricsCollector) UpdateAccessibility(issues int64) {
+	atomic.AddInt64(&m.metrics.PagesAudited, 1)
+	atomic.AddInt64(&m.metrics.AccessibilityIssues, issues)
+}
+
+// UpdateResponseCacheHits increments the counter of requests saved by the response cache
+func (m *MetricsCollector) UpdateResponseCacheHits(delta int64) {
+	atomic.AddInt64(&m.metrics.ResponseCacheHits, delta)
+}
+
+// UpdateBloomFilter records that the URL bloom filter grew to more layers
+func (m *MetricsCollector) UpdateBloomFilter(layers int, falsePositiveRate float64) {
+	m.metrics.BloomFilterLayers = layers
+	m.metrics.BloomFPRate = falsePositiveRate
+}
+
+// UpdateURLsRetried increments the counter of fetches retried after a transient failure
+func (m *MetricsCollector) UpdateURLsRetried(delta int64) {
+	atomic.AddInt64(&m.metrics.URLsRetried, delta)
+}
+
+// UpdateURLsDeadLettered increments the counter of URLs that failed all their retries
+func (m *MetricsCollector) UpdateURLsDeadLettered(delta int64) {
+	atomic.AddInt64(&m.metrics.URLsDeadLettered, delta)
+}
+
+// UpdatePagesUnchanged increments the counter of pages skipped by incremental recrawls
+func (m *MetricsCollector) UpdatePagesUnchanged(delta int64) {
+	atomic.AddInt64(&m.metrics.PagesUnchanged, delta)
+}
+
+// UpdateURLsRejected increments the counter of URLs dropped by the URL limits
+func (m *MetricsCollector) UpdateURLsRejected(delta int64) {
+	atomic.AddInt64(&m.metrics.URLsRejected, delta)
+}
+
+// UpdateDuplicatePages counts a page duplicating an earlier one, near is true when only its text is close
+func (m *MetricsCollector) UpdateDuplicatePages(near bool) {
+	if near {
+		atomic.AddInt64(&m.metrics.NearDuplicatePages, 1)
+	} else {
+		atomic.AddInt64(&m.metrics.DuplicatePages, 1)
+	}
+}
+
+// UpdatePagesNoIndex increments the counter of noindex pages whose findings were discarded
+func (m *MetricsCollector) UpdatePagesNoIndex(delta int64) {
+	atomic.AddInt64(&m.metrics.PagesNoIndex, delta)
+}
+
+// UpdateRobotsStatus counts a robots.txt fetch that did not find a file
+func (m *MetricsCollector) UpdateRobotsStatus(status domain.RobotsStatus) {
+	switch status {
+	case domain.RobotsMissing:
+		atomic.AddInt64(&m.metrics.RobotsMissing, 1)
+	case domain.RobotsForbidden:
+		atomic.AddInt64(&m.metrics.RobotsForbidden, 1)
+	case domain.RobotsUnreachable:
+		atomic.AddInt64(&m.metrics.RobotsUnreachable, 1)
+	}
+}
+
+// SetRateLimitReporter sets where the rate limit stats come from
+func (m *MetricsCollector) SetRateLimitReporter(reporter RateLimitReporter) {
+	m.rateLimits = reporter
+}
+
+// SetRobotsMode records the robots compliance mode of the crawl
+func (m *MetricsCollector) SetRobotsMode(mode domain.RobotsMode) {
+	m.metrics.RobotsMode = string(mode)
+}
+
 // GetMetrics returns current metrics with calculated values
 func (m *MetricsCollector) GetMetrics() *domain.CrawlMetrics {
 	now := time.Now()
@@ -116,6 +284,13 @@ func (m *MetricsCollector) GetMetrics() *domain.CrawlMetrics {
 	m.metrics.MemoryUsageMB = m.getMemoryUsageMB()
 	m.metrics.URLsPerSecond = m.calculateURLsPerSecond()
 	m.metrics.MemoryBreakdown = m.calculateMemoryBreakdown()
+	if flow, ok := m.queue.(QueueFlowReporter); ok {
+		m.metrics.QueueFlow = flow.GetQueueFlow()
+	}
+	if m.rateLimits != nil {
+		m.metrics.RateLimits = m.rateLimits.GetRateLimitStats()
+	}
+	m.metrics.Transport = m.transport.stats()
 
 	// Return a copy to avoid race conditions
 	metricsCopy := *m.metrics
@@ -210,6 +385,7 @@ func (m *MetricsCollector) Reset() {
 
 	m.lastResetTime = now
 	m.lastProcessCount = 0
+	m.transport = transportCounters{}
 }
 
 // GetUptimeSeconds returns the uptime in seconds
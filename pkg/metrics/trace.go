@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// FetchTrace times the phases of one request with httptrace. The dialer may call it
+// from its own goroutines, hence the lock
+type FetchTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	timing       domain.FetchTiming
+}
+
+// TraceRequest returns req with a trace attached, call Timing once the body was read
+func TraceRequest(req *http.Request) (*http.Request, *FetchTrace) {
+	t := &FetchTrace{}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timing.Reused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.since(t.dnsStart, &t.timing.DNS) },
+		ConnectStart: func(network, addr string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.since(t.connectStart, &t.timing.Connect)
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(t.tlsStart, &t.timing.TLS)
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+func (t *FetchTrace) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+// since sets phase to the time elapsed since start, the dialer racing several addresses
+// ends up with the last attempt
+func (t *FetchTrace) since(start time.Time, phase *time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !start.IsZero() {
+		*phase = time.Since(start)
+	}
+}
+
+// Timing returns the phases measured so far, the download ends now
+func (t *FetchTrace) Timing() domain.FetchTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timing := t.timing
+	if !t.firstByte.IsZero() {
+		if !t.wroteRequest.IsZero() {
+			timing.TTFB = t.firstByte.Sub(t.wroteRequest)
+		}
+		timing.Download = time.Since(t.firstByte)
+	}
+	return timing
+}
+
+// transportCounters adds up the fetch phases for TransportStats
+type transportCounters struct {
+	fetches, reused         int64
+	dnsCount, dnsNanos      int64
+	connectCount, connNanos int64
+	tlsCount, tlsNanos      int64
+	ttfbCount, ttfbNanos    int64
+	downloadNanos           int64
+	parses, parseNanos      int64
+}
+
+// RecordFetch adds the phases of one page fetch to the transport stats
+func (m *MetricsCollector) RecordFetch(timing domain.FetchTiming) {
+	c := &m.transport
+	atomic.AddInt64(&c.fetches, 1)
+	if timing.Reused {
+		atomic.AddInt64(&c.reused, 1)
+	}
+	addPhase(&c.dnsCount, &c.dnsNanos, timing.DNS)
+	addPhase(&c.connectCount, &c.connNanos, timing.Connect)
+	addPhase(&c.tlsCount, &c.tlsNanos, timing.TLS)
+	addPhase(&c.ttfbCount, &c.ttfbNanos, timing.TTFB)
+	atomic.AddInt64(&c.downloadNanos, int64(timing.Download))
+}
+
+// RecordExtract adds the time spent extracting findings and links from one page
+func (m *MetricsCollector) RecordExtract(elapsed time.Duration) {
+	addPhase(&m.transport.parses, &m.transport.parseNanos, elapsed)
+}
+
+func addPhase(count, nanos *int64, elapsed time.Duration) {
+	if elapsed > 0 {
+		atomic.AddInt64(count, 1)
+		atomic.AddInt64(nanos, int64(elapsed))
+	}
+}
+
+func (c *transportCounters) stats() domain.TransportStats {
+	stats := domain.TransportStats{
+		Fetches:       atomic.LoadInt64(&c.fetches),
+		ConnsReused:   atomic.LoadInt64(&c.reused),
+		AvgDNSMs:      averageMs(&c.dnsCount, &c.dnsNanos),
+		AvgConnectMs:  averageMs(&c.connectCount, &c.connNanos),
+		AvgTLSMs:      averageMs(&c.tlsCount, &c.tlsNanos),
+		AvgTTFBMs:     averageMs(&c.ttfbCount, &c.ttfbNanos),
+		AvgDownloadMs: averageMs(&c.fetches, &c.downloadNanos),
+		PagesParsed:   atomic.LoadInt64(&c.parses),
+		AvgExtractMs:  averageMs(&c.parses, &c.parseNanos),
+	}
+	if stats.Fetches > 0 {
+		stats.ConnReuseRate = float64(stats.ConnsReused) / float64(stats.Fetches)
+	}
+	return stats
+}
+
+func averageMs(count, nanos *int64) float64 {
+	n := atomic.LoadInt64(count)
+	if n == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(nanos)) / float64(n) / float64(time.Millisecond)
+}
@@ -3,6 +3,9 @@ package queue
 import (
 	"container/heap"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"golamv2/internal/domain"
 )
@@ -10,15 +13,135 @@ import (
 const (
 	MaxQueueSize    = 100000 // Increased from 50k for better throughput - roughly 80mb for normal urls
 	RefillThreshold = 0.2    // Refill when queue is <20% full (more aggressive)
+	ScoreWeight     = 1000   // One point of relevance outweighs one level of depth
+	DiversityWeight = 5      // Every URL a domain already has queued pushes its next one back, 200 make a depth level
+
+	// How often an empty queue checks the database for spilled URLs
+	EmptyRefillInterval = time.Second
+
+	// Depth is shifted above the push sequence in the strict orders, 2^40 pushes is plenty
+	depthShift = 40
 )
 
+// PriorityFunc ranks a task, lower goes first. seq numbers the pushes in order and queued
+// is how many tasks of the task's domain are already waiting
+type PriorityFunc func(task domain.URLTask, seq int64, queued int) int64
+
+// MixedPriority is the default order: shallow pages first, pulled forward by relevance scores
+// and pushed back when their domain already has many URLs queued, for breadth and politeness
+func MixedPriority(task domain.URLTask, seq int64, queued int) int64 {
+	priority := int64(task.Depth*1000) + task.Timestamp.Unix() - int64(task.Score*ScoreWeight)
+	return priority + int64(queued)*DiversityWeight
+}
+
+// BreadthFirst crawls level by level, each level in the order its URLs were found
+func BreadthFirst(task domain.URLTask, seq int64, queued int) int64 {
+	return int64(task.Depth)<<depthShift + seq
+}
+
+// DepthFirst follows the most recently found links down first, like a stack
+func DepthFirst(task domain.URLTask, seq int64, queued int) int64 {
+	return -(int64(task.Depth)<<depthShift + seq)
+}
+
+// PriorityFor returns the priority function of a traversal order
+func PriorityFor(order domain.TraversalOrder) PriorityFunc {
+	switch order {
+	case domain.TraversalBFS:
+		return BreadthFirst
+	case domain.TraversalDFS:
+		return DepthFirst
+	default:
+		return MixedPriority
+	}
+}
+
+// PriorityURLQueue keeps the tasks of every host in their own heap. Hosts that may be fetched
+// now are ranked by their best task, deferred ones wait aside until their time comes, so Pop
+// never walks past the tasks of hosts it can not hand out
 type PriorityURLQueue struct {
 	mu              sync.RWMutex
-	heap            *urlHeap
+	hosts           map[string]*hostQueue // Hosts with queued tasks
+	ready           *hostHeap             // Hosts that may be fetched now, best task first
+	waiting         *hostHeap             // Deferred hosts, the one due first on top
+	size            int                   // Queued tasks of all hosts
 	storage         domain.Storage
 	maxSize         int
 	refillThreshold int
 	refilling       bool
+	lastEmptyRefill time.Time
+	hostDelays      map[string]time.Duration // Crawl delays of hosts, see SetHostDelay
+	nextFetch       map[string]time.Time     // Hosts that may not be fetched before the given time
+	pending         map[string]int           // Queued tasks per domain
+	bytes           int64                    // Memory held by the queued tasks, see taskBytes
+	// Order of the tasks, see SetPriorityFunc. pushes numbers the pushes for it
+	priority PriorityFunc
+	pushes   int64
+	// Random pause between the tasks of a host, see SetJitter
+	jitter domain.Jitter
+	// Tasks of a host handed out and not Done yet, capped by maxPerHost, see SetMaxPerHost
+	maxPerHost int
+	active     map[string]int
+	// Flow counters, see domain.QueueFlow
+	pushesRejected  int64
+	urlsSpilled     int64
+	refillBatches   int64
+	emptyRefills    int64
+	urlsRefilled    int64
+	lastRefillNanos int64
+	refillNanos     int64 // Total time spent reading refills
+}
+
+// hostState tells where a host with queued tasks waits
+type hostState int
+
+const (
+	hostReady   hostState = iota + 1 // In the ready heap
+	hostWaiting                      // In the waiting heap until nextFetch
+	hostBusy                         // As many tasks in process as maxPerHost allows, until Done
+)
+
+// hostQueue holds the queued tasks of one host
+type hostQueue struct {
+	host  string
+	tasks urlHeap
+	state hostState // Zero while in no heap
+	due   time.Time // When a waiting host may be fetched
+	index int       // In the ready or waiting heap
+}
+
+// hostHeap orders hosts, the ready ones by their best task and the waiting ones by due time
+type hostHeap struct {
+	queues []*hostQueue
+	less   func(a, b *hostQueue) bool
+}
+
+func byBestTask(a, b *hostQueue) bool { return a.tasks[0].priority < b.tasks[0].priority }
+
+func byDueTime(a, b *hostQueue) bool { return a.due.Before(b.due) }
+
+func (h *hostHeap) Len() int           { return len(h.queues) }
+func (h *hostHeap) Less(i, j int) bool { return h.less(h.queues[i], h.queues[j]) }
+
+func (h *hostHeap) Swap(i, j int) {
+	h.queues[i], h.queues[j] = h.queues[j], h.queues[i]
+	h.queues[i].index = i
+	h.queues[j].index = j
+}
+
+func (h *hostHeap) Push(x interface{}) {
+	queue := x.(*hostQueue)
+	queue.index = len(h.queues)
+	h.queues = append(h.queues, queue)
+}
+
+func (h *hostHeap) Pop() interface{} {
+	last := len(h.queues) - 1
+	queue := h.queues[last]
+	h.queues[last] = nil
+	h.queues = h.queues[:last]
+	queue.index = -1
+	return queue
 }
 
 // urlItem represents an item in the priority queue
@@ -64,13 +187,19 @@ func (h *urlHeap) Pop() interface{} {
 // NewPriorityURLQueue creates a new priority URL queue
 func NewPriorityURLQueue(storage domain.Storage) *PriorityURLQueue {
 	q := &PriorityURLQueue{
-		heap:            &urlHeap{},
+		hosts:           make(map[string]*hostQueue),
+		ready:           &hostHeap{less: byBestTask},
+		waiting:         &hostHeap{less: byDueTime},
 		storage:         storage,
 		maxSize:         MaxQueueSize,
 		refillThreshold: int(float64(MaxQueueSize) * RefillThreshold),
 		refilling:       false,
+		hostDelays:      make(map[string]time.Duration),
+		nextFetch:       make(map[string]time.Time),
+		pending:         make(map[string]int),
+		active:          make(map[string]int),
+		priority:        MixedPriority,
 	}
-	heap.Init(q.heap)
 	return q
 }
 
@@ -79,61 +208,292 @@ func (q *PriorityURLQueue) Push(task domain.URLTask) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.heap.Len() >= q.maxSize {
+	if q.size >= q.maxSize {
+		atomic.AddInt64(&q.pushesRejected, 1)
 		return ErrQueueFull
 	}
 
-	// Priority based on depth (lower depth = higher priority) and timestamp
-	priority := int64(task.Depth*1000) + task.Timestamp.Unix()
+	// See SetPriorityFunc, by default shallow, relevant and less crowded domains go first
+	host := domain.GetDomain(task.URL)
+	priority := q.priority(task, q.pushes, q.pending[host])
+	q.pushes++
+	q.pending[host]++
+	q.bytes += taskBytes(task)
 
 	item := &urlItem{
 		task:     task,
 		priority: priority,
 	}
 
-	heap.Push(q.heap, item)
-
+	queue, known := q.hosts[host]
+	if !known {
+		queue = &hostQueue{host: host}
+		q.hosts[host] = queue
+	}
+	heap.Push(&queue.tasks, item)
+	q.size++
+
+	switch {
+	case !known:
+		q.place(queue, time.Now())
+	case queue.state == hostReady:
+		heap.Fix(q.ready, queue.index) // Its best task may have changed
+	}
 	return nil
 }
 
+// SetPriorityFunc changes the order of the tasks pushed from now on, see PriorityFor
+func (q *PriorityURLQueue) SetPriorityFunc(priority PriorityFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.priority = priority
+}
+
+// PushOrSpill adds a URL task to the queue, or to the database when the queue is full
+func (q *PriorityURLQueue) PushOrSpill(task domain.URLTask) error {
+	if err := q.Push(task); err != ErrQueueFull {
+		return err
+	}
+
+	atomic.AddInt64(&q.urlsSpilled, 1)
+	return q.storage.StoreURL(task)
+}
+
 // remove and returns the highest priority URL task
 func (q *PriorityURLQueue) Pop() (domain.URLTask, error) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.heap.Len() == 0 {
+	if q.size == 0 {
+		// URLs may still be waiting in the database
+		if !q.refilling && time.Since(q.lastEmptyRefill) >= EmptyRefillInterval {
+			q.lastEmptyRefill = time.Now()
+			go q.refillFromDB()
+		}
 		return domain.URLTask{}, ErrQueueEmpty
 	}
 
-	item := heap.Pop(q.heap).(*urlItem)
+	item := q.popEligible()
+	if item == nil {
+		return domain.URLTask{}, ErrNoEligibleTask
+	}
+	q.popped(item.task)
 
 	// Check if we need to refill from database
-	if q.heap.Len() < q.refillThreshold && !q.refilling {
+	if q.size < q.refillThreshold && !q.refilling {
 		go q.refillFromDB()
 	}
 
 	return item.task, nil
 }
 
+// popped updates the pending count of the task's domain and the memory accounting
+func (q *PriorityURLQueue) popped(task domain.URLTask) {
+	q.bytes -= taskBytes(task)
+	host := domain.GetDomain(task.URL)
+	if q.pending[host] <= 1 {
+		delete(q.pending, host)
+	} else {
+		q.pending[host]--
+	}
+}
+
+// popEligible pops the best task of the hosts that may be fetched now, nil when there is none
+func (q *PriorityURLQueue) popEligible() *urlItem {
+	now := time.Now()
+	for q.waiting.Len() > 0 && !now.Before(q.waiting.queues[0].due) {
+		queue := heap.Pop(q.waiting).(*hostQueue)
+		queue.state = 0
+		q.place(queue, now)
+	}
+	if q.ready.Len() == 0 {
+		return nil
+	}
+
+	queue := q.ready.queues[0]
+	item := heap.Pop(&queue.tasks).(*urlItem)
+	q.size--
+	if q.maxPerHost > 0 {
+		q.active[queue.host]++
+	}
+
+	// Handing the task out starts the host's next delay, plus some jitter
+	delay, delayed := q.hostDelays[queue.host]
+	if jitter := q.jitter.Draw(); delayed || jitter > 0 {
+		q.forgetPastHosts(now)
+		q.nextFetch[queue.host] = now.Add(delay + jitter)
+	}
+	q.replace(queue, now)
+	return item
+}
+
+// place puts a host in no heap where it belongs: ready, waiting for its next fetch or busy.
+// Hosts without tasks are forgotten
+func (q *PriorityURLQueue) place(queue *hostQueue, now time.Time) {
+	switch {
+	case queue.tasks.Len() == 0:
+		delete(q.hosts, queue.host)
+		queue.state = 0
+	case now.Before(q.nextFetch[queue.host]):
+		queue.state, queue.due = hostWaiting, q.nextFetch[queue.host]
+		heap.Push(q.waiting, queue)
+	case q.maxPerHost > 0 && q.active[queue.host] >= q.maxPerHost:
+		queue.state = hostBusy
+	default:
+		queue.state = hostReady
+		heap.Push(q.ready, queue)
+	}
+}
+
+// replace takes a host out of its heap and places it again
+func (q *PriorityURLQueue) replace(queue *hostQueue, now time.Time) {
+	switch queue.state {
+	case hostReady:
+		heap.Remove(q.ready, queue.index)
+	case hostWaiting:
+		heap.Remove(q.waiting, queue.index)
+	}
+	queue.state = 0
+	q.place(queue, now)
+}
+
+// SetHostDelay spaces out the tasks of host by delay, starting with the fetch happening now
+func (q *PriorityURLQueue) SetHostDelay(host string, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.hostDelays[host] = delay
+	now := time.Now()
+	if next := now.Add(delay); next.After(q.nextFetch[host]) {
+		q.nextFetch[host] = next
+		q.replaceHost(host, now)
+	}
+}
+
+// DeferHost holds the tasks of host back until the given time
+func (q *PriorityURLQueue) DeferHost(host string, until time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.forgetPastHosts(now)
+	if until.After(q.nextFetch[host]) {
+		q.nextFetch[host] = until
+		q.replaceHost(host, now)
+	}
+}
+
+// replaceHost places the tasks of a host again after its next fetch time changed, busy hosts
+// are placed by Done
+func (q *PriorityURLQueue) replaceHost(host string, now time.Time) {
+	if queue, ok := q.hosts[host]; ok && queue.state != hostBusy {
+		q.replace(queue, now)
+	}
+}
+
+// forgetPastHosts drops hosts whose time has passed so the map stays small
+func (q *PriorityURLQueue) forgetPastHosts(now time.Time) {
+	if len(q.nextFetch) <= 10000 {
+		return
+	}
+	for h, t := range q.nextFetch {
+		if _, delayed := q.hostDelays[h]; !delayed && now.After(t) {
+			delete(q.nextFetch, h)
+		}
+	}
+}
+
+// SetJitter adds a random pause between the tasks of each host. Workers are not held up,
+// they take tasks of other hosts meanwhile
+func (q *PriorityURLQueue) SetJitter(jitter domain.Jitter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jitter = jitter
+}
+
+// SetMaxPerHost caps the tasks of one host being processed at once, 0 means no cap. Workers
+// take the tasks of other hosts meanwhile. Set it before the first Pop, Done must be called
+// for every popped task from then on
+func (q *PriorityURLQueue) SetMaxPerHost(max int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxPerHost = max
+}
+
+// Done tells the queue a popped task was processed, see SetMaxPerHost
+func (q *PriorityURLQueue) Done(task domain.URLTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	host := domain.GetDomain(task.URL)
+	if q.active[host] <= 1 {
+		delete(q.active, host)
+	} else {
+		q.active[host]--
+	}
+	if queue, ok := q.hosts[host]; ok && queue.state == hostBusy {
+		q.replace(queue, time.Now())
+	}
+}
+
+// Snapshot returns the queued tasks without removing them, for checkpoints
+func (q *PriorityURLQueue) Snapshot() []domain.URLTask {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.tasks()
+}
+
+// tasks lists the queued tasks of all hosts
+func (q *PriorityURLQueue) tasks() []domain.URLTask {
+	tasks := make([]domain.URLTask, 0, q.size)
+	for _, queue := range q.hosts {
+		for _, item := range queue.tasks {
+			tasks = append(tasks, item.task)
+		}
+	}
+	return tasks
+}
+
+// clear removes every queued task
+func (q *PriorityURLQueue) clear() {
+	clear(q.hosts)
+	q.ready.queues = q.ready.queues[:0]
+	q.waiting.queues = q.waiting.queues[:0]
+	q.size = 0
+	clear(q.pending)
+	q.bytes = 0
+}
+
+// Drain removes and returns every queued task, deferred hosts included
+func (q *PriorityURLQueue) Drain() []domain.URLTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tasks := q.tasks()
+	q.clear()
+	return tasks
+}
+
 // Size returns the current size of the queue
 func (q *PriorityURLQueue) Size() int {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
-	return q.heap.Len()
+	return q.size
 }
 
 // IsFull checks if the queue is full
 func (q *PriorityURLQueue) IsFull() bool {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
-	return q.heap.Len() >= q.maxSize
+	return q.size >= q.maxSize
 }
 
 // IsEmpty checks if the queue is empty
 func (q *PriorityURLQueue) IsEmpty() bool {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
-	return q.heap.Len() == 0
+	return q.size == 0
 }
 
 // refillFromDB fills the queue from the database
@@ -161,11 +521,22 @@ func (q *PriorityURLQueue) refillFromDB() {
 	}
 
 	// Fetch URLs from database
+	start := time.Now()
 	urls, err := q.storage.GetURLs(needed)
+	elapsed := int64(time.Since(start))
+	atomic.StoreInt64(&q.lastRefillNanos, elapsed)
+	atomic.AddInt64(&q.refillNanos, elapsed)
 	if err != nil {
 		return
 	}
 
+	if len(urls) == 0 {
+		atomic.AddInt64(&q.emptyRefills, 1)
+		return
+	}
+	atomic.AddInt64(&q.refillBatches, 1)
+	atomic.AddInt64(&q.urlsRefilled, int64(len(urls)))
+
 	// Add URLs to queue
 	for _, task := range urls {
 		if err := q.Push(task); err != nil {
@@ -174,39 +545,52 @@ func (q *PriorityURLQueue) refillFromDB() {
 	}
 }
 
+// GetQueueFlow returns the flow counters between the queue and the database
+func (q *PriorityURLQueue) GetQueueFlow() domain.QueueFlow {
+	flow := domain.QueueFlow{
+		PushesRejected: atomic.LoadInt64(&q.pushesRejected),
+		URLsSpilled:    atomic.LoadInt64(&q.urlsSpilled),
+		RefillBatches:  atomic.LoadInt64(&q.refillBatches),
+		EmptyRefills:   atomic.LoadInt64(&q.emptyRefills),
+		URLsRefilled:   atomic.LoadInt64(&q.urlsRefilled),
+		LastRefillMs:   float64(atomic.LoadInt64(&q.lastRefillNanos)) / float64(time.Millisecond),
+	}
+	if reads := flow.RefillBatches + flow.EmptyRefills; reads > 0 {
+		flow.AvgRefillLatencyMs = float64(atomic.LoadInt64(&q.refillNanos)) / float64(reads) / float64(time.Millisecond)
+	}
+	return flow
+}
+
 // Close closes the queue
 func (q *PriorityURLQueue) Close() error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	// Clear the heap
-	*q.heap = (*q.heap)[:0]
+	q.clear()
 	return nil
 }
 
-// GetMemoryUsageMB estimated memory usage
+// GetMemoryUsageMB returns the memory held by the queued tasks
 func (q *PriorityURLQueue) GetMemoryUsageMB() float64 {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 
-	if q.heap == nil {
-		return 0
-	}
-
-	// Estimate memory usage based on queue size
-	// Each URLTask is approximately 300 bytes (URL string + metadata)
-	// With 50k max URLs: 50k * 300 bytes = ~15MB
-	//My Rough Estimates from my tests!, May vary based on URL length and metadata encountered
-	currentSize := len(*q.heap)
-	bytesPerTask := 300.0
+	return float64(q.bytes) / 1024 / 1024
+}
 
-	return float64(currentSize) * bytesPerTask / 1024 / 1024
+// taskBytes is the memory a queued task holds: its heap item, the slot pointing to it and the URL
+func taskBytes(task domain.URLTask) int64 {
+	return int64(unsafe.Sizeof(urlItem{})+unsafe.Sizeof(&urlItem{})) + int64(len(task.URL))
 }
 
 // Custom errors
 var (
 	ErrQueueFull  = &QueueError{Message: "queue is full"}
 	ErrQueueEmpty = &QueueError{Message: "queue is empty"}
+
+	// ErrNoEligibleTask means every queued task belongs to a deferred host, or one with as many
+	// tasks in process as SetMaxPerHost allows
+	ErrNoEligibleTask = &QueueError{Message: "no task is eligible yet"}
 )
 
 type QueueError struct {
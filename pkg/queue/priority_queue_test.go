@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// stubStorage has nothing spilled, refills find no URLs
+type stubStorage struct {
+	domain.Storage
+}
+
+func (stubStorage) GetURLs(limit int) ([]domain.URLTask, error) { return nil, nil }
+func (stubStorage) StoreURL(task domain.URLTask) error          { return nil }
+
+func newTestQueue(tasks ...string) *PriorityURLQueue {
+	q := NewPriorityURLQueue(stubStorage{})
+	q.SetPriorityFunc(BreadthFirst)
+	for _, url := range tasks {
+		q.Push(domain.URLTask{URL: url})
+	}
+	return q
+}
+
+func popURL(t *testing.T, q *PriorityURLQueue) string {
+	t.Helper()
+	task, err := q.Pop()
+	if err != nil {
+		return err.Error()
+	}
+	return task.URL
+}
+
+func TestPopEligible(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(q *PriorityURLQueue)
+		urls  []string
+		want  []string
+	}{
+		{
+			name: "push order without limits",
+			urls: []string{"https://a.com/1", "https://b.com/1", "https://a.com/2"},
+			want: []string{"https://a.com/1", "https://b.com/1", "https://a.com/2", ErrQueueEmpty.Error()},
+		},
+		{
+			name:  "deferred host is skipped",
+			setup: func(q *PriorityURLQueue) { q.DeferHost("a.com", time.Now().Add(time.Hour)) },
+			urls:  []string{"https://a.com/1", "https://a.com/2", "https://b.com/1"},
+			want:  []string{"https://b.com/1", ErrNoEligibleTask.Error()},
+		},
+		{
+			name:  "crawl delay spaces out a host",
+			setup: func(q *PriorityURLQueue) { q.hostDelays["a.com"] = time.Hour },
+			urls:  []string{"https://a.com/1", "https://a.com/2", "https://b.com/1"},
+			want:  []string{"https://a.com/1", "https://b.com/1", ErrNoEligibleTask.Error()},
+		},
+		{
+			name:  "max per host holds a busy host back",
+			setup: func(q *PriorityURLQueue) { q.SetMaxPerHost(1) },
+			urls:  []string{"https://a.com/1", "https://a.com/2", "https://b.com/1"},
+			want:  []string{"https://a.com/1", "https://b.com/1", ErrNoEligibleTask.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue()
+			if tt.setup != nil {
+				tt.setup(q)
+			}
+			for _, url := range tt.urls {
+				q.Push(domain.URLTask{URL: url})
+			}
+			for i, want := range tt.want {
+				if got := popURL(t, q); got != want {
+					t.Fatalf("pop %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// A deferred host with more queued tasks than any scan limit must not hide the others
+func TestPopEligibleBehindManyDeferredTasks(t *testing.T) {
+	q := newTestQueue()
+	q.DeferHost("slow.com", time.Now().Add(time.Hour))
+	for i := 0; i < 10000; i++ {
+		q.Push(domain.URLTask{URL: fmt.Sprintf("https://slow.com/%d", i)})
+	}
+	q.Push(domain.URLTask{URL: "https://fast.com/"})
+
+	if got := popURL(t, q); got != "https://fast.com/" {
+		t.Fatalf("Pop() = %q, want the task of the host that is not deferred", got)
+	}
+	if q.Size() != 10000 {
+		t.Fatalf("Size() = %d, want 10000", q.Size())
+	}
+}
+
+func TestPopEligibleAfterDeferralEnds(t *testing.T) {
+	q := newTestQueue()
+	q.DeferHost("a.com", time.Now().Add(20*time.Millisecond))
+	q.Push(domain.URLTask{URL: "https://a.com/1"})
+
+	if got := popURL(t, q); got != ErrNoEligibleTask.Error() {
+		t.Fatalf("Pop() = %q while deferred", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := popURL(t, q); got != "https://a.com/1" {
+		t.Fatalf("Pop() = %q once the deferral ended", got)
+	}
+}
+
+func TestDoneReleasesBusyHost(t *testing.T) {
+	q := newTestQueue()
+	q.SetMaxPerHost(1)
+	q.Push(domain.URLTask{URL: "https://a.com/1"})
+	q.Push(domain.URLTask{URL: "https://a.com/2"})
+
+	task, err := q.Pop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := popURL(t, q); got != ErrNoEligibleTask.Error() {
+		t.Fatalf("Pop() = %q while a.com is busy", got)
+	}
+	q.Done(task)
+	if got := popURL(t, q); got != "https://a.com/2" {
+		t.Fatalf("Pop() = %q after Done", got)
+	}
+}
+
+func TestDrainEmptiesAllHosts(t *testing.T) {
+	q := newTestQueue("https://a.com/1", "https://b.com/1")
+	q.DeferHost("a.com", time.Now().Add(time.Hour))
+
+	if tasks := q.Drain(); len(tasks) != 2 {
+		t.Fatalf("Drain() returned %d tasks, want 2", len(tasks))
+	}
+	if !q.IsEmpty() || q.GetMemoryUsageMB() != 0 {
+		t.Fatalf("queue not empty after Drain")
+	}
+}
+
+func benchmarkPushPop(b *testing.B, hosts int) {
+	q := newTestQueue()
+	q.SetPriorityFunc(MixedPriority)
+	urls := make([]string, 1024)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://host%d.example.com/page/%d", i%hosts, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(domain.URLTask{URL: urls[i%len(urls)], Depth: i % 5})
+		if i%2 == 1 {
+			if task, err := q.Pop(); err == nil {
+				q.Done(task)
+			}
+		}
+	}
+}
+
+func BenchmarkPushPopManyHosts(b *testing.B) { benchmarkPushPop(b, 500) }
+
+func BenchmarkPushPopOneHost(b *testing.B) { benchmarkPushPop(b, 1) }
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// RetryQueue holds failed tasks until their backoff has passed, soonest first
+type RetryQueue struct {
+	mu    sync.Mutex
+	tasks retryHeap
+}
+
+type retryItem struct {
+	task domain.URLTask
+	due  time.Time
+}
+
+type retryHeap []retryItem
+
+func (h retryHeap) Len() int            { return len(h) }
+func (h retryHeap) Less(i, j int) bool  { return h[i].due.Before(h[j].due) }
+func (h retryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *retryHeap) Push(x interface{}) { *h = append(*h, x.(retryItem)) }
+
+func (h *retryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+	return item
+}
+
+// NewRetryQueue creates an empty retry queue
+func NewRetryQueue() *RetryQueue {
+	return &RetryQueue{}
+}
+
+// Add schedules a task to be retried at due
+func (q *RetryQueue) Add(task domain.URLTask, due time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	heap.Push(&q.tasks, retryItem{task: task, due: due})
+}
+
+// PopDue removes and returns the tasks whose backoff has passed
+func (q *RetryQueue) PopDue(now time.Time) []domain.URLTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []domain.URLTask
+	for q.tasks.Len() > 0 && !q.tasks[0].due.After(now) {
+		due = append(due, heap.Pop(&q.tasks).(retryItem).task)
+	}
+	return due
+}
+
+// Drain removes and returns every waiting task, due or not
+func (q *RetryQueue) Drain() []domain.URLTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tasks := make([]domain.URLTask, len(q.tasks))
+	for i, item := range q.tasks {
+		tasks[i] = item.task
+	}
+	q.tasks = nil
+	return tasks
+}
+
+// Snapshot returns every waiting task and keeps them waiting, for checkpoints
+func (q *RetryQueue) Snapshot() []domain.URLTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tasks := make([]domain.URLTask, len(q.tasks))
+	for i, item := range q.tasks {
+		tasks[i] = item.task
+	}
+	return tasks
+}
+
+// Len returns the number of waiting tasks
+func (q *RetryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.tasks.Len()
+}
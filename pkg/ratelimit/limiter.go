@@ -0,0 +1,240 @@
+// Package ratelimit spaces out requests with token buckets at three levels: one for the whole
+// crawl, one per server address and one per host. A request waits for a token at every level
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/cache"
+
+	"golang.org/x/time/rate"
+)
+
+// Levels of the limiter, from the widest to the narrowest
+const (
+	LevelGlobal = "global"
+	LevelIP     = "ip"
+	LevelDomain = "domain"
+)
+
+const (
+	// MaxBuckets bounds the per-IP and per-domain buckets, the least recently used go first
+	MaxBuckets = 10000
+	// BucketIdleTTL drops buckets of hosts not requested for a while, they start full again
+	BucketIdleTTL = 10 * time.Minute
+	// AddressCacheTTL is how long a host's server address is reused before resolving it again
+	AddressCacheTTL = 10 * time.Minute
+	// ResolveTimeout bounds the lookup of a host's address, hosts that do not resolve skip the IP level
+	ResolveTimeout = 2 * time.Second
+
+	// Shorter waits are scheduling noise rather than the limit holding a request back
+	minCountedWait = time.Millisecond
+)
+
+// Config sets the requests per second of each level, 0 means no limit
+type Config struct {
+	Global    float64 // Across the whole crawl
+	PerIP     float64 // To one server address, hosts sharing a server share it
+	PerDomain float64 // To one host
+}
+
+// Limiter waits for a token at the host, server address and global level before each request.
+// It is shared by the fetcher and the dead link checker so both count against the same limits
+type Limiter struct {
+	global      *rate.Limiter
+	globalLimit atomic.Value // float64, 0 means no limit
+	globalStats levelStats
+	ip          *bucketLevel
+	domain      *bucketLevel
+	addrs       *cache.LRU[string, string] // Host -> server address, empty when it did not resolve
+}
+
+// levelStats counts the requests of one level and how long they waited
+type levelStats struct {
+	requests  int64
+	waits     int64
+	waitNanos int64
+}
+
+// bucketLevel is a level with one bucket per key
+type bucketLevel struct {
+	mu      sync.Mutex // Creating buckets
+	limit   float64
+	buckets *cache.LRU[string, *rate.Limiter]
+	stats   levelStats
+	// Minimum time between two requests of a key, lowering its limit
+	delays *cache.LRU[string, time.Duration]
+}
+
+// New creates a limiter with the given levels
+func New(config Config) *Limiter {
+	l := &Limiter{
+		global: rate.NewLimiter(rate.Inf, 1),
+		ip:     newBucketLevel(config.PerIP),
+		domain: newBucketLevel(config.PerDomain),
+		addrs:  cache.NewLRU[string, string](MaxBuckets, AddressCacheTTL),
+	}
+	l.SetGlobal(config.Global)
+	return l
+}
+
+func newBucketLevel(limit float64) *bucketLevel {
+	return &bucketLevel{
+		limit:   max(0, limit),
+		buckets: cache.NewLRU[string, *rate.Limiter](MaxBuckets, BucketIdleTTL),
+		delays:  cache.NewLRU[string, time.Duration](MaxBuckets, BucketIdleTTL),
+	}
+}
+
+// SetGlobal changes the global rate of a running crawl, 0 removes the limit
+func (l *Limiter) SetGlobal(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		l.global.SetLimit(rate.Inf)
+		l.globalLimit.Store(float64(0))
+		return
+	}
+	l.global.SetLimit(rate.Limit(requestsPerSecond))
+	l.global.SetBurst(burst(requestsPerSecond))
+	l.globalLimit.Store(requestsPerSecond)
+}
+
+// Global returns the global rate in requests per second, 0 means no limit
+func (l *Limiter) Global() float64 {
+	return l.globalLimit.Load().(float64)
+}
+
+// burst allows a second's worth of requests at once
+func burst(requestsPerSecond float64) int {
+	return max(1, int(requestsPerSecond))
+}
+
+// Wait blocks until a request to rawURL is allowed at every level, or ctx is done.
+// The narrowest level goes first so a request waiting on its host holds no global token
+func (l *Limiter) Wait(ctx context.Context, rawURL string) error {
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	if host != "" {
+		if err := l.domain.wait(ctx, host); err != nil {
+			return err
+		}
+		if l.ip.limit > 0 {
+			if addr := l.address(ctx, host); addr != "" {
+				if err := l.ip.wait(ctx, addr); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return l.globalStats.record(func() error { return l.global.Wait(ctx) })
+}
+
+// SetHostDelay spaces the requests to host at least delay apart, on top of --rate-per-domain.
+// Used for robots.txt Crawl-delay, so dead link checks to the host keep to it as well
+func (l *Limiter) SetHostDelay(host string, delay time.Duration) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if delay > 0 {
+		l.domain.delays.Add(strings.ToLower(host), delay)
+	}
+}
+
+// address returns the server address of host, resolved once per AddressCacheTTL
+func (l *Limiter) address(ctx context.Context, host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if addr, ok := l.addrs.Get(host); ok {
+		return addr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ResolveTimeout)
+	defer cancel()
+
+	addr := ""
+	if addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host); err == nil && len(addrs) > 0 {
+		addr = addrs[0].IP.String()
+	}
+	l.addrs.Add(host, addr)
+	return addr
+}
+
+// wait takes a token from the bucket of key, keys without a limit or delay pass right away
+func (b *bucketLevel) wait(ctx context.Context, key string) error {
+	limit, size := b.limit, burst(b.limit)
+	if delay, ok := b.delays.Get(key); ok {
+		// A delay allows no bursts, every request waits for it
+		if floor := 1 / delay.Seconds(); limit <= 0 || floor < limit {
+			limit = floor
+		}
+		size = 1
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	bucket, ok := b.buckets.Get(key)
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(limit), size)
+		b.buckets.Add(key, bucket)
+	} else if bucket.Limit() != rate.Limit(limit) || bucket.Burst() != size {
+		// A Crawl-delay came in or changed since the bucket was made
+		bucket.SetLimit(rate.Limit(limit))
+		bucket.SetBurst(size)
+	}
+	b.mu.Unlock()
+
+	return b.stats.record(func() error { return bucket.Wait(ctx) })
+}
+
+// record runs wait and counts the request and the time it waited
+func (s *levelStats) record(wait func() error) error {
+	start := time.Now()
+	err := wait()
+	if err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&s.requests, 1)
+	if waited := time.Since(start); waited >= minCountedWait {
+		atomic.AddInt64(&s.waits, 1)
+		atomic.AddInt64(&s.waitNanos, int64(waited))
+	}
+	return nil
+}
+
+func (s *levelStats) report(level string, limit float64, buckets int) domain.RateLimitStats {
+	stats := domain.RateLimitStats{
+		Level:       level,
+		Limit:       limit,
+		Buckets:     buckets,
+		Requests:    atomic.LoadInt64(&s.requests),
+		Waits:       atomic.LoadInt64(&s.waits),
+		WaitSeconds: time.Duration(atomic.LoadInt64(&s.waitNanos)).Seconds(),
+	}
+	if stats.Requests > 0 {
+		stats.Saturation = float64(stats.Waits) / float64(stats.Requests)
+	}
+	return stats
+}
+
+// GetRateLimitStats reports every level, the widest first
+func (l *Limiter) GetRateLimitStats() []domain.RateLimitStats {
+	return []domain.RateLimitStats{
+		l.globalStats.report(LevelGlobal, l.globalLimit.Load().(float64), 1),
+		l.ip.stats.report(LevelIP, l.ip.limit, l.ip.buckets.Len()),
+		l.domain.stats.report(LevelDomain, l.domain.limit, l.domain.buckets.Len()),
+	}
+}
@@ -0,0 +1,110 @@
+package sink
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClickHouse answers inserts with the status answer picks for their rows
+type fakeClickHouse struct {
+	inserts []string
+	answer  func(rows string) int
+}
+
+func (f *fakeClickHouse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.URL.Query().Get("query"), "INSERT") {
+		return
+	}
+	status := f.answer(string(body))
+	if status == http.StatusOK {
+		f.inserts = append(f.inserts, string(body))
+	}
+	w.WriteHeader(status)
+}
+
+func TestClickHouseSendSpool(t *testing.T) {
+	spooled := "{\"url\":\"a\"}\n{\"url\":\"bad\"}\n{\"url\":\"c\"}\n{\"url\":\"d\"}\n"
+
+	tests := []struct {
+		name     string
+		answer   func(rows string) int
+		wantErr  bool
+		inserts  []string
+		spool    string // Left in the spool
+		rejected string
+	}{
+		{
+			name:    "all sent",
+			answer:  func(string) int { return http.StatusOK },
+			inserts: []string{"{\"url\":\"a\"}\n{\"url\":\"bad\"}\n", "{\"url\":\"c\"}\n{\"url\":\"d\"}\n"},
+		},
+		{
+			name: "rejected chunk set aside",
+			answer: func(rows string) int {
+				if strings.Contains(rows, "bad") {
+					return http.StatusBadRequest
+				}
+				return http.StatusOK
+			},
+			inserts:  []string{"{\"url\":\"c\"}\n{\"url\":\"d\"}\n"},
+			rejected: "{\"url\":\"a\"}\n{\"url\":\"bad\"}\n",
+		},
+		{
+			name: "unavailable keeps the rest",
+			answer: func(rows string) int {
+				if strings.Contains(rows, "c") {
+					return http.StatusServiceUnavailable
+				}
+				return http.StatusOK
+			},
+			wantErr: true,
+			inserts: []string{"{\"url\":\"a\"}\n{\"url\":\"bad\"}\n"},
+			spool:   "{\"url\":\"c\"}\n{\"url\":\"d\"}\n",
+		},
+		{
+			name: "rate limited is retried",
+			answer: func(string) int {
+				return http.StatusTooManyRequests
+			},
+			wantErr: true,
+			spool:   spooled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clickHouse := &fakeClickHouse{answer: tt.answer}
+			server := httptest.NewServer(clickHouse)
+			defer server.Close()
+
+			dir := t.TempDir()
+			s, err := NewClickHouseSink(ClickHouseConfig{URL: server.URL, Database: "default", Table: "t"}, filepath.Join(dir, ClickHouseSpoolFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.chunkSize = 20 // Two rows
+			if err := os.WriteFile(s.spoolPath, []byte(spooled), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.sendSpool(); (err != nil) != tt.wantErr {
+				t.Fatalf("sendSpool() = %v, want error %v", err, tt.wantErr)
+			}
+			if strings.Join(clickHouse.inserts, "|") != strings.Join(tt.inserts, "|") {
+				t.Errorf("inserted %q, want %q", clickHouse.inserts, tt.inserts)
+			}
+			if spool, _ := os.ReadFile(s.spoolPath); string(spool) != tt.spool {
+				t.Errorf("spool holds %q, want %q", spool, tt.spool)
+			}
+			if rejected, _ := os.ReadFile(s.rejectedPath); string(rejected) != tt.rejected {
+				t.Errorf("rejected file holds %q, want %q", rejected, tt.rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package sink copies crawl results to databases next to the primary storage
+package sink
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+const (
+	QueueSize     = 10000       // Results waiting for the sinks before new ones are dropped
+	BatchSize     = 500         // Results written to a sink at once
+	FlushInterval = time.Second // Longest a result waits for its batch to fill
+)
+
+// DrainTimeout bounds how long Close waits for the queued results to reach the sinks
+const DrainTimeout = 30 * time.Second
+
+// Dispatcher hands stored results to the sinks in batches, in the background so a slow
+// or unreachable sink never holds up the crawl
+type Dispatcher struct {
+	sinks   map[string]domain.ResultSink
+	results chan domain.CrawlResult
+	mu      sync.RWMutex
+	closed  bool
+	dropped int64
+	done    chan struct{}
+
+	drainTimeout time.Duration
+}
+
+// NewDispatcher starts copying published results to sinks, keyed by their name for logs
+func NewDispatcher(sinks map[string]domain.ResultSink) *Dispatcher {
+	d := &Dispatcher{
+		sinks:   sinks,
+		results: make(chan domain.CrawlResult, QueueSize),
+		done:    make(chan struct{}),
+
+		drainTimeout: DrainTimeout,
+	}
+	go d.run()
+	return d
+}
+
+// Publish queues a result for the sinks, it is dropped when the queue is full
+func (d *Dispatcher) Publish(result domain.CrawlResult) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+	select {
+	case d.results <- result:
+	default:
+		atomic.AddInt64(&d.dropped, 1)
+	}
+}
+
+// Dropped returns how many results never reached the sinks because the queue was full
+func (d *Dispatcher) Dropped() int64 {
+	return atomic.LoadInt64(&d.dropped)
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.CrawlResult, 0, BatchSize)
+	for {
+		select {
+		case result, ok := <-d.results:
+			if !ok {
+				d.write(batch)
+				return
+			}
+			batch = append(batch, result)
+			if len(batch) == BatchSize {
+				d.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			d.write(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// write hands a batch to every sink, a failing sink loses the batch but not the next ones
+func (d *Dispatcher) write(batch []domain.CrawlResult) {
+	if len(batch) == 0 {
+		return
+	}
+	for name, sink := range d.sinks {
+		if err := sink.WriteResults(batch); err != nil {
+			logging.Warnf("%s sink lost %d results: %v", name, len(batch), err)
+		}
+	}
+}
+
+// Close writes the results still queued, for up to DrainTimeout, and closes the sinks
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	d.closed = true
+	close(d.results)
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+	case <-time.After(d.drainTimeout):
+		// A sink still writing is left open rather than closed under the write
+		return fmt.Errorf("sinks still writing after %s, %d queued results are lost", d.drainTimeout, len(d.results))
+	}
+
+	if dropped := d.Dropped(); dropped > 0 {
+		logging.Warnf("%d results were not copied to the sinks, they could not keep up", dropped)
+	}
+
+	var errors []error
+	for name, sink := range d.sinks {
+		if err := sink.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("failed to close %s sink: %v", name, err))
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("sink close errors: %v", errors)
+	}
+	return nil
+}
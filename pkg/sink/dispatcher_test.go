@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// fakeSink counts the results written, each batch taking delay
+type fakeSink struct {
+	delay   time.Duration
+	written int64
+	closed  int32
+}
+
+func (s *fakeSink) WriteResults(results []domain.CrawlResult) error {
+	time.Sleep(s.delay)
+	atomic.AddInt64(&s.written, int64(len(results)))
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	return nil
+}
+
+func TestDispatcherClose(t *testing.T) {
+	tests := []struct {
+		name        string
+		delay       time.Duration
+		wantErr     bool
+		wantWritten int64
+		wantClosed  bool
+	}{
+		{"queue drained", 0, false, 1200, true},
+		{"sink too slow", time.Second, true, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeSink{delay: tt.delay}
+			d := NewDispatcher(map[string]domain.ResultSink{"fake": sink})
+			d.drainTimeout = 100 * time.Millisecond
+			for i := 0; i < 1200; i++ {
+				d.Publish(domain.CrawlResult{URL: "https://example.com/"})
+			}
+
+			if err := d.Close(); (err != nil) != tt.wantErr {
+				t.Fatalf("Close() = %v, want error %v", err, tt.wantErr)
+			}
+			if written := atomic.LoadInt64(&sink.written); written != tt.wantWritten {
+				t.Errorf("%d results written, want %d", written, tt.wantWritten)
+			}
+			if closed := atomic.LoadInt32(&sink.closed) == 1; closed != tt.wantClosed {
+				t.Errorf("sink closed %v, want %v", closed, tt.wantClosed)
+			}
+			d.Publish(domain.CrawlResult{URL: "https://example.com/late"})
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package sink
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+const (
+	KafkaDefaultPort = "9092"
+	// Attempts at a batch, the leaders are reloaded between them
+	kafkaAttempts  = 3
+	kafkaRetryWait = time.Second
+)
+
+// KafkaSink produces every result as a JSON message to a topic, keyed by the page URL so
+// the results of a page stay in one partition. It speaks just enough of the Kafka protocol
+// to produce uncompressed batches over plain TCP, without SASL or TLS
+type KafkaSink struct {
+	seeds       []string
+	topic       string
+	metadata    *kafkaMetadata
+	conns       map[string]net.Conn // host:port -> connection
+	correlation int32
+}
+
+// NewKafkaSink creates a producer for topic, the topic is created if the brokers allow it.
+// Unreachable brokers are only a warning, they are tried again with every batch
+func NewKafkaSink(brokers []string, topic string) (*KafkaSink, error) {
+	s := &KafkaSink{topic: topic, conns: make(map[string]net.Conn)}
+	for _, broker := range brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			broker = net.JoinHostPort(broker, KafkaDefaultPort)
+		}
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			return nil, fmt.Errorf("invalid Kafka broker %q", broker)
+		}
+		s.seeds = append(s.seeds, broker)
+	}
+
+	if err := s.loadMetadata(); err != nil {
+		logging.Warnf("Kafka topic %s is not ready yet, publishing will be retried: %v", topic, err)
+	}
+	return s, nil
+}
+
+// WriteResults produces a batch to the partition leaders and waits until they acknowledged it
+func (s *KafkaSink) WriteResults(results []domain.CrawlResult) error {
+	records := make([]kafkaRecord, 0, len(results))
+	for _, result := range results {
+		value, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %v", err)
+		}
+		records = append(records, kafkaRecord{key: []byte(result.URL), value: value})
+	}
+
+	var err error
+	for attempt := 0; attempt < kafkaAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(kafkaRetryWait)
+		}
+		if s.metadata == nil {
+			if err = s.loadMetadata(); err != nil {
+				continue
+			}
+		}
+		if records, err = s.produce(records); err == nil {
+			return nil
+		}
+		// Leaders moved or a broker went away, what was acknowledged is not sent again
+		s.metadata = nil
+		var code kafkaError
+		if errors.As(err, &code) && !code.retriable() {
+			return err
+		}
+	}
+	return err
+}
+
+// produce sends the records partition by partition and returns those not acknowledged
+func (s *KafkaSink) produce(records []kafkaRecord) ([]kafkaRecord, error) {
+	byPartition := make(map[int32][]kafkaRecord)
+	for _, record := range records {
+		partition := s.partition(record.key)
+		byPartition[partition] = append(byPartition[partition], record)
+	}
+
+	var failed []kafkaRecord
+	var firstErr error
+	for partition, pending := range byPartition {
+		for len(pending) > 0 {
+			batch := pending[:1]
+			size := len(pending[0].key) + len(pending[0].value)
+			for _, record := range pending[1:] {
+				size += len(record.key) + len(record.value)
+				if size > kafkaBatchSize {
+					break
+				}
+				batch = pending[:len(batch)+1]
+			}
+
+			if err := s.produceBatch(partition, batch); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				failed = append(failed, pending...)
+				break
+			}
+			pending = pending[len(batch):]
+		}
+	}
+	return failed, firstErr
+}
+
+// partition picks the partition of a key like the default partitioner of the Java client,
+// over every partition of the topic so a key keeps its partition while a leader is away
+func (s *KafkaSink) partition(key []byte) int32 {
+	return s.metadata.partitions[int(murmur2(key)&0x7fffffff)%len(s.metadata.partitions)]
+}
+
+func (s *KafkaSink) produceBatch(partition int32, records []kafkaRecord) error {
+	leader, ok := s.metadata.brokers[s.metadata.leaders[partition]]
+	if !ok {
+		return kafkaError(kafkaLeaderNotAvailable)
+	}
+
+	request := encodeProduceRequest(s.topic, partition, encodeRecordBatch(records, time.Now()), PublishTimeout)
+	response, err := s.roundTrip(leader, kafkaProduceKey, kafkaProduceVersion, request)
+	if err != nil {
+		return err
+	}
+	return decodeProduceResponse(response)
+}
+
+// loadMetadata asks the brokers, seeds first, where the partitions of the topic are
+func (s *KafkaSink) loadMetadata() error {
+	brokers := append([]string{}, s.seeds...)
+	if s.metadata != nil {
+		for _, broker := range s.metadata.brokers {
+			brokers = append(brokers, broker)
+		}
+	}
+
+	var err error
+	for _, broker := range brokers {
+		var response []byte
+		response, err = s.roundTrip(broker, kafkaMetadataKey, kafkaMetadataVersion, encodeMetadataRequest(s.topic))
+		if err != nil {
+			continue
+		}
+		var metadata *kafkaMetadata
+		if metadata, err = decodeMetadataResponse(response, s.topic); err == nil {
+			s.metadata = metadata
+			return nil
+		}
+	}
+	return err
+}
+
+// roundTrip sends a request to a broker and returns the response body. A broker that
+// fails is disconnected, the next request reconnects
+func (s *KafkaSink) roundTrip(broker string, key, version int16, body []byte) ([]byte, error) {
+	conn, ok := s.conns[broker]
+	if !ok {
+		var err error
+		if conn, err = net.DialTimeout("tcp", broker, PublishTimeout); err != nil {
+			return nil, fmt.Errorf("failed to connect to Kafka broker %s: %v", broker, err)
+		}
+		s.conns[broker] = conn
+	}
+
+	s.correlation++
+	var request kafkaEncoder
+	request.int32(0) // Size, set below
+	request.int16(key)
+	request.int16(version)
+	request.int32(s.correlation)
+	request.string("golamv2")
+	request.buf = append(request.buf, body...)
+	binary.BigEndian.PutUint32(request.buf, uint32(len(request.buf)-4))
+
+	response, err := s.exchange(conn, request.buf)
+	if err != nil {
+		conn.Close()
+		delete(s.conns, broker)
+		return nil, fmt.Errorf("Kafka broker %s: %v", broker, err)
+	}
+	return response, nil
+}
+
+// exchange writes a request and reads the response matching its correlation id
+func (s *KafkaSink) exchange(conn net.Conn, request []byte) ([]byte, error) {
+	conn.SetDeadline(time.Now().Add(PublishTimeout + 5*time.Second))
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	if len(response) < 4 || int32(binary.BigEndian.Uint32(response)) != s.correlation {
+		return nil, fmt.Errorf("response out of order")
+	}
+	return response[4:], nil
+}
+
+// Close disconnects from the brokers, every batch was acknowledged already
+func (s *KafkaSink) Close() error {
+	for broker, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, broker)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package sink
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// The wire tests read what the sink writes with the protocol package of franz-go, and
+// write what the sink reads with it
+
+func TestMurmur2(t *testing.T) {
+	// Expected values of the Java client's Utils.murmur2
+	tests := []struct {
+		key  string
+		want int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+	for _, tt := range tests {
+		if got := int32(murmur2([]byte(tt.key))); got != tt.want {
+			t.Errorf("murmur2(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKafkaPartitionIgnoresLeaders(t *testing.T) {
+	withLeaders := &KafkaSink{metadata: &kafkaMetadata{partitions: []int32{0, 1, 2, 3}, leaders: map[int32]int32{0: 1, 1: 1, 2: 1, 3: 1}}}
+	leaderless := &KafkaSink{metadata: &kafkaMetadata{partitions: []int32{0, 1, 2, 3}, leaders: map[int32]int32{0: 1}}}
+
+	for _, key := range []string{"https://example.com/", "https://example.com/a", "https://example.org/b?c=d"} {
+		want := int32(int(murmur2([]byte(key))&0x7fffffff) % 4)
+		if got := withLeaders.partition([]byte(key)); got != want {
+			t.Errorf("partition(%s) = %d, want %d", key, got, want)
+		}
+		if got := leaderless.partition([]byte(key)); got != want {
+			t.Errorf("partition(%s) without leaders = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestEncodeRecordBatch(t *testing.T) {
+	now := time.UnixMilli(1700000000123)
+	records := []kafkaRecord{
+		{key: []byte("https://example.com/"), value: []byte(`{"url":"https://example.com/"}`)},
+		{key: nil, value: []byte(`{}`)},
+		{key: []byte("k"), value: nil},
+	}
+
+	data := encodeRecordBatch(records, now)
+	var batch kmsg.RecordBatch
+	if err := batch.ReadFrom(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if int(batch.Length) != len(data)-12 {
+		t.Errorf("length = %d, want %d", batch.Length, len(data)-12)
+	}
+	if batch.Magic != 2 || batch.Attributes != 0 || batch.ProducerID != -1 || batch.ProducerEpoch != -1 || batch.FirstSequence != -1 {
+		t.Errorf("header = %+v", batch)
+	}
+	if batch.FirstTimestamp != now.UnixMilli() || batch.MaxTimestamp != now.UnixMilli() {
+		t.Errorf("timestamps = %d, %d", batch.FirstTimestamp, batch.MaxTimestamp)
+	}
+	if batch.LastOffsetDelta != 2 || batch.NumRecords != 3 {
+		t.Errorf("last offset delta %d, %d records", batch.LastOffsetDelta, batch.NumRecords)
+	}
+	// The CRC covers everything from the attributes on
+	if crc := int32(crc32.Checksum(data[21:], castagnoli)); batch.CRC != crc {
+		t.Errorf("crc = %d, want %d", batch.CRC, crc)
+	}
+
+	rest := batch.Records
+	for i, want := range records {
+		length, n := binary.Varint(rest)
+		var record kmsg.Record
+		if err := record.ReadFrom(rest[:n+int(length)]); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		rest = rest[n+int(length):]
+
+		if record.OffsetDelta != int32(i) || record.TimestampDelta64 != 0 {
+			t.Errorf("record %d: offset delta %d, timestamp delta %d", i, record.OffsetDelta, record.TimestampDelta64)
+		}
+		if !reflect.DeepEqual(record.Key, want.key) || !reflect.DeepEqual(record.Value, want.value) {
+			t.Errorf("record %d = %q: %q, want %q: %q", i, record.Key, record.Value, want.key, want.value)
+		}
+	}
+	if len(rest) != 0 {
+		t.Errorf("%d bytes after the records", len(rest))
+	}
+}
+
+func TestEncodeProduceRequest(t *testing.T) {
+	batch := encodeRecordBatch([]kafkaRecord{{key: []byte("k"), value: []byte("v")}}, time.UnixMilli(1))
+
+	request := kmsg.ProduceRequest{Version: kafkaProduceVersion}
+	if err := request.ReadFrom(encodeProduceRequest("results", 7, batch, 30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if request.TransactionID != nil || request.Acks != -1 || request.TimeoutMillis != 30000 {
+		t.Errorf("request = %+v", request)
+	}
+	if len(request.Topics) != 1 || request.Topics[0].Topic != "results" || len(request.Topics[0].Partitions) != 1 {
+		t.Fatalf("topics = %+v", request.Topics)
+	}
+	partition := request.Topics[0].Partitions[0]
+	if partition.Partition != 7 || !reflect.DeepEqual(partition.Records, batch) {
+		t.Errorf("partition = %+v", partition)
+	}
+}
+
+func TestEncodeMetadataRequest(t *testing.T) {
+	request := kmsg.MetadataRequest{Version: kafkaMetadataVersion}
+	if err := request.ReadFrom(encodeMetadataRequest("results")); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Topics) != 1 || request.Topics[0].Topic == nil || *request.Topics[0].Topic != "results" || !request.AllowAutoTopicCreation {
+		t.Errorf("request = %+v", request)
+	}
+}
+
+func TestDecodeMetadataResponse(t *testing.T) {
+	name, other := "results", "other"
+	partition := func(id, leader int32) kmsg.MetadataResponseTopicPartition {
+		p := kmsg.NewMetadataResponseTopicPartition()
+		p.Partition, p.Leader, p.Replicas, p.ISR = id, leader, []int32{1, 2}, []int32{1}
+		return p
+	}
+	topic := func(name *string, code int16, partitions ...kmsg.MetadataResponseTopicPartition) kmsg.MetadataResponseTopic {
+		t := kmsg.NewMetadataResponseTopic()
+		t.Topic, t.ErrorCode, t.Partitions = name, code, partitions
+		return t
+	}
+	brokers := []kmsg.MetadataResponseBroker{{NodeID: 1, Host: "kafka-1", Port: 9092}, {NodeID: 2, Host: "kafka-2", Port: 9093}}
+
+	tests := []struct {
+		name    string
+		topics  []kmsg.MetadataResponseTopic
+		want    *kafkaMetadata
+		wantErr error
+	}{
+		{
+			name: "every partition kept",
+			topics: []kmsg.MetadataResponseTopic{
+				topic(&other, 0, partition(0, 2)),
+				topic(&name, 0, partition(2, 1), partition(0, 2), partition(1, -1)),
+			},
+			want: &kafkaMetadata{
+				brokers:    map[int32]string{1: "kafka-1:9092", 2: "kafka-2:9093"},
+				partitions: []int32{0, 1, 2},
+				leaders:    map[int32]int32{0: 2, 2: 1},
+			},
+		},
+		{
+			name:    "topic error",
+			topics:  []kmsg.MetadataResponseTopic{topic(&name, kafkaUnknownTopic)},
+			wantErr: kafkaError(kafkaUnknownTopic),
+		},
+		{
+			name:    "no partitions",
+			topics:  []kmsg.MetadataResponseTopic{topic(&other, 0, partition(0, 1))},
+			wantErr: kafkaError(kafkaLeaderNotAvailable),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := kmsg.NewPtrMetadataResponse()
+			response.Version = kafkaMetadataVersion
+			response.Brokers, response.Topics, response.ControllerID = brokers, tt.topics, 1
+
+			got, err := decodeMetadataResponse(response.AppendTo(nil), name)
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadata = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeProduceResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []int16
+		want  error
+	}{
+		{"acknowledged", []int16{0}, nil},
+		{"not leader", []int16{kafkaNotLeader}, kafkaError(kafkaNotLeader)},
+		{"first error", []int16{0, 10, kafkaNotLeader}, kafkaError(10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic := kmsg.NewProduceResponseTopic()
+			topic.Topic = "results"
+			for i, code := range tt.codes {
+				partition := kmsg.NewProduceResponseTopicPartition()
+				partition.Partition, partition.ErrorCode = int32(i), code
+				topic.Partitions = append(topic.Partitions, partition)
+			}
+			response := kmsg.ProduceResponse{Version: kafkaProduceVersion, Topics: []kmsg.ProduceResponseTopic{topic}}
+
+			if err := decodeProduceResponse(response.AppendTo(nil)); err != tt.want {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package sink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"time"
+)
+
+// Kafka API keys and the versions spoken, old enough for brokers since 1.0 and still
+// accepted by 4.x
+const (
+	kafkaProduceKey      = 0
+	kafkaProduceVersion  = 3
+	kafkaMetadataKey     = 3
+	kafkaMetadataVersion = 4
+)
+
+// Kafka error codes the sink recovers from by reloading the partition leaders
+const (
+	kafkaUnknownTopic       = 3
+	kafkaLeaderNotAvailable = 5
+	kafkaNotLeader          = 6
+)
+
+var kafkaErrorNames = map[int16]string{
+	kafkaUnknownTopic:       "unknown topic or partition",
+	kafkaLeaderNotAvailable: "leader not available",
+	kafkaNotLeader:          "not the leader of the partition",
+	7:                       "request timed out",
+	10:                      "message too large",
+	17:                      "invalid topic",
+	19:                      "not enough replicas",
+	29:                      "topic authorization failed",
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaError is an error code answered by a broker
+type kafkaError int16
+
+func (e kafkaError) Error() string {
+	if name, ok := kafkaErrorNames[int16(e)]; ok {
+		return fmt.Sprintf("Kafka error %d: %s", int16(e), name)
+	}
+	return fmt.Sprintf("Kafka error %d", int16(e))
+}
+
+// retriable tells whether the error goes away once the leaders are reloaded
+func (e kafkaError) retriable() bool {
+	return e == kafkaUnknownTopic || e == kafkaLeaderNotAvailable || e == kafkaNotLeader
+}
+
+// kafkaEncoder appends the big endian primitives of the Kafka protocol
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (e *kafkaEncoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *kafkaEncoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *kafkaEncoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *kafkaEncoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+func (e *kafkaEncoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *kafkaEncoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *kafkaEncoder) bytes(v []byte) {
+	e.int32(int32(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// varbytes writes the length prefixed bytes of a record, nil as -1
+func (e *kafkaEncoder) varbytes(v []byte) {
+	if v == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// kafkaDecoder reads a response, the first read past the end sets err and the rest read zeros
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *kafkaDecoder) take(n int) []byte {
+	if d.err != nil || n < 0 || len(d.buf) < n {
+		if d.err == nil {
+			d.err = fmt.Errorf("truncated Kafka response")
+		}
+		return make([]byte, max(n, 0))
+	}
+	v := d.buf[:n]
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *kafkaDecoder) int8() int8   { return int8(d.take(1)[0]) }
+func (d *kafkaDecoder) int16() int16 { return int16(binary.BigEndian.Uint16(d.take(2))) }
+func (d *kafkaDecoder) int32() int32 { return int32(binary.BigEndian.Uint32(d.take(4))) }
+func (d *kafkaDecoder) int64() int64 { return int64(binary.BigEndian.Uint64(d.take(8))) }
+
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return "" // Null
+	}
+	return string(d.take(int(n)))
+}
+
+// array returns the element count of an array, null arrays are empty
+func (d *kafkaDecoder) array() int {
+	n := d.int32()
+	if n < 0 || d.err != nil {
+		return 0
+	}
+	if int(n) > len(d.buf) {
+		d.err = fmt.Errorf("truncated Kafka response")
+		return 0
+	}
+	return int(n)
+}
+
+// kafkaRecord is one message produced to the topic
+type kafkaRecord struct {
+	key   []byte
+	value []byte
+}
+
+// kafkaBatchSize is the size of the records in a batch, a record added past it starts the
+// next batch. Brokers reject batches over message.max.bytes, 1MB by default
+const kafkaBatchSize = 900 * 1024
+
+// encodeRecordBatch writes records as a v2 record batch, uncompressed and without producer id
+func encodeRecordBatch(records []kafkaRecord, now time.Time) []byte {
+	timestamp := now.UnixMilli()
+
+	// Everything the CRC covers, from the attributes on
+	var tail kafkaEncoder
+	tail.int16(0) // Attributes
+	tail.int32(int32(len(records) - 1))
+	tail.int64(timestamp) // First timestamp
+	tail.int64(timestamp) // Max timestamp
+	tail.int64(-1)        // Producer id
+	tail.int16(-1)        // Producer epoch
+	tail.int32(-1)        // Base sequence
+	tail.int32(int32(len(records)))
+	for i, record := range records {
+		var body kafkaEncoder
+		body.int8(0)   // Attributes
+		body.varint(0) // Timestamp delta
+		body.varint(int64(i))
+		body.varbytes(record.key)
+		body.varbytes(record.value)
+		body.varint(0) // Headers
+		tail.varint(int64(len(body.buf)))
+		tail.buf = append(tail.buf, body.buf...)
+	}
+
+	var batch kafkaEncoder
+	batch.int64(0) // Base offset, assigned by the broker
+	batch.int32(int32(4 + 1 + 4 + len(tail.buf)))
+	batch.int32(-1) // Partition leader epoch
+	batch.int8(2)   // Magic
+	batch.int32(int32(crc32.Checksum(tail.buf, castagnoli)))
+	batch.buf = append(batch.buf, tail.buf...)
+	return batch.buf
+}
+
+// kafkaMetadata is where the partitions of the topic live
+type kafkaMetadata struct {
+	brokers    map[int32]string // node id -> host:port
+	partitions []int32          // Every partition of the topic, sorted
+	leaders    map[int32]int32  // partition -> node id, partitions without a leader are missing
+}
+
+// encodeMetadataRequest asks for the partitions of topic, creating it if the brokers allow it
+func encodeMetadataRequest(topic string) []byte {
+	var e kafkaEncoder
+	e.int32(1)
+	e.string(topic)
+	e.int8(1) // Allow auto topic creation
+	return e.buf
+}
+
+// decodeMetadataResponse reads a v4 metadata response for topic
+func decodeMetadataResponse(data []byte, topic string) (*kafkaMetadata, error) {
+	d := &kafkaDecoder{buf: data}
+	metadata := &kafkaMetadata{brokers: make(map[int32]string), leaders: make(map[int32]int32)}
+
+	d.int32() // Throttle time
+	for i, n := 0, d.array(); i < n; i++ {
+		node := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // Rack
+		metadata.brokers[node] = fmt.Sprintf("%s:%d", host, port)
+	}
+	d.string() // Cluster id
+	d.int32()  // Controller id
+
+	var topicErr error
+	for i, n := 0, d.array(); i < n; i++ {
+		code := d.int16()
+		name := d.string()
+		d.int8() // Internal
+		for j, m := 0, d.array(); j < m; j++ {
+			d.int16() // Partition error, a missing leader says enough
+			partition := d.int32()
+			leader := d.int32()
+			for k, r := 0, d.array(); k < r; k++ {
+				d.int32() // Replicas
+			}
+			for k, r := 0, d.array(); k < r; k++ {
+				d.int32() // In-sync replicas
+			}
+			if name != topic {
+				continue
+			}
+			metadata.partitions = append(metadata.partitions, partition)
+			if leader >= 0 {
+				metadata.leaders[partition] = leader
+			}
+		}
+		if name == topic && code != 0 {
+			topicErr = kafkaError(code)
+		}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if topicErr != nil {
+		return nil, topicErr
+	}
+	if len(metadata.partitions) == 0 {
+		return nil, kafkaError(kafkaLeaderNotAvailable)
+	}
+	slices.Sort(metadata.partitions)
+	return metadata, nil
+}
+
+// murmur2 is the hash the Java client partitions keys with, so both put a key in the same partition
+func murmur2(data []byte) uint32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+	)
+
+	h := seed ^ uint32(len(data))
+	for ; len(data) >= 4; data = data[4:] {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// encodeProduceRequest produces a record batch to one partition, acknowledged once every
+// in-sync replica has it
+func encodeProduceRequest(topic string, partition int32, batch []byte, timeout time.Duration) []byte {
+	var e kafkaEncoder
+	e.int16(-1) // No transactional id
+	e.int16(-1) // Acks from all in-sync replicas
+	e.int32(int32(timeout.Milliseconds()))
+	e.int32(1)
+	e.string(topic)
+	e.int32(1)
+	e.int32(partition)
+	e.bytes(batch)
+	return e.buf
+}
+
+// decodeProduceResponse returns the error the broker answered for the batch, if any
+func decodeProduceResponse(data []byte) error {
+	d := &kafkaDecoder{buf: data}
+	var err error
+	for i, n := 0, d.array(); i < n; i++ {
+		d.string() // Topic
+		for j, m := 0, d.array(); j < m; j++ {
+			d.int32() // Partition
+			if code := d.int16(); code != 0 && err == nil {
+				err = kafkaError(code)
+			}
+			d.int64() // Base offset
+			d.int64() // Log append time
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golamv2/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoTimeout bounds connecting and every batch insert
+const MongoTimeout = 10 * time.Second
+
+// MongoConfig points at the collection results are written to
+type MongoConfig struct {
+	URI        string // e.g. mongodb://localhost:27017, empty disables the sink
+	Database   string
+	Collection string
+}
+
+// DefaultMongoConfig is the database and collection used unless configured otherwise
+var DefaultMongoConfig = MongoConfig{Database: "golamv2", Collection: "results"}
+
+// MongoSink writes results as documents into a MongoDB collection, with the field names
+// of the JSON exports plus the domain of the page
+type MongoSink struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+// NewMongoSink connects to MongoDB and makes sure the url, domain and processed_at indexes exist
+func NewMongoSink(config MongoConfig) (*MongoSink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), MongoTimeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(config.URI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to reach MongoDB: %v", err)
+	}
+
+	collection := client.Database(config.Database).Collection(config.Collection)
+	_, err = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "url", Value: 1}}},
+		{Keys: bson.D{{Key: "domain", Value: 1}}},
+		{Keys: bson.D{{Key: "processed_at", Value: 1}}},
+	})
+	if err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to create MongoDB indexes: %v", err)
+	}
+
+	return &MongoSink{client: client, collection: collection}, nil
+}
+
+// WriteResults inserts a batch, results failing to insert do not stop the others
+func (s *MongoSink) WriteResults(results []domain.CrawlResult) error {
+	documents := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		document, err := mongoDocument(result)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, document)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), MongoTimeout)
+	defer cancel()
+
+	_, err := s.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// Close disconnects from MongoDB
+func (s *MongoSink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), MongoTimeout)
+	defer cancel()
+	return s.client.Disconnect(ctx)
+}
+
+// mongoDocument converts a result through its JSON form so the documents share the field names
+// of every other export, processed_at is kept a date so time range queries use the index
+func mongoDocument(result domain.CrawlResult) (bson.M, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	var document bson.M
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to convert result: %v", err)
+	}
+	document["domain"] = domain.GetDomain(result.URL)
+	document["processed_at"] = result.ProcessedAt
+	return document, nil
+}
@@ -0,0 +1,74 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes every result as a JSON message to a subject
+type NATSSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSSink connects to the NATS servers, the client keeps reconnecting while they are
+// away and buffers what is published meanwhile
+func NewNATSSink(servers []string, subject string) (*NATSSink, error) {
+	urls := make([]string, len(servers))
+	for i, server := range servers {
+		urls[i] = "nats://" + server
+	}
+
+	conn, err := nats.Connect(strings.Join(urls, ","),
+		nats.Name("golamv2"),
+		nats.Timeout(PublishTimeout),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logging.Warnf("NATS publisher disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			logging.Infof("NATS publisher reconnected")
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+	return &NATSSink{conn: conn, subject: subject}, nil
+}
+
+// WriteResults publishes a batch, it fails once the reconnect buffer is full
+func (s *NATSSink) WriteResults(results []domain.CrawlResult) error {
+	for _, result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %v", err)
+		}
+		if err := s.conn.Publish(s.subject, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close sends what is still buffered and disconnects, what waits for a server that never
+// came back is lost
+func (s *NATSSink) Close() error {
+	defer s.conn.Close()
+
+	if !s.conn.IsConnected() {
+		if buffered, _ := s.conn.Buffered(); buffered > 0 {
+			logging.Warnf("NATS is not connected, %d bytes of results for %s are lost", buffered, s.subject)
+		}
+		return nil
+	}
+	return s.conn.FlushTimeout(PublishTimeout)
+}
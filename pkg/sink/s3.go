@@ -0,0 +1,478 @@
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+// S3OutboxDir is where result files wait in the data directory until they are uploaded
+const S3OutboxDir = "s3_outbox"
+
+const (
+	S3Timeout    = 5 * time.Minute // Longest an upload may take
+	S3MinBackoff = 5 * time.Second // First wait after a failed upload, doubled on every failure
+	S3MaxBackoff = 5 * time.Minute
+	// Suffixes of the file being written and of the files ready to upload
+	s3PartSuffix  = ".part"
+	s3ReadySuffix = ".ndjson.gz"
+)
+
+// S3Config points at the bucket result files are uploaded to. Any S3 compatible store
+// works: AWS, MinIO, or GCS through its interoperability API with HMAC keys
+type S3Config struct {
+	Endpoint     string // e.g. https://s3.amazonaws.com, http://localhost:9000 or https://storage.googleapis.com
+	Bucket       string // Empty disables the sink
+	Prefix       string // Prepended to the object keys
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string        // Only for temporary credentials
+	RotateSize   int64         // Compressed bytes a file grows to before it is uploaded
+	RotateEvery  time.Duration // Longest a file stays open before it is uploaded
+}
+
+// DefaultS3Config is the endpoint, region and rotation used unless configured otherwise
+var DefaultS3Config = S3Config{
+	Endpoint:    "https://s3.amazonaws.com",
+	Region:      "us-east-1",
+	Prefix:      "golamv2/",
+	RotateSize:  64 << 20,
+	RotateEvery: 10 * time.Minute,
+}
+
+// S3Sink writes results as gzipped NDJSON, one CrawlResult per line like the jsonl storage,
+// into files in an outbox directory. Files are closed once they reach RotateSize or
+// RotateEvery, uploaded in the background and removed, so long crawls keep little on disk.
+// Files that fail to upload stay in the outbox and are retried, also by the next crawl
+type S3Sink struct {
+	client *http.Client
+	config S3Config
+	outbox string
+
+	mu      sync.Mutex
+	file    *os.File
+	gzip    *gzip.Writer
+	written *countingWriter
+	opened  time.Time
+	seq     int
+
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	backoff time.Duration
+}
+
+// countingWriter counts the compressed bytes written to the current file
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewS3Sink checks the config and starts uploading the files the outbox holds, files a
+// killed crawl left half written are closed first
+func NewS3Sink(config S3Config, outbox string) (*S3Sink, error) {
+	u, err := url.Parse(config.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: use https://host", config.Endpoint)
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("S3 credentials missing: set --s3-access-key and --s3-secret-key or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if config.RotateSize <= 0 || config.RotateEvery <= 0 {
+		return nil, fmt.Errorf("S3 files need a positive rotation size and interval")
+	}
+	if err := os.MkdirAll(outbox, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create S3 outbox: %v", err)
+	}
+
+	s := &S3Sink{
+		client: &http.Client{Timeout: S3Timeout},
+		config: config,
+		outbox: outbox,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := s.recoverParts(); err != nil {
+		return nil, err
+	}
+	go s.run()
+	s.notify()
+	return s, nil
+}
+
+// WriteResults appends a batch to the current file, which is rotated once it is big or old
+// enough. The batch is flushed so a killed crawl loses none of it
+func (s *S3Sink) WriteResults(results []domain.CrawlResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		if size := s.outboxSize(); size >= MaxSpoolBytes {
+			return fmt.Errorf("S3 outbox %s is full with %d bytes", s.outbox, size)
+		}
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(s.gzip)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode result: %v", err)
+		}
+	}
+	if err := s.gzip.Flush(); err != nil {
+		return fmt.Errorf("failed to write S3 file: %v", err)
+	}
+
+	if s.written.n >= s.config.RotateSize {
+		return s.rotate()
+	}
+	return nil
+}
+
+// Close uploads the current file with the rest of the outbox, files that still fail are
+// kept for the next crawl on the same data directory
+func (s *S3Sink) Close() error {
+	s.mu.Lock()
+	err := s.rotate()
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+
+	if files, _ := s.readyFiles(); len(files) > 0 {
+		logging.Warnf("%d result files left in %s for the next crawl", len(files), s.outbox)
+	}
+	return err
+}
+
+// open starts a new file named after the time it was opened, the names sort in upload order
+func (s *S3Sink) open() error {
+	s.seq++
+	name := fmt.Sprintf("results-%s-%04d", time.Now().UTC().Format("20060102T150405Z"), s.seq)
+	file, err := os.OpenFile(filepath.Join(s.outbox, name+s3PartSuffix), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 file: %v", err)
+	}
+
+	s.file = file
+	s.written = &countingWriter{w: file}
+	s.gzip = gzip.NewWriter(s.written)
+	s.opened = time.Now()
+	return nil
+}
+
+// rotate closes the current file and hands it to the uploader
+func (s *S3Sink) rotate() error {
+	if s.file == nil {
+		return nil
+	}
+
+	file := s.file
+	s.file = nil
+	err := s.gzip.Close()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close S3 file: %v", err)
+	}
+
+	name := file.Name()
+	if err := os.Rename(name, strings.TrimSuffix(name, s3PartSuffix)+s3ReadySuffix); err != nil {
+		return fmt.Errorf("failed to close S3 file: %v", err)
+	}
+	s.notify()
+	return nil
+}
+
+func (s *S3Sink) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run rotates files that stayed open too long and uploads the outbox whenever a file is
+// ready, backing off while uploads fail
+func (s *S3Sink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(min(s.config.RotateEvery, time.Minute))
+	defer ticker.Stop()
+
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-s.stop:
+			s.upload()
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.file != nil && time.Since(s.opened) >= s.config.RotateEvery {
+				if err := s.rotate(); err != nil {
+					logging.Warnf("%v", err)
+				}
+			}
+			s.mu.Unlock()
+			continue
+		case <-s.wake:
+			if retry != nil {
+				continue // A retry is already waiting
+			}
+		case <-retry:
+			retry = nil
+		}
+
+		if s.upload() {
+			s.backoff = 0
+		} else {
+			s.backoff = min(max(s.backoff*2, S3MinBackoff), S3MaxBackoff)
+			retry = time.After(s.backoff)
+		}
+	}
+}
+
+// upload sends the ready files oldest first and removes them, false when one failed
+func (s *S3Sink) upload() bool {
+	files, err := s.readyFiles()
+	if err != nil {
+		logging.Warnf("Failed to read S3 outbox: %v", err)
+		return false
+	}
+
+	for _, name := range files {
+		if err := s.put(name); err != nil {
+			logging.Warnf("Failed to upload %s to S3, retrying later: %v", filepath.Base(name), err)
+			return false
+		}
+		if err := os.Remove(name); err != nil {
+			logging.Warnf("Failed to remove uploaded %s: %v", name, err)
+		}
+		logging.Debugf("Uploaded %s to s3://%s/%s", filepath.Base(name), s.config.Bucket, s.objectKey(name))
+	}
+	return true
+}
+
+// readyFiles lists the closed files of the outbox in the order they were written
+func (s *S3Sink) readyFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.outbox, "*"+s3ReadySuffix))
+	sort.Strings(files)
+	return files, err
+}
+
+// outboxSize is the bytes of the files waiting for upload
+func (s *S3Sink) outboxSize() int64 {
+	files, _ := s.readyFiles()
+	var size int64
+	for _, name := range files {
+		if info, err := os.Stat(name); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// objectKey is where a file goes in the bucket, under the prefix and the day it was written
+func (s *S3Sink) objectKey(name string) string {
+	base := filepath.Base(name)
+	day := "unknown"
+	if stamp := strings.TrimPrefix(base, "results-"); len(stamp) >= 8 {
+		day = stamp[:4] + "/" + stamp[4:6] + "/" + stamp[6:8]
+	}
+	return path.Join(s.config.Prefix, day, base)
+}
+
+// put uploads a file with a path style PUT signed with AWS Signature Version 4
+func (s *S3Sink) put(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(s.config.Endpoint, "/")
+	target := endpoint + "/" + s3Escape(s.config.Bucket) + "/" + s3Escape(s.objectKey(name))
+	req, err := http.NewRequest(http.MethodPut, target, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+	signV4(req, hex.EncodeToString(hash.Sum(nil)), s.config, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// recoverParts closes the files a killed crawl was writing: everything up to the last
+// flushed batch is recompressed into a complete file ready for upload
+func (s *S3Sink) recoverParts() error {
+	parts, err := filepath.Glob(filepath.Join(s.outbox, "*"+s3PartSuffix))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range parts {
+		ready := strings.TrimSuffix(name, s3PartSuffix) + s3ReadySuffix
+		if err := recompress(name, ready); err != nil {
+			return fmt.Errorf("failed to recover %s: %v", name, err)
+		}
+		os.Remove(name)
+	}
+	return nil
+}
+
+// recompress copies the complete lines of a truncated gzip file into a new one, nothing is
+// left when there were none
+func recompress(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(out)
+
+	lines := 0
+	if reader, err := gzip.NewReader(in); err == nil {
+		buffered := bufio.NewReader(reader)
+		for {
+			line, err := buffered.ReadBytes('\n')
+			if err != nil {
+				break // The torn end of the file
+			}
+			if _, err := writer.Write(line); err != nil {
+				out.Close()
+				return err
+			}
+			lines++
+		}
+	}
+
+	err = writer.Close()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && lines == 0 {
+		err = os.Remove(to)
+	}
+	return err
+}
+
+// signV4 signs a request with AWS Signature Version 4, over the host and every header set
+func signV4(req *http.Request, payloadHash string, config S3Config, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + config.Region + "/s3/aws4_request"
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + config.SecretKey)
+	for _, part := range []string{now.Format("20060102"), config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape escapes every path segment of a key the way Signature Version 4 expects: all
+// but the unreserved characters, which leaves more escaped than url.PathEscape does
+func s3Escape(key string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '/' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
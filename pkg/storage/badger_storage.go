@@ -2,32 +2,66 @@ package storage
 
 // Took Up Badger After A chatgpt pros and cons. Ha!. In the Previous Version I used a sqlite but would suffer from write lock and bottlenecks due to its single item write nature.
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"golamv2/internal/domain"
+	"golamv2/pkg/bufpool"
+	"golamv2/pkg/logging"
 
 	"github.com/dgraph-io/badger/v4"
 )
 
 const (
-	URLPrefix    = "url:"
-	ResultPrefix = "result:"
-	MetricsKey   = "metrics"
-	BatchSize    = 1000
+	URLPrefix        = "url:"
+	ResultPrefix     = "result:"
+	PagePrefix       = "page:"
+	RobotsPrefix     = "robots:"
+	SeenPrefix       = "seen:"
+	DeadLetterPrefix = "deadletter:"
+	DNSPrefix        = "dns:"
+	MetricsKey       = "metrics"
+	BatchSize        = 1000
 )
 
+// DrainTimeout bounds how long Close waits for writes still in flight
+const DrainTimeout = 10 * time.Second
+
+// ErrStorageClosed is returned by writes arriving after Close
+var ErrStorageClosed = errors.New("storage is closed")
+
 // BadgerStorage implements domain.Storage using BadgerDB
 type BadgerStorage struct {
 	urlDB     *badger.DB
 	resultsDB *badger.DB
 	mode      domain.CrawlMode
-	dbPath    string
+	dbPath    string // Empty for in-memory storages
 	metrics   *domain.CrawlMetrics
+	// Raw page archive, opened on first use
+	archiveMu sync.Mutex
+	archiveDB *badger.DB
+	// Results and URLs being written, Close waits for them
+	writeMu sync.Mutex
+	writes  sync.WaitGroup
+	closed  bool
+	// Copies every stored result to the result sinks, optional
+	publish func(result domain.CrawlResult)
+	// Closed by Close to stop the value log GC
+	stopGC chan struct{}
+	// Namespace views share the databases of their parent and prefix every key
+	parent    *BadgerStorage
+	keyPrefix string
 	// Memory tracking
 	allocatedMemoryMB float64
 }
@@ -39,6 +73,34 @@ func NewBadgerStorage(dbPath string, mode domain.CrawlMode, maxMemoryMB int) (*B
 		return nil, fmt.Errorf("failed to create db directory: %v", err)
 	}
 
+	return openBadgerStorage(dbPath, mode, maxMemoryMB)
+}
+
+// NewMemoryBadgerStorage creates a storage keeping every database in memory, nothing is
+// written to disk and everything is gone once it is closed. Used by dry runs
+func NewMemoryBadgerStorage(mode domain.CrawlMode, maxMemoryMB int) (*BadgerStorage, error) {
+	return openBadgerStorage("", mode, maxMemoryMB)
+}
+
+// badgerOptions returns the options of the named database under dbPath, in memory without a dbPath
+func badgerOptions(dbPath, name string) badger.Options {
+	if dbPath == "" {
+		return badger.DefaultOptions("").WithInMemory(true)
+	}
+	return badger.DefaultOptions(filepath.Join(dbPath, name))
+}
+
+// resultsDBName is the directory of the results database, one per mode
+func resultsDBName(mode domain.CrawlMode) string {
+	if mode == domain.ModeAll {
+		return "finds"
+	}
+	return fmt.Sprintf("finds_%s", mode)
+}
+
+// openBadgerStorage opens the databases under dbPath, or in memory when dbPath is empty
+func openBadgerStorage(dbPath string, mode domain.CrawlMode, maxMemoryMB int) (*BadgerStorage, error) {
+
 	// Ensure total memory usage stays within limits
 	totalMemoryBytes := int64(maxMemoryMB) * 1024 * 1024
 	urlMemory := totalMemoryBytes * 40 / 100    // 40% for URLs
@@ -49,8 +111,8 @@ func NewBadgerStorage(dbPath string, mode domain.CrawlMode, maxMemoryMB int) (*B
 	allocatedMemoryMB := float64(maxMemoryMB) * 0.7
 
 	// Open URL database
-	urlOpts := badger.DefaultOptions(filepath.Join(dbPath, "urls"))
-	urlOpts.Logger = nil // Disable logging for performance
+	urlOpts := badgerOptions(dbPath, "urls")
+	urlOpts.Logger = logging.NewBadgerLogger("urls")
 	urlOpts.ValueLogMaxEntries = 1000000
 	urlOpts.MemTableSize = urlMemory
 	urlOpts.ValueLogFileSize = 64 << 20 // 64MB
@@ -66,13 +128,9 @@ func NewBadgerStorage(dbPath string, mode domain.CrawlMode, maxMemoryMB int) (*B
 	}
 
 	// Open results database with specific name based on mode
-	resultsDBName := "finds"
-	if mode != domain.ModeAll {
-		resultsDBName = fmt.Sprintf("finds_%s", mode)
-	}
-
-	resultOpts := badger.DefaultOptions(filepath.Join(dbPath, resultsDBName))
-	resultOpts.Logger = nil
+	resultsDBName := resultsDBName(mode)
+	resultOpts := badgerOptions(dbPath, resultsDBName)
+	resultOpts.Logger = logging.NewBadgerLogger(resultsDBName)
 	resultOpts.ValueLogMaxEntries = 1000000
 	resultOpts.MemTableSize = resultMemory
 	resultOpts.ValueLogFileSize = 64 << 20
@@ -98,11 +156,18 @@ func NewBadgerStorage(dbPath string, mode domain.CrawlMode, maxMemoryMB int) (*B
 			LastUpdateTime: time.Now(),
 		},
 		allocatedMemoryMB: allocatedMemoryMB,
+		stopGC:            make(chan struct{}),
 	}
 
 	// Load existing metrics
 	storage.loadMetrics()
 
+	if err := storage.indexStoredResults(); err != nil {
+		urlDB.Close()
+		resultsDB.Close()
+		return nil, fmt.Errorf("failed to index stored results: %v", err)
+	}
+
 	// Start background garbage collection
 	go storage.startGC()
 
@@ -111,15 +176,18 @@ func NewBadgerStorage(dbPath string, mode domain.CrawlMode, maxMemoryMB int) (*B
 
 // StoreURL stores a URL task in the database
 func (s *BadgerStorage) StoreURL(task domain.URLTask) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
 	data, err := json.Marshal(task)
 	if err != nil {
 		return fmt.Errorf("failed to marshal URL task: %v", err)
 	}
 
-	key := fmt.Sprintf("%s%s", URLPrefix, task.URL)
-
 	return s.urlDB.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(key), data)
+		return txn.Set(s.key(URLPrefix+task.URL), data)
 	})
 }
 
@@ -133,7 +201,7 @@ func (s *BadgerStorage) GetURLs(limit int) ([]domain.URLTask, error) {
 		iterator := txn.NewIterator(opts)
 		defer iterator.Close()
 
-		prefix := []byte(URLPrefix)
+		prefix := s.key(URLPrefix)
 		count := 0
 
 		for iterator.Seek(prefix); iterator.ValidForPrefix(prefix) && count < limit; iterator.Next() {
@@ -171,26 +239,45 @@ func (s *BadgerStorage) deleteURLsBatch(tasks []domain.URLTask) {
 	defer batch.Cancel()
 
 	for _, task := range tasks {
-		key := fmt.Sprintf("%s%s", URLPrefix, task.URL)
-		batch.Delete([]byte(key))
+		batch.Delete(s.key(URLPrefix + task.URL))
 	}
 
 	batch.Flush()
 }
 
 func (s *BadgerStorage) StoreResult(result domain.CrawlResult) error {
-	data, err := json.Marshal(result)
-	if err != nil {
-		return fmt.Errorf("failed to marshal result: %v", err)
+	if err := s.beginWrite(); err != nil {
+		return err
 	}
+	defer s.writes.Done()
 
-	key := fmt.Sprintf("%s%s_%d", ResultPrefix, result.URL, result.ProcessedAt.Unix())
-
-	err = s.resultsDB.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(key), data)
+	// Marshaled into a pooled buffer, Update is done with it once it returns
+	buf := bufpool.Get()
+	defer bufpool.Put(buf)
+	if err := json.NewEncoder(buf).Encode(result); err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+	data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	key := make([]byte, 0, len(s.keyPrefix)+len(ResultPrefix)+len(result.URL)+21)
+	key = append(key, s.keyPrefix...)
+	key = append(key, ResultPrefix...)
+	key = append(key, result.URL...)
+	key = append(key, '_')
+	key = strconv.AppendInt(key, result.ProcessedAt.Unix(), 10)
+
+	err := s.resultsDB.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(key, data); err != nil {
+			return err
+		}
+		return s.indexResult(txn, key, result)
 	})
 
 	if err == nil {
+		if s.publish != nil {
+			s.publish(result)
+		}
+
 		// Update metrics
 		atomic.AddInt64(&s.metrics.URLsProcessed, 1)
 
@@ -214,9 +301,132 @@ func (s *BadgerStorage) StoreResult(result domain.CrawlResult) error {
 	return err
 }
 
+// MaxMergeAttempts is how often MergeResult retries when another write changed the same result
+const MaxMergeAttempts = 3
+
+// MergeResult adds the dead links of result to the latest result stored for its URL, so the
+// async dead link checks extend the page's record instead of adding a bare one per link.
+// result is stored as is when the URL has no result yet
+func (s *BadgerStorage) MergeResult(result domain.CrawlResult) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	prefix := s.key(ResultPrefix + result.URL + "_")
+	merged := false
+	var links, domains int
+	var err error
+	for attempt := 0; attempt < MaxMergeAttempts; attempt++ {
+		err = s.resultsDB.Update(func(txn *badger.Txn) error {
+			key, stored, err := latestResult(txn, prefix)
+			if err != nil || key == nil {
+				return err
+			}
+
+			links, domains = stored.MergeDeadLinks(result)
+			data, err := json.Marshal(stored)
+			if err != nil {
+				return err
+			}
+			merged = true
+			return txn.Set(key, data)
+		})
+		if err != badger.ErrConflict {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to merge result: %v", err)
+	}
+	if !merged {
+		return s.StoreResult(result)
+	}
+
+	// Sinks and events get the dead links found, not the whole merged result
+	if s.publish != nil {
+		s.publish(result)
+	}
+	atomic.AddInt64(&s.metrics.DeadLinksFound, int64(links))
+	atomic.AddInt64(&s.metrics.DeadDomainsFound, int64(domains))
+	return nil
+}
+
+// latestResult returns the key and value of the newest result under prefix, the URL of a
+// result key followed by "_". The key is nil when there is none
+func latestResult(txn *badger.Txn, prefix []byte) ([]byte, *domain.CrawlResult, error) {
+	iterator := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+	defer iterator.Close()
+
+	var latestKey []byte
+	latestTime := int64(-1)
+	for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+		key := iterator.Item().Key()
+		// Longer URLs sharing the prefix have more than the timestamp after it
+		unix, err := strconv.ParseInt(string(key[len(prefix):]), 10, 64)
+		if err != nil || unix <= latestTime {
+			continue
+		}
+		latestTime = unix
+		latestKey = iterator.Item().KeyCopy(nil)
+	}
+	if latestKey == nil {
+		return nil, nil, nil
+	}
+
+	item, err := txn.Get(latestKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	var result domain.CrawlResult
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &result)
+	})
+	return latestKey, &result, err
+}
+
 // Retrrieve Result from the database--CrawlResult
-func (s *BadgerStorage) GetResults(mode domain.CrawlMode, limit int) ([]domain.CrawlResult, error) {
+func (s *BadgerStorage) GetResults(mode domain.CrawlMode, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults(cursor, limit, nil)
+}
+
+// GetLabeledResults returns up to limit results tagged with label
+func (s *BadgerStorage) GetLabeledResults(label, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults(cursor, limit, func(result domain.CrawlResult) bool {
+		return result.HasLabel(label)
+	})
+}
+
+// GetDeadLinkResults returns up to limit results with dead links
+func (s *BadgerStorage) GetDeadLinkResults(cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults(cursor, limit, func(result domain.CrawlResult) bool {
+		return len(result.DeadLinks) > 0 || len(result.DeadLinkDetails) > 0
+	})
+}
+
+// SearchResults returns up to limit results matching query and filters after cursor
+func (s *BadgerStorage) SearchResults(query string, filters domain.ResultFilters, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults(cursor, limit, func(result domain.CrawlResult) bool {
+		return domain.MatchesResult(result, query, filters)
+	})
+}
+
+// readResults returns up to limit results after the key of cursor, those keep accepts when
+// it is set. The cursor of the next page is the key of the last result returned
+func (s *BadgerStorage) readResults(cursor string, limit int, keep func(result domain.CrawlResult) bool) ([]domain.CrawlResult, string, error) {
 	var results []domain.CrawlResult
+	var next string
+	var lastKey []byte
+
+	prefix := s.key(ResultPrefix)
+	start := prefix
+	if cursor != "" {
+		key, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || !bytes.HasPrefix(key, prefix) {
+			return nil, "", domain.ErrInvalidCursor
+		}
+		start = key
+	}
 
 	err := s.resultsDB.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
@@ -224,10 +434,18 @@ func (s *BadgerStorage) GetResults(mode domain.CrawlMode, limit int) ([]domain.C
 		iterator := txn.NewIterator(opts)
 		defer iterator.Close()
 
-		prefix := []byte(ResultPrefix)
-		count := 0
+		iterator.Seek(start)
+		if cursor != "" && iterator.Valid() && bytes.Equal(iterator.Item().Key(), start) {
+			iterator.Next() // The last result of the previous page
+		}
+
+		for ; iterator.ValidForPrefix(prefix); iterator.Next() {
+			// More keys follow the page, they may still be filtered out
+			if len(results) == limit {
+				next = base64.RawURLEncoding.EncodeToString(lastKey)
+				break
+			}
 
-		for iterator.Seek(prefix); iterator.ValidForPrefix(prefix) && count < limit; iterator.Next() {
 			item := iterator.Item()
 
 			err := item.Value(func(val []byte) error {
@@ -235,21 +453,217 @@ func (s *BadgerStorage) GetResults(mode domain.CrawlMode, limit int) ([]domain.C
 				if err := json.Unmarshal(val, &result); err != nil {
 					return err
 				}
-				results = append(results, result)
+				if keep == nil || keep(result) {
+					results = append(results, result)
+					lastKey = item.KeyCopy(lastKey)
+				}
 				return nil
 			})
 
 			if err != nil {
 				return err
 			}
-
-			count++
 		}
 
 		return nil
 	})
 
-	return results, err
+	return results, next, err
+}
+
+// ForEachKnownURL calls fn with every URL still queued and every URL with a stored result,
+// only keys are read so this stays fast on large databases
+func (s *BadgerStorage) ForEachKnownURL(fn func(url string)) error {
+	keys := func(db *badger.DB, prefix string, parse func(key string) string) error {
+		return db.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.PrefetchValues = false
+			iterator := txn.NewIterator(opts)
+			defer iterator.Close()
+
+			for iterator.Seek([]byte(prefix)); iterator.ValidForPrefix([]byte(prefix)); iterator.Next() {
+				if url := parse(string(iterator.Item().Key()[len(prefix):])); url != "" {
+					fn(url)
+				}
+			}
+			return nil
+		})
+	}
+
+	if err := keys(s.urlDB, s.keyPrefix+URLPrefix, func(key string) string { return key }); err != nil {
+		return fmt.Errorf("failed to read queued URLs: %v", err)
+	}
+
+	// Result keys are URL_unixtime
+	err := keys(s.resultsDB, s.keyPrefix+ResultPrefix, func(key string) string {
+		if i := strings.LastIndexByte(key, '_'); i > 0 {
+			return key[:i]
+		}
+		return ""
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read crawled URLs: %v", err)
+	}
+	return nil
+}
+
+// StoreDeadLetter keeps a URL that failed all its retries, replacing an older letter for the same URL
+func (s *BadgerStorage) StoreDeadLetter(letter domain.DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %v", err)
+	}
+
+	return s.update(s.urlDB, func(txn *badger.Txn) error {
+		return txn.Set(s.key(DeadLetterPrefix+letter.Task.URL), data)
+	})
+}
+
+// MarkURLSeen records a URL key in the exact dedup keyspace, true when it was not there yet.
+// Keys are hashed so long URLs do not bloat the LSM tree
+func (s *BadgerStorage) MarkURLSeen(key string) (bool, error) {
+	seenKey := s.seenURLKey(key)
+
+	added := false
+	err := s.update(s.urlDB, func(txn *badger.Txn) error {
+		_, err := txn.Get(seenKey)
+		if err == nil {
+			return nil
+		}
+		if err != badger.ErrKeyNotFound {
+			return err
+		}
+		added = true
+		return txn.Set(seenKey, nil)
+	})
+
+	// Another worker marked the same key at the same time
+	if err == badger.ErrConflict {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mark URL seen: %v", err)
+	}
+	return added, nil
+}
+
+// ForgetURL removes a URL key from the exact dedup keyspace
+func (s *BadgerStorage) ForgetURL(key string) error {
+	return s.update(s.urlDB, func(txn *badger.Txn) error {
+		return txn.Delete(s.seenURLKey(key))
+	})
+}
+
+// ResetSeenURLs empties the exact dedup keyspace
+func (s *BadgerStorage) ResetSeenURLs() error {
+	return s.urlDB.DropPrefix(s.key(SeenPrefix))
+}
+
+func (s *BadgerStorage) seenURLKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return append(s.key(SeenPrefix), sum[:16]...)
+}
+
+// GetPageState returns the stored state of a page, or nil if it was never fetched
+func (s *BadgerStorage) GetPageState(url string) (*domain.PageState, error) {
+	var state *domain.PageState
+
+	err := s.urlDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.key(PagePrefix + url))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			state = &domain.PageState{}
+			return json.Unmarshal(val, state)
+		})
+	})
+
+	return state, err
+}
+
+// StorePageState saves the state of a fetched page for later incremental crawls
+func (s *BadgerStorage) StorePageState(state domain.PageState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal page state: %v", err)
+	}
+
+	return s.update(s.urlDB, func(txn *badger.Txn) error {
+		return txn.Set(s.key(PagePrefix+state.URL), data)
+	})
+}
+
+// GetRobots returns the stored robots.txt of a host, or nil if it was never fetched
+func (s *BadgerStorage) GetRobots(host string) (*domain.RobotsFile, error) {
+	var file *domain.RobotsFile
+
+	err := s.urlDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.key(RobotsPrefix + host))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			file = &domain.RobotsFile{}
+			return json.Unmarshal(val, file)
+		})
+	})
+
+	return file, err
+}
+
+// StoreRobots saves a fetched robots.txt for later runs
+func (s *BadgerStorage) StoreRobots(file domain.RobotsFile) error {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal robots.txt: %v", err)
+	}
+
+	return s.update(s.urlDB, func(txn *badger.Txn) error {
+		return txn.Set(s.key(RobotsPrefix+file.Host), data)
+	})
+}
+
+// StoreDNSRecords saves the DNS records of a host, replacing older ones
+func (s *BadgerStorage) StoreDNSRecords(records domain.DNSRecords) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal DNS records: %v", err)
+	}
+
+	return s.update(s.urlDB, func(txn *badger.Txn) error {
+		return txn.Set(s.key(DNSPrefix+records.Host), data)
+	})
+}
+
+// GetDNSRecords returns the stored DNS records of a host, or nil if they were never collected
+func (s *BadgerStorage) GetDNSRecords(host string) (*domain.DNSRecords, error) {
+	var records *domain.DNSRecords
+
+	err := s.urlDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.key(DNSPrefix + host))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			records = &domain.DNSRecords{}
+			return json.Unmarshal(val, records)
+		})
+	})
+
+	return records, err
 }
 
 // GetMetrics returns current crawler metrics
@@ -283,7 +697,7 @@ func (s *BadgerStorage) countURLsInDB() int64 {
 		iterator := txn.NewIterator(opts)
 		defer iterator.Close()
 
-		prefix := []byte(URLPrefix)
+		prefix := s.key(URLPrefix)
 
 		for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
 			count++
@@ -298,7 +712,7 @@ func (s *BadgerStorage) countURLsInDB() int64 {
 // loadMetrics loads metrics from database
 func (s *BadgerStorage) loadMetrics() {
 	s.urlDB.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(MetricsKey))
+		item, err := txn.Get(s.key(MetricsKey))
 		if err != nil {
 			return err // Metrics don't exist yet
 		}
@@ -317,25 +731,118 @@ func (s *BadgerStorage) saveMetrics() error {
 	}
 
 	return s.urlDB.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(MetricsKey), data)
+		return txn.Set(s.key(MetricsKey), data)
 	})
 }
 
-// startGC starts background garbage collection
+// startGC starts background garbage collection, until Close
 func (s *BadgerStorage) startGC() {
 	ticker := time.NewTicker(10 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		// Run garbage collection
-		s.urlDB.RunValueLogGC(0.5)
-		s.resultsDB.RunValueLogGC(0.5)
+	for {
+		select {
+		case <-s.stopGC:
+			return
+		case <-ticker.C:
+			// Run garbage collection
+			s.urlDB.RunValueLogGC(0.5)
+			s.resultsDB.RunValueLogGC(0.5)
+		}
+	}
+}
+
+// SetResultPublisher sets where stored results are copied to, set it before the crawl starts
+func (s *BadgerStorage) SetResultPublisher(publish func(result domain.CrawlResult)) {
+	s.publish = publish
+}
+
+// beginWrite registers a write Close has to wait for, call writes.Done once it is stored
+func (s *BadgerStorage) beginWrite() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+	s.writes.Add(1)
+	return nil
+}
+
+// update runs a write transaction Close waits for
+func (s *BadgerStorage) update(db *badger.DB, fn func(txn *badger.Txn) error) error {
+	if err := s.beginWrite(); err != nil {
+		return err
 	}
+	defer s.writes.Done()
+	return db.Update(fn)
 }
 
-// Close closes the storage
+// Flush saves the metrics and syncs every database to disk
+func (s *BadgerStorage) Flush() error {
+	if err := s.saveMetrics(); err != nil {
+		return fmt.Errorf("failed to save metrics: %v", err)
+	}
+
+	// In-memory databases have no files to sync
+	if s.dbPath == "" {
+		return nil
+	}
+
+	// Namespace views sync the shared databases
+	s = s.owner()
+	if err := s.urlDB.Sync(); err != nil {
+		return fmt.Errorf("failed to sync URL database: %v", err)
+	}
+	if err := s.resultsDB.Sync(); err != nil {
+		return fmt.Errorf("failed to sync results database: %v", err)
+	}
+
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+	if s.archiveDB != nil {
+		if err := s.archiveDB.Sync(); err != nil {
+			return fmt.Errorf("failed to sync archive database: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close refuses new writes, waits up to DrainTimeout for the ones in flight and flushes
+// everything before closing the databases
 func (s *BadgerStorage) Close() error {
-	s.saveMetrics()
+	s.writeMu.Lock()
+	s.closed = true
+	s.writeMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.writes.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(DrainTimeout):
+		logging.Warnf("storage closed with writes still in flight after %s, they are lost", DrainTimeout)
+	}
+
+	if err := s.Flush(); err != nil {
+		logging.Errorf("%v", err)
+	}
+
+	// The databases stay open for the other namespaces
+	if s.parent != nil {
+		return nil
+	}
+
+	select {
+	case <-s.stopGC:
+	default:
+		close(s.stopGC)
+	}
+	if err := s.closeArchive(); err != nil {
+		return err
+	}
 
 	if err := s.urlDB.Close(); err != nil {
 		return err
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+func newBenchmarkStorage(b *testing.B) *BadgerStorage {
+	store, err := NewMemoryBadgerStorage(domain.ModeAll, 256)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestWritesAfterClose(t *testing.T) {
+	store, err := NewMemoryBadgerStorage(domain.ModeAll, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		write func() error
+	}{
+		{"url", func() error { return store.StoreURL(domain.URLTask{URL: "https://example.com/"}) }},
+		{"result", func() error { return store.StoreResult(domain.CrawlResult{URL: "https://example.com/"}) }},
+		{"page meta", func() error { return store.StorePageMeta(domain.PageMeta{URL: "https://example.com/"}) }},
+		{"archive", func() error { return store.ArchivePage(domain.ArchivedPage{URL: "https://example.com/"}) }},
+		{"slow request", func() error { return store.StoreSlowRequest(domain.SlowRequest{URL: "https://example.com/"}) }},
+		{"forget", func() error { return store.ForgetURL("https://example.com/") }},
+		{"dns", func() error { return store.StoreDNSRecords(domain.DNSRecords{Host: "example.com"}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); !errors.Is(err, ErrStorageClosed) {
+				t.Errorf("error = %v, want %v", err, ErrStorageClosed)
+			}
+		})
+	}
+}
+
+func BenchmarkStoreURL(b *testing.B) {
+	store := newBenchmarkStorage(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := store.StoreURL(domain.URLTask{URL: fmt.Sprintf("https://example.com/page/%d", i), Depth: 2, Timestamp: time.Now()}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStoreResult(b *testing.B) {
+	store := newBenchmarkStorage(b)
+	result := domain.CrawlResult{
+		StatusCode: 200,
+		Title:      "A page",
+		Emails:     []string{"a@example.com", "b@example.com"},
+		Keywords:   map[string]int{"golang": 3},
+		NewURLs:    []string{"https://example.com/a", "https://example.com/b"},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		result.URL = fmt.Sprintf("https://example.com/page/%d", i)
+		result.ProcessedAt = time.Now()
+		if err := store.StoreResult(result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Dead link findings added to results already stored
+func BenchmarkMergeResult(b *testing.B) {
+	store := newBenchmarkStorage(b)
+	const pages = 1000
+	for i := 0; i < pages; i++ {
+		store.StoreResult(domain.CrawlResult{URL: fmt.Sprintf("https://example.com/page/%d", i), StatusCode: 200, ProcessedAt: time.Now()})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dead := fmt.Sprintf("https://example.org/gone/%d", i)
+		err := store.MergeResult(domain.CrawlResult{
+			URL:             fmt.Sprintf("https://example.com/page/%d", i%pages),
+			DeadLinks:       []string{dead},
+			DeadLinkDetails: []domain.DeadLink{{URL: dead, StatusCode: 404}},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golamv2/internal/domain"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+const (
+	// CheckpointKey keeps what the last checkpoint holds, its tasks and bloom filter are
+	// written in chunks so large frontiers fit in Badger transactions
+	CheckpointKey = "checkpoint:queue"
+	// CheckpointTasksPrefix and CheckpointBloomPrefix key the chunks, numbered from 0
+	CheckpointTasksPrefix = "checkpoint:tasks:"
+	CheckpointBloomPrefix = "checkpoint:bloom:"
+
+	// Chunk sizes, tasks per chunk and bytes of bloom filter per chunk
+	checkpointTaskChunk  = 5000
+	checkpointBloomChunk = 1 << 20
+)
+
+// checkpointHeader is stored under CheckpointKey
+type checkpointHeader struct {
+	SavedAt     time.Time `json:"saved_at"`
+	TaskChunks  int       `json:"task_chunks"`
+	BloomChunks int       `json:"bloom_chunks"`
+}
+
+func chunkKey(prefix string, i int) string {
+	return fmt.Sprintf("%s%06d", prefix, i)
+}
+
+// SaveCheckpoint replaces the saved checkpoint. The chunks are written before the header
+// pointing at them, chunks beyond what the header counts are left over from bigger ones
+func (s *BadgerStorage) SaveCheckpoint(checkpoint domain.Checkpoint) error {
+	header := checkpointHeader{SavedAt: checkpoint.SavedAt}
+
+	batch := s.urlDB.NewWriteBatch()
+	defer batch.Cancel()
+
+	for start := 0; start < len(checkpoint.Tasks); start += checkpointTaskChunk {
+		data, err := json.Marshal(checkpoint.Tasks[start:min(start+checkpointTaskChunk, len(checkpoint.Tasks))])
+		if err != nil {
+			return fmt.Errorf("failed to marshal checkpoint: %v", err)
+		}
+		if err := batch.Set(s.key(chunkKey(CheckpointTasksPrefix, header.TaskChunks)), data); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %v", err)
+		}
+		header.TaskChunks++
+	}
+
+	for start := 0; start < len(checkpoint.Bloom); start += checkpointBloomChunk {
+		data := checkpoint.Bloom[start:min(start+checkpointBloomChunk, len(checkpoint.Bloom))]
+		if err := batch.Set(s.key(chunkKey(CheckpointBloomPrefix, header.BloomChunks)), data); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %v", err)
+		}
+		header.BloomChunks++
+	}
+
+	if err := batch.Flush(); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+	err = s.urlDB.Update(func(txn *badger.Txn) error {
+		return txn.Set(s.key(CheckpointKey), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint returns the saved checkpoint, or nil if there is none
+func (s *BadgerStorage) LoadCheckpoint() (*domain.Checkpoint, error) {
+	var checkpoint *domain.Checkpoint
+
+	err := s.urlDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.key(CheckpointKey))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var header checkpointHeader
+		if err := item.Value(func(val []byte) error { return json.Unmarshal(val, &header) }); err != nil {
+			return err
+		}
+		checkpoint = &domain.Checkpoint{SavedAt: header.SavedAt}
+
+		for i := 0; i < header.TaskChunks; i++ {
+			item, err := txn.Get(s.key(chunkKey(CheckpointTasksPrefix, i)))
+			if err != nil {
+				return err
+			}
+			var tasks []domain.URLTask
+			if err := item.Value(func(val []byte) error { return json.Unmarshal(val, &tasks) }); err != nil {
+				return err
+			}
+			checkpoint.Tasks = append(checkpoint.Tasks, tasks...)
+		}
+
+		for i := 0; i < header.BloomChunks; i++ {
+			item, err := txn.Get(s.key(chunkKey(CheckpointBloomPrefix, i)))
+			if err != nil {
+				return err
+			}
+			err = item.Value(func(val []byte) error {
+				checkpoint.Bloom = append(checkpoint.Bloom, val...)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %v", err)
+	}
+
+	return checkpoint, nil
+}
@@ -4,25 +4,46 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"golamv2/internal/domain"
 )
 
-// FastFileStorage implements high-performance file-based storage
+// Files of a FastFileStorage in its data directory
+const (
+	ResultsFileName   = "crawl_results.jsonl"
+	URLsFileName      = "crawl_urls.jsonl"
+	URLsOffsetName    = "crawl_urls.offset" // Bytes of the URLs file already handed back by GetURLs
+	FileMetricsName   = "crawl_metrics.json"
+	fileStorageBuffer = 64 * 1024
+)
+
+// FastFileStorage implements high-performance file-based storage. Results are appended to a
+// JSON Lines file, URLs spilled by the queue to another one read back in order by GetURLs
 type FastFileStorage struct {
+	dataDir     string
 	resultsFile *os.File
 	urlsFile    *os.File
 	writer      *bufio.Writer
 	urlWriter   *bufio.Writer
 	mutex       sync.Mutex
 	metrics     *domain.CrawlMetrics
+	closed      bool
+	// Read position in the URLs file and the URLs after it
+	urlsOffset int64
+	urlsQueued int64
+	// Copies every stored result to the result sinks, optional
+	publish func(result domain.CrawlResult)
 }
 
-// NewFastFileStorage creates a new file-based storage
+// NewFastFileStorage creates a new file-based storage, URLs left in the URLs file by an
+// earlier run on the same directory are handed out again
 func NewFastFileStorage(dataDir string) (*FastFileStorage, error) {
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
@@ -30,51 +51,124 @@ func NewFastFileStorage(dataDir string) (*FastFileStorage, error) {
 	}
 
 	// Open results file in append mode
-	resultsPath := filepath.Join(dataDir, "crawl_results.jsonl")
+	resultsPath := filepath.Join(dataDir, ResultsFileName)
 	resultsFile, err := os.OpenFile(resultsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open results file: %v", err)
 	}
 
 	// Open URLs file in append mode
-	urlsPath := filepath.Join(dataDir, "crawl_urls.jsonl")
+	urlsPath := filepath.Join(dataDir, URLsFileName)
 	urlsFile, err := os.OpenFile(urlsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		resultsFile.Close()
 		return nil, fmt.Errorf("failed to open URLs file: %v", err)
 	}
 
-	return &FastFileStorage{
+	s := &FastFileStorage{
+		dataDir:     dataDir,
 		resultsFile: resultsFile,
 		urlsFile:    urlsFile,
-		writer:      bufio.NewWriterSize(resultsFile, 64*1024), // 64KB buffer
-		urlWriter:   bufio.NewWriterSize(urlsFile, 64*1024),    // 64KB buffer
+		writer:      bufio.NewWriterSize(resultsFile, fileStorageBuffer),
+		urlWriter:   bufio.NewWriterSize(urlsFile, fileStorageBuffer),
 		metrics: &domain.CrawlMetrics{
 			StartTime:      time.Now(),
 			LastUpdateTime: time.Now(),
 		},
-	}, nil
+	}
+
+	if err := s.loadURLsOffset(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadURLsOffset restores where the last run stopped reading the URLs file and counts the URLs after it
+func (s *FastFileStorage) loadURLsOffset() error {
+	if data, err := os.ReadFile(filepath.Join(s.dataDir, URLsOffsetName)); err == nil {
+		s.urlsOffset, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+
+	file, err := os.Open(filepath.Join(s.dataDir, URLsFileName))
+	if err != nil {
+		return fmt.Errorf("failed to open URLs file: %v", err)
+	}
+	defer file.Close()
+
+	// An offset past the end belongs to a file that was replaced
+	if info, err := file.Stat(); err != nil || s.urlsOffset > info.Size() {
+		s.urlsOffset = 0
+	}
+	if _, err := file.Seek(s.urlsOffset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read URLs file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, fileStorageBuffer), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			s.urlsQueued++
+		}
+	}
+	return scanner.Err()
+}
+
+// SetResultPublisher sets where stored results are copied to, set it before the crawl starts
+func (s *FastFileStorage) SetResultPublisher(publish func(result domain.CrawlResult)) {
+	s.publish = publish
 }
 
 // StoreResult stores a crawl result to file (FAST)
 func (s *FastFileStorage) StoreResult(result domain.CrawlResult) error {
+	if err := s.appendResult(result, func() {
+		s.metrics.URLsProcessed++
+		if len(result.Emails) > 0 {
+			s.metrics.EmailsFound += int64(len(result.Emails))
+		}
+		if len(result.Keywords) > 0 {
+			s.metrics.KeywordsFound += int64(len(result.Keywords))
+		}
+	}); err != nil {
+		return err
+	}
+
+	if s.publish != nil {
+		s.publish(result)
+	}
+	return nil
+}
+
+// MergeResult appends result like StoreResult, the results file is append only. The
+// dead links are counted but the result is not, its page was counted already
+func (s *FastFileStorage) MergeResult(result domain.CrawlResult) error {
+	if err := s.appendResult(result, func() {
+		s.metrics.DeadLinksFound += int64(len(result.DeadLinks))
+		s.metrics.DeadDomainsFound += int64(len(result.DeadDomains))
+	}); err != nil {
+		return err
+	}
+
+	if s.publish != nil {
+		s.publish(result)
+	}
+	return nil
+}
+
+// appendResult writes a result as one JSON line and updates the metrics with count
+func (s *FastFileStorage) appendResult(result domain.CrawlResult, count func()) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if s.closed {
+		return ErrStorageClosed
+	}
+
 	// Write as JSON Lines format (one JSON object per line)
 	if err := json.NewEncoder(s.writer).Encode(result); err != nil {
 		return fmt.Errorf("failed to encode result: %v", err)
 	}
-
-	// Update metrics
-	s.metrics.URLsProcessed++
-	if len(result.Emails) > 0 {
-		s.metrics.EmailsFound += int64(len(result.Emails))
-	}
-	if len(result.Keywords) > 0 {
-		s.metrics.KeywordsFound += int64(len(result.Keywords))
-	}
-
+	count()
 	return nil
 }
 
@@ -83,8 +177,143 @@ func (s *FastFileStorage) StoreURL(task domain.URLTask) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if s.closed {
+		return ErrStorageClosed
+	}
+
 	// Write as JSON Lines format
-	return json.NewEncoder(s.urlWriter).Encode(task)
+	if err := json.NewEncoder(s.urlWriter).Encode(task); err != nil {
+		return fmt.Errorf("failed to encode URL task: %v", err)
+	}
+	s.urlsQueued++
+	return nil
+}
+
+// GetURLs hands back up to limit URLs in the order they were stored, each one once. The
+// URLs file is emptied once every URL in it was handed back
+func (s *FastFileStorage) GetURLs(limit int) ([]domain.URLTask, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+	if s.urlsQueued == 0 || limit <= 0 {
+		return nil, nil
+	}
+	if err := s.urlWriter.Flush(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(s.dataDir, URLsFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open URLs file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(s.urlsOffset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read URLs file: %v", err)
+	}
+
+	var tasks []domain.URLTask
+	reader := bufio.NewReaderSize(file, fileStorageBuffer)
+	for len(tasks) < limit {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return tasks, fmt.Errorf("failed to read URLs file: %v", err)
+		}
+		s.urlsOffset += int64(len(line))
+
+		var task domain.URLTask
+		if json.Unmarshal(line, &task) != nil {
+			continue // Torn line of a killed run
+		}
+		tasks = append(tasks, task)
+		s.urlsQueued--
+	}
+
+	if s.urlsQueued <= 0 {
+		s.urlsQueued = 0
+		if err := s.urlsFile.Truncate(0); err == nil {
+			s.urlsOffset = 0
+		}
+	}
+	return tasks, nil
+}
+
+// GetResults reads up to limit results of the results file from the offset in cursor, pages
+// whose dead links were merged in later appear once more with those links. The cursor of
+// the next page is the offset after the last result read
+func (s *FastFileStorage) GetResults(mode domain.CrawlMode, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults(cursor, limit, nil)
+}
+
+// SearchResults returns up to limit results matching query and filters from the offset in cursor
+func (s *FastFileStorage) SearchResults(query string, filters domain.ResultFilters, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults(cursor, limit, func(result domain.CrawlResult) bool {
+		return domain.MatchesResult(result, query, filters)
+	})
+}
+
+// GetResultsByTimeRange returns up to limit results processed within r from the offset in
+// cursor, the whole file is read as results are appended in the order they were stored
+func (s *FastFileStorage) GetResultsByTimeRange(r domain.TimeRange, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults(cursor, limit, func(result domain.CrawlResult) bool {
+		return r.Contains(result.ProcessedAt)
+	})
+}
+
+// readResults reads up to limit results from the offset in cursor, those keep accepts when it is set
+func (s *FastFileStorage) readResults(cursor string, limit int, keep func(result domain.CrawlResult) bool) ([]domain.CrawlResult, string, error) {
+	s.mutex.Lock()
+	err := s.writer.Flush()
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, err := os.Open(filepath.Join(s.dataDir, ResultsFileName))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open results file: %v", err)
+	}
+	defer file.Close()
+
+	var offset int64
+	if cursor != "" {
+		offset, err = strconv.ParseInt(cursor, 10, 64)
+		info, statErr := file.Stat()
+		if err != nil || statErr != nil || offset < 0 || offset > info.Size() {
+			return nil, "", domain.ErrInvalidCursor
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return nil, "", fmt.Errorf("failed to read results file: %v", err)
+		}
+	}
+
+	var results []domain.CrawlResult
+	reader := bufio.NewReaderSize(file, fileStorageBuffer)
+	for len(results) < limit {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return results, "", nil
+		}
+		if err != nil {
+			return results, "", fmt.Errorf("failed to read results file: %v", err)
+		}
+		offset += int64(len(line))
+
+		var result domain.CrawlResult
+		if json.Unmarshal(line, &result) == nil && (keep == nil || keep(result)) {
+			results = append(results, result)
+		}
+	}
+
+	if _, err := reader.Peek(1); err != nil {
+		return results, "", nil
+	}
+	return results, strconv.FormatInt(offset, 10), nil
 }
 
 // Flush ensures all data is written to disk
@@ -92,6 +321,10 @@ func (s *FastFileStorage) Flush() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	return s.flush()
+}
+
+func (s *FastFileStorage) flush() error {
 	if err := s.writer.Flush(); err != nil {
 		return err
 	}
@@ -103,12 +336,28 @@ func (s *FastFileStorage) Flush() error {
 	if err := s.resultsFile.Sync(); err != nil {
 		return err
 	}
-	return s.urlsFile.Sync()
+	if err := s.urlsFile.Sync(); err != nil {
+		return err
+	}
+
+	// The next run starts reading the URLs where this one stopped
+	offset := strconv.FormatInt(s.urlsOffset, 10)
+	return os.WriteFile(filepath.Join(s.dataDir, URLsOffsetName), []byte(offset), 0644)
 }
 
-// Close closes the storage
+// Close flushes the buffers and closes the files, writes in flight finish first as they
+// hold the lock
 func (s *FastFileStorage) Close() error {
-	s.Flush()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if err := s.flush(); err != nil {
+		return err
+	}
 
 	if err := s.resultsFile.Close(); err != nil {
 		return err
@@ -116,36 +365,33 @@ func (s *FastFileStorage) Close() error {
 	return s.urlsFile.Close()
 }
 
-// GetMetrics returns current metrics
-func (s *FastFileStorage) GetMetrics() *domain.CrawlMetrics {
+// GetMetrics returns a copy of the current metrics
+func (s *FastFileStorage) GetMetrics() (*domain.CrawlMetrics, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.metrics.URLsInDB = s.urlsQueued
 	s.metrics.LastUpdateTime = time.Now()
-	return s.metrics
-}
-
-// Stub methods to satisfy interface (not needed for file storage)
-func (s *FastFileStorage) GetURLs(limit int) ([]domain.URLTask, error) {
-	return []domain.URLTask{}, nil
-}
-
-func (s *FastFileStorage) GetResults(limit int) ([]domain.CrawlResult, error) {
-	return []domain.CrawlResult{}, nil
+	metrics := *s.metrics
+	return &metrics, nil
 }
 
-func (s *FastFileStorage) GetEmails(limit int) ([]string, error) {
-	return []string{}, nil
-}
+// UpdateMetrics replaces the metrics and saves them next to the results
+func (s *FastFileStorage) UpdateMetrics(metrics *domain.CrawlMetrics) error {
+	data, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %v", err)
+	}
 
-func (s *FastFileStorage) GetKeywords(limit int) (map[string]int, error) {
-	return map[string]int{}, nil
-}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-func (s *FastFileStorage) GetDeadLinks(limit int) ([]string, error) {
-	return []string{}, nil
+	copied := *metrics
+	s.metrics = &copied
+	return os.WriteFile(filepath.Join(s.dataDir, FileMetricsName), data, 0644)
 }
 
-func (s *FastFileStorage) SearchResults(query string, limit int) ([]domain.CrawlResult, error) {
-	return []domain.CrawlResult{}, nil
+// GetMemoryUsageMB returns the memory held by the write buffers
+func (s *FastFileStorage) GetMemoryUsageMB() float64 {
+	return float64(2*fileStorageBuffer) / 1024 / 1024
 }
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+// NamespacePrefix starts the keys of a namespace, the default namespace keeps unprefixed keys
+// so databases written before namespaces existed read the same
+const NamespacePrefix = "ns:"
+
+// ValidateNamespace checks a namespace name: letters, digits, '-', '_' and '.'
+func ValidateNamespace(name string) error {
+	if name == "" {
+		return fmt.Errorf("namespace name is empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return fmt.Errorf("invalid namespace %q: only letters, digits, '-', '_' and '.' are allowed", name)
+		}
+	}
+	return nil
+}
+
+// NamespaceKeyPrefix returns what the keys of a namespace start with, for tools reading the
+// databases directly
+func NamespaceKeyPrefix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return NamespacePrefix + name + "/"
+}
+
+// Namespace returns a view of the storage isolated under name: URLs, results, dedup keys,
+// page states, robots.txt files, dead letters, metrics and archived pages are all kept apart.
+// Views share the databases, closing one flushes it but only closing s closes the databases
+func (s *BadgerStorage) Namespace(name string) (*BadgerStorage, error) {
+	if err := ValidateNamespace(name); err != nil {
+		return nil, err
+	}
+
+	owner := s.owner()
+	view := &BadgerStorage{
+		urlDB:     owner.urlDB,
+		resultsDB: owner.resultsDB,
+		mode:      owner.mode,
+		dbPath:    owner.dbPath,
+		metrics: &domain.CrawlMetrics{
+			StartTime:      time.Now(),
+			LastUpdateTime: time.Now(),
+		},
+		parent:            owner,
+		keyPrefix:         NamespaceKeyPrefix(name),
+		allocatedMemoryMB: owner.allocatedMemoryMB,
+	}
+	view.loadMetrics()
+
+	return view, nil
+}
+
+// owner returns the storage that opened the databases
+func (s *BadgerStorage) owner() *BadgerStorage {
+	if s.parent != nil {
+		return s.parent
+	}
+	return s
+}
+
+// key puts a key into the namespace of the storage
+func (s *BadgerStorage) key(key string) []byte {
+	return []byte(s.keyPrefix + key)
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/golang/snappy"
+)
+
+const (
+	// ArchivePrefix keys archived page bodies in the archive database
+	ArchivePrefix = "html:"
+
+	// archiveDBName is the directory of the archive database, only created when pages get archived
+	archiveDBName = "archive"
+)
+
+// openArchive opens the archive database on first use, with create false a missing archive returns nil.
+// Namespace views use the archive of their parent
+func (s *BadgerStorage) openArchive(create bool) (*badger.DB, error) {
+	s = s.owner()
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+
+	if s.archiveDB != nil {
+		return s.archiveDB, nil
+	}
+
+	if s.dbPath != "" {
+		path := filepath.Join(s.dbPath, archiveDBName)
+		if _, err := os.Stat(path); os.IsNotExist(err) && !create {
+			return nil, nil
+		}
+	} else if !create {
+		return nil, nil
+	}
+
+	opts := badgerOptions(s.dbPath, archiveDBName)
+	opts.Logger = logging.NewBadgerLogger(archiveDBName)
+	opts.MemTableSize = 16 << 20
+	opts.ValueLogFileSize = 256 << 20
+	opts.ValueThreshold = 1 << 10 // Bodies live in the value log, keys stay small
+	opts.NumMemtables = 2
+	opts.NumCompactors = 2
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive database: %v", err)
+	}
+
+	s.archiveDB = db
+	return db, nil
+}
+
+// ArchivePage stores the snappy compressed raw body of a page, replacing older versions
+func (s *BadgerStorage) ArchivePage(page domain.ArchivedPage) error {
+	// Checked before opening, a closed storage must not open the archive again
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	db, err := s.openArchive(true)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to marshal archived page: %v", err)
+	}
+
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set(s.key(ArchivePrefix+page.URL), snappy.Encode(nil, data))
+	})
+}
+
+// GetArchivedPage returns the archived body of a page, or nil if it was never archived
+func (s *BadgerStorage) GetArchivedPage(url string) (*domain.ArchivedPage, error) {
+	db, err := s.openArchive(false)
+	if err != nil || db == nil {
+		return nil, err
+	}
+
+	var page *domain.ArchivedPage
+	err = db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(s.key(ArchivePrefix + url))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			decoded, err := decodeArchivedPage(val)
+			if err != nil {
+				return err
+			}
+			page = &decoded
+			return nil
+		})
+	})
+
+	return page, err
+}
+
+// ForEachArchivedPage calls fn for every archived page until it returns an error
+func (s *BadgerStorage) ForEachArchivedPage(fn func(page domain.ArchivedPage) error) error {
+	db, err := s.openArchive(false)
+	if err != nil || db == nil {
+		return err
+	}
+
+	return db.View(func(txn *badger.Txn) error {
+		iterator := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+
+		prefix := s.key(ArchivePrefix)
+		for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+			var page domain.ArchivedPage
+			err := iterator.Item().Value(func(val []byte) error {
+				var err error
+				page, err = decodeArchivedPage(val)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// closeArchive closes the archive database if it was opened
+func (s *BadgerStorage) closeArchive() error {
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+
+	if s.archiveDB == nil {
+		return nil
+	}
+	err := s.archiveDB.Close()
+	s.archiveDB = nil
+	return err
+}
+
+func decodeArchivedPage(val []byte) (domain.ArchivedPage, error) {
+	var page domain.ArchivedPage
+
+	data, err := snappy.Decode(nil, val)
+	if err != nil {
+		return page, fmt.Errorf("failed to decompress archived page: %v", err)
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return page, fmt.Errorf("failed to unmarshal archived page: %v", err)
+	}
+	return page, nil
+}
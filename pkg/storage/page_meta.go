@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golamv2/internal/domain"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+const (
+	// MetaPrefix indexes titles and descriptions: meta:<kind>:<domain>|<value hash>|<url>,
+	// pages sharing a value sit next to each other
+	MetaPrefix = "meta:"
+
+	// PageMetaPrefix keeps the last indexed values of a page, so a recrawl can drop them
+	PageMetaPrefix = "pagemeta:"
+
+	// metaHashLength is the length of the value hash in index keys
+	metaHashLength = 16
+)
+
+// metaKey is the index key of a page's title or description
+func (s *BadgerStorage) metaKey(kind, pageURL, value string) []byte {
+	sum := sha256.Sum256([]byte(domain.NormalizeMeta(value)))
+	hash := hex.EncodeToString(sum[:])[:metaHashLength]
+	return s.key(MetaPrefix + kind + ":" + domain.GetDomain(pageURL) + "|" + hash + "|" + pageURL)
+}
+
+// StorePageMeta indexes the title and description of a page under its domain, replacing
+// what an earlier crawl of the page indexed
+func (s *BadgerStorage) StorePageMeta(meta domain.PageMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal page meta: %v", err)
+	}
+
+	return s.update(s.urlDB, func(txn *badger.Txn) error {
+		var previous domain.PageMeta
+		item, err := txn.Get(s.key(PageMetaPrefix + meta.URL))
+		if err == nil {
+			err = item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &previous)
+			})
+		}
+		if err != nil && err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		for _, kind := range []string{domain.MetaTitle, domain.MetaDescription} {
+			old, value := previous.Value(kind), meta.Value(kind)
+			if old != "" && domain.NormalizeMeta(old) != domain.NormalizeMeta(value) {
+				if err := txn.Delete(s.metaKey(kind, meta.URL, old)); err != nil {
+					return err
+				}
+			}
+			if strings.TrimSpace(value) != "" {
+				if err := txn.Set(s.metaKey(kind, meta.URL, value), []byte(value)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return txn.Set(s.key(PageMetaPrefix+meta.URL), data)
+	})
+}
+
+// GetDuplicateMeta returns the titles or descriptions shared by several pages of a domain,
+// the largest groups first. An empty domain looks at every domain, limit 0 returns every group
+func (s *BadgerStorage) GetDuplicateMeta(kind, domainName string, limit int) ([]domain.DuplicateMeta, error) {
+	var groups []domain.DuplicateMeta
+	err := s.urlDB.View(func(txn *badger.Txn) error {
+		var err error
+		groups, err = ScanDuplicateMeta(txn, s.keyPrefix, kind, domainName)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page meta: %v", err)
+	}
+
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+// ScanDuplicateMeta groups the index keys of a namespace, "" for the default one, by domain and
+// value, keeping the groups of more than one page, largest first. Also used by the explorer
+func ScanDuplicateMeta(txn *badger.Txn, namespacePrefix, kind, domainName string) ([]domain.DuplicateMeta, error) {
+	kindPrefix := namespacePrefix + MetaPrefix + kind + ":"
+	prefix := []byte(kindPrefix)
+	if domainName != "" {
+		prefix = []byte(kindPrefix + domainName + "|")
+	}
+
+	var groups []domain.DuplicateMeta
+	var current domain.DuplicateMeta
+	currentKey := ""
+	flush := func() {
+		if len(current.URLs) > 1 {
+			groups = append(groups, current)
+		}
+	}
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+		key := string(iterator.Item().Key()[len(kindPrefix):])
+		bar := strings.IndexByte(key, '|')
+		if bar < 0 || len(key) < bar+metaHashLength+2 {
+			continue
+		}
+		groupKey, pageURL := key[:bar+1+metaHashLength], key[bar+metaHashLength+2:]
+
+		if groupKey != currentKey {
+			flush()
+			currentKey = groupKey
+			current = domain.DuplicateMeta{Domain: key[:bar], Kind: kind}
+			err := iterator.Item().Value(func(val []byte) error {
+				current.Value = string(val)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		current.URLs = append(current.URLs, pageURL)
+	}
+	flush()
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].URLs) > len(groups[j].URLs)
+	})
+	return groups, nil
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// Secondary indexes of the results database: <index><value>|<url> -> result key, so the
+// pages with an email, a keyword or a host are read without decoding every result. A
+// recrawl points the entries of a page at its newest result
+const (
+	EmailIndexPrefix   = "email:"
+	KeywordIndexPrefix = "kw:"
+	DomainIndexPrefix  = "domain:"
+
+	// TimeIndexPrefix orders every result by when it was processed: time:<UTC time>|<url>,
+	// recrawls of a page keep an entry each
+	TimeIndexPrefix = "time:"
+
+	// ResultIndexKey holds the version of the indexes every stored result was indexed with
+	ResultIndexKey = "resultindex"
+)
+
+// resultIndexVersion is raised with every index added, databases indexed with an older
+// version are indexed again once
+const resultIndexVersion = "2"
+
+// timeIndexLayout is fixed width so index keys sort in time order
+const timeIndexLayout = "2006-01-02T15:04:05.000000000Z"
+
+// IndexValue normalizes a value the way index keys store it: emails and keywords in
+// lowercase, hosts in lowercase ACE form without a port
+func IndexValue(index, value string) string {
+	value = strings.TrimSpace(value)
+	if index == DomainIndexPrefix {
+		if host, err := domain.ASCIIHost(strings.TrimSuffix(value, ".")); err == nil {
+			return host
+		}
+	}
+	return strings.ToLower(value)
+}
+
+// resultIndexKeys returns the index keys of a result in the namespace of namespacePrefix
+func resultIndexKeys(namespacePrefix string, result domain.CrawlResult) [][]byte {
+	var keys [][]byte
+	add := func(index, value string) {
+		if value = IndexValue(index, value); value != "" {
+			keys = append(keys, []byte(namespacePrefix+index+value+"|"+result.URL))
+		}
+	}
+
+	for _, email := range result.Emails {
+		add(EmailIndexPrefix, email)
+	}
+	for keyword := range result.Keywords {
+		add(KeywordIndexPrefix, keyword)
+	}
+	if u, err := url.Parse(result.URL); err == nil {
+		add(DomainIndexPrefix, u.Hostname())
+	}
+	keys = append(keys, []byte(namespacePrefix+TimeIndexPrefix+result.ProcessedAt.UTC().Format(timeIndexLayout)+"|"+result.URL))
+	return keys
+}
+
+// indexResult writes the index keys of a result stored under resultKey
+func (s *BadgerStorage) indexResult(txn *badger.Txn, resultKey []byte, result domain.CrawlResult) error {
+	for _, key := range resultIndexKeys(s.keyPrefix, result) {
+		if err := txn.Set(key, resultKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResultsByEmail returns up to limit pages an email was found on, paged like GetResults
+func (s *BadgerStorage) ResultsByEmail(email, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.lookupResults(EmailIndexPrefix, email, cursor, limit)
+}
+
+// ResultsByKeyword returns up to limit pages a keyword was found on
+func (s *BadgerStorage) ResultsByKeyword(keyword, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.lookupResults(KeywordIndexPrefix, keyword, cursor, limit)
+}
+
+// ResultsByDomain returns up to limit pages of a host, its subdomains are not included
+func (s *BadgerStorage) ResultsByDomain(host, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.lookupResults(DomainIndexPrefix, host, cursor, limit)
+}
+
+func (s *BadgerStorage) lookupResults(index, value, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	var results []domain.CrawlResult
+	var next string
+	err := s.resultsDB.View(func(txn *badger.Txn) error {
+		var err error
+		results, next, err = LookupIndex(txn, s.keyPrefix, index, value, cursor, limit)
+		return err
+	})
+	return results, next, err
+}
+
+// GetResultsByTimeRange returns up to limit results processed within r, oldest first, paged
+// like GetResults
+func (s *BadgerStorage) GetResultsByTimeRange(r domain.TimeRange, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	var results []domain.CrawlResult
+	var next string
+	err := s.resultsDB.View(func(txn *badger.Txn) error {
+		var err error
+		results, next, err = ScanTimeRange(txn, s.keyPrefix, r, cursor, limit)
+		return err
+	})
+	return results, next, err
+}
+
+// LookupIndex reads up to limit results an index points to for value, in URL order, after
+// the index key of cursor. Also used by the explorer
+func LookupIndex(txn *badger.Txn, namespacePrefix, index, value, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	prefix := []byte(namespacePrefix + index + IndexValue(index, value) + "|")
+	return readIndex(txn, prefix, prefix, nil, cursor, limit)
+}
+
+// ScanTimeRange reads up to limit results processed within r from the time index, oldest
+// first, after the index key of cursor. Also used by the explorer
+func ScanTimeRange(txn *badger.Txn, namespacePrefix string, r domain.TimeRange, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	prefix := []byte(namespacePrefix + TimeIndexPrefix)
+	start := prefix
+	if !r.From.IsZero() {
+		start = append(bytes.Clone(prefix), r.From.UTC().Format(timeIndexLayout)...)
+	}
+	var end []byte
+	if !r.To.IsZero() {
+		end = append(bytes.Clone(prefix), r.To.UTC().Format(timeIndexLayout)...)
+	}
+	return readIndex(txn, prefix, start, end, cursor, limit)
+}
+
+// readIndex reads up to limit results the index keys under prefix point to, from start or
+// after the key of cursor, up to end when it is set
+func readIndex(txn *badger.Txn, prefix, start, end []byte, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	if cursor != "" {
+		key, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || !bytes.HasPrefix(key, prefix) {
+			return nil, "", domain.ErrInvalidCursor
+		}
+		start = key
+	}
+
+	iterator := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+	defer iterator.Close()
+
+	iterator.Seek(start)
+	if cursor != "" && iterator.Valid() && bytes.Equal(iterator.Item().Key(), start) {
+		iterator.Next() // The last result of the previous page
+	}
+
+	var results []domain.CrawlResult
+	var lastKey []byte
+	for ; iterator.Valid(); iterator.Next() {
+		if end != nil && bytes.Compare(iterator.Item().Key(), end) >= 0 {
+			break
+		}
+		if len(results) == limit {
+			return results, base64.RawURLEncoding.EncodeToString(lastKey), nil
+		}
+
+		resultKey, err := iterator.Item().ValueCopy(nil)
+		if err != nil {
+			return nil, "", err
+		}
+		item, err := txn.Get(resultKey)
+		if err == badger.ErrKeyNotFound {
+			continue // Result deleted since
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		var result domain.CrawlResult
+		err = item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &result)
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		results = append(results, result)
+		lastKey = iterator.Item().KeyCopy(lastKey)
+	}
+	return results, "", nil
+}
+
+// indexStoredResults indexes the results of every namespace stored before the indexes
+// existed, or before some of them did, once per database
+func (s *BadgerStorage) indexStoredResults() error {
+	var version []byte
+	err := s.resultsDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(ResultIndexKey))
+		if err != nil {
+			return err
+		}
+		version, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil && err != badger.ErrKeyNotFound {
+		return err
+	}
+	if string(version) == resultIndexVersion {
+		return nil
+	}
+
+	batch := s.resultsDB.NewWriteBatch()
+	defer batch.Cancel()
+
+	indexed := 0
+	err = s.resultsDB.View(func(txn *badger.Txn) error {
+		iterator := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			key := string(iterator.Item().Key())
+			// Results of a namespace sit behind ns:<name>/
+			namespacePrefix := ""
+			if strings.HasPrefix(key, NamespacePrefix) {
+				slash := strings.IndexByte(key, '/')
+				if slash < 0 {
+					continue
+				}
+				namespacePrefix = key[:slash+1]
+			}
+			if !strings.HasPrefix(key[len(namespacePrefix):], ResultPrefix) {
+				continue
+			}
+
+			var result domain.CrawlResult
+			err := iterator.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &result)
+			})
+			if err != nil {
+				continue
+			}
+			for _, indexKey := range resultIndexKeys(namespacePrefix, result) {
+				if err := batch.Set(indexKey, []byte(key)); err != nil {
+					return err
+				}
+			}
+			indexed++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := batch.Set([]byte(ResultIndexKey), []byte(resultIndexVersion)); err != nil {
+		return err
+	}
+	if err := batch.Flush(); err != nil {
+		return err
+	}
+	if indexed > 0 {
+		logging.Infof("Indexed %d stored results", indexed)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+
+	"golamv2/internal/domain"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// SlowPrefix keeps the fetches slower than --slow-threshold in the URLs database:
+// slow:<UTC time>|<url>, a page fetched slowly again keeps an entry each
+const SlowPrefix = "slow:"
+
+// StoreSlowRequest keeps a fetch that took longer than the slow threshold
+func (s *BadgerStorage) StoreSlowRequest(request domain.SlowRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slow request: %v", err)
+	}
+
+	key := s.key(SlowPrefix + request.FetchedAt.UTC().Format(timeIndexLayout) + "|" + request.URL)
+	return s.update(s.urlDB, func(txn *badger.Txn) error {
+		return txn.Set(key, data)
+	})
+}
+
+// GetSlowRequests returns up to limit slow fetches, slowest first
+func (s *BadgerStorage) GetSlowRequests(limit int) ([]domain.SlowRequest, error) {
+	var requests []domain.SlowRequest
+	err := s.urlDB.View(func(txn *badger.Txn) error {
+		var err error
+		requests, err = ReadSlowRequests(txn, s.keyPrefix, limit)
+		return err
+	})
+	return requests, err
+}
+
+// ReadSlowRequests reads up to limit slow fetches of a namespace, slowest first. Only the
+// limit slowest are held while reading, all of them with no limit. Also used by the explorer
+func ReadSlowRequests(txn *badger.Txn, namespacePrefix string, limit int) ([]domain.SlowRequest, error) {
+	prefix := []byte(namespacePrefix + SlowPrefix)
+	iterator := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+	defer iterator.Close()
+
+	slowest := &slowHeap{}
+	for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+		var request domain.SlowRequest
+		err := iterator.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, &request)
+		})
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case limit <= 0 || slowest.Len() < limit:
+			heap.Push(slowest, request)
+		case request.Duration > (*slowest)[0].Duration:
+			(*slowest)[0] = request
+			heap.Fix(slowest, 0)
+		}
+	}
+
+	// Popping the min-heap yields the fastest first
+	requests := make([]domain.SlowRequest, slowest.Len())
+	for i := len(requests) - 1; i >= 0; i-- {
+		requests[i] = heap.Pop(slowest).(domain.SlowRequest)
+	}
+	return requests, nil
+}
+
+// slowHeap is a min-heap of slow fetches by duration, its top is the fastest one kept
+type slowHeap []domain.SlowRequest
+
+func (h slowHeap) Len() int           { return len(h) }
+func (h slowHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h slowHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *slowHeap) Push(x any)        { *h = append(*h, x.(domain.SlowRequest)) }
+func (h *slowHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
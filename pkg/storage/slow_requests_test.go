@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"golamv2/internal/domain"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func TestReadSlowRequests(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Stored in fetch order, not by duration
+	durations := []int{3, 9, 1, 7, 5, 8}
+	err = db.Update(func(txn *badger.Txn) error {
+		for i, seconds := range durations {
+			data, _ := json.Marshal(domain.SlowRequest{URL: fmt.Sprintf("https://example.com/%d", i), Duration: time.Duration(seconds) * time.Second})
+			if err := txn.Set([]byte(fmt.Sprintf("%s%d", SlowPrefix, i)), data); err != nil {
+				return err
+			}
+		}
+		return txn.Set([]byte("other:"+SlowPrefix+"x"), []byte(`{"duration":99000000000}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		limit int
+		want  []int
+	}{
+		{3, []int{9, 8, 7}},
+		{1, []int{9}},
+		{0, []int{9, 8, 7, 5, 3, 1}},
+		{10, []int{9, 8, 7, 5, 3, 1}},
+	}
+	for _, tt := range tests {
+		var requests []domain.SlowRequest
+		db.View(func(txn *badger.Txn) error {
+			requests, err = ReadSlowRequests(txn, "", tt.limit)
+			return err
+		})
+		var got []int
+		for _, request := range requests {
+			got = append(got, int(request.Duration/time.Second))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("limit %d: got %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
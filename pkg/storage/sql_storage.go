@@ -0,0 +1,644 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golamv2/internal/domain"
+
+	_ "github.com/lib/pq" // postgres driver, sqlite is in sqlite_cgo.go
+)
+
+// Drivers of NewSQLStorage
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// ErrSQLiteUnavailable is returned for sqlite by builds without cgo, the driver needs it
+var ErrSQLiteUnavailable = errors.New("sqlite needs a build with CGO_ENABLED=1, this one has none: use --storage badger or postgres")
+
+// sqlDialect is what differs between the SQL databases
+type sqlDialect struct {
+	serial    string // Auto-incremented primary key column
+	timestamp string
+	forUpdate string // Locks the rows read by a merge
+	numbered  bool   // $1 placeholders instead of ?
+	name      string
+	size      string // Query of the bytes the database takes
+}
+
+var sqlDialects = map[string]sqlDialect{
+	DriverSQLite: {
+		serial: "INTEGER PRIMARY KEY", timestamp: "TIMESTAMP",
+		name: "sqlite", size: "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()",
+	},
+	DriverPostgres: {
+		serial: "BIGSERIAL PRIMARY KEY", timestamp: "TIMESTAMPTZ", forUpdate: " FOR UPDATE", numbered: true,
+		name: "postgres", size: "SELECT pg_database_size(current_database())",
+	},
+}
+
+// sqlTables are the tables CollectionStats counts the rows of
+var sqlTables = []string{"urls", "results", "emails", "keywords", "dead_links", "dead_domains"}
+
+// sqlMigrations build the schema, each runs once in its own transaction and the version
+// applied last is kept in schema_migrations. New ones are appended, never edited.
+// {serial} and {timestamp} are replaced by the column types of the database
+var sqlMigrations = []string{
+	`CREATE TABLE urls (
+	id   {serial},
+	url  TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE results (
+	id             {serial},
+	url            TEXT NOT NULL,
+	domain         TEXT NOT NULL,
+	status_code    INTEGER NOT NULL,
+	title          TEXT,
+	description    TEXT,
+	error          TEXT,
+	content_length BIGINT NOT NULL,
+	content_hash   TEXT,
+	processed_at   {timestamp} NOT NULL,
+	data           TEXT NOT NULL
+);
+CREATE TABLE emails (
+	result_id BIGINT NOT NULL REFERENCES results(id),
+	email     TEXT NOT NULL
+);
+CREATE TABLE keywords (
+	result_id BIGINT NOT NULL REFERENCES results(id),
+	keyword   TEXT NOT NULL,
+	count     INTEGER NOT NULL
+);
+CREATE TABLE dead_links (
+	result_id   BIGINT NOT NULL REFERENCES results(id),
+	url         TEXT NOT NULL,
+	status_code INTEGER NOT NULL,
+	error       TEXT
+);
+CREATE TABLE dead_domains (
+	result_id BIGINT NOT NULL REFERENCES results(id),
+	domain    TEXT NOT NULL,
+	cause     TEXT,
+	error     TEXT
+);
+CREATE TABLE metrics (
+	id   INTEGER PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE INDEX idx_results_url ON results(url);
+CREATE INDEX idx_results_domain ON results(domain);
+CREATE INDEX idx_results_content_hash ON results(content_hash);
+CREATE INDEX idx_emails_email ON emails(email);
+CREATE INDEX idx_emails_result ON emails(result_id);
+CREATE INDEX idx_keywords_keyword ON keywords(keyword);
+CREATE INDEX idx_keywords_result ON keywords(result_id);
+CREATE INDEX idx_dead_links_url ON dead_links(url);
+CREATE INDEX idx_dead_links_result ON dead_links(result_id);
+CREATE INDEX idx_dead_domains_domain ON dead_domains(domain);
+CREATE INDEX idx_dead_domains_result ON dead_domains(result_id);`,
+	`CREATE INDEX idx_results_processed_at ON results(processed_at);`,
+}
+
+// SQLStorage implements domain.Storage on SQLite or PostgreSQL. Results are spread over
+// tables per finding so they can be queried with plain SQL, the whole result is kept as
+// JSON in results.data for GetResults
+type SQLStorage struct {
+	db      *sql.DB
+	dialect sqlDialect
+	metrics *domain.CrawlMetrics
+	// Writes hold a read lock, Close waits for them
+	closeMu sync.RWMutex
+	closed  bool
+	// Copies every stored result to the result sinks, optional
+	publish func(result domain.CrawlResult)
+}
+
+// NewSQLStorage opens the database of dsn with driver, DriverSQLite or DriverPostgres, and
+// migrates its schema to the latest version
+func NewSQLStorage(driver, dsn string) (*SQLStorage, error) {
+	dialect, ok := sqlDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown SQL driver %q", driver)
+	}
+	if driver == DriverSQLite && !SQLiteAvailable {
+		return nil, ErrSQLiteUnavailable
+	}
+
+	if driver == DriverSQLite && !strings.Contains(dsn, "?") {
+		// Workers write at once, they wait for each other instead of failing
+		dsn += "?_busy_timeout=5000&_journal_mode=WAL"
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %v", driver, err)
+	}
+	if driver == DriverSQLite {
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %v", driver, err)
+	}
+
+	s := &SQLStorage{
+		db:      db,
+		dialect: dialect,
+		metrics: &domain.CrawlMetrics{
+			StartTime:      time.Now(),
+			LastUpdateTime: time.Now(),
+		},
+	}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.loadMetrics()
+	return s, nil
+}
+
+// query rewrites the ? placeholders of a query for the database
+func (s *SQLStorage) query(query string) string {
+	if !s.dialect.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// migrate applies the migrations the database has not seen yet
+func (s *SQLStorage) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	var version int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+	if version > len(sqlMigrations) {
+		return fmt.Errorf("database schema version %d is newer than this build knows (%d)", version, len(sqlMigrations))
+	}
+
+	replacer := strings.NewReplacer("{serial}", s.dialect.serial, "{timestamp}", s.dialect.timestamp)
+	for i := version; i < len(sqlMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to start migration: %v", err)
+		}
+		if _, err := tx.Exec(replacer.Replace(sqlMigrations[i])); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %v", i+1, err)
+		}
+		if _, err := tx.Exec(s.query(`INSERT INTO schema_migrations (version) VALUES (?)`), i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %v", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// beginWrite keeps Close from closing the database under a write
+func (s *SQLStorage) beginWrite() error {
+	s.closeMu.RLock()
+	if s.closed {
+		s.closeMu.RUnlock()
+		return ErrStorageClosed
+	}
+	return nil
+}
+
+// SetResultPublisher sets where stored results are copied to, set it before the crawl starts
+func (s *SQLStorage) SetResultPublisher(publish func(result domain.CrawlResult)) {
+	s.publish = publish
+}
+
+// StoreURL stores a URL task for GetURLs
+func (s *SQLStorage) StoreURL(task domain.URLTask) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.closeMu.RUnlock()
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal URL task: %v", err)
+	}
+	if _, err := s.db.Exec(s.query(`INSERT INTO urls (url, data) VALUES (?, ?)`), task.URL, string(data)); err != nil {
+		return fmt.Errorf("failed to store URL: %v", err)
+	}
+	return nil
+}
+
+// GetURLs takes up to limit URLs out of the database, oldest first
+func (s *SQLStorage) GetURLs(limit int) ([]domain.URLTask, error) {
+	if err := s.beginWrite(); err != nil {
+		return nil, err
+	}
+	defer s.closeMu.RUnlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(s.query(`SELECT id, data FROM urls ORDER BY id LIMIT ?`+s.dialect.forUpdate), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read URLs: %v", err)
+	}
+	var ids []int64
+	var tasks []domain.URLTask
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read URLs: %v", err)
+		}
+		ids = append(ids, id)
+
+		var task domain.URLTask
+		if json.Unmarshal([]byte(data), &task) == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URLs: %v", err)
+	}
+
+	// Row by row, URLs inserted meanwhile may sit between the ones read
+	del, err := tx.Prepare(s.query(`DELETE FROM urls WHERE id = ?`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare URL delete: %v", err)
+	}
+	defer del.Close()
+	for _, id := range ids {
+		if _, err := del.Exec(id); err != nil {
+			return nil, fmt.Errorf("failed to delete URL: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit URLs: %v", err)
+	}
+	return tasks, nil
+}
+
+// StoreResult stores a crawl result and its findings
+func (s *SQLStorage) StoreResult(result domain.CrawlResult) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.closeMu.RUnlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.insertResult(tx, result); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit result: %v", err)
+	}
+
+	if s.publish != nil {
+		s.publish(result)
+	}
+	atomic.AddInt64(&s.metrics.URLsProcessed, 1)
+	atomic.AddInt64(&s.metrics.EmailsFound, int64(len(result.Emails)))
+	atomic.AddInt64(&s.metrics.KeywordsFound, int64(len(result.Keywords)))
+	atomic.AddInt64(&s.metrics.DeadLinksFound, int64(len(result.DeadLinks)))
+	atomic.AddInt64(&s.metrics.DeadDomainsFound, int64(len(result.DeadDomains)))
+	if result.Error != "" {
+		atomic.AddInt64(&s.metrics.Errors, 1)
+	}
+	return nil
+}
+
+// insertResult writes a result row and the rows of its findings
+func (s *SQLStorage) insertResult(tx *sql.Tx, result domain.CrawlResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	var id int64
+	err = tx.QueryRow(s.query(`INSERT INTO results (url, domain, status_code, title, description, error, content_length, content_hash, processed_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		result.URL, domain.GetDomain(result.URL), result.StatusCode, result.Title, result.Description, result.Error,
+		result.ContentLength, result.ContentHash, result.ProcessedAt.UTC(), string(data)).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to insert result %s: %v", result.URL, err)
+	}
+
+	for _, email := range result.Emails {
+		if _, err := tx.Exec(s.query(`INSERT INTO emails (result_id, email) VALUES (?, ?)`), id, email); err != nil {
+			return fmt.Errorf("failed to insert email: %v", err)
+		}
+	}
+	for keyword, count := range result.Keywords {
+		if _, err := tx.Exec(s.query(`INSERT INTO keywords (result_id, keyword, count) VALUES (?, ?, ?)`), id, keyword, count); err != nil {
+			return fmt.Errorf("failed to insert keyword: %v", err)
+		}
+	}
+	return s.insertDeadLinks(tx, id, result)
+}
+
+// insertDeadLinks writes the dead links and dead domains of the result with id
+func (s *SQLStorage) insertDeadLinks(tx *sql.Tx, id int64, result domain.CrawlResult) error {
+	links := make(map[string]domain.DeadLink, len(result.DeadLinkDetails))
+	for _, detail := range result.DeadLinkDetails {
+		links[detail.URL] = detail
+	}
+	for _, link := range result.DeadLinks {
+		detail := links[link]
+		_, err := tx.Exec(s.query(`INSERT INTO dead_links (result_id, url, status_code, error) VALUES (?, ?, ?, ?)`),
+			id, link, detail.StatusCode, detail.Error)
+		if err != nil {
+			return fmt.Errorf("failed to insert dead link: %v", err)
+		}
+	}
+
+	domains := make(map[string]domain.DeadDomain, len(result.DeadDomainDetails))
+	for _, detail := range result.DeadDomainDetails {
+		domains[detail.Domain] = detail
+	}
+	for _, deadDomain := range result.DeadDomains {
+		detail := domains[deadDomain]
+		_, err := tx.Exec(s.query(`INSERT INTO dead_domains (result_id, domain, cause, error) VALUES (?, ?, ?, ?)`),
+			id, deadDomain, string(detail.Cause), detail.Error)
+		if err != nil {
+			return fmt.Errorf("failed to insert dead domain: %v", err)
+		}
+	}
+	return nil
+}
+
+// MergeResult adds the dead links of result to the latest result stored for its URL,
+// result is stored as is when there is none yet
+func (s *SQLStorage) MergeResult(result domain.CrawlResult) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.closeMu.RUnlock()
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var data string
+	err = tx.QueryRow(s.query(`SELECT id, data FROM results WHERE url = ? ORDER BY id DESC LIMIT 1`+s.dialect.forUpdate), result.URL).Scan(&id, &data)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		s.closeMu.RUnlock()
+		return s.StoreResult(result)
+	}
+	defer s.closeMu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to read result: %v", err)
+	}
+
+	var stored domain.CrawlResult
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return fmt.Errorf("failed to unmarshal result: %v", err)
+	}
+	links, domains := stored.MergeDeadLinks(result)
+	merged, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	if _, err := tx.Exec(s.query(`UPDATE results SET data = ? WHERE id = ?`), string(merged), id); err != nil {
+		return fmt.Errorf("failed to merge result: %v", err)
+	}
+	for _, table := range []string{"dead_links", "dead_domains"} {
+		if _, err := tx.Exec(s.query(`DELETE FROM `+table+` WHERE result_id = ?`), id); err != nil {
+			return fmt.Errorf("failed to merge result: %v", err)
+		}
+	}
+	if err := s.insertDeadLinks(tx, id, stored); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit result: %v", err)
+	}
+
+	// Sinks and events get the dead links found, not the whole merged result
+	if s.publish != nil {
+		s.publish(result)
+	}
+	atomic.AddInt64(&s.metrics.DeadLinksFound, int64(links))
+	atomic.AddInt64(&s.metrics.DeadDomainsFound, int64(domains))
+	return nil
+}
+
+// GetResults returns up to limit results after the id in cursor, oldest first. The cursor
+// of the next page is the id of the last result returned
+func (s *SQLStorage) GetResults(mode domain.CrawlMode, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	return s.readResults("", nil, cursor, limit, nil)
+}
+
+// SearchResults returns up to limit results matching query and filters after cursor. The
+// status and type filters are left to the database, the rest is matched on the rows read
+func (s *SQLStorage) SearchResults(query string, filters domain.ResultFilters, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	var where []string
+	var args []any
+	if class, ok := strings.CutSuffix(filters.Status, "xx"); ok {
+		low := int(class[0]-'0') * 100
+		where = append(where, `status_code BETWEEN ? AND ?`)
+		args = append(args, low, low+99)
+	} else if filters.Status != "" {
+		code, _ := strconv.Atoi(filters.Status)
+		where = append(where, `status_code = ?`)
+		args = append(args, code)
+	}
+	if table, ok := sqlTypeTables[filters.Type]; ok {
+		where = append(where, `EXISTS (SELECT 1 FROM `+table+` WHERE result_id = results.id)`)
+	} else if filters.Type == "error" {
+		where = append(where, `error <> ''`)
+	}
+
+	var condition string
+	for _, clause := range where {
+		condition += ` AND ` + clause
+	}
+	return s.readResults(condition, args, cursor, limit, func(result domain.CrawlResult) bool {
+		return domain.MatchesResult(result, query, filters)
+	})
+}
+
+// GetResultsByTimeRange returns up to limit results processed within r after cursor
+func (s *SQLStorage) GetResultsByTimeRange(r domain.TimeRange, cursor string, limit int) ([]domain.CrawlResult, string, error) {
+	var condition string
+	var args []any
+	if !r.From.IsZero() {
+		condition += ` AND processed_at >= ?`
+		args = append(args, r.From.UTC())
+	}
+	if !r.To.IsZero() {
+		condition += ` AND processed_at < ?`
+		args = append(args, r.To.UTC())
+	}
+	return s.readResults(condition, args, cursor, limit, nil)
+}
+
+// sqlTypeTables are the tables of the findings of a type: filter
+var sqlTypeTables = map[string]string{
+	"email":       "emails",
+	"keyword":     "keywords",
+	"dead_link":   "dead_links",
+	"dead_domain": "dead_domains",
+}
+
+// readResults returns up to limit results after the id in cursor matching condition, those
+// keep accepts when it is set
+func (s *SQLStorage) readResults(condition string, args []any, cursor string, limit int, keep func(result domain.CrawlResult) bool) ([]domain.CrawlResult, string, error) {
+	var after int64
+	if cursor != "" {
+		var err error
+		if after, err = strconv.ParseInt(cursor, 10, 64); err != nil || after < 0 {
+			return nil, "", domain.ErrInvalidCursor
+		}
+	}
+
+	query := `SELECT id, data FROM results WHERE id > ?` + condition + ` ORDER BY id`
+	args = append([]any{after}, args...)
+	if keep == nil {
+		// One row more tells whether there is a next page
+		query += ` LIMIT ?`
+		args = append(args, limit+1)
+	}
+	rows, err := s.db.Query(s.query(query), args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read results: %v", err)
+	}
+	defer rows.Close()
+
+	var results []domain.CrawlResult
+	var next string
+	var id int64
+	for rows.Next() {
+		// More rows follow the page, they may still be filtered out
+		if len(results) == limit {
+			next = strconv.FormatInt(after, 10)
+			break
+		}
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, "", fmt.Errorf("failed to read results: %v", err)
+		}
+		var result domain.CrawlResult
+		if json.Unmarshal([]byte(data), &result) == nil && (keep == nil || keep(result)) {
+			results = append(results, result)
+			after = id
+		}
+	}
+	return results, next, rows.Err()
+}
+
+// GetMetrics returns the metrics with the URLs waiting in the database counted
+func (s *SQLStorage) GetMetrics() (*domain.CrawlMetrics, error) {
+	var queued int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM urls`).Scan(&queued); err != nil {
+		return nil, fmt.Errorf("failed to count URLs: %v", err)
+	}
+
+	s.metrics.URLsInDB = queued
+	s.metrics.LastUpdateTime = time.Now()
+	return s.metrics, nil
+}
+
+// CollectionStats returns the size of the database and the rows of every table
+func (s *SQLStorage) CollectionStats() ([]domain.DatabaseStats, error) {
+	stats := domain.DatabaseStats{Name: s.dialect.name, Collections: make(map[string]int64)}
+	if err := s.db.QueryRow(s.dialect.size).Scan(&stats.LSMBytes); err != nil {
+		return nil, fmt.Errorf("failed to read database size: %v", err)
+	}
+
+	for _, table := range sqlTables {
+		var rows int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&rows); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %v", table, err)
+		}
+		stats.Collections[table] = rows
+	}
+	return []domain.DatabaseStats{stats}, nil
+}
+
+// UpdateMetrics replaces the metrics and saves them
+func (s *SQLStorage) UpdateMetrics(metrics *domain.CrawlMetrics) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.closeMu.RUnlock()
+
+	s.metrics = metrics
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %v", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM metrics`); err != nil {
+		return fmt.Errorf("failed to save metrics: %v", err)
+	}
+	if _, err := tx.Exec(s.query(`INSERT INTO metrics (id, data) VALUES (1, ?)`), string(data)); err != nil {
+		return fmt.Errorf("failed to save metrics: %v", err)
+	}
+	return tx.Commit()
+}
+
+// loadMetrics restores the metrics saved by an earlier run
+func (s *SQLStorage) loadMetrics() {
+	var data string
+	if s.db.QueryRow(`SELECT data FROM metrics WHERE id = 1`).Scan(&data) == nil {
+		json.Unmarshal([]byte(data), s.metrics)
+	}
+}
+
+// Close waits for the writes in flight and closes the database
+func (s *SQLStorage) Close() error {
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.db.Close()
+}
@@ -0,0 +1,8 @@
+//go:build cgo
+
+package storage
+
+import _ "github.com/mattn/go-sqlite3" // sqlite driver, needs cgo
+
+// SQLiteAvailable reports whether this build has the sqlite driver, it needs CGO_ENABLED=1
+const SQLiteAvailable = true
@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package storage
+
+// SQLiteAvailable reports whether this build has the sqlite driver, it needs CGO_ENABLED=1
+const SQLiteAvailable = false
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golamv2/internal/domain"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// namedDB is a database with the name of its directory
+type namedDB struct {
+	name string
+	db   *badger.DB
+}
+
+// CollectionStats returns the size of every database with its keys counted per collection,
+// the key prefix up to ':'. Namespace views count their own keys, the sizes are shared
+func (s *BadgerStorage) CollectionStats() ([]domain.DatabaseStats, error) {
+	owner := s.owner()
+	databases := []namedDB{
+		{"urls", owner.urlDB},
+		{resultsDBName(owner.mode), owner.resultsDB},
+	}
+	owner.archiveMu.Lock()
+	if owner.archiveDB != nil {
+		databases = append(databases, namedDB{archiveDBName, owner.archiveDB})
+	}
+	owner.archiveMu.Unlock()
+
+	stats := make([]domain.DatabaseStats, 0, len(databases))
+	for _, database := range databases {
+		dbStats, err := s.databaseStats(database.name, database.db)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, dbStats)
+	}
+	return stats, nil
+}
+
+// databaseStats measures a database and counts its keys. The tables are measured as they
+// are, the value log as Badger last sized it, it does so every minute
+func (s *BadgerStorage) databaseStats(name string, db *badger.DB) (domain.DatabaseStats, error) {
+	_, vlog := db.Size()
+	tables := db.Tables()
+	stats := domain.DatabaseStats{
+		Name:        name,
+		VLogBytes:   vlog,
+		Tables:      len(tables),
+		Collections: make(map[string]int64),
+	}
+	for _, table := range tables {
+		stats.LSMBytes += int64(table.OnDiskSize)
+		stats.TableKeys += int64(table.KeyCount)
+	}
+
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false // Only count keys
+		opts.Prefix = []byte(s.keyPrefix)
+		iterator := txn.NewIterator(opts)
+		defer iterator.Close()
+
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			key := string(iterator.Item().Key()[len(s.keyPrefix):])
+			// The default namespace leaves the keys of the others out
+			if s.keyPrefix == "" && strings.HasPrefix(key, NamespacePrefix) {
+				continue
+			}
+			collection, _, _ := strings.Cut(key, ":")
+			stats.Collections[collection]++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// CollectionStats returns the sizes of the results and URLs files, with the results stored
+// by this run and the URLs waiting in the file
+func (s *FastFileStorage) CollectionStats() ([]domain.DatabaseStats, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+	if err := s.writer.Flush(); err != nil {
+		return nil, err
+	}
+	if err := s.urlWriter.Flush(); err != nil {
+		return nil, err
+	}
+
+	var stats []domain.DatabaseStats
+	for _, file := range []struct {
+		name  string
+		count int64
+	}{
+		{ResultsFileName, s.metrics.URLsProcessed},
+		{URLsFileName, s.urlsQueued},
+	} {
+		info, err := os.Stat(filepath.Join(s.dataDir, file.name))
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, domain.DatabaseStats{
+			Name:        file.name,
+			LSMBytes:    info.Size(),
+			Collections: map[string]int64{strings.TrimSuffix(strings.TrimPrefix(file.name, "crawl_"), ".jsonl"): file.count},
+		})
+	}
+	return stats, nil
+}
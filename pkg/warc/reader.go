@@ -0,0 +1,154 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golamv2/internal/domain"
+	"golamv2/pkg/logging"
+)
+
+// ReadDir calls fn for the HTTP response records of the .warc.gz and .warc files in dir, in
+// name order, until it returns an error. Files of other tools are read too
+func ReadDir(dir string, fn func(page domain.ArchivedPage) error) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read WARC directory: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && (strings.HasSuffix(entry.Name(), FileExtension) || strings.HasSuffix(entry.Name(), ".warc")) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := ReadFile(filepath.Join(dir, name), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFile calls fn for the HTTP response records of one WARC file. A record cut short at
+// the end, like the last one of a crawl that was killed, ends the file with a warning
+func ReadFile(path string, fn func(page domain.ArchivedPage) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open WARC file: %v", err)
+	}
+	defer file.Close()
+
+	var source io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		decompressor, err := gzip.NewReader(file)
+		if err == io.EOF {
+			return nil // Created but nothing written yet
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		defer decompressor.Close()
+		source = decompressor
+	}
+
+	reader := bufio.NewReader(source)
+	for {
+		page, err := readRecord(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			logging.Warnf("%s ends in a truncated record, the rest of it is skipped", path)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if page == nil {
+			continue // Not an HTTP response
+		}
+		if err := fn(*page); err != nil {
+			return err
+		}
+	}
+}
+
+// readRecord reads the next record, it returns a nil page for records other than responses
+func readRecord(reader *bufio.Reader) (*domain.ArchivedPage, error) {
+	// Records are separated by blank lines
+	var version string
+	for version == "" {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		version = strings.TrimSpace(line)
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("not a WARC record: %q", version)
+	}
+
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	length, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid WARC Content-Length %q", headers.Get("Content-Length"))
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(reader, block); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if headers.Get("WARC-Type") != "response" || !strings.HasPrefix(headers.Get("Content-Type"), "application/http") {
+		return nil, nil
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil {
+		return nil, nil // A response that is not HTTP is no page
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		if body, err = gzip.NewReader(resp.Body); err != nil {
+			return nil, nil
+		}
+	}
+	content, err := io.ReadAll(body)
+	if err != nil && len(content) == 0 {
+		return nil, nil
+	}
+
+	fetchedAt, _ := time.Parse(time.RFC3339Nano, headers.Get("WARC-Date"))
+	return &domain.ArchivedPage{
+		URL:         strings.Trim(headers.Get("WARC-Target-URI"), "<>"), // WARC 1.0 wrote it in brackets
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(content),
+		FetchedAt:   fetchedAt,
+	}, nil
+}
@@ -0,0 +1,197 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golamv2/internal/domain"
+)
+
+const (
+	// MaxFileSize is the size a WARC file is closed at, the next record starts a new one
+	MaxFileSize = 1 << 30
+
+	// FileExtension of the files written, every record is its own gzip member so tools can
+	// seek straight to one
+	FileExtension = ".warc.gz"
+
+	// dateLayout is the WARC-Date format, UTC to the second
+	dateLayout = "2006-01-02T15:04:05Z"
+)
+
+// ErrArchiveClosed is returned for pages arriving after Close
+var ErrArchiveClosed = errors.New("WARC archive is closed")
+
+// Archive keeps fetched pages as response records of WARC 1.1 files in a directory. Every
+// run starts a new file, so files of earlier runs are never appended to
+type Archive struct {
+	dir string
+
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+	serial     int
+	started    string // Timestamp in the names of this run's files
+	warcinfoID string // Record id of the warcinfo of the current file
+
+	// Set by Close, a late page must not start a new file
+	closed bool
+}
+
+// NewArchive creates an archive writing to dir, the first file is created with the first page
+func NewArchive(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create WARC directory: %v", err)
+	}
+	return &Archive{dir: dir, started: time.Now().UTC().Format("20060102150405")}, nil
+}
+
+// ArchivePage appends a page as a response record, its HTTP block rebuilt from the status,
+// content type and the body as received
+func (a *Archive) ArchivePage(page domain.ArchivedPage) error {
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "HTTP/1.1 %d %s\r\n", page.StatusCode, http.StatusText(page.StatusCode))
+	if page.ContentType != "" {
+		fmt.Fprintf(&block, "Content-Type: %s\r\n", page.ContentType)
+	}
+	fmt.Fprintf(&block, "Content-Length: %d\r\n\r\n", len(page.Body))
+	block.WriteString(page.Body)
+
+	fetchedAt := page.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return ErrArchiveClosed
+	}
+	if a.file == nil || a.size >= MaxFileSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+
+	headers := []string{
+		"WARC-Type: response",
+		"WARC-Record-ID: " + recordID(),
+		"WARC-Warcinfo-ID: " + a.warcinfoID,
+		"WARC-Date: " + fetchedAt.UTC().Format(dateLayout),
+		"WARC-Target-URI: " + page.URL,
+		"WARC-Payload-Digest: " + digest([]byte(page.Body)),
+		"WARC-Block-Digest: " + digest(block.Bytes()),
+		"Content-Type: application/http;msgtype=response",
+	}
+	return a.writeRecord(headers, block.Bytes())
+}
+
+// rotate closes the current file and starts the next one with its warcinfo record
+func (a *Archive) rotate() error {
+	if a.file != nil {
+		if err := a.file.Close(); err != nil {
+			return fmt.Errorf("failed to close WARC file: %v", err)
+		}
+		a.file = nil
+	}
+
+	name := fmt.Sprintf("golamv2-%s-%05d%s", a.started, a.serial, FileExtension)
+	file, err := os.OpenFile(filepath.Join(a.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create WARC file: %v", err)
+	}
+	a.file, a.size = file, 0
+	a.serial++
+
+	block := []byte("software: golamv2\r\n" +
+		"format: WARC File Format 1.1\r\n" +
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n")
+	a.warcinfoID = recordID()
+	headers := []string{
+		"WARC-Type: warcinfo",
+		"WARC-Record-ID: " + a.warcinfoID,
+		"WARC-Date: " + time.Now().UTC().Format(dateLayout),
+		"WARC-Filename: " + name,
+		"Content-Type: application/warc-fields",
+	}
+	return a.writeRecord(headers, block)
+}
+
+// writeRecord compresses a record into a gzip member of its own and appends it
+func (a *Archive) writeRecord(headers []string, block []byte) error {
+	var record bytes.Buffer
+	compressor := gzip.NewWriter(&record)
+	fmt.Fprintf(compressor, "WARC/1.1\r\n%s\r\nContent-Length: %d\r\n\r\n", strings.Join(headers, "\r\n"), len(block))
+	compressor.Write(block)
+	compressor.Write([]byte("\r\n\r\n"))
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("failed to compress WARC record: %v", err)
+	}
+
+	n, err := a.file.Write(record.Bytes())
+	a.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write WARC record: %v", err)
+	}
+	return nil
+}
+
+// GetArchivedPage returns the newest record of a page, or nil if it was never archived. It
+// reads every file, use ForEachArchivedPage for more than a few pages
+func (a *Archive) GetArchivedPage(url string) (*domain.ArchivedPage, error) {
+	var found *domain.ArchivedPage
+	err := a.ForEachArchivedPage(func(page domain.ArchivedPage) error {
+		if page.URL == url {
+			found = &page
+		}
+		return nil
+	})
+	return found, err
+}
+
+// ForEachArchivedPage calls fn for the response records of every file in the directory, in
+// the order they were written
+func (a *Archive) ForEachArchivedPage(fn func(page domain.ArchivedPage) error) error {
+	return ReadDir(a.dir, fn)
+}
+
+// Close closes the current file once the page being written is done, later pages are refused
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closed = true
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// recordID returns a new random urn:uuid record id
+func recordID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // Version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+// digest returns the sha1 digest of data the way WARC headers write it
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}